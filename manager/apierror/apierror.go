@@ -0,0 +1,206 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code is the stable, machine readable kind of an error. Clients should switch
+// on it rather than on the message or the HTTP status.
+type Code string
+
+const (
+	InvalidArgument    Code = "INVALID_ARGUMENT"
+	Unauthenticated    Code = "UNAUTHENTICATED"
+	PermissionDenied   Code = "PERMISSION_DENIED"
+	NotFound           Code = "NOT_FOUND"
+	MethodNotAllowed   Code = "METHOD_NOT_ALLOWED"
+	AlreadyExists      Code = "ALREADY_EXISTS"
+	FailedPrecondition Code = "FAILED_PRECONDITION"
+	ResourceExhausted  Code = "RESOURCE_EXHAUSTED"
+	Cancelled          Code = "CANCELLED"
+	Internal           Code = "INTERNAL"
+	Unimplemented      Code = "UNIMPLEMENTED"
+	Upstream           Code = "UPSTREAM_ERROR"
+	Unavailable        Code = "UNAVAILABLE"
+	DeadlineExceeded   Code = "DEADLINE_EXCEEDED"
+)
+
+// StatusClientClosedRequest is the non standard status used when the caller
+// went away before the answer was ready.
+const StatusClientClosedRequest = 499
+
+var statuses = map[Code]int{
+	InvalidArgument:    http.StatusBadRequest,
+	Unauthenticated:    http.StatusUnauthorized,
+	PermissionDenied:   http.StatusForbidden,
+	NotFound:           http.StatusNotFound,
+	MethodNotAllowed:   http.StatusMethodNotAllowed,
+	AlreadyExists:      http.StatusConflict,
+	FailedPrecondition: http.StatusUnprocessableEntity,
+	ResourceExhausted:  http.StatusTooManyRequests,
+	Cancelled:          StatusClientClosedRequest,
+	Internal:           http.StatusInternalServerError,
+	Unimplemented:      http.StatusNotImplemented,
+	Upstream:           http.StatusBadGateway,
+	Unavailable:        http.StatusServiceUnavailable,
+	DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// Error is an error with a code, and optionally the time after which the call
+// may succeed and details for the client. It wraps the error it was made from.
+type Error struct {
+	Code       Code
+	Message    string
+	RetryAfter time.Duration
+	Details    map[string]interface{}
+	err        error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap gives err a code, its message is kept.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Status is the HTTP status of the code.
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// WithRetryAfter returns a copy of e telling the client when to try again.
+func (e *Error) WithRetryAfter(retryAfter time.Duration) *Error {
+	result := *e
+	result.RetryAfter = retryAfter
+
+	return &result
+}
+
+// WithDetail returns a copy of e with one more detail.
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	result := *e
+	result.Details = make(map[string]interface{}, len(e.Details)+1)
+	for k, v := range e.Details {
+		result.Details[k] = v
+	}
+	result.Details[key] = value
+
+	return &result
+}
+
+// From classifies any error. Coded errors keep their code, with the message of
+// the outermost error, context errors become timeouts and cancellations, gRPC
+// statuses are mapped by code and everything else is internal.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		result := *apiErr
+		result.Message = err.Error()
+		result.err = err
+
+		return &result
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(DeadlineExceeded, err)
+	case errors.Is(err, context.Canceled):
+		return Wrap(Cancelled, err)
+	}
+
+	if grpcStatus, ok := status.FromError(err); ok && grpcStatus.Code() != codes.Unknown {
+		result := GRPC(grpcStatus.Code(), err.Error())
+		result.err = err
+
+		return result
+	}
+
+	return Wrap(Internal, err)
+}
+
+// GRPC maps a gRPC status code.
+func GRPC(code codes.Code, message string) *Error {
+	switch code {
+	case codes.OK:
+		return New(Internal, message)
+	case codes.InvalidArgument, codes.OutOfRange:
+		return New(InvalidArgument, message)
+	case codes.Unauthenticated:
+		return New(Unauthenticated, message)
+	case codes.PermissionDenied:
+		return New(PermissionDenied, message)
+	case codes.NotFound:
+		return New(NotFound, message)
+	case codes.AlreadyExists, codes.Aborted:
+		return New(AlreadyExists, message)
+	case codes.FailedPrecondition:
+		return New(FailedPrecondition, message)
+	case codes.ResourceExhausted:
+		return New(ResourceExhausted, message)
+	case codes.Canceled:
+		return New(Cancelled, message)
+	case codes.Unimplemented:
+		return New(Unimplemented, message)
+	case codes.Unavailable:
+		return New(Unavailable, message)
+	case codes.DeadlineExceeded:
+		return New(DeadlineExceeded, message)
+	}
+
+	return New(Upstream, message)
+}
+
+// TendermintRPC maps a Tendermint JSON-RPC error. Tendermint reports missing
+// heights, blocks and txs as internal errors, only the text tells them apart.
+func TendermintRPC(code int, message, data string) *Error {
+	text := message
+	if data != "" {
+		text = data
+	}
+
+	switch code {
+	case -32700, -32600, -32602:
+		return New(InvalidArgument, text)
+	case -32601:
+		return New(NotFound, text)
+	}
+
+	lower := strings.ToLower(text)
+	switch {
+	case strings.Contains(lower, "not found"):
+		return New(NotFound, text)
+	case strings.Contains(lower, "must be less than or equal to the current blockchain height"),
+		strings.Contains(lower, "is not available, lowest height is"):
+		return New(NotFound, text)
+	case strings.Contains(lower, "invalid"), strings.Contains(lower, "must be"):
+		return New(InvalidArgument, text)
+	}
+
+	return New(Upstream, text)
+}
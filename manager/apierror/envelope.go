@@ -0,0 +1,38 @@
+package apierror
+
+import "math"
+
+// Envelope is the body of every failed request, the proxy and the P2P load
+// balancer pass it through unchanged.
+type Envelope struct {
+	Error Body `json:"error"`
+}
+
+type Body struct {
+	Code       Code                   `json:"code"`
+	Status     int                    `json:"status"`
+	Message    string                 `json:"message"`
+	RetryAfter int64                  `json:"retry_after,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	TraceID    string                 `json:"trace_id,omitempty"`
+}
+
+// Response classifies err and returns the envelope with its HTTP status.
+// RetryAfter is rounded up to whole seconds.
+func Response(err error, traceID string) (*Envelope, int) {
+	apiErr := From(err)
+
+	body := Body{
+		Code:    apiErr.Code,
+		Status:  apiErr.Status(),
+		Message: apiErr.Message,
+		Details: apiErr.Details,
+		TraceID: traceID,
+	}
+
+	if apiErr.RetryAfter > 0 {
+		body.RetryAfter = int64(math.Ceil(apiErr.RetryAfter.Seconds()))
+	}
+
+	return &Envelope{Error: body}, body.Status
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+)
+
+const (
+	SchemeADR036 = "adr036"
+
+	accountPrefix = "kira"
+)
+
+// adr036SignDoc is the amino JSON sign doc of an ADR-036 MsgSignData, fields in
+// the sorted order wallets sign them.
+type adr036SignDoc struct {
+	AccountNumber string      `json:"account_number"`
+	ChainID       string      `json:"chain_id"`
+	Fee           adr036Fee   `json:"fee"`
+	Memo          string      `json:"memo"`
+	Msgs          []adr036Msg `json:"msgs"`
+	Sequence      string      `json:"sequence"`
+}
+
+type adr036Fee struct {
+	Amount []struct{} `json:"amount"`
+	Gas    string     `json:"gas"`
+}
+
+type adr036Msg struct {
+	Type  string         `json:"type"`
+	Value adr036SignData `json:"value"`
+}
+
+type adr036SignData struct {
+	Data   string `json:"data"`
+	Signer string `json:"signer"`
+}
+
+// verifyADR036 checks the off-chain signature of message by the kira address,
+// as made by Keplr's signArbitrary: a secp256k1 signature of the sign doc by
+// the key whose public key is given, base64 encoded.
+func verifyADR036(address, message, signature, pubKey string) error {
+	hrp, addressBytes, err := bech32.DecodeAndConvert(address)
+	if err != nil || hrp != accountPrefix {
+		return apierror.Newf(apierror.InvalidArgument, "invalid kira address %s", address)
+	}
+
+	keyBytes, err := base64.StdEncoding.DecodeString(pubKey)
+	if err != nil || len(keyBytes) != secp256k1.PubKeySize {
+		return apierror.New(apierror.InvalidArgument, "pub_key must be a base64 compressed secp256k1 key")
+	}
+
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return apierror.New(apierror.InvalidArgument, "signature must be base64")
+	}
+
+	key := &secp256k1.PubKey{Key: keyBytes}
+	if !bytes.Equal(key.Address(), addressBytes) {
+		return apierror.New(apierror.Unauthenticated, "pub_key does not match the address")
+	}
+
+	signDoc, err := json.Marshal(adr036SignDoc{
+		AccountNumber: "0",
+		ChainID:       "",
+		Fee:           adr036Fee{Amount: []struct{}{}, Gas: "0"},
+		Memo:          "",
+		Msgs: []adr036Msg{{
+			Type: "sign/MsgSignData",
+			Value: adr036SignData{
+				Data:   base64.StdEncoding.EncodeToString([]byte(message)),
+				Signer: address,
+			},
+		}},
+		Sequence: "0",
+	})
+	if err != nil {
+		return err
+	}
+
+	if !key.VerifySignature(signDoc, signatureBytes) {
+		return ErrBadSignature
+	}
+
+	return nil
+}
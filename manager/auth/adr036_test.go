@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+// signArbitrary of adr036Message by the secp256k1 key made from the secret
+// "interx adr036 fixture", and the key of "interx other key"
+const (
+	adr036Address   = "kira1szkjafldq392qw4u5nh487ppe6glw60z73uhy8"
+	adr036PubKey    = "A5K+nOtheKRvbXuLU3kSq5UTf22PhbkGqJXjSsx0RNKY"
+	adr036Signature = "Y1pjYd28cjSPXf0f/35YCM9PpdUsR9l1y9xCHEI47o9A5LQj9wvqumHIRBem0fdIsl/YWq6XYUxshIGdjy08Yg=="
+	adr036Message   = "interx wants you to sign in with your account:\n" + adr036Address + "\n\nNonce: 0f1e2d3c4b5a6978\nIssued At: 2024-01-01T00:00:00Z\nExpiration Time: 2024-01-01T00:05:00Z"
+
+	adr036OtherAddress   = "kira1yf96a975kky3nyumxcxvj6ytnfk85mwmdc56qt"
+	adr036OtherPubKey    = "Avfvl01Cf8br9cMgRz0NZWQuQL1/ku0RQlYdgKGPyZ+T"
+	adr036OtherSignature = "ZpUYJETlOuQcMLSo4DB57Ru3WR0H4ga4gAj8PcWfHk8BZvXZCOrTpfdDH95ZJ5u28L+hPNw6J47t57bqLXJIhw=="
+)
+
+func TestVerifyADR036(t *testing.T) {
+	tests := []struct {
+		name      string
+		address   string
+		message   string
+		signature string
+		pubKey    string
+		valid     bool
+		err       error
+	}{
+		{name: "valid", address: adr036Address, message: adr036Message, signature: adr036Signature, pubKey: adr036PubKey, valid: true},
+		{name: "other message", address: adr036Address, message: adr036Message + " ", signature: adr036Signature, pubKey: adr036PubKey, err: ErrBadSignature},
+		{name: "signed by another key", address: adr036Address, message: adr036Message, signature: adr036OtherSignature, pubKey: adr036PubKey, err: ErrBadSignature},
+		{name: "wrong address", address: adr036OtherAddress, message: adr036Message, signature: adr036Signature, pubKey: adr036PubKey},
+		{name: "wrong pub_key", address: adr036Address, message: adr036Message, signature: adr036Signature, pubKey: adr036OtherPubKey},
+		{name: "not a kira address", address: "cosmos1szkjafldq392qw4u5nh487ppe6glw60z73uhy8", message: adr036Message, signature: adr036Signature, pubKey: adr036PubKey},
+		{name: "pub_key not base64", address: adr036Address, message: adr036Message, signature: adr036Signature, pubKey: "pub_key"},
+		{name: "signature not base64", address: adr036Address, message: adr036Message, signature: "!", pubKey: adr036PubKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyADR036(test.address, test.message, test.signature, test.pubKey)
+
+			switch {
+			case test.valid && err != nil:
+				t.Fatalf("got %v, want a valid signature", err)
+			case !test.valid && err == nil:
+				t.Fatal("got a valid signature")
+			case test.err != nil && !errors.Is(err, test.err):
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	defaultDomain              = "interx"
+	defaultChallengeCollection = "auth_challenges"
+	defaultSessionCollection   = "auth_sessions"
+	defaultChallengeTTL        = 5 * time.Minute
+	defaultSessionTTL          = time.Hour
+
+	sessionPrefix   = "ixs_"
+	sessionCacheTTL = time.Minute
+	cleanupInterval = time.Minute
+	// maxCachedSessions bounds the session cache, unknown tokens are not
+	// cached while it is full.
+	maxCachedSessions = 10000
+	// usedPrefix marks a used challenge in the challenge collection.
+	usedPrefix = "used:"
+)
+
+var (
+	ErrBadSignature     = apierror.New(apierror.Unauthenticated, "signature does not match the challenge")
+	ErrUnknownChallenge = apierror.New(apierror.Unauthenticated, "unknown or expired challenge")
+	ErrUnknownSession   = apierror.New(apierror.Unauthenticated, "unknown or expired session")
+	ErrDisabled         = apierror.New(apierror.Unimplemented, "wallet login is disabled")
+)
+
+type cachedSession struct {
+	session *types.AuthSession
+	expires time.Time
+}
+
+// Service logs wallets in: a challenge is issued for an address, the wallet
+// signs it, ADR-036 for kira addresses and EIP-191 for 0x ones, and the signed
+// challenge is exchanged once for a session token. Session lookups are cached
+// for a minute, unknown tokens included while the cache is not full, so a
+// logout takes up to that long on the other managers.
+type Service struct {
+	config  types.AuthConfig
+	storage types.Storage
+
+	mu    sync.Mutex
+	cache map[string]cachedSession
+}
+
+func New(config types.AuthConfig, storage types.Storage) *Service {
+	if config.Domain == "" {
+		config.Domain = defaultDomain
+	}
+	if config.ChallengeCollection == "" {
+		config.ChallengeCollection = defaultChallengeCollection
+	}
+	if config.SessionCollection == "" {
+		config.SessionCollection = defaultSessionCollection
+	}
+
+	return &Service{
+		config:  config,
+		storage: storage,
+		cache:   map[string]cachedSession{},
+	}
+}
+
+func (s *Service) Enabled() bool {
+	return s.config.Enabled
+}
+
+// Challenge issues the message address has to sign.
+func (s *Service) Challenge(address string) (*types.AuthChallenge, error) {
+	if !s.config.Enabled {
+		return nil, ErrDisabled
+	}
+
+	if _, err := scheme(address); err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	challenge := &types.AuthChallenge{
+		Nonce:     nonce,
+		Address:   address,
+		ExpiresAt: now.Add(ttl(s.config.ChallengeTTL, defaultChallengeTTL)),
+	}
+	challenge.Message = fmt.Sprintf("%s wants you to sign in with your account:\n%s\n\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		s.config.Domain, address, nonce, now.Format(time.RFC3339), challenge.ExpiresAt.Format(time.RFC3339))
+
+	_, err = s.storage.Upsert(s.config.ChallengeCollection, map[string]interface{}{"nonce": nonce}, map[string]interface{}{
+		"nonce":   nonce,
+		"address": address,
+		"message": challenge.Message,
+		"expires": challenge.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		logger.Logger.Error("Auth - Challenge", zap.Error(err))
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// Verify checks the signature of a challenge and opens a session. A challenge
+// is used once, whether the signature matches or not. pubKey is only needed
+// for kira addresses, EVM keys are recovered from the signature.
+func (s *Service) Verify(address, nonce, signature, pubKey string) (*types.AuthSession, error) {
+	if !s.config.Enabled {
+		return nil, ErrDisabled
+	}
+
+	response, err := s.storage.Read(s.config.ChallengeCollection, map[string]interface{}{"nonce": nonce}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("Auth - Verify", zap.Error(err))
+		return nil, err
+	}
+
+	if len(response.Result) == 0 {
+		return nil, ErrUnknownChallenge
+	}
+
+	challenge := response.Result[0]
+
+	// concurrent verifies of a nonce can all read it, only one marks it used
+	consumed, err := types.Consume(s.storage, s.config.ChallengeCollection, usedPrefix+nonce, cast.ToInt64(challenge["expires"]))
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrUnknownChallenge
+	}
+
+	if _, err := s.storage.Delete(s.config.ChallengeCollection, map[string]interface{}{"nonce": nonce}); err != nil {
+		logger.Logger.Error("Auth - Verify", zap.Error(err))
+	}
+
+	if cast.ToString(challenge["address"]) != address || time.Now().Unix() > cast.ToInt64(challenge["expires"]) {
+		return nil, ErrUnknownChallenge
+	}
+
+	kind, err := scheme(address)
+	if err != nil {
+		return nil, err
+	}
+
+	message := cast.ToString(challenge["message"])
+
+	switch kind {
+	case SchemeADR036:
+		err = verifyADR036(address, message, signature, pubKey)
+	case SchemeEIP191:
+		err = verifyEIP191(address, message, signature)
+		address = common.HexToAddress(address).Hex()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.open(address, kind)
+}
+
+// Session returns the session of a token.
+func (s *Service) Session(token string) (*types.AuthSession, error) {
+	if !s.config.Enabled {
+		return nil, ErrDisabled
+	}
+
+	hash := hashToken(token)
+
+	s.mu.Lock()
+	cached, ok := s.cache[hash]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		if cached.session == nil {
+			return nil, ErrUnknownSession
+		}
+		return cached.session, nil
+	}
+
+	response, err := s.storage.Read(s.config.SessionCollection, map[string]interface{}{"hash": hash}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("Auth - Session", zap.Error(err))
+		return nil, apierror.Wrap(apierror.Unavailable, err)
+	}
+
+	var session *types.AuthSession
+	cacheUntil := time.Now().Add(sessionCacheTTL)
+
+	if len(response.Result) > 0 {
+		document := response.Result[0]
+		session = &types.AuthSession{
+			Address:   cast.ToString(document["address"]),
+			Scheme:    cast.ToString(document["scheme"]),
+			ExpiresAt: time.Unix(cast.ToInt64(document["expires"]), 0).UTC(),
+		}
+
+		if time.Now().After(session.ExpiresAt) {
+			session = nil
+		} else if session.ExpiresAt.Before(cacheUntil) {
+			cacheUntil = session.ExpiresAt
+		}
+	}
+
+	s.mu.Lock()
+	if session != nil || len(s.cache) < maxCachedSessions {
+		s.cache[hash] = cachedSession{session: session, expires: cacheUntil}
+	}
+	s.mu.Unlock()
+
+	if session == nil {
+		return nil, ErrUnknownSession
+	}
+
+	return session, nil
+}
+
+// Logout ends the session of a token.
+func (s *Service) Logout(token string) error {
+	if !s.config.Enabled {
+		return ErrDisabled
+	}
+
+	hash := hashToken(token)
+
+	if _, err := s.storage.Delete(s.config.SessionCollection, map[string]interface{}{"hash": hash}); err != nil {
+		logger.Logger.Error("Auth - Logout", zap.Error(err))
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, hash)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// open stores a new session.
+func (s *Service) open(address, kind string) (*types.AuthSession, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &types.AuthSession{
+		Token:     sessionPrefix + secret,
+		Address:   address,
+		Scheme:    kind,
+		ExpiresAt: time.Now().UTC().Add(ttl(s.config.SessionTTL, defaultSessionTTL)),
+	}
+
+	hash := hashToken(session.Token)
+
+	_, err = s.storage.Upsert(s.config.SessionCollection, map[string]interface{}{"hash": hash}, map[string]interface{}{
+		"hash":    hash,
+		"address": session.Address,
+		"scheme":  session.Scheme,
+		"expires": session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		logger.Logger.Error("Auth - open", zap.Error(err))
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Run deletes the expired challenges and sessions, and drops the expired
+// session lookups, every minute until ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	if !s.config.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.cleanup(now)
+		}
+	}
+}
+
+func (s *Service) cleanup(now time.Time) {
+	expired := map[string]interface{}{"expires": map[string]interface{}{"$lt": now.Unix()}}
+	for _, collection := range []string{s.config.SessionCollection, s.config.ChallengeCollection} {
+		if _, err := s.storage.Delete(collection, expired); err != nil {
+			logger.Logger.Error("Auth - cleanup", zap.String("collection", collection), zap.Error(err))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, cached := range s.cache {
+		if !now.Before(cached.expires) {
+			delete(s.cache, hash)
+		}
+	}
+}
+
+// scheme tells how address signs: kira bech32 addresses with ADR-036, 0x
+// addresses with EIP-191.
+func scheme(address string) (string, error) {
+	switch {
+	case strings.HasPrefix(address, accountPrefix+"1"):
+		return SchemeADR036, nil
+	case common.IsHexAddress(address) && strings.HasPrefix(address, "0x"):
+		return SchemeEIP191, nil
+	}
+
+	return "", apierror.Newf(apierror.InvalidArgument, "unsupported address %s, expected kira1... or 0x...", address)
+}
+
+func ttl(seconds int, fallback time.Duration) time.Duration {
+	if seconds <= 0 {
+		return fallback
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		logger.Logger.Error("Auth - randomHex", zap.Error(err))
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	m.Run()
+}
+
+// authStorage keeps documents by collection and, like MongoDB, refuses a
+// second document with the same _id.
+type authStorage struct {
+	mu          sync.Mutex
+	collections map[string][]map[string]interface{}
+}
+
+func newAuthStorage() *authStorage {
+	return &authStorage{collections: map[string][]map[string]interface{}{}}
+}
+
+func (s *authStorage) Create(collection string, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, document := range cast.ToSlice(document) {
+		document := cast.ToStringMap(document)
+		if id, ok := document["_id"]; ok && s.find(collection, map[string]interface{}{"_id": id}) != nil {
+			return &adapter.SaiStorageResponse{Status: "NOK"}, nil
+		}
+		s.collections[collection] = append(s.collections[collection], document)
+	}
+
+	return &adapter.SaiStorageResponse{}, nil
+}
+
+func (s *authStorage) Read(collection string, criteria map[string]interface{}, options *adapter.Options, fields []string) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []map[string]interface{}
+	if document := s.find(collection, criteria); document != nil {
+		result = append(result, document)
+	}
+
+	return &adapter.SaiStorageResponse{Result: result, Count: len(result)}, nil
+}
+
+func (s *authStorage) Upsert(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.Delete(collection, criteria)
+	return s.Create(collection, []interface{}{document})
+}
+
+func (s *authStorage) Update(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{}, nil
+}
+
+func (s *authStorage) Delete(collection string, criteria map[string]interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.collections[collection][:0]
+	for _, document := range s.collections[collection] {
+		if !matchesAll(document, criteria) {
+			kept = append(kept, document)
+		}
+	}
+	s.collections[collection] = kept
+
+	return &adapter.SaiStorageResponse{}, nil
+}
+
+func (s *authStorage) find(collection string, criteria map[string]interface{}) map[string]interface{} {
+	for _, document := range s.collections[collection] {
+		if matchesAll(document, criteria) {
+			return document
+		}
+	}
+
+	return nil
+}
+
+// matchesAll understands equality only, the expiry selector of cleanup
+// matches nothing.
+func matchesAll(document, criteria map[string]interface{}) bool {
+	for key, value := range criteria {
+		if _, ok := value.(map[string]interface{}); ok || document[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// signEIP191 signs message with the key of eip191Address.
+func signEIP191(t *testing.T, message string) string {
+	t.Helper()
+
+	key, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatalf("HexToECDSA: %v", err)
+	}
+
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	return "0x" + hex.EncodeToString(signature)
+}
+
+func TestVerifyOnce(t *testing.T) {
+	s := New(types.AuthConfig{Enabled: true}, newAuthStorage())
+
+	challenge, err := s.Challenge(eip191Address)
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+
+	signature := signEIP191(t, challenge.Message)
+
+	const verifiers = 8
+	var wg sync.WaitGroup
+	sessions := make(chan *types.AuthSession, verifiers)
+	errs := make(chan error, verifiers)
+
+	for range verifiers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			session, err := s.Verify(eip191Address, challenge.Nonce, signature, "")
+			if err != nil {
+				errs <- err
+				return
+			}
+			sessions <- session
+		}()
+	}
+	wg.Wait()
+	close(sessions)
+	close(errs)
+
+	if len(sessions) != 1 {
+		t.Fatalf("%d sessions opened for one challenge, want 1", len(sessions))
+	}
+
+	for err := range errs {
+		if !errors.Is(err, ErrUnknownChallenge) {
+			t.Fatalf("concurrent verify = %v, want %v", err, ErrUnknownChallenge)
+		}
+	}
+
+	session := <-sessions
+	if _, err = s.Session(session.Token); err != nil {
+		t.Fatalf("Session of the opened token: %v", err)
+	}
+}
+
+func TestVerifyWrongSignatureUsesChallenge(t *testing.T) {
+	s := New(types.AuthConfig{Enabled: true}, newAuthStorage())
+
+	challenge, err := s.Challenge(eip191Address)
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+
+	if _, err = s.Verify(eip191Address, challenge.Nonce, signEIP191(t, "other message"), ""); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("verify with another signature = %v, want %v", err, ErrBadSignature)
+	}
+
+	if _, err = s.Verify(eip191Address, challenge.Nonce, signEIP191(t, challenge.Message), ""); !errors.Is(err, ErrUnknownChallenge) {
+		t.Fatalf("verify of a used challenge = %v, want %v", err, ErrUnknownChallenge)
+	}
+}
+
+func TestSessionCache(t *testing.T) {
+	s := New(types.AuthConfig{Enabled: true}, newAuthStorage())
+
+	for i := range maxCachedSessions + 10 {
+		if _, err := s.Session("ixs_bogus" + cast.ToString(i)); !errors.Is(err, ErrUnknownSession) {
+			t.Fatalf("Session of a bogus token = %v, want %v", err, ErrUnknownSession)
+		}
+	}
+
+	if len(s.cache) != maxCachedSessions {
+		t.Fatalf("cache holds %d tokens, want %d", len(s.cache), maxCachedSessions)
+	}
+
+	s.cleanup(time.Now().Add(sessionCacheTTL))
+
+	if len(s.cache) != 0 {
+		t.Fatalf("cache holds %d tokens after they expired, want none", len(s.cache))
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+)
+
+const SchemeEIP191 = "eip191"
+
+// verifyEIP191 checks a personal_sign signature of message by the 0x address,
+// hex encoded with the recovery id as 0/1 or 27/28.
+func verifyEIP191(address, message, signature string) error {
+	if !common.IsHexAddress(address) {
+		return apierror.Newf(apierror.InvalidArgument, "invalid EVM address %s", address)
+	}
+
+	signatureBytes, err := hexutil.Decode(signature)
+	if err != nil || len(signatureBytes) != crypto.SignatureLength {
+		return apierror.New(apierror.InvalidArgument, "signature must be 65 hex encoded bytes")
+	}
+
+	if signatureBytes[crypto.RecoveryIDOffset] >= 27 {
+		signatureBytes[crypto.RecoveryIDOffset] -= 27
+	}
+
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+
+	key, err := crypto.SigToPub(hash, signatureBytes)
+	if err != nil {
+		return ErrBadSignature
+	}
+
+	if !strings.EqualFold(crypto.PubkeyToAddress(*key).Hex(), address) {
+		return ErrBadSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+// the personal_sign example of the web3.js documentation, key
+// 0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318
+const (
+	eip191Address = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+	// "Some data", recovery id 1
+	eip191Signature = "0xb91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a029"
+	// "hello", recovery id 0
+	eip191SignatureHello = "0xa5d58782075bdf09490159d634d1aae66a8f6777c7247d2f233e9511cfd7c64c34f288cdbcea5370e4863fdbe9f4d86654c2ba1d86589e9ebb64494c64900859"
+)
+
+func TestVerifyEIP191(t *testing.T) {
+	tests := []struct {
+		name      string
+		address   string
+		message   string
+		signature string
+		err       error
+	}{
+		{name: "v=28", address: eip191Address, message: "Some data", signature: eip191Signature + "1c"},
+		{name: "v=1", address: eip191Address, message: "Some data", signature: eip191Signature + "01"},
+		{name: "v=27", address: eip191Address, message: "hello", signature: eip191SignatureHello + "1b"},
+		{name: "v=0", address: eip191Address, message: "hello", signature: eip191SignatureHello + "00"},
+		{name: "lower case address", address: "0x2c7536e3605d9c16a7a3d7b1898e529396a65c23", message: "Some data", signature: eip191Signature + "1c"},
+		{name: "wrong address", address: "0x0000000000000000000000000000000000000001", message: "Some data", signature: eip191Signature + "1c", err: ErrBadSignature},
+		{name: "other message", address: eip191Address, message: "Other data", signature: eip191Signature + "1c", err: ErrBadSignature},
+		{name: "wrong recovery id", address: eip191Address, message: "Some data", signature: eip191Signature + "1b", err: ErrBadSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := verifyEIP191(test.address, test.message, test.signature); !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestVerifyEIP191Malformed(t *testing.T) {
+	tests := []struct {
+		name      string
+		address   string
+		signature string
+	}{
+		{name: "not an address", address: "kira1szkjafldq392qw4u5nh487ppe6glw60z73uhy8", signature: eip191Signature + "1c"},
+		{name: "short signature", address: eip191Address, signature: eip191Signature},
+		{name: "not hex", address: eip191Address, signature: "signature"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := verifyEIP191(test.address, "Some data", test.signature); err == nil || errors.Is(err, ErrBadSignature) {
+				t.Fatalf("got %v, want an invalid argument", err)
+			}
+		})
+	}
+}
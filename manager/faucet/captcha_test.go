@@ -0,0 +1,74 @@
+package faucet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestStubCaptcha(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		token      string
+		valid      bool
+	}{
+		{name: "matching token", configured: "solved", token: "solved", valid: true},
+		{name: "wrong token", configured: "solved", token: "wrong"},
+		{name: "prefix of the token", configured: "solved", token: "solve"},
+		{name: "empty token", configured: "solved", token: ""},
+		{name: "no token configured", configured: "", token: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := StubCaptcha{Token: test.configured}.Verify(context.Background(), test.token, "10.0.0.1")
+
+			switch {
+			case test.valid && err != nil:
+				t.Fatalf("got %v, want the token accepted", err)
+			case !test.valid && reason(err) != ReasonCaptchaInvalid:
+				t.Fatalf("got %v, want %s", err, ReasonCaptchaInvalid)
+			}
+		})
+	}
+}
+
+func TestNewCaptcha(t *testing.T) {
+	tests := []struct {
+		provider string
+		url      string
+		want     string
+		err      bool
+	}{
+		{provider: ""},
+		{provider: "stub"},
+		{provider: "hcaptcha", want: verifyURLs["hcaptcha"]},
+		{provider: "turnstile", url: "http://localhost/verify", want: "http://localhost/verify"},
+		{provider: "unknown", err: true},
+	}
+
+	for _, test := range tests {
+		captcha, err := NewCaptcha(types.CaptchaConfig{Provider: test.provider, URL: test.url})
+		if (err != nil) != test.err {
+			t.Errorf("provider %q: got error %v", test.provider, err)
+			continue
+		}
+
+		switch captcha := captcha.(type) {
+		case nil:
+			if test.provider != "" && !test.err {
+				t.Errorf("provider %q: no captcha", test.provider)
+			}
+		case StubCaptcha:
+			if test.provider != "stub" {
+				t.Errorf("provider %q: got the stub", test.provider)
+			}
+		case *siteVerify:
+			if captcha.url != test.want {
+				t.Errorf("provider %q: verify url %q, want %q", test.provider, captcha.url, test.want)
+			}
+		}
+	}
+}
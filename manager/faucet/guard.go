@@ -0,0 +1,335 @@
+package faucet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+)
+
+const (
+	// HistoryCollection keeps one entry per accepted claim, cooldowns and
+	// budgets are computed from it.
+	HistoryCollection = "cosmos_faucet"
+
+	defaultChallengeCollection = "faucet_challenges"
+	defaultPowTTL              = 5 * time.Minute
+	defaultCaptchaTimeout      = 10 * time.Second
+	defaultSubnetV4            = 24
+	defaultSubnetV6            = 64
+)
+
+// Rejection reasons, sent as the "reason" detail of the error.
+const (
+	ReasonInProgress       = "in_progress"
+	ReasonAddressCooldown  = "address_cooldown"
+	ReasonIPCooldown       = "ip_cooldown"
+	ReasonSubnetCooldown   = "subnet_cooldown"
+	ReasonIdentityCooldown = "identity_cooldown"
+	ReasonPowDisabled      = "pow_disabled"
+	ReasonPowRequired      = "pow_required"
+	ReasonPowInvalid       = "pow_invalid"
+	ReasonCaptchaRequired  = "captcha_required"
+	ReasonCaptchaInvalid   = "captcha_invalid"
+	ReasonDailyBudget      = "daily_budget"
+)
+
+var reasonCodes = map[string]apierror.Code{
+	ReasonInProgress:       apierror.ResourceExhausted,
+	ReasonAddressCooldown:  apierror.ResourceExhausted,
+	ReasonIPCooldown:       apierror.ResourceExhausted,
+	ReasonSubnetCooldown:   apierror.ResourceExhausted,
+	ReasonIdentityCooldown: apierror.ResourceExhausted,
+	ReasonPowDisabled:      apierror.FailedPrecondition,
+	ReasonPowRequired:      apierror.PermissionDenied,
+	ReasonPowInvalid:       apierror.PermissionDenied,
+	ReasonCaptchaRequired:  apierror.PermissionDenied,
+	ReasonCaptchaInvalid:   apierror.PermissionDenied,
+	ReasonDailyBudget:      apierror.ResourceExhausted,
+}
+
+func reject(reason, message string) *apierror.Error {
+	return apierror.New(reasonCodes[reason], "[faucet] "+message).WithDetail("reason", reason)
+}
+
+// Claim is a claim as the guard sees it: who asks, from where, and the proofs
+// sent along.
+type Claim struct {
+	ID          string
+	Address     string
+	IP          string
+	Identity    string
+	PowNonce    string
+	PowSolution string
+	Captcha     string
+	Coins       sdk.Coins
+}
+
+// Guard decides whether a claim is accepted. Claims are checked against the
+// cooldowns of their address, client address, subnet and identity, a proof of
+// work and a captcha when configured, and the daily budget of every denom.
+// Accepted claims are recorded in the history until they are forgotten.
+type Guard struct {
+	config    types.FaucetProtection
+	timeLimit int64
+	storage   types.Storage
+	captcha   Captcha
+
+	mu      sync.Mutex
+	pending map[string]bool
+
+	budgetMu sync.Mutex
+}
+
+func NewGuard(config types.FaucetProtection, timeLimit int64, storage types.Storage) (*Guard, error) {
+	if config.ChallengeCollection == "" {
+		config.ChallengeCollection = defaultChallengeCollection
+	}
+	if config.SubnetV4 <= 0 {
+		config.SubnetV4 = defaultSubnetV4
+	}
+	if config.SubnetV6 <= 0 {
+		config.SubnetV6 = defaultSubnetV6
+	}
+
+	captcha, err := NewCaptcha(config.Captcha)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Guard{
+		config:    config,
+		timeLimit: timeLimit,
+		storage:   storage,
+		captcha:   captcha,
+		pending:   map[string]bool{},
+	}, nil
+}
+
+// Begin marks the address of a claim, and its client address, subnet and
+// identity when they have a cooldown, as busy until the returned func is
+// called, so that two claims sharing any of them cannot both pass the
+// cooldowns.
+func (g *Guard) Begin(claim Claim) (func(), error) {
+	keys := []string{"address:" + claim.Address}
+	if claim.IP != "" && g.config.IPCooldown > 0 {
+		keys = append(keys, "ip:"+claim.IP)
+	}
+	if subnet := g.Subnet(claim.IP); subnet != "" && g.config.SubnetCooldown > 0 {
+		keys = append(keys, "subnet:"+subnet)
+	}
+	if claim.Identity != "" && g.config.IdentityCooldown > 0 {
+		keys = append(keys, "identity:"+claim.Identity)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, key := range keys {
+		if g.pending[key] {
+			return nil, reject(ReasonInProgress, "a claim from this client is in progress")
+		}
+	}
+
+	for _, key := range keys {
+		g.pending[key] = true
+	}
+
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		for _, key := range keys {
+			delete(g.pending, key)
+		}
+	}, nil
+}
+
+// Check runs the cooldowns first, then the captcha and the proof of work. The
+// challenge is only used up by Record, so that a claim rejected before it is
+// recorded keeps its challenge.
+func (g *Guard) Check(ctx context.Context, claim Claim) error {
+	cooldowns := []struct {
+		field  string
+		value  string
+		limit  int64
+		reason string
+	}{
+		{"address", claim.Address, g.timeLimit, ReasonAddressCooldown},
+		{"ip", claim.IP, g.config.IPCooldown, ReasonIPCooldown},
+		{"subnet", g.Subnet(claim.IP), g.config.SubnetCooldown, ReasonSubnetCooldown},
+		{"identity", claim.Identity, g.config.IdentityCooldown, ReasonIdentityCooldown},
+	}
+
+	for _, cooldown := range cooldowns {
+		if cooldown.value == "" || cooldown.limit <= 0 {
+			continue
+		}
+
+		if err := g.checkCooldown(cooldown.field, cooldown.value, cooldown.limit, cooldown.reason); err != nil {
+			return err
+		}
+	}
+
+	if g.captcha != nil {
+		if claim.Captcha == "" {
+			return reject(ReasonCaptchaRequired, "a captcha token is required")
+		}
+
+		if err := g.captcha.Verify(ctx, claim.Captcha, claim.IP); err != nil {
+			logger.Logger.Error("Faucet - Check - captcha", zap.String("Address", claim.Address), zap.Error(err))
+			return err
+		}
+	}
+
+	if g.config.PowDifficulty > 0 {
+		return g.checkPow(claim.Address, claim.PowNonce, claim.PowSolution)
+	}
+
+	return nil
+}
+
+func (g *Guard) checkCooldown(field, value string, limit int64, reason string) error {
+	result, err := g.storage.Read(HistoryCollection, map[string]interface{}{field: value}, &adapter.Options{Sort: map[string]interface{}{"timestamp": -1}, Limit: 1}, []string{})
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get faucet history", zap.String(field, value), zap.Error(err))
+		return err
+	}
+
+	if len(result.Result) == 0 {
+		return nil
+	}
+
+	left := cast.ToInt64(result.Result[0]["timestamp"]) + limit - time.Now().UTC().Unix()
+	if left > 0 {
+		logger.Logger.Error("[faucet] Claim time left", zap.String(field, value), zap.Int64("Time left", left))
+		return reject(reason, fmt.Sprintf("Claim time left: %d", left)).WithRetryAfter(time.Duration(left) * time.Second)
+	}
+
+	return nil
+}
+
+// Record checks the daily budgets, uses up the proof of work challenge and
+// adds the claim to the history. Budgets are checked and spent under one lock,
+// managers sharing the storage can still overshoot them by the claims they
+// accept at the same time.
+func (g *Guard) Record(claim Claim) error {
+	g.budgetMu.Lock()
+	defer g.budgetMu.Unlock()
+
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	for _, coin := range claim.Coins {
+		budget, ok := g.config.DailyBudget[coin.Denom]
+		if !ok || budget <= 0 {
+			continue
+		}
+
+		spent, err := g.spent(coin.Denom, day)
+		if err != nil {
+			return err
+		}
+
+		if spent.Add(coin.Amount).GT(sdk.NewInt(budget)) {
+			return reject(ReasonDailyBudget, fmt.Sprintf("Daily budget of %s is spent", coin.Denom)).
+				WithRetryAfter(day.Add(24 * time.Hour).Sub(now))
+		}
+	}
+
+	if g.config.PowDifficulty > 0 {
+		if err := g.usePow(claim.PowNonce); err != nil {
+			return err
+		}
+	}
+
+	entry := map[string]interface{}{
+		"claim_id":  claim.ID,
+		"address":   claim.Address,
+		"timestamp": now.Unix(),
+		"amount":    claim.Coins.String(),
+		"coins":     claim.Coins,
+	}
+	if claim.IP != "" {
+		entry["ip"] = claim.IP
+		entry["subnet"] = g.Subnet(claim.IP)
+	}
+	if claim.Identity != "" {
+		entry["identity"] = claim.Identity
+	}
+
+	if _, err := g.storage.Create(HistoryCollection, []interface{}{entry}); err != nil {
+		logger.Logger.Error("[faucet] Failed to write faucet claim to database", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (g *Guard) spent(denom string, since time.Time) (sdk.Int, error) {
+	result, err := g.storage.Read(HistoryCollection, map[string]interface{}{
+		"timestamp":   map[string]interface{}{"$gte": since.Unix()},
+		"coins.denom": denom,
+	}, nil, []string{"coins"})
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get faucet history", zap.String("Denom", denom), zap.Error(err))
+		return sdk.Int{}, err
+	}
+
+	spent := sdk.ZeroInt()
+	for _, entry := range result.Result {
+		for _, coin := range cast.ToSlice(entry["coins"]) {
+			fields := cast.ToStringMap(coin)
+			if cast.ToString(fields["denom"]) != denom {
+				continue
+			}
+
+			if amount, ok := sdk.NewIntFromString(cast.ToString(fields["amount"])); ok {
+				spent = spent.Add(amount)
+			}
+		}
+	}
+
+	return spent, nil
+}
+
+// Forget removes a claim that was not dispensed from the history, it no longer
+// counts towards cooldowns and budgets.
+func (g *Guard) Forget(id string) {
+	if _, err := g.storage.Delete(HistoryCollection, map[string]interface{}{"claim_id": id}); err != nil {
+		logger.Logger.Error("[faucet] Failed to remove faucet claim from history", zap.String("ID", id), zap.Error(err))
+	}
+}
+
+// Subnet is the network of ip with the configured prefix length, empty when ip
+// is not an IP address.
+func (g *Guard) Subnet(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(g.config.SubnetV4, 32)), Mask: net.CIDRMask(g.config.SubnetV4, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(g.config.SubnetV6, 128)), Mask: net.CIDRMask(g.config.SubnetV6, 128)}).String()
+}
+
+func seconds(value int64, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,492 @@
+package faucet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	m.Run()
+}
+
+// memStorage keeps documents the way sai-storage-mongo returns them, decoded
+// from JSON, and understands the selectors the guard uses: equality, $lt,
+// $gte and dotted paths into arrays. Like MongoDB it refuses a second document
+// with the same _id.
+type memStorage struct {
+	mu          sync.Mutex
+	collections map[string][]map[string]interface{}
+}
+
+var _ types.Storage = (*memStorage)(nil)
+
+func newMemStorage() *memStorage {
+	return &memStorage{collections: map[string][]map[string]interface{}{}}
+}
+
+func (s *memStorage) Create(collection string, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, document := range cast.ToSlice(document) {
+		document := decoded(document)
+		if id, ok := document["_id"]; ok {
+			for _, stored := range s.collections[collection] {
+				if stored["_id"] == id {
+					return &adapter.SaiStorageResponse{Status: "NOK"}, nil
+				}
+			}
+		}
+		s.collections[collection] = append(s.collections[collection], document)
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *memStorage) Read(collection string, criteria map[string]interface{}, options *adapter.Options, fields []string) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []map[string]interface{}
+	for _, document := range s.collections[collection] {
+		if matches(document, criteria) {
+			result = append(result, document)
+		}
+	}
+
+	if options != nil {
+		// the guard only sorts by timestamp, newest first
+		if options.Sort != nil {
+			sort.SliceStable(result, func(i, j int) bool {
+				return cast.ToInt64(result[i]["timestamp"]) > cast.ToInt64(result[j]["timestamp"])
+			})
+		}
+
+		if options.Limit > 0 && int64(len(result)) > options.Limit {
+			result = result[:options.Limit]
+		}
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK", Result: result, Count: len(result)}, nil
+}
+
+func (s *memStorage) Upsert(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.Delete(collection, criteria)
+	return s.Create(collection, []interface{}{document})
+}
+
+func (s *memStorage) Update(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.collections[collection] {
+		if matches(stored, criteria) {
+			for key, value := range decoded(document) {
+				stored[key] = value
+			}
+		}
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *memStorage) Delete(collection string, criteria map[string]interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.collections[collection][:0]
+	for _, document := range s.collections[collection] {
+		if !matches(document, criteria) {
+			kept = append(kept, document)
+		}
+	}
+	s.collections[collection] = kept
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *memStorage) count(collection string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.collections[collection])
+}
+
+func decoded(document interface{}) map[string]interface{} {
+	data, _ := json.Marshal(document)
+
+	var result map[string]interface{}
+	json.Unmarshal(data, &result)
+
+	return result
+}
+
+func matches(document map[string]interface{}, criteria map[string]interface{}) bool {
+	for path, condition := range criteria {
+		matched := false
+		for _, value := range lookup(document, path) {
+			if satisfies(value, condition) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookup returns the values at a dotted path, one per array element crossed.
+func lookup(value interface{}, path string) []interface{} {
+	if path == "" {
+		return []interface{}{value}
+	}
+
+	key, rest := path, ""
+	for i := range path {
+		if path[i] == '.' {
+			key, rest = path[:i], path[i+1:]
+			break
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		field, ok := value[key]
+		if !ok {
+			return nil
+		}
+		return lookup(field, rest)
+	case []interface{}:
+		var result []interface{}
+		for _, element := range value {
+			result = append(result, lookup(element, path)...)
+		}
+		return result
+	}
+
+	return nil
+}
+
+func satisfies(value, condition interface{}) bool {
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		return cast.ToString(value) == cast.ToString(condition)
+	}
+
+	for operator, operand := range operators {
+		switch operator {
+		case "$lt":
+			if !(cast.ToFloat64(value) < cast.ToFloat64(operand)) {
+				return false
+			}
+		case "$gte":
+			if !(cast.ToFloat64(value) >= cast.ToFloat64(operand)) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func reason(err error) string {
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+
+	return cast.ToString(apiErr.Details["reason"])
+}
+
+func newTestGuard(t *testing.T, config types.FaucetProtection, timeLimit int64) (*Guard, *memStorage) {
+	t.Helper()
+
+	storage := newMemStorage()
+	guard, err := NewGuard(config, timeLimit, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return guard, storage
+}
+
+func TestGuardBegin(t *testing.T) {
+	config := types.FaucetProtection{IPCooldown: 60, SubnetCooldown: 60, IdentityCooldown: 60}
+
+	tests := []struct {
+		name   string
+		config types.FaucetProtection
+		first  Claim
+		second Claim
+		reason string
+	}{
+		{
+			name:   "same address",
+			first:  Claim{Address: "kira1a"},
+			second: Claim{Address: "kira1a"},
+			reason: ReasonInProgress,
+		},
+		{
+			name:   "other address",
+			first:  Claim{Address: "kira1a", IP: "10.0.0.1"},
+			second: Claim{Address: "kira1b", IP: "10.0.0.1"},
+		},
+		{
+			name:   "same ip",
+			config: config,
+			first:  Claim{Address: "kira1a", IP: "10.0.0.1"},
+			second: Claim{Address: "kira1b", IP: "10.0.0.1"},
+			reason: ReasonInProgress,
+		},
+		{
+			name:   "same subnet",
+			config: config,
+			first:  Claim{Address: "kira1a", IP: "10.0.0.1"},
+			second: Claim{Address: "kira1b", IP: "10.0.0.2"},
+			reason: ReasonInProgress,
+		},
+		{
+			name:   "other subnet",
+			config: config,
+			first:  Claim{Address: "kira1a", IP: "10.0.0.1"},
+			second: Claim{Address: "kira1b", IP: "10.0.1.1"},
+		},
+		{
+			name:   "same identity",
+			config: config,
+			first:  Claim{Address: "kira1a", Identity: "0xabc"},
+			second: Claim{Address: "kira1b", Identity: "0xabc"},
+			reason: ReasonInProgress,
+		},
+		{
+			name:   "identity without cooldown",
+			first:  Claim{Address: "kira1a", Identity: "0xabc"},
+			second: Claim{Address: "kira1b", Identity: "0xabc"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guard, _ := newTestGuard(t, test.config, 0)
+
+			end, err := guard.Begin(test.first)
+			if err != nil {
+				t.Fatalf("first claim: %v", err)
+			}
+
+			second, err := guard.Begin(test.second)
+			if got := reason(err); got != test.reason {
+				t.Fatalf("second claim: got reason %q, want %q", got, test.reason)
+			}
+			if err == nil {
+				second()
+			}
+
+			end()
+
+			again, err := guard.Begin(test.second)
+			if err != nil {
+				t.Fatalf("after the first ended: %v", err)
+			}
+			again()
+		})
+	}
+}
+
+func TestGuardCheckCooldowns(t *testing.T) {
+	config := types.FaucetProtection{IPCooldown: 600, SubnetCooldown: 300, IdentityCooldown: 900}
+	previous := Claim{ID: "1", Address: "kira1a", IP: "10.0.0.1", Identity: "0xabc"}
+
+	tests := []struct {
+		name   string
+		age    int64
+		claim  Claim
+		reason string
+	}{
+		{name: "same address", age: 10, claim: Claim{Address: "kira1a"}, reason: ReasonAddressCooldown},
+		{name: "address cooldown over", age: 3600, claim: Claim{Address: "kira1a"}},
+		{name: "same ip", age: 10, claim: Claim{Address: "kira1b", IP: "10.0.0.1"}, reason: ReasonIPCooldown},
+		{name: "same subnet", age: 10, claim: Claim{Address: "kira1b", IP: "10.0.0.9"}, reason: ReasonSubnetCooldown},
+		{name: "subnet cooldown over", age: 400, claim: Claim{Address: "kira1b", IP: "10.0.0.9"}},
+		{name: "same identity", age: 700, claim: Claim{Address: "kira1b", IP: "10.0.1.1", Identity: "0xabc"}, reason: ReasonIdentityCooldown},
+		{name: "unrelated", age: 10, claim: Claim{Address: "kira1b", IP: "10.0.1.1", Identity: "0xdef"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guard, storage := newTestGuard(t, config, 1800)
+
+			if err := guard.Record(previous); err != nil {
+				t.Fatal(err)
+			}
+			storage.Update(HistoryCollection, map[string]interface{}{"claim_id": "1"}, map[string]interface{}{
+				"timestamp": time.Now().Unix() - test.age,
+			})
+
+			err := guard.Check(context.Background(), test.claim)
+			if got := reason(err); got != test.reason {
+				t.Fatalf("got reason %q (%v), want %q", got, err, test.reason)
+			}
+		})
+	}
+}
+
+func TestGuardCheckCaptcha(t *testing.T) {
+	guard, _ := newTestGuard(t, types.FaucetProtection{Captcha: types.CaptchaConfig{Provider: "stub", Token: "solved"}}, 0)
+
+	tests := []struct {
+		token  string
+		reason string
+	}{
+		{token: "", reason: ReasonCaptchaRequired},
+		{token: "wrong", reason: ReasonCaptchaInvalid},
+		{token: "solved"},
+	}
+
+	for _, test := range tests {
+		err := guard.Check(context.Background(), Claim{Address: "kira1a", Captcha: test.token})
+		if got := reason(err); got != test.reason {
+			t.Errorf("token %q: got reason %q, want %q", test.token, got, test.reason)
+		}
+	}
+}
+
+func TestGuardRecordBudget(t *testing.T) {
+	config := types.FaucetProtection{DailyBudget: map[string]int64{"ukex": 100}}
+
+	tests := []struct {
+		name   string
+		spent  []sdk.Coins
+		claim  sdk.Coins
+		reason string
+	}{
+		{name: "first claim", claim: sdk.NewCoins(sdk.NewInt64Coin("ukex", 60))},
+		{name: "whole budget", claim: sdk.NewCoins(sdk.NewInt64Coin("ukex", 100))},
+		{name: "over the budget", claim: sdk.NewCoins(sdk.NewInt64Coin("ukex", 101)), reason: ReasonDailyBudget},
+		{
+			name:  "up to the budget",
+			spent: []sdk.Coins{sdk.NewCoins(sdk.NewInt64Coin("ukex", 30)), sdk.NewCoins(sdk.NewInt64Coin("ukex", 30))},
+			claim: sdk.NewCoins(sdk.NewInt64Coin("ukex", 40)),
+		},
+		{
+			name:   "past the budget",
+			spent:  []sdk.Coins{sdk.NewCoins(sdk.NewInt64Coin("ukex", 30)), sdk.NewCoins(sdk.NewInt64Coin("ukex", 30))},
+			claim:  sdk.NewCoins(sdk.NewInt64Coin("ukex", 41)),
+			reason: ReasonDailyBudget,
+		},
+		{
+			name:  "other denoms do not count",
+			spent: []sdk.Coins{sdk.NewCoins(sdk.NewInt64Coin("ukex", 50), sdk.NewInt64Coin("test", 1000))},
+			claim: sdk.NewCoins(sdk.NewInt64Coin("ukex", 50), sdk.NewInt64Coin("test", 1000)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guard, storage := newTestGuard(t, config, 0)
+
+			for i, coins := range test.spent {
+				if err := guard.Record(Claim{ID: cast.ToString(i), Address: "kira1a", Coins: coins}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := guard.Record(Claim{ID: "claim", Address: "kira1b", Coins: test.claim})
+			if got := reason(err); got != test.reason {
+				t.Fatalf("got reason %q (%v), want %q", got, err, test.reason)
+			}
+
+			recorded := len(test.spent)
+			if err == nil {
+				recorded++
+			}
+			if got := storage.count(HistoryCollection); got != recorded {
+				t.Fatalf("history holds %d claims, want %d", got, recorded)
+			}
+		})
+	}
+}
+
+func TestGuardRecordBudgetIsDaily(t *testing.T) {
+	guard, storage := newTestGuard(t, types.FaucetProtection{DailyBudget: map[string]int64{"ukex": 100}}, 0)
+
+	if err := guard.Record(Claim{ID: "yesterday", Address: "kira1a", Coins: sdk.NewCoins(sdk.NewInt64Coin("ukex", 100))}); err != nil {
+		t.Fatal(err)
+	}
+	storage.Update(HistoryCollection, map[string]interface{}{"claim_id": "yesterday"}, map[string]interface{}{
+		"timestamp": time.Now().Add(-24 * time.Hour).Unix(),
+	})
+
+	if err := guard.Record(Claim{ID: "today", Address: "kira1a", Coins: sdk.NewCoins(sdk.NewInt64Coin("ukex", 100))}); err != nil {
+		t.Fatalf("yesterday's claims count towards today's budget: %v", err)
+	}
+}
+
+func TestGuardForget(t *testing.T) {
+	guard, _ := newTestGuard(t, types.FaucetProtection{}, 1800)
+
+	claim := Claim{ID: "1", Address: "kira1a"}
+	if err := guard.Record(claim); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := reason(guard.Check(context.Background(), claim)); got != ReasonAddressCooldown {
+		t.Fatalf("got reason %q, want %q", got, ReasonAddressCooldown)
+	}
+
+	guard.Forget(claim.ID)
+
+	if err := guard.Check(context.Background(), claim); err != nil {
+		t.Fatalf("forgotten claim still has a cooldown: %v", err)
+	}
+}
+
+func TestGuardSubnet(t *testing.T) {
+	tests := []struct {
+		name     string
+		subnetV4 int
+		subnetV6 int
+		ip       string
+		want     string
+	}{
+		{name: "ipv4 default", ip: "192.168.1.77", want: "192.168.1.0/24"},
+		{name: "ipv4 /16", subnetV4: 16, ip: "192.168.1.77", want: "192.168.0.0/16"},
+		{name: "ipv4 /32", subnetV4: 32, ip: "192.168.1.77", want: "192.168.1.77/32"},
+		{name: "ipv4 mapped ipv6", ip: "::ffff:192.168.1.77", want: "192.168.1.0/24"},
+		{name: "ipv6 default", ip: "2001:db8:1:2:3:4:5:6", want: "2001:db8:1:2::/64"},
+		{name: "ipv6 /48", subnetV6: 48, ip: "2001:db8:1:2:3:4:5:6", want: "2001:db8:1::/48"},
+		{name: "not an ip", ip: "localhost", want: ""},
+		{name: "empty", ip: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guard, _ := newTestGuard(t, types.FaucetProtection{SubnetV4: test.subnetV4, SubnetV6: test.subnetV6}, 0)
+
+			if got := guard.Subnet(test.ip); got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
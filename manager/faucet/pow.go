@@ -0,0 +1,153 @@
+package faucet
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+	"time"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	powAlgorithm    = "sha256"
+	cleanupInterval = time.Minute
+	// usedPrefix marks a used challenge in the challenge collection.
+	usedPrefix = "used:"
+)
+
+// Challenge issues a proof of work for address. Challenges are kept in storage
+// so that the claim can reach any manager, and are used once.
+func (g *Guard) Challenge(address string) (*types.FaucetChallenge, error) {
+	if g.config.PowDifficulty <= 0 {
+		return nil, reject(ReasonPowDisabled, "proof of work is not required")
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	challenge := &types.FaucetChallenge{
+		Nonce:      hex.EncodeToString(buf),
+		Address:    address,
+		Algorithm:  powAlgorithm,
+		Difficulty: g.config.PowDifficulty,
+		ExpiresAt:  time.Now().UTC().Add(seconds(int64(g.config.PowTTL), defaultPowTTL)),
+	}
+
+	_, err := g.storage.Upsert(g.config.ChallengeCollection, map[string]interface{}{"nonce": challenge.Nonce}, map[string]interface{}{
+		"nonce":      challenge.Nonce,
+		"address":    address,
+		"difficulty": challenge.Difficulty,
+		"expires":    challenge.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		logger.Logger.Error("Faucet - Challenge", zap.Error(err))
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// checkPow checks that solution solves the challenge nonce for address, with
+// the difficulty it was issued with. The challenge is left in place, usePow
+// uses it up once the claim is accepted.
+func (g *Guard) checkPow(address, nonce, solution string) error {
+	if nonce == "" || solution == "" {
+		return reject(ReasonPowRequired, "a solved proof of work challenge is required")
+	}
+
+	response, err := g.storage.Read(g.config.ChallengeCollection, map[string]interface{}{"nonce": nonce}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("Faucet - checkPow", zap.Error(err))
+		return err
+	}
+
+	if len(response.Result) == 0 {
+		return reject(ReasonPowInvalid, "unknown or used challenge")
+	}
+
+	challenge := response.Result[0]
+	if cast.ToString(challenge["address"]) != address {
+		return reject(ReasonPowInvalid, "challenge was issued for another address")
+	}
+
+	if time.Now().Unix() > cast.ToInt64(challenge["expires"]) {
+		return reject(ReasonPowInvalid, "challenge expired")
+	}
+
+	if powBits(nonce, address, solution) < cast.ToInt(challenge["difficulty"]) {
+		return reject(ReasonPowInvalid, "solution does not reach the difficulty")
+	}
+
+	return nil
+}
+
+// usePow uses up a challenge checked by checkPow. Claims checked with the same
+// challenge at the same time, on any manager, can all pass checkPow, only one
+// of them uses it up.
+func (g *Guard) usePow(nonce string) error {
+	expires := time.Now().Add(seconds(int64(g.config.PowTTL), defaultPowTTL)).Unix()
+
+	used, err := types.Consume(g.storage, g.config.ChallengeCollection, usedPrefix+nonce, expires)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return reject(ReasonPowInvalid, "unknown or used challenge")
+	}
+
+	if _, err = g.storage.Delete(g.config.ChallengeCollection, map[string]interface{}{"nonce": nonce}); err != nil {
+		logger.Logger.Error("Faucet - usePow", zap.Error(err))
+	}
+
+	return nil
+}
+
+// Run deletes the expired challenges every minute until ctx is done.
+func (g *Guard) Run(ctx context.Context) {
+	if g.config.PowDifficulty <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.cleanup(now)
+		}
+	}
+}
+
+func (g *Guard) cleanup(now time.Time) {
+	expired := map[string]interface{}{"expires": map[string]interface{}{"$lt": now.Unix()}}
+	if _, err := g.storage.Delete(g.config.ChallengeCollection, expired); err != nil {
+		logger.Logger.Error("Faucet - cleanup", zap.Error(err))
+	}
+}
+
+// powBits counts the leading zero bits of sha256(nonce:address:solution).
+func powBits(nonce, address, solution string) int {
+	hash := sha256.Sum256([]byte(nonce + ":" + address + ":" + solution))
+
+	count := 0
+	for _, b := range hash {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+
+	return count
+}
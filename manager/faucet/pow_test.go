@@ -0,0 +1,231 @@
+package faucet
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestPowBits(t *testing.T) {
+	tests := []struct {
+		solution string
+		want     int
+	}{
+		{solution: "0", want: 0}, // f8beba...
+		{solution: "3", want: 1}, // 48bb14...
+		{solution: "2", want: 2}, // 24a8f2...
+		{solution: "1", want: 4}, // 096960...
+		{solution: "4819", want: 12},
+	}
+
+	for _, test := range tests {
+		if got := powBits("nonce", "kira1a", test.solution); got != test.want {
+			t.Errorf("solution %s: got %d bits, want %d", test.solution, got, test.want)
+		}
+	}
+}
+
+// solve finds a solution of at least bits leading zero bits, and one of fewer
+// when below is set.
+func solve(nonce, address string, bits int, below bool) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if got := powBits(nonce, address, solution); (got >= bits) != below {
+			return solution
+		}
+	}
+}
+
+func TestCheckPow(t *testing.T) {
+	const difficulty = 8
+
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (address, nonce, solution string)
+		reason string
+	}{
+		{
+			name: "solved",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				return challenge.Address, challenge.Nonce, solve(challenge.Nonce, challenge.Address, difficulty, false)
+			},
+		},
+		{
+			name: "missing",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				return challenge.Address, challenge.Nonce, ""
+			},
+			reason: ReasonPowRequired,
+		},
+		{
+			name: "not solved",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				return challenge.Address, challenge.Nonce, solve(challenge.Nonce, challenge.Address, difficulty, true)
+			},
+			reason: ReasonPowInvalid,
+		},
+		{
+			name: "unknown nonce",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				return challenge.Address, "unknown", solve("unknown", challenge.Address, difficulty, false)
+			},
+			reason: ReasonPowInvalid,
+		},
+		{
+			name: "other address",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				return "kira1b", challenge.Nonce, solve(challenge.Nonce, "kira1b", difficulty, false)
+			},
+			reason: ReasonPowInvalid,
+		},
+		{
+			name: "expired",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				storage.Update(g.config.ChallengeCollection, map[string]interface{}{"nonce": challenge.Nonce}, map[string]interface{}{
+					"expires": time.Now().Add(-time.Second).Unix(),
+				})
+				return challenge.Address, challenge.Nonce, solve(challenge.Nonce, challenge.Address, difficulty, false)
+			},
+			reason: ReasonPowInvalid,
+		},
+		{
+			name: "checked twice",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				solution := solve(challenge.Nonce, challenge.Address, difficulty, false)
+				if err := g.checkPow(challenge.Address, challenge.Nonce, solution); err != nil {
+					t.Fatalf("first check: %v", err)
+				}
+				return challenge.Address, challenge.Nonce, solution
+			},
+		},
+		{
+			name: "used",
+			setup: func(t *testing.T, g *Guard, storage *memStorage, challenge *types.FaucetChallenge) (string, string, string) {
+				if err := g.usePow(challenge.Nonce); err != nil {
+					t.Fatalf("use: %v", err)
+				}
+				return challenge.Address, challenge.Nonce, solve(challenge.Nonce, challenge.Address, difficulty, false)
+			},
+			reason: ReasonPowInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guard, storage := newTestGuard(t, types.FaucetProtection{PowDifficulty: difficulty}, 0)
+
+			challenge, err := guard.Challenge("kira1a")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			address, nonce, solution := test.setup(t, guard, storage, challenge)
+
+			err = guard.checkPow(address, nonce, solution)
+			if got := reason(err); got != test.reason {
+				t.Fatalf("got reason %q (%v), want %q", got, err, test.reason)
+			}
+		})
+	}
+}
+
+func TestChallengeDisabled(t *testing.T) {
+	guard, _ := newTestGuard(t, types.FaucetProtection{}, 0)
+
+	if _, err := guard.Challenge("kira1a"); reason(err) != ReasonPowDisabled {
+		t.Fatalf("got %v, want %s", err, ReasonPowDisabled)
+	}
+}
+
+func TestCleanupChallenges(t *testing.T) {
+	guard, storage := newTestGuard(t, types.FaucetProtection{PowDifficulty: 8}, 0)
+
+	expired, err := guard.Challenge("kira1a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = guard.Challenge("kira1b"); err != nil {
+		t.Fatal(err)
+	}
+
+	storage.Update(guard.config.ChallengeCollection, map[string]interface{}{"nonce": expired.Nonce}, map[string]interface{}{
+		"expires": time.Now().Add(-time.Minute).Unix(),
+	})
+
+	guard.cleanup(time.Now())
+
+	if got := storage.count(guard.config.ChallengeCollection); got != 1 {
+		t.Fatalf("%d challenges left, want 1", got)
+	}
+
+	if err = guard.checkPow("kira1a", expired.Nonce, "0"); reason(err) != ReasonPowInvalid {
+		t.Fatalf("expired challenge still stored: %v", err)
+	}
+
+	// Run returns once ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	guard.Run(ctx)
+}
+
+func TestRecordUsesPow(t *testing.T) {
+	config := types.FaucetProtection{PowDifficulty: 8, DailyBudget: map[string]int64{"ukex": 100}}
+	guard, _ := newTestGuard(t, config, 0)
+
+	challenge, err := guard.Challenge("kira1a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solution := solve(challenge.Nonce, challenge.Address, config.PowDifficulty, false)
+	claim := func(id string, amount int64) Claim {
+		return Claim{ID: id, Address: "kira1a", PowNonce: challenge.Nonce, PowSolution: solution, Coins: sdk.NewCoins(sdk.NewInt64Coin("ukex", amount))}
+	}
+
+	// a claim rejected before it is recorded keeps its challenge
+	if err = guard.Record(claim("over", 101)); reason(err) != ReasonDailyBudget {
+		t.Fatalf("got %v, want %s", err, ReasonDailyBudget)
+	}
+
+	if err = guard.checkPow(challenge.Address, challenge.Nonce, solution); err != nil {
+		t.Fatalf("challenge used by a rejected claim: %v", err)
+	}
+
+	// of the claims checked with one challenge at the same time, one is recorded
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- guard.Record(claim(strconv.Itoa(i), 10))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	recorded := 0
+	for err := range errs {
+		switch reason(err) {
+		case "":
+			recorded++
+		case ReasonPowInvalid:
+		default:
+			t.Fatalf("got %v, want %s", err, ReasonPowInvalid)
+		}
+	}
+
+	if recorded != 1 {
+		t.Fatalf("%d claims recorded with one challenge, want 1", recorded)
+	}
+
+	if err = guard.checkPow(challenge.Address, challenge.Nonce, solution); reason(err) != ReasonPowInvalid {
+		t.Fatalf("used challenge still stored: %v", err)
+	}
+}
@@ -1,52 +1,519 @@
 package gateway
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"github.com/KiraCore/sai-interx-manager/logger"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
 	"github.com/KiraCore/sai-service/service"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
 	"go.uber.org/zap"
-	"time"
 
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-interx-manager/types"
 )
 
+// bitcoinMethods are the bitcoind RPC methods exposed by default: chain and
+// mempool reads plus raw transaction broadcast. Wallet and node control calls
+// stay private.
+var bitcoinMethods = []string{
+	"decoderawtransaction",
+	"decodescript",
+	"estimatesmartfee",
+	"getbestblockhash",
+	"getblock",
+	"getblockchaininfo",
+	"getblockcount",
+	"getblockhash",
+	"getblockheader",
+	"getblockstats",
+	"getchaintips",
+	"getdifficulty",
+	"getmempoolancestors",
+	"getmempooldescendants",
+	"getmempoolentry",
+	"getmempoolinfo",
+	"getnetworkinfo",
+	"getrawmempool",
+	"getrawtransaction",
+	"gettxout",
+	"sendrawtransaction",
+	"testmempoolaccept",
+	"validateaddress",
+}
+
+// bitcoinTxMethods change the node state and are not retried.
+var bitcoinTxMethods = map[string]struct{}{
+	"sendrawtransaction": {},
+}
+
+// bitcoinFeeTargets are the confirmation targets reported by /status.
+var bitcoinFeeTargets = []int{1, 3, 6, 12, 24}
+
 type BitcoinGateway struct {
 	*BaseGateway
-	url string
+	url        string
+	storage    types.Storage
+	collection string
+	methods    map[string]struct{}
+	router     *Router
+	timeouts   Timeouts
+	breaker    *CircuitBreaker
+	requestID  atomic.Uint64
 }
 
 var _ types.Gateway = (*BitcoinGateway)(nil)
 
-func NewBitcoinGateway(ctx *service.Context, url string, retryAttempts int, retryDelay time.Duration, rateLimit int) (*BitcoinGateway, error) {
-	return &BitcoinGateway{
-		BaseGateway: NewBaseGateway(ctx, retryAttempts, retryDelay, rateLimit),
+func NewBitcoinGateway(ctx *service.Context, url string, storage types.Storage, collection string, methods []string, timeouts Timeouts, retry types.RetryConfig, breakers *Breakers, rateLimit int) (*BitcoinGateway, error) {
+	if len(methods) == 0 {
+		methods = bitcoinMethods
+	}
+
+	gateway := &BitcoinGateway{
+		BaseGateway: NewBaseGateway(ctx, retry, breakers, rateLimit),
 		url:         url,
-	}, nil
+		storage:     storage,
+		collection:  collection,
+		methods:     make(map[string]struct{}, len(methods)),
+		timeouts:    timeouts,
+		breaker:     breakers.Get("bitcoin", upstreamTarget(url)),
+	}
+
+	for _, method := range methods {
+		gateway.methods[strings.ToLower(method)] = struct{}{}
+	}
+
+	router, err := NewRouter(gateway.routes()...)
+	if err != nil {
+		logger.Logger.Error("NewBitcoinGateway", zap.Error(err))
+		return nil, err
+	}
+
+	gateway.router = router
+
+	return gateway, nil
 }
 
-func (g *BitcoinGateway) Handle(data []byte) (interface{}, error) {
-	var req struct {
-		Method   string      `json:"method"`
-		Data     interface{} `json:"data"`
-		Metadata struct {
-			Token string `json:"token"`
-		} `json:"metadata"`
+func (g *BitcoinGateway) routes() []Route {
+	return []Route{
+		{
+			Method: http.MethodGet,
+			Path:   "/status",
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.status(ctx)
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/addresses/{address}/balance",
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.balance(params["address"])
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/addresses/{address}/utxos",
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.utxos(req, params["address"])
+			},
+		},
+		{
+			Path: "/{method}",
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.passthrough(ctx, req, params["method"])
+			},
+		},
 	}
+}
+
+func (g *BitcoinGateway) Handle(ctx context.Context, data []byte, metadata types.RequestMetadata) (interface{}, error) {
+	var req types.InboundRequest
 
 	if err := json.Unmarshal(data, &req); err != nil {
-		logger.Logger.Error("CosmosGateway - Handle", zap.Error(err))
+		logger.Logger.Error("BitcoinGateway - Handle", zap.Error(err))
 		return nil, err
 	}
 
-	return g.retry.Do(func() (interface{}, error) {
-		if err := g.rateLimit.Wait(g.context.Context); err != nil {
-			logger.Logger.Error("CosmosGateway - Handle", zap.Error(err))
+	route, params, err := g.router.Match(req.Method, strings.TrimPrefix(req.Path, "/bitcoin"))
+	if err != nil {
+		logger.Logger.Error("BitcoinGateway - Handle - No route", zap.Error(err), zap.String("method", req.Method), zap.String("path", req.Path))
+		return nil, err
+	}
+
+	// passthrough calls are bounded and retried per RPC method, the other
+	// routes by path
+	timeout := route.Path
+	idempotent := true
+	if method, ok := params["method"]; ok {
+		if _, allowed := g.methods[strings.ToLower(method)]; !allowed {
+			err = fmt.Errorf("%w: %s", ErrRouteNotFound, method)
+			logger.Logger.Error("BitcoinGateway - Handle - Method is not allowed", zap.Error(err))
+			return nil, err
+		}
+		timeout = strings.ToLower(method)
+		_, broadcast := bitcoinTxMethods[timeout]
+		idempotent = !broadcast
+	}
+
+	ctx, cancel := withTimeout(types.WithRequestMetadata(ctx, metadata), g.timeouts.For(timeout, route.Timeout))
+	defer cancel()
+
+	return g.retry.For(timeout, idempotent).Do(ctx, func() (interface{}, error) {
+		if err := g.rateLimit.Wait(ctx); err != nil {
+			logger.Logger.Error("BitcoinGateway - Handle", zap.Error(err))
 			return nil, err
 		}
-		return g.makeSaiRequest(g.context.Context, g.url, req)
+		return route.Handler(ctx, req, params)
 	})
 }
 
 func (g *BitcoinGateway) Close() {
 
 }
+
+// passthrough forwards an allowed RPC call. Positional arguments are taken from
+// the "params" field, otherwise the whole payload is sent as named arguments.
+func (g *BitcoinGateway) passthrough(ctx context.Context, req types.InboundRequest, method string) (interface{}, error) {
+	var params interface{} = req.Payload
+	if positional, ok := req.Payload["params"]; ok {
+		if _, isArray := positional.([]interface{}); isArray {
+			params = positional
+		} else {
+			params = []interface{}{positional}
+		}
+	}
+
+	if req.Payload == nil {
+		params = []interface{}{}
+	}
+
+	var result interface{}
+	if err := g.call(ctx, strings.ToLower(method), params, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// call goes through the circuit breaker of bitcoind, RPC errors are answers
+// and do not count as failures.
+func (g *BitcoinGateway) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if err := g.breaker.Allow(); err != nil {
+		return err
+	}
+
+	err := g.send(ctx, method, params, result)
+
+	var rpcErr *types.BitcoinRPCError
+	if errors.As(err, &rpcErr) {
+		g.breaker.Done(nil)
+	} else {
+		g.breaker.Done(err)
+	}
+
+	return err
+}
+
+func (g *BitcoinGateway) send(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	payload, err := json.Marshal(types.BitcoinRPCRequest{
+		Jsonrpc: "1.0",
+		ID:      strconv.FormatUint(g.requestID.Add(1), 10),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewBuffer(payload))
+	if err != nil {
+		logger.Logger.Error("BitcoinGateway - call", zap.Error(err))
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		logger.Logger.Error("BitcoinGateway - call - Unable to connect to server", zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Logger.Error("BitcoinGateway - call", zap.Error(err))
+		return err
+	}
+
+	// bitcoind reports RPC errors with a non-200 status and a JSON body, so the
+	// body is decoded first and the status only matters when it is not JSON.
+	response := new(types.BitcoinRPCResponse)
+	if err = json.Unmarshal(body, response); err != nil {
+		err = apierror.Newf(apierror.Upstream, "non-200 status code: %s", resp.Status)
+		logger.Logger.Error("BitcoinGateway - call", zap.Error(err), zap.String("method", method))
+		return err
+	}
+
+	if response.Error != nil {
+		logger.Logger.Error("BitcoinGateway - call", zap.Error(response.Error), zap.String("method", method))
+		return bitcoinError(response.Error)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(response.Result, result)
+}
+
+// bitcoinError gives bitcoind RPC errors a code, the RPC error stays wrapped.
+func bitcoinError(rpcErr *types.BitcoinRPCError) error {
+	code := apierror.Upstream
+
+	switch rpcErr.Code {
+	case -5:
+		// RPC_INVALID_ADDRESS_OR_KEY, also "No such mempool or blockchain transaction"
+		code = apierror.NotFound
+	case -3, -8, -22, -32602:
+		code = apierror.InvalidArgument
+	case -25, -26:
+		code = apierror.FailedPrecondition
+	case -27:
+		code = apierror.AlreadyExists
+	case -32601:
+		code = apierror.NotFound
+	case -28:
+		code = apierror.Unavailable
+	}
+
+	return apierror.Wrap(code, rpcErr)
+}
+
+func (g *BitcoinGateway) status(ctx context.Context) (*types.BitcoinStatus, error) {
+	var response = new(types.BitcoinStatus)
+
+	if address, err := url.Parse(g.url); err == nil {
+		address.User = nil
+		response.NodeInfo.RPCAddress = address.String()
+	}
+
+	var chainInfo struct {
+		Chain                string  `json:"chain"`
+		Blocks               uint64  `json:"blocks"`
+		Headers              uint64  `json:"headers"`
+		BestBlockHash        string  `json:"bestblockhash"`
+		VerificationProgress float64 `json:"verificationprogress"`
+		InitialBlockDownload bool    `json:"initialblockdownload"`
+		Pruned               bool    `json:"pruned"`
+		PruneHeight          uint64  `json:"pruneheight"`
+	}
+	if err := g.call(ctx, "getblockchaininfo", nil, &chainInfo); err != nil {
+		logger.Logger.Error("[bitcoin-status] Failed to get blockchain info", zap.Error(err))
+		return nil, err
+	}
+
+	response.NodeInfo.Network = chainInfo.Chain
+	response.SyncInfo.CatchingUp = chainInfo.InitialBlockDownload || chainInfo.Blocks < chainInfo.Headers
+	response.SyncInfo.Headers = chainInfo.Headers
+	response.SyncInfo.VerificationProgress = chainInfo.VerificationProgress
+	response.SyncInfo.Pruned = chainInfo.Pruned
+	response.SyncInfo.LatestBlockHash = chainInfo.BestBlockHash
+	response.SyncInfo.LatestBlockHeight = chainInfo.Blocks
+
+	var networkInfo struct {
+		Version         int    `json:"version"`
+		SubVersion      string `json:"subversion"`
+		ProtocolVersion int    `json:"protocolversion"`
+	}
+	if err := g.call(ctx, "getnetworkinfo", nil, &networkInfo); err != nil {
+		logger.Logger.Error("[bitcoin-status] Failed to get network info", zap.Error(err))
+		return nil, err
+	}
+
+	response.NodeInfo.Version.Node = networkInfo.Version
+	response.NodeInfo.Version.SubVersion = networkInfo.SubVersion
+	response.NodeInfo.Version.Protocol = networkInfo.ProtocolVersion
+
+	var latestHeader struct {
+		Time uint64 `json:"time"`
+	}
+	if err := g.call(ctx, "getblockheader", []interface{}{chainInfo.BestBlockHash}, &latestHeader); err != nil {
+		logger.Logger.Error("[bitcoin-status] Failed to get latest block", zap.Error(err))
+		return nil, err
+	}
+	response.SyncInfo.LatestBlockTime = latestHeader.Time
+
+	// a pruned node can only serve blocks above its prune height
+	var earliestHash string
+	if err := g.call(ctx, "getblockhash", []interface{}{chainInfo.PruneHeight}, &earliestHash); err != nil {
+		logger.Logger.Error("[bitcoin-status] Failed to get earliest block hash", zap.Error(err))
+		return nil, err
+	}
+
+	var earliestHeader struct {
+		Time uint64 `json:"time"`
+	}
+	if err := g.call(ctx, "getblockheader", []interface{}{earliestHash}, &earliestHeader); err != nil {
+		logger.Logger.Error("[bitcoin-status] Failed to get earliest block", zap.Error(err))
+		return nil, err
+	}
+
+	response.SyncInfo.EarliestBlockHash = earliestHash
+	response.SyncInfo.EarliestBlockHeight = chainInfo.PruneHeight
+	response.SyncInfo.EarliestBlockTime = earliestHeader.Time
+
+	var mempoolInfo struct {
+		Size          uint64  `json:"size"`
+		Bytes         uint64  `json:"bytes"`
+		MempoolMinFee float64 `json:"mempoolminfee"`
+	}
+	if err := g.call(ctx, "getmempoolinfo", nil, &mempoolInfo); err != nil {
+		logger.Logger.Error("[bitcoin-status] Failed to get mempool info", zap.Error(err))
+		return nil, err
+	}
+
+	response.Mempool.Size = mempoolInfo.Size
+	response.Mempool.Bytes = mempoolInfo.Bytes
+	response.Mempool.MinFeeRate = mempoolInfo.MempoolMinFee
+
+	// nodes without enough history (fresh nodes, regtest) have no estimates,
+	// those targets are left out rather than failing the whole status
+	response.FeeEstimates = make(map[string]float64, len(bitcoinFeeTargets))
+	for _, target := range bitcoinFeeTargets {
+		var estimate struct {
+			FeeRate float64  `json:"feerate"`
+			Errors  []string `json:"errors"`
+		}
+		if err := g.call(ctx, "estimatesmartfee", []interface{}{target}, &estimate); err != nil || len(estimate.Errors) > 0 {
+			continue
+		}
+
+		response.FeeEstimates[strconv.Itoa(target)] = estimate.FeeRate
+	}
+
+	return response, nil
+}
+
+func (g *BitcoinGateway) balance(address string) (*types.BitcoinBalanceResponse, error) {
+	utxos, _, err := g.readUtxos(address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &types.BitcoinBalanceResponse{
+		Address:   address,
+		UtxoCount: len(utxos),
+	}
+
+	for _, utxo := range utxos {
+		response.Balance += utxo.Value
+	}
+
+	response.IndexedHeight, err = g.indexedHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (g *BitcoinGateway) utxos(req types.InboundRequest, address string) (*types.BitcoinUtxosResponse, error) {
+	request := pageRequest{}
+
+	jsonData, err := json.Marshal(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(jsonData, &request); err != nil {
+		return nil, err
+	}
+
+	options := &adapter.Options{
+		Limit: int64(request.Limit),
+		Skip:  int64(request.Offset),
+		Sort:  map[string]interface{}{"height": 1},
+	}
+	if request.CountTotal > 0 {
+		options.Count = 1
+	}
+
+	utxos, total, err := g.readUtxos(address, options)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &types.BitcoinUtxosResponse{Utxos: utxos}
+	if request.CountTotal > 0 {
+		response.Pagination = &types.Pagination{Total: total}
+	}
+
+	response.IndexedHeight, err = g.indexedHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (g *BitcoinGateway) readUtxos(address string, options *adapter.Options) ([]types.BitcoinUtxo, int, error) {
+	criteria := map[string]interface{}{
+		"address": address,
+		"spent":   false,
+	}
+
+	response, err := g.storage.Read(g.collection+"_utxos", criteria, options, []string{})
+	if err != nil {
+		logger.Logger.Error("[bitcoin-utxos] Failed to read utxos", zap.Error(err))
+		return nil, 0, err
+	}
+
+	utxos := make([]types.BitcoinUtxo, 0, len(response.Result))
+	if err = remarshal(response.Result, &utxos); err != nil {
+		logger.Logger.Error("[bitcoin-utxos] Invalid utxo format", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return utxos, response.Count, nil
+}
+
+// indexedHeight is the height of the last block handled by the bitcoin indexer,
+// balances and utxos are only complete up to it.
+func (g *BitcoinGateway) indexedHeight() (int64, error) {
+	options := &adapter.Options{
+		Limit: 1,
+		Sort:  map[string]interface{}{"height": -1},
+	}
+
+	response, err := g.storage.Read(g.collection+"_blocks", map[string]interface{}{}, options, []string{"height"})
+	if err != nil {
+		logger.Logger.Error("[bitcoin-indexed-height] Failed to read blocks", zap.Error(err))
+		return 0, err
+	}
+
+	if len(response.Result) == 0 {
+		return 0, nil
+	}
+
+	var block struct {
+		Height int64 `json:"height"`
+	}
+	if err = remarshal(response.Result[0], &block); err != nil {
+		return 0, err
+	}
+
+	return block.Height, nil
+}
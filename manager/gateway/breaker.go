@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+
+	defaultBreakerFailures = 5
+	defaultBreakerOpen     = 30 * time.Second
+)
+
+var ErrCircuitOpen = apierror.New(apierror.Unavailable, "circuit open")
+
+// CircuitBreaker fails calls to an upstream fast once it failed too many times
+// in a row. After the open period one probe call is let through: its success
+// closes the circuit, its failure opens it again.
+type CircuitBreaker struct {
+	name      string
+	target    string
+	threshold int
+	openFor   time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	lastError string
+}
+
+// Allow returns ErrCircuitOpen while calls to the upstream must not be made.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openFor {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, b.name)
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+	case BreakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, b.name)
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// Done records the outcome of an allowed call. Only transient errors count as
+// failures, an upstream answering that something is not found is healthy, and
+// calls given up by the caller say nothing about it.
+func (b *CircuitBreaker) Done(err error) {
+	failed := err != nil && transient(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	if !failed {
+		if b.state != BreakerClosed {
+			logger.Logger.Info("CircuitBreaker - closed", zap.String("upstream", b.name))
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		if b.state != BreakerOpen {
+			logger.Logger.Warn("CircuitBreaker - open", zap.String("upstream", b.name), zap.Int("failures", b.failures), zap.Error(err))
+		}
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+func (b *CircuitBreaker) Status() types.BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := types.BreakerStatus{
+		Name:      b.name,
+		Target:    b.target,
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+
+	return status
+}
+
+// Breakers holds the circuit breaker of every upstream, by name. now is the
+// clock of the breakers, time.Now outside of tests.
+type Breakers struct {
+	threshold int
+	openFor   time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	items map[string]*CircuitBreaker
+}
+
+func NewBreakers(config types.BreakerConfig) *Breakers {
+	breakers := &Breakers{
+		threshold: config.Failures,
+		openFor:   time.Duration(config.Open) * time.Second,
+		now:       time.Now,
+		items:     map[string]*CircuitBreaker{},
+	}
+
+	if breakers.threshold <= 0 {
+		breakers.threshold = defaultBreakerFailures
+	}
+	if breakers.openFor <= 0 {
+		breakers.openFor = defaultBreakerOpen
+	}
+
+	return breakers
+}
+
+// Get returns the breaker of the upstream, created on first use. target is
+// only reported and should not carry credentials.
+func (b *Breakers) Get(name, target string) *CircuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if breaker, ok := b.items[name]; ok {
+		return breaker
+	}
+
+	breaker := &CircuitBreaker{
+		name:      name,
+		target:    target,
+		threshold: b.threshold,
+		openFor:   b.openFor,
+		now:       b.now,
+		state:     BreakerClosed,
+	}
+	b.items[name] = breaker
+
+	return breaker
+}
+
+// Status lists the breakers by name.
+func (b *Breakers) Status() []types.BreakerStatus {
+	b.mu.Lock()
+	breakers := make([]*CircuitBreaker, 0, len(b.items))
+	for _, breaker := range b.items {
+		breakers = append(breakers, breaker)
+	}
+	b.mu.Unlock()
+
+	statuses := make([]types.BreakerStatus, 0, len(breakers))
+	for _, breaker := range breakers {
+		statuses = append(statuses, breaker.Status())
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// upstreamTarget is the address of an upstream without credentials or path,
+// which public provider URLs often carry their API key in.
+func upstreamTarget(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+
+	return parsed.Scheme + "://" + parsed.Host
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	breakers := NewBreakers(types.BreakerConfig{Failures: 3, Open: 30})
+	breakers.now = func() time.Time { return now }
+	breaker := breakers.Get("sekai/grpc", "http://sekai:9090")
+
+	failure := apierror.New(apierror.Unavailable, "node down")
+
+	call := func(err error) error {
+		t.Helper()
+
+		if allowErr := breaker.Allow(); allowErr != nil {
+			return allowErr
+		}
+		breaker.Done(err)
+
+		return nil
+	}
+
+	state := func(want string) {
+		t.Helper()
+
+		if status := breaker.Status(); status.State != want {
+			t.Fatalf("state = %s, want %s", status.State, want)
+		}
+	}
+
+	// caller errors and cancellations are not failures of the upstream
+	for _, err := range []error{ErrRouteNotFound, context.Canceled, context.DeadlineExceeded, failure, failure} {
+		if err := call(err); err != nil {
+			t.Fatalf("closed breaker refused a call: %v", err)
+		}
+	}
+	state(BreakerClosed)
+
+	if err := call(failure); err != nil {
+		t.Fatalf("closed breaker refused a call: %v", err)
+	}
+	state(BreakerOpen)
+
+	if status := breaker.Status(); status.Failures != 3 || status.OpenedAt == nil || !status.OpenedAt.Equal(now) {
+		t.Fatalf("status = %+v, want 3 failures and opened now", status)
+	}
+
+	now = now.Add(29 * time.Second)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow of an open breaker = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// the open period is over, one probe is let through
+	now = now.Add(time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow after the open period = %v, want the probe", err)
+	}
+	state(BreakerHalfOpen)
+
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow during the probe = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// a failed probe opens the circuit for another period
+	breaker.Done(failure)
+	state(BreakerOpen)
+
+	now = now.Add(29 * time.Second)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow after a failed probe = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	now = now.Add(time.Second)
+	if err := call(nil); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	state(BreakerClosed)
+
+	if status := breaker.Status(); status.Failures != 0 || status.OpenedAt != nil {
+		t.Fatalf("status after a successful probe = %+v, want reset", status)
+	}
+}
+
+func TestBreakersGet(t *testing.T) {
+	breakers := NewBreakers(types.BreakerConfig{})
+
+	first := breakers.Get("b", "")
+	if breakers.Get("b", "") != first {
+		t.Fatal("Get created a second breaker for the same upstream")
+	}
+	breakers.Get("a", "")
+
+	statuses := breakers.Status()
+	if len(statuses) != 2 || statuses[0].Name != "a" || statuses[1].Name != "b" {
+		t.Fatalf("Status = %+v, want a and b", statuses)
+	}
+
+	if first.threshold != defaultBreakerFailures || first.openFor != defaultBreakerOpen {
+		t.Fatalf("defaults = %d failures for %s, want %d for %s", first.threshold, first.openFor, defaultBreakerFailures, defaultBreakerOpen)
+	}
+}
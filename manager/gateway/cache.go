@@ -0,0 +1,292 @@
+package gateway
+
+import (
+	"container/list"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	CacheBackendMemory  = "memory"
+	CacheBackendStorage = "storage"
+
+	cacheCollection = "interx_cache"
+
+	cacheSweepInterval = time.Minute
+)
+
+// CacheBackend stores raw response bodies. Entries written with a non-zero
+// height are dropped by Invalidate once the chain moves past that height.
+type CacheBackend interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration, height int64)
+	Invalidate(height int64)
+}
+
+// cacheSweeper is implemented by backends that keep expired entries until they
+// are deleted, Sweep drops the ones expired at now.
+type cacheSweeper interface {
+	Sweep(now time.Time)
+}
+
+type ResponseCache struct {
+	backend CacheBackend
+	height  atomic.Int64
+}
+
+func NewResponseCache(config types.CacheConfig, storage types.Storage) (*ResponseCache, error) {
+	var backend CacheBackend
+
+	switch config.Backend {
+	case "":
+		return nil, nil
+	case CacheBackendMemory:
+		backend = NewMemoryCache(config.Size)
+	case CacheBackendStorage:
+		backend = NewStorageCache(storage)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", config.Backend)
+	}
+
+	return &ResponseCache{backend: backend}, nil
+}
+
+func (c *ResponseCache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	return c.backend.Get(key)
+}
+
+// Set stores the value. Height bound entries live until the next block is seen,
+// immutable ones only expire by TTL.
+func (c *ResponseCache) Set(key string, value []byte, ttl time.Duration, immutable bool) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+
+	height := int64(0)
+	if !immutable {
+		height = c.height.Load()
+		if height == 0 {
+			return
+		}
+	}
+
+	c.backend.Set(key, value, ttl, height)
+}
+
+// ObserveHeight records the latest chain height and drops every height bound
+// entry produced before it.
+func (c *ResponseCache) ObserveHeight(height int64) {
+	if c == nil {
+		return
+	}
+
+	for {
+		current := c.height.Load()
+		if height <= current {
+			return
+		}
+
+		if c.height.CompareAndSwap(current, height) {
+			c.backend.Invalidate(height)
+			return
+		}
+	}
+}
+
+// Run sweeps expired entries out of backends that do not evict them on their
+// own, until ctx ends.
+func (c *ResponseCache) Run(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	sweeper, ok := c.backend.(cacheSweeper)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(cacheSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sweeper.Sweep(now)
+		}
+	}
+}
+
+func (c *ResponseCache) Height() int64 {
+	if c == nil {
+		return 0
+	}
+
+	return c.height.Load()
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	height  int64
+}
+
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func NewMemoryCache(capacity int) *MemoryCache {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+
+	return &MemoryCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration, height int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryEntry{
+		key:     key,
+		value:   value,
+		expires: time.Now().Add(ttl),
+		height:  height,
+	}
+
+	if element, ok := c.items[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *MemoryCache) Invalidate(height int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*memoryEntry)
+		if entry.height > 0 && entry.height < height {
+			c.remove(element)
+		}
+		element = next
+	}
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*memoryEntry).key)
+}
+
+// StorageCache keeps entries in sai-storage-mongo so that every manager sharing
+// the storage service also shares the cache.
+type StorageCache struct {
+	storage types.Storage
+}
+
+func NewStorageCache(storage types.Storage) *StorageCache {
+	return &StorageCache{storage: storage}
+}
+
+func (c *StorageCache) Get(key string) ([]byte, bool) {
+	response, err := c.storage.Read(cacheCollection, map[string]interface{}{"key": key}, nil, []string{})
+	if err != nil || len(response.Result) == 0 {
+		return nil, false
+	}
+
+	entry := response.Result[0]
+	if time.Now().Unix() > cast.ToInt64(entry["expires"]) {
+		return nil, false
+	}
+
+	value, err := base64.StdEncoding.DecodeString(cast.ToString(entry["value"]))
+	if err != nil {
+		logger.Logger.Error("StorageCache - Get - Invalid cache entry", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+
+	return value, true
+}
+
+func (c *StorageCache) Set(key string, value []byte, ttl time.Duration, height int64) {
+	_, err := c.storage.Upsert(cacheCollection, map[string]interface{}{"key": key}, map[string]interface{}{
+		"key":     key,
+		"value":   base64.StdEncoding.EncodeToString(value),
+		"expires": time.Now().Add(ttl).Unix(),
+		"height":  height,
+	})
+	if err != nil {
+		logger.Logger.Error("StorageCache - Set", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func (c *StorageCache) Invalidate(height int64) {
+	_, err := c.storage.Delete(cacheCollection, map[string]interface{}{
+		"height": map[string]interface{}{"$gt": 0, "$lt": height},
+	})
+	if err != nil {
+		logger.Logger.Error("StorageCache - Invalidate", zap.Int64("height", height), zap.Error(err))
+	}
+}
+
+// Sweep deletes the entries expired at now, Get ignores them but nothing else
+// removes entries that are not bound to a height.
+func (c *StorageCache) Sweep(now time.Time) {
+	_, err := c.storage.Delete(cacheCollection, map[string]interface{}{
+		"expires": map[string]interface{}{"$lt": now.Unix()},
+	})
+	if err != nil {
+		logger.Logger.Error("StorageCache - Sweep", zap.Error(err))
+	}
+}
@@ -0,0 +1,217 @@
+package gateway
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cast"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+)
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache(2)
+
+	cache.Set("a", []byte("a"), time.Minute, 0)
+	cache.Set("b", []byte("b"), time.Minute, 0)
+
+	// a is used last, b is the least recently used entry
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+
+	cache.Set("c", []byte("c"), time.Minute, 0)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("b kept, want it evicted as the least recently used entry")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if value, ok := cache.Get(key); !ok || string(value) != key {
+			t.Fatalf("Get(%s) = %q, %v, want %q", key, value, ok, key)
+		}
+	}
+
+	// overwriting keeps a single entry
+	cache.Set("a", []byte("a2"), time.Minute, 0)
+	if value, _ := cache.Get("a"); string(value) != "a2" || cache.order.Len() != 2 {
+		t.Fatalf("overwrite = %q with %d entries, want a2 with 2", value, cache.order.Len())
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := NewMemoryCache(0)
+
+	cache.Set("a", []byte("a"), -time.Second, 0)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("expired entry served")
+	}
+
+	if len(cache.items) != 0 || cache.order.Len() != 0 {
+		t.Fatal("expired entry kept after Get")
+	}
+}
+
+func TestMemoryCacheInvalidate(t *testing.T) {
+	cache := NewMemoryCache(0)
+
+	cache.Set("old", []byte("old"), time.Minute, 10)
+	cache.Set("new", []byte("new"), time.Minute, 11)
+	cache.Set("immutable", []byte("immutable"), time.Minute, 0)
+
+	cache.Invalidate(11)
+
+	tests := map[string]bool{"old": false, "new": true, "immutable": true}
+	for key, want := range tests {
+		if _, ok := cache.Get(key); ok != want {
+			t.Fatalf("Get(%s) after invalidating below 11 = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestResponseCacheHeight(t *testing.T) {
+	cache, err := NewResponseCache(types.CacheConfig{Backend: CacheBackendMemory}, nil)
+	if err != nil {
+		t.Fatalf("NewResponseCache: %v", err)
+	}
+
+	// nothing is bound to a height before one is known
+	cache.Set("early", []byte("early"), time.Minute, false)
+	if _, ok := cache.Get("early"); ok {
+		t.Fatal("height bound entry stored before any height was observed")
+	}
+
+	cache.ObserveHeight(10)
+	cache.Set("block", []byte("block"), time.Minute, false)
+	cache.Set("immutable", []byte("immutable"), time.Minute, true)
+
+	cache.ObserveHeight(9)
+	if _, ok := cache.Get("block"); !ok || cache.Height() != 10 {
+		t.Fatalf("an older height moved the cache to %d", cache.Height())
+	}
+
+	cache.ObserveHeight(11)
+	if _, ok := cache.Get("block"); ok {
+		t.Fatal("entry of height 10 served at height 11")
+	}
+	if _, ok := cache.Get("immutable"); !ok {
+		t.Fatal("immutable entry dropped by a new height")
+	}
+
+	var disabled *ResponseCache
+	disabled.Set("a", []byte("a"), time.Minute, true)
+	disabled.ObserveHeight(1)
+	if _, ok := disabled.Get("a"); ok || disabled.Height() != 0 {
+		t.Fatal("disabled cache stored an entry")
+	}
+}
+
+// cacheStorage keeps cache documents by key and understands the $gt and $lt
+// selectors of Invalidate and Sweep.
+type cacheStorage struct {
+	mu        sync.Mutex
+	documents map[string]map[string]interface{}
+}
+
+func (s *cacheStorage) Create(collection string, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *cacheStorage) Read(collection string, criteria map[string]interface{}, options *adapter.Options, fields []string) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []map[string]interface{}
+	if document, ok := s.documents[cast.ToString(criteria["key"])]; ok {
+		result = append(result, document)
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK", Result: result, Count: len(result)}, nil
+}
+
+func (s *cacheStorage) Upsert(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.documents[cast.ToString(criteria["key"])] = document.(map[string]interface{})
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *cacheStorage) Update(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *cacheStorage) Delete(collection string, criteria map[string]interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, document := range s.documents {
+		if inRange(document, criteria) {
+			delete(s.documents, key)
+		}
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func inRange(document, criteria map[string]interface{}) bool {
+	for field, selector := range criteria {
+		value := cast.ToInt64(document[field])
+		for operator, bound := range selector.(map[string]interface{}) {
+			switch operator {
+			case "$gt":
+				if value <= cast.ToInt64(bound) {
+					return false
+				}
+			case "$lt":
+				if value >= cast.ToInt64(bound) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+func TestStorageCache(t *testing.T) {
+	storage := &cacheStorage{documents: map[string]map[string]interface{}{}}
+	cache := NewStorageCache(storage)
+
+	cache.Set("old", []byte("old"), time.Minute, 10)
+	cache.Set("new", []byte("new"), time.Minute, 11)
+	cache.Set("immutable", []byte("immutable"), time.Minute, 0)
+	cache.Set("expired", []byte("expired"), -time.Minute, 0)
+
+	if value, ok := cache.Get("new"); !ok || string(value) != "new" {
+		t.Fatalf("Get(new) = %q, %v, want new", value, ok)
+	}
+
+	if _, ok := cache.Get("expired"); ok {
+		t.Fatal("expired entry served")
+	}
+
+	cache.Invalidate(11)
+
+	if _, ok := storage.documents["old"]; ok {
+		t.Fatal("entry of height 10 kept after invalidating below 11")
+	}
+	if _, ok := storage.documents["immutable"]; !ok {
+		t.Fatal("immutable entry dropped by invalidation")
+	}
+
+	cache.Sweep(time.Now())
+
+	if _, ok := storage.documents["expired"]; ok {
+		t.Fatal("expired entry kept after a sweep")
+	}
+	for _, key := range []string{"new", "immutable"} {
+		if _, ok := storage.documents[key]; !ok {
+			t.Fatalf("live entry %s dropped by a sweep", key)
+		}
+	}
+}
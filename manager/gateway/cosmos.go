@@ -1,25 +1,33 @@
 package gateway
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	cosmosAuth "github.com/KiraCore/sai-interx-manager/proto-gen/cosmos/auth/v1beta1"
 	cosmosBank "github.com/KiraCore/sai-interx-manager/proto-gen/cosmos/bank/v1beta1"
 	cosmosTx "github.com/KiraCore/sai-interx-manager/proto-gen/cosmos/tx/v1beta1"
+	kiraBasket "github.com/KiraCore/sai-interx-manager/proto-gen/kira/basket"
+	kiraBridge "github.com/KiraCore/sai-interx-manager/proto-gen/kira/bridge"
+	kiraCollectives "github.com/KiraCore/sai-interx-manager/proto-gen/kira/collectives"
+	kiraCustody "github.com/KiraCore/sai-interx-manager/proto-gen/kira/custody"
+	kiraDistributor "github.com/KiraCore/sai-interx-manager/proto-gen/kira/distributor"
+	kiraEvidence "github.com/KiraCore/sai-interx-manager/proto-gen/kira/evidence"
 	kiraGov "github.com/KiraCore/sai-interx-manager/proto-gen/kira/gov"
+	kiraLayer2 "github.com/KiraCore/sai-interx-manager/proto-gen/kira/layer2"
 	kiraMultiStaking "github.com/KiraCore/sai-interx-manager/proto-gen/kira/multistaking"
+	kiraRecovery "github.com/KiraCore/sai-interx-manager/proto-gen/kira/recovery"
 	kiraSlashing "github.com/KiraCore/sai-interx-manager/proto-gen/kira/slashing/v1beta1"
 	kiraSpending "github.com/KiraCore/sai-interx-manager/proto-gen/kira/spending"
 	kiraStaking "github.com/KiraCore/sai-interx-manager/proto-gen/kira/staking"
@@ -27,31 +35,47 @@ import (
 	kiraUbi "github.com/KiraCore/sai-interx-manager/proto-gen/kira/ubi"
 	kiraUpgrades "github.com/KiraCore/sai-interx-manager/proto-gen/kira/upgrade"
 
+	"github.com/KiraCore/sai-interx-manager/apierror"
 	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/signer"
 	"github.com/KiraCore/sai-interx-manager/types"
 	"github.com/KiraCore/sai-service/service"
 	sekaitypes "github.com/KiraCore/sekai/types"
+	basketTypes "github.com/KiraCore/sekai/x/basket/types"
+	bridgeTypes "github.com/KiraCore/sekai/x/bridge/types"
+	collectivesTypes "github.com/KiraCore/sekai/x/collectives/types"
+	custodyTypes "github.com/KiraCore/sekai/x/custody/types"
+	distributorTypes "github.com/KiraCore/sekai/x/distributor/types"
+	ethereumTypes "github.com/KiraCore/sekai/x/ethereum/types"
+	evidenceTypes "github.com/KiraCore/sekai/x/evidence/types"
+	govTypes "github.com/KiraCore/sekai/x/gov/types"
+	layer2Types "github.com/KiraCore/sekai/x/layer2/types"
+	multiStakingTypes "github.com/KiraCore/sekai/x/multistaking/types"
+	recoveryTypes "github.com/KiraCore/sekai/x/recovery/types"
+	slashingTypes "github.com/KiraCore/sekai/x/slashing/types"
+	spendingTypes "github.com/KiraCore/sekai/x/spending/types"
+	stakingTypes "github.com/KiraCore/sekai/x/staking/types"
+	tokensTypes "github.com/KiraCore/sekai/x/tokens/types"
+	ubiTypes "github.com/KiraCore/sekai/x/ubi/types"
+	upgradeTypes "github.com/KiraCore/sekai/x/upgrade/types"
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/codec"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
-	"github.com/cosmos/cosmos-sdk/crypto/hd"
-	"github.com/cosmos/cosmos-sdk/crypto/keyring"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
-	"github.com/cosmos/go-bip39"
-	"github.com/google/uuid"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
 )
 
 type Proxy struct {
-	mux  *runtime.ServeMux
-	conn *grpc.ClientConn
+	pool  *NodePool
+	cache *ResponseCache
+	ttl   time.Duration
 }
 
 type CosmosGateway struct {
@@ -59,10 +83,18 @@ type CosmosGateway struct {
 	storage   types.Storage
 	config    types.CosmosConfig
 	grpcProxy *Proxy
+	router    *Router
 	txConfig  client.TxConfig
-	kRing     keyring.Keyring
-	kName     string
-	PubKey    *secp256k1.PubKey
+	signer    *signer.Signer
+
+	faucetService *faucetService
+
+	rateLimits map[string]*RateLimiter
+	timeouts   Timeouts
+	cache      *ResponseCache
+	memo       *heightMemo
+	height     atomic.Int64
+	stop       context.CancelFunc
 }
 
 const (
@@ -81,40 +113,108 @@ var (
 	ConsNodePubKeyPrefix   = "kiravalconspub"
 )
 
+const routingErrorHeader = "X-Interx-Routing-Error"
+
+var uncachedTendermintPaths = []string{
+	"/status",
+	"/broadcast_",
+	"/unconfirmed_txs",
+	"/num_unconfirmed_txs",
+	"/net_info",
+	"/health",
+	"/consensus_state",
+	"/dump_consensus_state",
+}
+
+var kiraInterfaces = []func(codectypes.InterfaceRegistry){
+	basketTypes.RegisterInterfaces,
+	bridgeTypes.RegisterInterfaces,
+	collectivesTypes.RegisterInterfaces,
+	custodyTypes.RegisterInterfaces,
+	distributorTypes.RegisterInterfaces,
+	ethereumTypes.RegisterInterfaces,
+	evidenceTypes.RegisterInterfaces,
+	govTypes.RegisterInterfaces,
+	layer2Types.RegisterInterfaces,
+	multiStakingTypes.RegisterInterfaces,
+	recoveryTypes.RegisterInterfaces,
+	slashingTypes.RegisterInterfaces,
+	spendingTypes.RegisterInterfaces,
+	stakingTypes.RegisterInterfaces,
+	tokensTypes.RegisterInterfaces,
+	ubiTypes.RegisterInterfaces,
+	upgradeTypes.RegisterInterfaces,
+}
+
 var _ types.Gateway = (*CosmosGateway)(nil)
 
-func newGRPCGatewayProxy(ctx *service.Context, address string) (*Proxy, error) {
-	conn, err := grpc.DialContext(
-		ctx.Context,
-		address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to gRPC server: %v", err)
+func (p *Proxy) ServeGRPC(r *http.Request) ([]byte, error) {
+	r.Header.Set("Content-Type", "application/json")
+
+	cacheKey := "grpc:" + r.URL.RequestURI()
+	cacheable := r.Method == http.MethodGet && p.ttl > 0
+
+	height, pinned := blockHeightFromContext(r.Context())
+	if pinned {
+		r.Header.Set(blockHeightMetadata, strconv.FormatInt(height, 10))
+		cacheKey = fmt.Sprintf("grpc@%d:%s", height, r.URL.RequestURI())
 	}
 
-	mux := runtime.NewServeMux()
+	if cacheable {
+		if cached, ok := p.cache.Get(cacheKey); ok {
+			return cached, nil
+		}
+	}
 
-	if err := registerHandlers(ctx, mux, conn); err != nil {
-		conn.Close()
-		logger.Logger.Error("newGRPCGatewayProxy", zap.Error(err))
-		return nil, err
+	var reqBody []byte
+	if r.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(r.Body)
+		if err != nil {
+			logger.Logger.Error("CosmosGateway - Handle - gRPC gateway request", zap.Error(err))
+			return nil, err
+		}
 	}
 
-	return &Proxy{
-		mux:  mux,
-		conn: conn,
-	}, nil
+	var lastErr error = ErrNoHealthyNode
+	for _, node := range p.pool.candidates(routeHeight(r.Context(), "")) {
+		if err := node.grpcBreaker.Allow(); err != nil {
+			lastErr = err
+			continue
+		}
+
+		bodyBytes, err := p.serveNode(node, r, reqBody)
+		node.grpcBreaker.Done(err)
+		if errors.Is(err, errNodeUnavailable) {
+			p.pool.eject(node, err)
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if cacheable {
+			p.cache.Set(cacheKey, bodyBytes, p.ttl, pinned)
+		}
+
+		return bodyBytes, nil
+	}
+
+	logger.Logger.Error("CosmosGateway - Handle - no sekai node answered", zap.String("path", r.URL.Path), zap.Error(lastErr))
+
+	return nil, lastErr
 }
 
-func (p *Proxy) ServeGRPC(r *http.Request) ([]byte, error) {
-	r.Header.Set("Content-Type", "application/json")
+// errNodeUnavailable marks a gRPC Unavailable answer, the node is failed over.
+var errNodeUnavailable = apierror.New(apierror.Unavailable, "sekai node unavailable")
 
-	//Todo: add cache here
+func (p *Proxy) serveNode(node *sekaiNode, r *http.Request, reqBody []byte) ([]byte, error) {
+	nodeReq := r.Clone(r.Context())
+	nodeReq.Body = io.NopCloser(bytes.NewReader(reqBody))
 
 	recorder := httptest.NewRecorder()
-	p.mux.ServeHTTP(recorder, r)
+	node.mux.ServeHTTP(recorder, nodeReq)
 	resp := recorder.Result()
 
 	defer resp.Body.Close()
@@ -125,6 +225,17 @@ func (p *Proxy) ServeGRPC(r *http.Request) ([]byte, error) {
 		return nil, err
 	}
 
+	switch resp.Header.Get(routingErrorHeader) {
+	case strconv.Itoa(http.StatusNotFound):
+		return nil, fmt.Errorf("%w: %s", ErrRouteNotFound, r.URL.Path)
+	case strconv.Itoa(http.StatusMethodNotAllowed):
+		return nil, fmt.Errorf("%w: %s %s", ErrMethodNotAllowed, r.Method, r.URL.Path)
+	}
+
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("%w: %s: %s", errNodeUnavailable, node.name, bodyBytes)
+	}
+
 	if resp.StatusCode >= 400 {
 		var result = new(types.GRPCResponse)
 		err := json.Unmarshal(bodyBytes, &result)
@@ -133,20 +244,26 @@ func (p *Proxy) ServeGRPC(r *http.Request) ([]byte, error) {
 			return nil, err
 		}
 
-		errMsg := fmt.Sprintf("gRPC gateway error: url=%s status=%d, code=%f, message=%s, details=%s", r.URL.Path, resp.StatusCode, result.Code, result.Message, string(result.Details))
-
 		logger.Logger.Error("CosmosGateway - Handle - gRPC gateway error response",
+			zap.String("node", node.name),
 			zap.Int("status", resp.StatusCode),
 			zap.Any("code", result.Code),
 			zap.Any("message", result.Message),
 			zap.Any("details", result.Details))
 
-		return nil, fmt.Errorf(errMsg)
+		return nil, apierror.GRPC(codes.Code(result.Code), result.Message)
 	}
 
 	return bodyBytes, nil
 }
 
+// routingErrorHandler marks mux routing failures so ServeGRPC can tell an unknown
+// path apart from a gRPC NotFound returned by sekai.
+func routingErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, httpStatus int) {
+	w.Header().Set(routingErrorHeader, strconv.Itoa(httpStatus))
+	runtime.DefaultRoutingErrorHandler(ctx, mux, marshaler, w, r, httpStatus)
+}
+
 func registerHandlers(ctx *service.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {
 	if err := cosmosTx.RegisterServiceHandler(ctx.Context, mux, conn); err != nil {
 		logger.Logger.Error("registerHandlers", zap.Error(err))
@@ -203,77 +320,137 @@ func registerHandlers(ctx *service.Context, mux *runtime.ServeMux, conn *grpc.Cl
 		return err
 	}
 
+	if err := kiraBasket.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
+	if err := kiraBridge.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
+	if err := kiraCollectives.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
+	if err := kiraCustody.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
+	if err := kiraDistributor.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
+	if err := kiraLayer2.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
+	if err := kiraRecovery.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
+	if err := kiraEvidence.RegisterQueryHandler(ctx.Context, mux, conn); err != nil {
+		logger.Logger.Error("registerHandlers", zap.Error(err))
+		return err
+	}
+
 	return nil
 }
 
-func NewCosmosGateway(ctx *service.Context, storage types.Storage, cosmosConfig types.CosmosConfig) (*CosmosGateway, error) {
+func NewCosmosGateway(ctx *service.Context, storage types.Storage, cosmosConfig types.CosmosConfig, breakers *Breakers) (*CosmosGateway, error) {
 	config := sdk.GetConfig()
 	config.SetBech32PrefixForAccount(AccountAddressPrefix, AccountPubKeyPrefix)
 	config.SetBech32PrefixForValidator(ValidatorAddressPrefix, ValidatorPubKeyPrefix)
 	config.SetBech32PrefixForConsensusNode(ConsNodeAddressPrefix, ConsNodePubKeyPrefix)
 	config.Seal()
 
-	mnemonic := loadMnemonicFile()
-
-	if mnemonic == "" {
-		entropy, _ := bip39.NewEntropy(256)
-		mnemonic, _ = bip39.NewMnemonic(entropy)
-		saveMnemonicFile([]byte(mnemonic))
-	}
-
-	if !bip39.IsMnemonicValid(mnemonic) {
-		fmt.Println("Invalid Interx Mnemonic: ", mnemonic)
-		panic("Invalid Interx Mnemonic")
+	keys, err := signer.New(cosmosConfig.Signer)
+	if err != nil {
+		logger.Logger.Error("NewCosmosGateway - signer", zap.Error(err))
+		return nil, err
 	}
 
-	hdPath := sdk.GetConfig().GetFullBIP44Path()
 	interfaceRegistry := codectypes.NewInterfaceRegistry()
 	interfaceRegistry.RegisterInterface("types.PubKey", (*cryptotypes.PubKey)(nil), &secp256k1.PubKey{})
 	interfaceRegistry.RegisterInterface("types.PrivKey", (*cryptotypes.PrivKey)(nil), &secp256k1.PrivKey{})
-	interfaceRegistry.RegisterInterface("types.Msg", (*sdk.Msg)(nil), &banktypes.MsgSend{})
+	interfaceRegistry.RegisterInterface("types.Msg", (*sdk.Msg)(nil), &banktypes.MsgSend{}, &banktypes.MsgMultiSend{})
+	// sekai messages, so that any KIRA transaction can be decoded and simulated
+	for _, register := range kiraInterfaces {
+		register(interfaceRegistry)
+	}
 	_codec := codec.NewProtoCodec(interfaceRegistry)
 	txConfig := authtx.NewTxConfig(_codec, authtx.DefaultSignModes)
-	kRing := keyring.NewInMemory(_codec)
-	kName := uuid.New().String()
-
-	kInfo, err := kRing.NewAccount(kName, mnemonic, "", hdPath, hd.Secp256k1)
+	pool, err := newNodePool(ctx, cosmosConfig, breakers)
 	if err != nil {
-		logger.Logger.Error("Failed to create account from mnemonic", zap.Error(err))
+		logger.Logger.Error("NewCosmosGateway", zap.Error(err))
 		return nil, err
 	}
 
-	pubKey, err := kInfo.GetPubKey()
+	proxy := &Proxy{pool: pool}
+
+	cache, err := NewResponseCache(cosmosConfig.Cache, storage)
 	if err != nil {
-		logger.Logger.Error("Failed to create account from mnemonic", zap.Error(err))
+		pool.Close()
+		logger.Logger.Error("NewCosmosGateway", zap.Error(err))
 		return nil, err
 	}
 
-	faucetPubKey := pubKey.(*secp256k1.PubKey)
+	proxy.cache = cache
+	proxy.ttl = time.Duration(cosmosConfig.Cache.GRPCTTL) * time.Second
 
-	proxy, err := newGRPCGatewayProxy(ctx, cosmosConfig.Node.JsonRpc)
-	if err != nil {
-		logger.Logger.Error("NewCosmosGateway", zap.Error(err))
-		return nil, err
+	rateLimits := map[string]*RateLimiter{}
+	for class, limit := range cosmosConfig.RateLimits {
+		rateLimits[class] = NewRateLimiter(limit)
 	}
 
-	return &CosmosGateway{
-		BaseGateway: NewBaseGateway(ctx, cosmosConfig.Retries, time.Duration(cosmosConfig.RetryDelay)*time.Second, cosmosConfig.RateLimit),
+	timeouts := NewTimeouts(cosmosConfig.GWTimeout, cosmosConfig.Timeouts)
+
+	gateway := &CosmosGateway{
+		BaseGateway: NewBaseGateway(ctx, cosmosConfig.Retry, breakers, cosmosConfig.RateLimit),
 		storage:     storage,
 		config:      cosmosConfig,
 		grpcProxy:   proxy,
 		txConfig:    txConfig,
-		kRing:       kRing,
-		kName:       kName,
-		PubKey:      faucetPubKey,
-	}, nil
-}
+		signer:      keys,
+		rateLimits:  rateLimits,
+		timeouts:    timeouts,
+		cache:       cache,
+		memo:        newHeightMemo(timeouts.Default),
+	}
 
-func (g *CosmosGateway) Handle(data []byte) (interface{}, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.config.GWTimeout)*time.Second)
-	defer cancel()
+	gateway.router, err = NewRouter(gateway.routes()...)
+	if err != nil {
+		pool.Close()
+		logger.Logger.Error("NewCosmosGateway", zap.Error(err))
+		return nil, err
+	}
+
+	gateway.faucetService, err = newFaucetService(gateway, cosmosConfig.Faucet)
+	if err != nil {
+		pool.Close()
+		logger.Logger.Error("NewCosmosGateway", zap.Error(err))
+		return nil, err
+	}
 
-	g.context.Context = ctx
+	watchCtx, stop := context.WithCancel(ctx.Context)
+	gateway.stop = stop
+	go gateway.watchHeight(watchCtx)
+	go pool.watch(watchCtx)
+	go cache.Run(watchCtx)
+	go gateway.faucetService.run(watchCtx)
+	go gateway.faucetService.guard.Run(watchCtx)
+	go keys.Watch(watchCtx)
 
+	return gateway, nil
+}
+
+func (g *CosmosGateway) Handle(ctx context.Context, data []byte, metadata types.RequestMetadata) (interface{}, error) {
 	var req types.InboundRequest
 
 	if err := json.Unmarshal(data, &req); err != nil {
@@ -281,343 +458,229 @@ func (g *CosmosGateway) Handle(data []byte) (interface{}, error) {
 		return nil, err
 	}
 
-	accountsRegex := regexp.MustCompile(`^/kira/accounts/(.+)$`)
+	route, params, err := g.router.Match(req.Method, req.Path)
+	if err != nil {
+		logger.Logger.Error("CosmosGateway - Handle - No route", zap.Error(err), zap.String("method", req.Method), zap.String("path", req.Path))
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(types.WithRequestMetadata(ctx, metadata), g.timeouts.For(route.Path, route.Timeout))
+	defer cancel()
 
-	if matches := accountsRegex.FindStringSubmatch(req.Path); matches != nil {
-		accountID := matches[1]
+	ttl := g.routeTTL(route)
+	cacheKey := "route:" + strings.ToUpper(req.Method) + " " + req.Path + "?" + mapToQuery(req.Payload).Encode()
+	immutable := route.Immutable
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
+	if value, ok := req.Payload["height"]; ok && route.Historical {
+		height, err := parseBlockHeight(value)
+		if err != nil {
+			logger.Logger.Error("CosmosGateway - Handle - Invalid height", zap.Error(err))
+			return nil, err
+		}
 
-			return g.account(accountID)
-		})
+		delete(req.Payload, "height")
+		ctx = withBlockHeight(ctx, height)
+		immutable = true
 	}
 
-	balancesRegex := regexp.MustCompile(`^/kira/balances/(.+)$`)
+	if ttl > 0 {
+		if cached, ok := g.cache.Get(cacheKey); ok {
+			return json.RawMessage(cached), nil
+		}
+	}
 
-	if matches := balancesRegex.FindStringSubmatch(req.Path); matches != nil {
-		accountID := matches[1]
+	result, err := g.retry.For(route.Path, route.RateClass != RateClassTx).Do(ctx, func() (interface{}, error) {
+		if err := g.rateLimiter(route.RateClass).Wait(ctx); err != nil {
+			logger.Logger.Error("CosmosGateway - Handle - Rate limit exceeded", zap.Error(err), zap.String("class", route.RateClass))
+			return nil, err
+		}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
-			return g.balances(req, accountID)
-		})
+		return route.Handler(ctx, req, params)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	identityRecordsRegex := regexp.MustCompile(`^/kira/gov/identity_records/(.+)$`)
+	if ttl > 0 {
+		if resultBytes, err := json.Marshal(result); err == nil {
+			g.cache.Set(cacheKey, resultBytes, ttl, immutable)
+		}
+	}
 
-	if matches := identityRecordsRegex.FindStringSubmatch(req.Path); matches != nil {
-		address := matches[1]
+	return result, nil
+}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
-			return g.identityRecords(address)
-		})
+// routeTTL returns the cache TTL of a route, letting cosmos.cache.routes
+// override the value declared in the route table.
+func (g *CosmosGateway) routeTTL(route *Route) time.Duration {
+	if g.cache == nil {
+		return 0
 	}
 
-	identityVerifyRequestsByApproverRegex := regexp.MustCompile(`^/kira/gov/identity_verify_requests_by_approver/(.+)$`)
+	if seconds, ok := g.config.Cache.Routes[route.Path]; ok {
+		return time.Duration(seconds) * time.Second
+	}
 
-	if matches := identityVerifyRequestsByApproverRegex.FindStringSubmatch(req.Path); matches != nil {
-		approver := matches[1]
+	return route.CacheTTL
+}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
-			return g.identityVerifyRequestsByApprover(req, approver)
-		})
+// watchHeight polls the latest block height so that aggregates are recomputed
+// once per block and cached entries of older blocks are dropped.
+func (g *CosmosGateway) watchHeight(ctx context.Context) {
+	interval := time.Duration(g.config.Cache.HeightPoll) * time.Second
+	if interval <= 0 {
+		interval = 2 * time.Second
 	}
 
-	identityVerifyRequestsByRequesterRegex := regexp.MustCompile(`^/kira/gov/identity_verify_requests_by_requester/(.+)$`)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	if matches := identityVerifyRequestsByRequesterRegex.FindStringSubmatch(req.Path); matches != nil {
-		approver := matches[1]
+	for {
+		height, err := g.latestHeight(ctx)
+		if err != nil {
+			logger.Logger.Debug("CosmosGateway - watchHeight", zap.Error(err))
+		} else {
+			g.observeHeight(height)
+		}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
-			return g.identityVerifyRequestsByRequester(req, approver)
-		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
+}
 
-	txByHashRegex := regexp.MustCompile(`^/transactions/(.+)$`)
+func (g *CosmosGateway) observeHeight(height int64) {
+	for {
+		current := g.height.Load()
+		if height <= current {
+			return
+		}
 
-	if matches := txByHashRegex.FindStringSubmatch(req.Path); matches != nil {
-		hash := matches[1]
+		if g.height.CompareAndSwap(current, height) {
+			break
+		}
+	}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
-			return g.txByHash(hash)
-		})
+	g.cache.ObserveHeight(height)
+	g.memo.Prune(height)
+}
+
+// blockHeight is the height aggregates are computed for: the pinned height of
+// the request if any, otherwise the latest height seen by watchHeight.
+func (g *CosmosGateway) blockHeight(ctx context.Context) int64 {
+	if height, ok := blockHeightFromContext(ctx); ok {
+		return height
 	}
 
-	txByBlockRegex := regexp.MustCompile(`^/blocks/(.+)/transactions$`)
+	if height := g.height.Load(); height > 0 {
+		return height
+	}
 
-	if matches := txByBlockRegex.FindStringSubmatch(req.Path); matches != nil {
-		blockID := matches[1]
+	height, err := g.latestHeight(ctx)
+	if err != nil {
+		logger.Logger.Error("CosmosGateway - blockHeight", zap.Error(err))
+		return 0
+	}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
-			return g.txByBlock(req, blockID)
-		})
+	g.observeHeight(height)
+
+	return height
+}
+
+func (g *CosmosGateway) latestHeight(ctx context.Context) (int64, error) {
+	result, err := g.makeTendermintRPCRequest(ctx, "/status", "")
+	if err != nil {
+		return 0, err
 	}
 
-	blockById := regexp.MustCompile(`^/blocks/(.+)$`)
+	status := new(types.KiraStatus)
 
-	if matches := blockById.FindStringSubmatch(req.Path); matches != nil {
-		blockID := matches[1]
+	byteData, err := json.Marshal(result)
+	if err != nil {
+		return 0, err
+	}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
-			return g.blockById(req, blockID)
-		})
+	if err = json.Unmarshal(byteData, status); err != nil {
+		return 0, err
 	}
 
-	proposalById := regexp.MustCompile(`^/kira/gov/proposal/(.+)$`)
+	return strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+}
 
-	if matches := proposalById.FindStringSubmatch(req.Path); matches != nil {
-		proposalId := matches[1]
+func (g *CosmosGateway) rateLimiter(class string) *RateLimiter {
+	if limiter, ok := g.rateLimits[class]; ok {
+		return limiter
+	}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-				return nil, err
-			}
+	return g.rateLimit
+}
 
-			req.Path = "/kira/gov/proposals/" + proposalId
+// Signer holds the key of the faucet and of the other transactions interx signs.
+func (g *CosmosGateway) Signer() *signer.Signer {
+	return g.signer
+}
 
-			return g.proxy(req)
-		})
+func (g *CosmosGateway) Close() {
+	if g.stop != nil {
+		g.stop()
 	}
+	g.grpcProxy.pool.Close()
+}
 
-	tendermint := regexp.MustCompile(`^/tendermint/(.+)$`)
+func (g *CosmosGateway) makeTendermintRPCRequest(ctx context.Context, url string, query string) (interface{}, error) {
+	cacheKey := "rpc:" + url + "?" + query
+	ttl := g.tendermintTTL(url)
+
+	if ttl > 0 {
+		if cached, ok := g.cache.Get(cacheKey); ok {
+			var result interface{}
+			if err := json.Unmarshal(cached, &result); err == nil {
+				return result, nil
+			}
+		}
+	}
 
-	if matches := tendermint.FindStringSubmatch(req.Path); matches != nil {
-		req.Path = "/" + matches[1]
+	var lastErr error = ErrNoHealthyNode
+	for _, node := range g.grpcProxy.pool.candidates(routeHeight(ctx, query)) {
+		if err := node.rpcBreaker.Allow(); err != nil {
+			lastErr = err
+			continue
+		}
 
-		return g.retry.Do(func() (interface{}, error) {
-			if err := g.rateLimit.Wait(g.context.Context); err != nil {
-				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
+		response, err := g.tendermintNodeRequest(ctx, node, url, query)
+		node.rpcBreaker.Done(err)
+		if err != nil {
+			if ctx.Err() != nil {
 				return nil, err
 			}
 
-			return g.tendermint(req)
-		})
-	}
-
-	switch req.Path {
-	case "/dashboard":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.dashboard()
-			})
-		}
-	case "/status":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.statusAPI()
-			})
-		}
-	case "/valopers":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.validators(req)
-			})
-		}
-	case "/kira/txs":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.txs(req)
-			})
-		}
-	case "/kira/delegations":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.delegations(req)
-			})
-		}
-	case "/kira/gov/execution_fee":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.executionFee(req)
-			})
-		}
-	case "/kira/gov/network_properties":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.networkProperties()
-			})
-		}
-	case "/kira/staking-pool":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.stakingPool(req)
-			})
-		}
-	case "/kira/undelegations":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.undelegations(req)
-			})
-		}
-	case "/transactions":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.transactions(req)
-			})
-		}
-	case "/blocks":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.blocks(req)
-			})
-		}
-	case "/kira/status":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.status()
-			})
-		}
-	case "/kira/tokens/rates":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.tokenRates()
-			})
-		}
-	case "/kira/gov/proposals":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.proposals(req)
-			})
-		}
-	case "/kira/tokens/aliases":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.tokenAliases(req)
-			})
-		}
-	case "/kira/faucet":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.faucet(req)
-			})
+			g.grpcProxy.pool.eject(node, err)
+			lastErr = err
+			continue
 		}
 
-	case "/tendermint":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err), zap.Any("ctx", g.context.Context))
-					return nil, err
-				}
-				return g.tendermint(req)
-			})
+		if response.Error.Code != 0 {
+			return nil, apierror.TendermintRPC(int(response.Error.Code), response.Error.Message, response.Error.Data)
 		}
-	}
 
-	return g.retry.Do(func() (interface{}, error) {
-		if err := g.rateLimit.Wait(g.context.Context); err != nil {
-			logger.Logger.Error("CosmosGateway - Handle - Rate limit exceeded", zap.Error(err))
-			return nil, err
+		if ttl > 0 {
+			if resultBytes, err := json.Marshal(response.Result); err == nil {
+				g.cache.Set(cacheKey, resultBytes, ttl, false)
+			}
 		}
 
-		return g.proxy(req)
-	})
-}
+		return response.Result, nil
+	}
 
-func (g *CosmosGateway) Close() {
-	g.grpcProxy.conn.Close()
+	return nil, apierror.Wrap(apierror.Unavailable, lastErr)
 }
 
-func (g *CosmosGateway) makeTendermintRPCRequest(ctx context.Context, url string, query string) (interface{}, error) {
-	endpoint := fmt.Sprintf("%s%s?%s", g.config.Node.Tendermint, url, query)
-
-	//Todo: add cache here
+// tendermintNodeRequest fails only when the node could not be reached or did
+// not answer with a JSON-RPC envelope, RPC errors are left to the caller.
+func (g *CosmosGateway) tendermintNodeRequest(ctx context.Context, node *sekaiNode, url string, query string) (*types.RPCResponse, error) {
+	endpoint := fmt.Sprintf("%s%s?%s", node.tendermint, url, query)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
@@ -628,7 +691,7 @@ func (g *CosmosGateway) makeTendermintRPCRequest(ctx context.Context, url string
 	httpClient := &http.Client{}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		logger.Logger.Error("MakeTendermintRPCRequest - [rpc-call] Unable to connect to server", zap.Error(err))
+		logger.Logger.Error("MakeTendermintRPCRequest - [rpc-call] Unable to connect to server", zap.String("node", node.name), zap.Error(err))
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -641,21 +704,32 @@ func (g *CosmosGateway) makeTendermintRPCRequest(ctx context.Context, url string
 		return nil, err
 	}
 
-	if response.Error.Code != 0 {
-		return nil, errors.New(response.Error.Data)
+	return response, nil
+}
+
+// tendermintTTL keeps broadcasts, mempool and node state calls out of the cache.
+func (g *CosmosGateway) tendermintTTL(url string) time.Duration {
+	if g.cache == nil {
+		return 0
+	}
+
+	for _, prefix := range uncachedTendermintPaths {
+		if strings.HasPrefix(url, prefix) {
+			return 0
+		}
 	}
 
-	return response.Result, nil
+	return time.Duration(g.config.Cache.TendermintTTL) * time.Second
 }
 
-func (g *CosmosGateway) proxy(req types.InboundRequest) ([]byte, error) {
+func (g *CosmosGateway) proxy(ctx context.Context, req types.InboundRequest) ([]byte, error) {
 	dataBytes, err := json.Marshal(req.Payload)
 	if err != nil {
 		logger.Logger.Error("[query-proxy] Marshal payload failed", zap.Error(err))
 		return nil, err
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, req.Method, req.Path, strings.NewReader(string(dataBytes)))
+	gatewayReq, err := http.NewRequestWithContext(ctx, req.Method, req.Path, strings.NewReader(string(dataBytes)))
 	if err != nil {
 		logger.Logger.Error("[query-proxy] Create request failed", zap.Error(err))
 		return nil, err
@@ -679,9 +753,9 @@ func (g *CosmosGateway) proxy(req types.InboundRequest) ([]byte, error) {
 	return grpcBytes, nil
 }
 
-func (g *CosmosGateway) tendermint(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) tendermint(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	query := mapToQuery(req.Payload)
-	return g.makeTendermintRPCRequest(g.context.Context, req.Path, query.Encode())
+	return g.makeTendermintRPCRequest(ctx, req.Path, query.Encode())
 }
 
 func (g *CosmosGateway) filterAndPaginateValidators(response *types.ValidatorsResponse, payload map[string]interface{}) (*types.ValidatorsResponse, error) {
@@ -826,22 +900,6 @@ func (g *CosmosGateway) encodeQuery(gatewayReq *http.Request, req types.InboundR
 	return gatewayReq
 }
 
-func loadMnemonicFile() string {
-	mnemonic, err := ioutil.ReadFile("mnemonic.data")
-	if err != nil {
-		return ""
-	}
-
-	return string(mnemonic)
-}
-
-func saveMnemonicFile(data []byte) {
-	err := ioutil.WriteFile("mnemonic.data", data, 0644)
-	if err != nil {
-		panic(err)
-	}
-}
-
 func mapToQuery(m map[string]interface{}) url.Values {
 	query := url.Values{}
 
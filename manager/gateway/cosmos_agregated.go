@@ -1,10 +1,10 @@
 package gateway
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -15,17 +15,36 @@ import (
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"go.uber.org/zap"
 
+	"github.com/KiraCore/sai-interx-manager/apierror"
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-interx-manager/types"
 )
 
-func (g *CosmosGateway) allValidators() (*types.ValidatorsResponse, error) {
+// allValidators returns a copy of the memoised validator set, so callers such as
+// dashboard can enrich the entries without touching the shared snapshot.
+func (g *CosmosGateway) allValidators(ctx context.Context) (*types.ValidatorsResponse, error) {
+	result, err := g.memo.Do(ctx, "validators", g.blockHeight(ctx), func(ctx context.Context) (interface{}, error) {
+		return g.fetchAllValidators(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := result.(*types.ValidatorsResponse)
+	validators := new(types.ValidatorsResponse)
+	validators.Actors = snapshot.Actors
+	validators.Validators = append([]types.QueryValidator{}, snapshot.Validators...)
+
+	return validators, nil
+}
+
+func (g *CosmosGateway) fetchAllValidators(ctx context.Context) (*types.ValidatorsResponse, error) {
 	validators := new(types.ValidatorsResponse)
 	limit := sekaitypes.PageIterationLimit - 1
 	offset := 0
 
 	for {
-		gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/staking/validators", nil)
+		gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/staking/validators", nil)
 		if err != nil {
 			logger.Logger.Error("[query-validators] Create request failed", zap.Error(err))
 			return nil, err
@@ -61,10 +80,10 @@ func (g *CosmosGateway) allValidators() (*types.ValidatorsResponse, error) {
 	return validators, nil
 }
 
-func (g *CosmosGateway) supply() (*types.TokenSupplyResponse, error) {
+func (g *CosmosGateway) supply(ctx context.Context) (*types.TokenSupplyResponse, error) {
 	var tokenSupplyResponse = new(types.TokenSupplyResponse)
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/cosmos/bank/v1beta1/supply", nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/cosmos/bank/v1beta1/supply", nil)
 	if err != nil {
 		logger.Logger.Error("[query-supply] Create request failed", zap.Error(err))
 		return nil, err
@@ -85,11 +104,22 @@ func (g *CosmosGateway) supply() (*types.TokenSupplyResponse, error) {
 	return tokenSupplyResponse, nil
 }
 
-func (g *CosmosGateway) tokens() ([]string, error) {
+func (g *CosmosGateway) tokens(ctx context.Context) ([]string, error) {
+	result, err := g.memo.Do(ctx, "tokens", g.blockHeight(ctx), func(ctx context.Context) (interface{}, error) {
+		return g.fetchTokens(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]string), nil
+}
+
+func (g *CosmosGateway) fetchTokens(ctx context.Context) ([]string, error) {
 	tokenRatesResponse := types.TokenAliasesGRPCResponse{}
 	poolTokens := make([]string, 0)
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/tokens/infos", nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/tokens/infos", nil)
 	if err != nil {
 		logger.Logger.Error("[query-tokens] Create request failed", zap.Error(err))
 		return nil, err
@@ -114,13 +144,24 @@ func (g *CosmosGateway) tokens() ([]string, error) {
 	return poolTokens, nil
 }
 
-func (g *CosmosGateway) signingInfos() (*types.ValidatorInfoResponse, error) {
+func (g *CosmosGateway) signingInfos(ctx context.Context) (*types.ValidatorInfoResponse, error) {
+	result, err := g.memo.Do(ctx, "signing_infos", g.blockHeight(ctx), func(ctx context.Context) (interface{}, error) {
+		return g.fetchSigningInfos(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*types.ValidatorInfoResponse), nil
+}
+
+func (g *CosmosGateway) fetchSigningInfos(ctx context.Context) (*types.ValidatorInfoResponse, error) {
 	validatorInfosResponse := new(types.ValidatorInfoResponse)
 	limit := sekaitypes.PageIterationLimit - 1
 	offset := 0
 
 	for {
-		gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/slashing/v1beta1/signing_infos", nil)
+		gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/slashing/v1beta1/signing_infos", nil)
 		if err != nil {
 			logger.Logger.Error("[query-signing-infos] Create request failed", zap.Error(err))
 			return nil, err
@@ -155,7 +196,18 @@ func (g *CosmosGateway) signingInfos() (*types.ValidatorInfoResponse, error) {
 	return validatorInfosResponse, nil
 }
 
-func (g *CosmosGateway) validatorsPool() (*types.AllPools, error) {
+func (g *CosmosGateway) validatorsPool(ctx context.Context) (*types.AllPools, error) {
+	result, err := g.memo.Do(ctx, "validators_pool", g.blockHeight(ctx), func(ctx context.Context) (interface{}, error) {
+		return g.fetchValidatorsPool(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*types.AllPools), nil
+}
+
+func (g *CosmosGateway) fetchValidatorsPool(ctx context.Context) (*types.AllPools, error) {
 	type ValidatorPoolsResponse struct {
 		Pools []types.ValidatorPool `json:"pools,omitempty"`
 	}
@@ -167,7 +219,7 @@ func (g *CosmosGateway) validatorsPool() (*types.AllPools, error) {
 		IdToPool:  make(map[int64]types.ValidatorPool),
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/multistaking/v1beta1/staking_pools", nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/multistaking/v1beta1/staking_pools", nil)
 	if err != nil {
 		logger.Logger.Error("[query-validators-pool] Create request failed", zap.Error(err))
 		return nil, err
@@ -193,32 +245,43 @@ func (g *CosmosGateway) validatorsPool() (*types.AllPools, error) {
 	return allPools, nil
 }
 
-func (g *CosmosGateway) dashboard() (*types.AllValidators, error) {
+func (g *CosmosGateway) dashboard(ctx context.Context) (*types.AllValidators, error) {
+	result, err := g.memo.Do(ctx, "dashboard", g.blockHeight(ctx), func(ctx context.Context) (interface{}, error) {
+		return g.fetchDashboard(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*types.AllValidators), nil
+}
+
+func (g *CosmosGateway) fetchDashboard(ctx context.Context) (*types.AllValidators, error) {
 	allValidators := &types.AllValidators{
 		AddrToValidator: make(map[string]string),
 		PoolToValidator: make(map[int64]types.QueryValidator),
 		PoolTokens:      make([]string, 0),
 	}
 
-	validatorsData, err := g.allValidators()
+	validatorsData, err := g.allValidators(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-dashboard] validators", zap.Error(err))
 		return nil, err
 	}
 
-	tokens, err := g.tokens()
+	tokens, err := g.tokens(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-dashboard] failed to get tokens", zap.Error(err))
 		return nil, err
 	}
 
-	signingInfos, err := g.signingInfos()
+	signingInfos, err := g.signingInfos(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-dashboard] failed to get signingInfos", zap.Error(err))
 		return nil, err
 	}
 
-	validatorsPool, err := g.validatorsPool()
+	validatorsPool, err := g.validatorsPool(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-dashboard] failed to get validatorsPool", zap.Error(err))
 		return nil, err
@@ -330,7 +393,7 @@ func (g *CosmosGateway) dashboard() (*types.AllValidators, error) {
 	return allValidators, nil
 }
 
-func (g *CosmosGateway) txs(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) txs(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	type PostTxReq struct {
 		Tx   string `json:"tx"`
 		Mode string `json:"mode"`
@@ -352,7 +415,7 @@ func (g *CosmosGateway) txs(req types.InboundRequest) (interface{}, error) {
 
 	if request.Mode != "" {
 		if allowed, ok := g.config.TxModes[request.Mode]; !ok || !allowed {
-			err = errors.New("[post-transaction] Invalid transaction mode")
+			err = apierror.New(apierror.InvalidArgument, "[post-transaction] Invalid transaction mode")
 			return nil, err
 		}
 	}
@@ -372,11 +435,11 @@ func (g *CosmosGateway) txs(req types.InboundRequest) (interface{}, error) {
 		return nil, err
 	}
 
-	return g.makeTendermintRPCRequest(g.context.Context, _url, fmt.Sprintf("tx=0x%X", txBytes))
+	return g.makeTendermintRPCRequest(ctx, _url, fmt.Sprintf("tx=0x%X", txBytes))
 }
 
-func (g *CosmosGateway) validators(req types.InboundRequest) (*types.ValidatorsResponse, error) {
-	validatorsResponse, err := g.allValidators()
+func (g *CosmosGateway) validators(ctx context.Context, req types.InboundRequest) (*types.ValidatorsResponse, error) {
+	validatorsResponse, err := g.allValidators(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-validators] allValidators failed", zap.Error(err))
 		return nil, err
@@ -385,14 +448,14 @@ func (g *CosmosGateway) validators(req types.InboundRequest) (*types.ValidatorsR
 	return g.filterAndPaginateValidators(validatorsResponse, req.Payload)
 }
 
-func (g *CosmosGateway) account(address string) (*types.AccountInfo, error) {
+func (g *CosmosGateway) account(ctx context.Context, address string) (*types.AccountInfo, error) {
 	accountReq := types.InboundRequest{
 		Method:  "GET",
 		Path:    "/cosmos/auth/v1beta1/accounts/" + address,
 		Payload: map[string]interface{}{},
 	}
 
-	accountInfoBytes, err := g.proxy(accountReq)
+	accountInfoBytes, err := g.proxy(ctx, accountReq)
 	if err != nil {
 		logger.Logger.Error("[query-account] Failed getting account info", zap.Error(err))
 		return nil, err
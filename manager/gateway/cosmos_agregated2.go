@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -15,7 +16,6 @@ import (
 	sekaitypes "github.com/KiraCore/sekai/types"
 	tmjson "github.com/cometbft/cometbft/libs/json"
 	types2 "github.com/cometbft/cometbft/types"
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/spf13/cast"
 	"go.uber.org/zap"
 
@@ -24,12 +24,12 @@ import (
 	"github.com/KiraCore/sai-interx-manager/utils"
 )
 
-func (g *CosmosGateway) statusAPI() (interface{}, error) {
+func (g *CosmosGateway) statusAPI(ctx context.Context) (interface{}, error) {
 	result := types.InterxStatus{
 		ID: cast.ToString(g.context.GetConfig("p2p.id", "")),
 	}
 
-	genesis, err := g.genesis()
+	genesis, err := g.genesis(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-status] Failed to query genesis", zap.Error(err))
 		return nil, err
@@ -38,7 +38,7 @@ func (g *CosmosGateway) statusAPI() (interface{}, error) {
 	result.InterxInfo.ChainID = genesis.GenesisDoc.ChainID
 	result.InterxInfo.GenesisChecksum = fmt.Sprintf("%x", sha256.Sum256(genesis.GenesisData))
 
-	sentryStatus, err := g.status()
+	sentryStatus, err := g.status(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-status] Failed to query status", zap.Error(err))
 		return nil, err
@@ -53,16 +53,17 @@ func (g *CosmosGateway) statusAPI() (interface{}, error) {
 
 	//result.InterxInfo.Node = sentryStatus.NodeInfo.Other.RpcAddress
 	result.InterxInfo.KiraAddr = sentryStatus.ValidatorInfo.Address
-	result.InterxInfo.KiraPubKey = g.PubKey.String()
-	result.InterxInfo.FaucetAddr = g.PubKey.Address().String()
+	faucetKey := g.signer.Key().PubKey()
+	result.InterxInfo.KiraPubKey = faucetKey.String()
+	result.InterxInfo.FaucetAddr = faucetKey.Address().String()
 	result.InterxInfo.InterxVersion = cast.ToString(g.context.GetConfig("version", ""))
 	result.InterxInfo.SekaiVersion = sentryStatus.NodeInfo.Version
 
 	return result, nil
 }
 
-func (g *CosmosGateway) status() (*types.KiraStatus, error) {
-	success, err := g.makeTendermintRPCRequest(g.context.Context, "/status", "")
+func (g *CosmosGateway) status(ctx context.Context) (*types.KiraStatus, error) {
+	success, err := g.makeTendermintRPCRequest(ctx, "/status", "")
 	if err != nil {
 		logger.Logger.Error("[kira-status] Invalid response format", zap.Error(err))
 		return nil, err
@@ -85,8 +86,12 @@ func (g *CosmosGateway) status() (*types.KiraStatus, error) {
 	return result, nil
 }
 
-func (g *CosmosGateway) genesisChunked(chunk int) (*types.GenesisChunkedResponse, error) {
-	data, _ := g.makeTendermintRPCRequest(g.context.Context, "/genesis_chunked", fmt.Sprintf("chunk=%d", chunk))
+func (g *CosmosGateway) genesisChunked(ctx context.Context, chunk int) (*types.GenesisChunkedResponse, error) {
+	data, err := g.makeTendermintRPCRequest(ctx, "/genesis_chunked", fmt.Sprintf("chunk=%d", chunk))
+	if err != nil {
+		logger.Logger.Error("[genesis-chunked] Failed to query genesis chunk", zap.Error(err))
+		return nil, err
+	}
 
 	genesis := new(types.GenesisChunkedResponse)
 	byteData, err := json.Marshal(data)
@@ -104,11 +109,14 @@ func (g *CosmosGateway) genesisChunked(chunk int) (*types.GenesisChunkedResponse
 	return genesis, nil
 }
 
-func (g *CosmosGateway) genesis() (*types.GenesisInfo, error) {
+func (g *CosmosGateway) genesis(ctx context.Context) (*types.GenesisInfo, error) {
 	gInfo := new(types.GenesisInfo)
 	gInfo.GenesisDoc = new(types2.GenesisDoc)
 
-	genesisData, err := g.genesisChunked(0)
+	// pruned nodes may not serve the genesis, route to one holding the first block
+	ctx = withRequiredHeight(ctx, 1)
+
+	genesisData, err := g.genesisChunked(ctx, 0)
 	if err != nil {
 		logger.Logger.Error("[query-genesis] Failed to get genesis part", zap.Error(err))
 		return nil, err
@@ -122,7 +130,7 @@ func (g *CosmosGateway) genesis() (*types.GenesisInfo, error) {
 
 	if total > 1 {
 		for i := 1; i < total; i++ {
-			nextData, err := g.genesisChunked(i)
+			nextData, err := g.genesisChunked(ctx, i)
 			if err != nil {
 				logger.Logger.Error("[query-genesis] Failed to get genesis part", zap.Error(err))
 				return nil, err
@@ -240,7 +248,7 @@ func (g *CosmosGateway) blocks(req types.InboundRequest) (*types.BlocksResultRes
 	return &result, nil
 }
 
-func (g *CosmosGateway) balances(req types.InboundRequest, accountID string) (*types.BalancesResponse, error) {
+func (g *CosmosGateway) balances(ctx context.Context, req types.InboundRequest, accountID string) (*types.BalancesResponse, error) {
 	type BalancesRequest struct {
 		Limit      int `json:"limit,string,omitempty"`
 		Offset     int `json:"offset,string,omitempty"`
@@ -263,7 +271,7 @@ func (g *CosmosGateway) balances(req types.InboundRequest, accountID string) (*t
 		return nil, err
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/cosmos/bank/v1beta1/balances/"+accountID, nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/cosmos/bank/v1beta1/balances/"+accountID, nil)
 	if err != nil {
 		logger.Logger.Error("[query-balances] Create request failed", zap.Error(err))
 		return nil, err
@@ -296,7 +304,7 @@ func (g *CosmosGateway) balances(req types.InboundRequest, accountID string) (*t
 	return result, nil
 }
 
-func (g *CosmosGateway) delegations(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) delegations(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	var response = new(types.QueryDelegationsResult)
 
 	type DelegationsRequest struct {
@@ -327,7 +335,7 @@ func (g *CosmosGateway) delegations(req types.InboundRequest) (interface{}, erro
 		req.Path = "/cosmos/bank/v1beta1/balances/" + request.Account
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", req.Path, nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", req.Path, nil)
 	if err != nil {
 		logger.Logger.Error("[query-delegations] Create request failed", zap.Error(err))
 		return nil, err
@@ -339,19 +347,19 @@ func (g *CosmosGateway) delegations(req types.InboundRequest) (interface{}, erro
 		return nil, err
 	}
 
-	allPools, err := g.validatorsPool()
+	allPools, err := g.validatorsPool(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-delegations] Error getting validators pool", zap.Error(err))
 		return nil, err
 	}
 
-	tokens, err := g.tokens()
+	tokens, err := g.tokens(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-delegations] Error getting tokens", zap.Error(err))
 		return nil, err
 	}
 
-	validators, err := g.dashboard()
+	validators, err := g.dashboard(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-delegations] Error getting validators", zap.Error(err))
 		return nil, err
@@ -422,10 +430,14 @@ func (g *CosmosGateway) delegations(req types.InboundRequest) (interface{}, erro
 	return response, nil
 }
 
-func (g *CosmosGateway) identityRecords(address string) (interface{}, error) {
-	accAddr, _ := sdk.AccAddressFromBech32(address)
+func (g *CosmosGateway) identityRecords(ctx context.Context, address string) (interface{}, error) {
+	accAddr, err := accAddress(address)
+	if err != nil {
+		logger.Logger.Error("[query-identity-records] Invalid address", zap.Error(err))
+		return nil, err
+	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/identity_records/"+base64.URLEncoding.EncodeToString(accAddr.Bytes()), nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/identity_records/"+base64.URLEncoding.EncodeToString(accAddr.Bytes()), nil)
 	if err != nil {
 		logger.Logger.Error("[query-identity-records] Create request failed", zap.Error(err))
 		return nil, err
@@ -448,7 +460,7 @@ func (g *CosmosGateway) identityRecords(address string) (interface{}, error) {
 	return result, nil
 }
 
-func (g *CosmosGateway) identityVerifyRequestsByApprover(req types.InboundRequest, approver string) (interface{}, error) {
+func (g *CosmosGateway) identityVerifyRequestsByApprover(ctx context.Context, req types.InboundRequest, approver string) (interface{}, error) {
 	type IdentityVerifyRequestsByApproverRequest struct {
 		Key        int `json:"key,string,omitempty"`
 		Limit      int `json:"limit,string,omitempty"`
@@ -473,8 +485,13 @@ func (g *CosmosGateway) identityVerifyRequestsByApprover(req types.InboundReques
 		return nil, err
 	}
 
-	accAddr, _ := sdk.AccAddressFromBech32(approver)
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/identity_verify_requests_by_approver/"+base64.URLEncoding.EncodeToString(accAddr.Bytes()), nil)
+	accAddr, err := accAddress(approver)
+	if err != nil {
+		logger.Logger.Error("[query-identity-record-verify-requests-by-approver] Invalid address", zap.Error(err))
+		return nil, err
+	}
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/identity_verify_requests_by_approver/"+base64.URLEncoding.EncodeToString(accAddr.Bytes()), nil)
 	if err != nil {
 		logger.Logger.Error("[query-identity-record-verify-requests-by-approver] Create request failed", zap.Error(err))
 		return nil, err
@@ -505,7 +522,7 @@ func (g *CosmosGateway) identityVerifyRequestsByApprover(req types.InboundReques
 	}
 
 	for idx, record := range res.VerifyRecords {
-		coin, err := g.parseCoinString(record.Tip)
+		coin, err := g.parseCoinString(ctx, record.Tip)
 		if err != nil {
 			logger.Logger.Error("[query-identity-record-verify-requests-by-approver] Coin can not be parsed", zap.Error(err))
 			return nil, err
@@ -517,7 +534,7 @@ func (g *CosmosGateway) identityVerifyRequestsByApprover(req types.InboundReques
 	return res, nil
 }
 
-func (g *CosmosGateway) identityVerifyRequestsByRequester(req types.InboundRequest, requester string) (interface{}, error) {
+func (g *CosmosGateway) identityVerifyRequestsByRequester(ctx context.Context, req types.InboundRequest, requester string) (interface{}, error) {
 	type IdentityVerifyRequestsByRequesterRequest struct {
 		Key        int `json:"key,string,omitempty"`
 		Limit      int `json:"limit,string,omitempty"`
@@ -542,8 +559,13 @@ func (g *CosmosGateway) identityVerifyRequestsByRequester(req types.InboundReque
 		return nil, err
 	}
 
-	accAddr, _ := sdk.AccAddressFromBech32(requester)
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/identity_verify_requests_by_requester/"+base64.URLEncoding.EncodeToString(accAddr.Bytes()), nil)
+	accAddr, err := accAddress(requester)
+	if err != nil {
+		logger.Logger.Error("[query-identity-record-verify-requests-by-requester] Invalid address", zap.Error(err))
+		return nil, err
+	}
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/identity_verify_requests_by_requester/"+base64.URLEncoding.EncodeToString(accAddr.Bytes()), nil)
 	if err != nil {
 		logger.Logger.Error("[query-identity-record-verify-requests-by-requester] Create request failed", zap.Error(err))
 		return nil, err
@@ -574,7 +596,7 @@ func (g *CosmosGateway) identityVerifyRequestsByRequester(req types.InboundReque
 	}
 
 	for idx, record := range res.VerifyRecords {
-		coin, err := g.parseCoinString(record.Tip)
+		coin, err := g.parseCoinString(ctx, record.Tip)
 		if err != nil {
 			logger.Logger.Error("[query-identity-record-verify-requests-by-approver] Coin can not be parsed", zap.Error(err))
 			continue
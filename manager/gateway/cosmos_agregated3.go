@@ -1,11 +1,11 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"math"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,6 +15,7 @@ import (
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"go.uber.org/zap"
 
+	"github.com/KiraCore/sai-interx-manager/apierror"
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-interx-manager/types"
 	"github.com/KiraCore/sai-interx-manager/utils"
@@ -30,7 +31,7 @@ func (g *CosmosGateway) txByHash(hash string) (interface{}, error) {
 	return g.transactions(req)
 }
 
-func (g *CosmosGateway) blockById(req types.InboundRequest, blockID string) (interface{}, error) {
+func (g *CosmosGateway) blockById(ctx context.Context, req types.InboundRequest, blockID string) (interface{}, error) {
 	req.Payload["height"] = blockID
 
 	result, err := g.blocks(req)
@@ -40,7 +41,12 @@ func (g *CosmosGateway) blockById(req types.InboundRequest, blockID string) (int
 	}
 
 	if len(result.Blocks) < 1 {
-		err = errors.New(fmt.Sprintf("Block %s not found", blockID))
+		// not indexed, ask a node that still holds the height
+		if block, err := g.makeTendermintRPCRequest(ctx, "/block", "height="+url.QueryEscape(blockID)); err == nil {
+			return block, nil
+		}
+
+		err = apierror.Newf(apierror.NotFound, "Block %s not found", blockID)
 		logger.Logger.Error("[query-block-by-id] Block not found", zap.Error(err))
 		return nil, err
 	}
@@ -53,11 +59,11 @@ func (g *CosmosGateway) txByBlock(req types.InboundRequest, blockID string) (int
 	return g.transactions(req)
 }
 
-func (g *CosmosGateway) parseCoinString(input string) (*sdk.Coin, error) {
+func (g *CosmosGateway) parseCoinString(ctx context.Context, input string) (*sdk.Coin, error) {
 	denom := ""
 	amount := 0
 
-	tokens, err := g.tokens()
+	tokens, err := g.tokens(ctx)
 	if err != nil {
 		logger.Logger.Error("[parse-coin-string] Failed to get tokens", zap.Error(err))
 		return nil, err
@@ -79,7 +85,7 @@ func (g *CosmosGateway) parseCoinString(input string) (*sdk.Coin, error) {
 	}, nil
 }
 
-func (g *CosmosGateway) executionFee(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) executionFee(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	type ExecutionFeeRequest struct {
 		Message string `json:"message,omitempty"`
 	}
@@ -96,7 +102,7 @@ func (g *CosmosGateway) executionFee(req types.InboundRequest) (interface{}, err
 		return nil, err
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/execution_fee/"+request.Message, nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/execution_fee/"+request.Message, nil)
 	if err != nil {
 		logger.Logger.Error("[execution-fee] Create request failed", zap.Error(err))
 		return nil, err
@@ -119,8 +125,8 @@ func (g *CosmosGateway) executionFee(req types.InboundRequest) (interface{}, err
 	return result, nil
 }
 
-func (g *CosmosGateway) networkProperties() (interface{}, error) {
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/network_properties", nil)
+func (g *CosmosGateway) networkProperties(ctx context.Context) (interface{}, error) {
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/network_properties", nil)
 	if err != nil {
 		logger.Logger.Error("[query-network-properties] Create request failed", zap.Error(err))
 		return nil, err
@@ -141,7 +147,7 @@ func (g *CosmosGateway) networkProperties() (interface{}, error) {
 	return result, nil
 }
 
-func (g *CosmosGateway) stakingPool(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) stakingPool(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	type StakingPoolRequest struct {
 		Account string `json:"validatorAddress,omitempty"`
 	}
@@ -158,30 +164,30 @@ func (g *CosmosGateway) stakingPool(req types.InboundRequest) (interface{}, erro
 		return nil, err
 	}
 
-	tokens, err := g.tokens()
+	tokens, err := g.tokens(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-staking-pool] Getting tokens failed", zap.Error(err))
 		return nil, err
 	}
 
-	validators, err := g.dashboard()
+	validators, err := g.dashboard(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-staking-pool] Getting validators failed", zap.Error(err))
 		return nil, err
 	}
 
 	if request.Account == "" {
-		err = fmt.Errorf("[query-staking-pool] validatorAddress required")
+		err = apierror.New(apierror.InvalidArgument, "[query-staking-pool] validatorAddress required")
 		return nil, err
 	}
 
 	valAddr, found := validators.AddrToValidator[request.Account]
 	if !found {
-		err = fmt.Errorf("[query-staking-pool] validatorAddress not found")
+		err = apierror.New(apierror.NotFound, "[query-staking-pool] validatorAddress not found")
 		return nil, err
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/multistaking/v1beta1/staking_pool_delegators/"+valAddr, nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/multistaking/v1beta1/staking_pool_delegators/"+valAddr, nil)
 	if err != nil {
 		logger.Logger.Error("[query-staking-pool] Create request failed", zap.Error(err))
 		return nil, err
@@ -208,7 +214,7 @@ func (g *CosmosGateway) stakingPool(req types.InboundRequest) (interface{}, erro
 
 	newResponse.VotingPower = []sdk.Coin{}
 	for _, coinStr := range responseResult.Pool.TotalStakingTokens {
-		coin, err := g.parseCoinString(coinStr)
+		coin, err := g.parseCoinString(ctx, coinStr)
 		if err != nil {
 			logger.Logger.Error("[query-staking-pool] Coin can not be parsed", zap.Error(err))
 			continue
@@ -223,7 +229,7 @@ func (g *CosmosGateway) stakingPool(req types.InboundRequest) (interface{}, erro
 	return newResponse, nil
 }
 
-func (g *CosmosGateway) undelegations(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) undelegations(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	type Undelegation struct {
 		ID            int `json:"id,omitempty"`
 		ValidatorInfo struct {
@@ -264,11 +270,11 @@ func (g *CosmosGateway) undelegations(req types.InboundRequest) (interface{}, er
 	}
 
 	if request.Account == "" {
-		err = fmt.Errorf("[query-undelegations] validatorAddress required")
+		err = apierror.New(apierror.InvalidArgument, "[query-undelegations] validatorAddress required")
 		return nil, err
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/multistaking/v1beta1/undelegations", nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/multistaking/v1beta1/undelegations", nil)
 	if err != nil {
 		logger.Logger.Error("[query-undelegations] Create request failed", zap.Error(err))
 		return nil, err
@@ -284,7 +290,7 @@ func (g *CosmosGateway) undelegations(req types.InboundRequest) (interface{}, er
 		return nil, err
 	}
 
-	validators, err := g.allValidators()
+	validators, err := g.allValidators(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-undelegations] Getting validators failed", zap.Error(err))
 		return nil, err
@@ -322,7 +328,7 @@ func (g *CosmosGateway) undelegations(req types.InboundRequest) (interface{}, er
 		undelegationData.Expiry = undelegation.Expiry
 
 		for _, token := range undelegation.Amount {
-			coin, err := g.parseCoinString(token)
+			coin, err := g.parseCoinString(ctx, token)
 			if err != nil {
 				logger.Logger.Error("[query-undelegations] Parsing coin failed", zap.Error(err))
 				continue
@@ -461,7 +467,7 @@ func (g *CosmosGateway) transactions(req types.InboundRequest) (interface{}, err
 	}
 
 	if len(request.Directions) > 0 && request.Address == "" {
-		return nil, fmt.Errorf("directions filter requires address to be specified")
+		return nil, apierror.New(apierror.InvalidArgument, "directions filter requires address to be specified")
 	}
 
 	logger.Logger.Debug("[query-transactions] request",
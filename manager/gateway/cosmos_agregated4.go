@@ -1,38 +1,46 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"math"
-	"math/big"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	sekaitypes "github.com/KiraCore/sekai/types"
-	sdk "github.com/cosmos/cosmos-sdk/types"
-	"github.com/cosmos/cosmos-sdk/types/tx/signing"
-	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
-	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"go.uber.org/zap"
 
+	"github.com/KiraCore/sai-interx-manager/apierror"
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-interx-manager/types"
 	"github.com/KiraCore/sai-interx-manager/utils"
 	"github.com/KiraCore/sai-storage-mongo/external/adapter"
 )
 
-func (g *CosmosGateway) tokenRates() (interface{}, error) {
-	tokenAliasGRPCResponse := types.TokenAliasesGRPCResponse{}
-
+func (g *CosmosGateway) tokenRates(ctx context.Context) (interface{}, error) {
 	type TokenRatesResponse struct {
 		Data []types.TokenAlias `json:"data"`
 	}
 	result := TokenRatesResponse{}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/tokens/infos", nil)
+	tokens, err := g.tokenInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Data = tokens
+
+	return result, nil
+}
+
+// tokenInfos returns every registered token with its rates converted from sdk.Dec.
+func (g *CosmosGateway) tokenInfos(ctx context.Context) ([]types.TokenAlias, error) {
+	tokenAliasGRPCResponse := types.TokenAliasesGRPCResponse{}
+	var result []types.TokenAlias
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/tokens/infos", nil)
 	if err != nil {
 		logger.Logger.Error("[query-token-rates] Create request failed", zap.Error(err))
 		return nil, err
@@ -55,16 +63,16 @@ func (g *CosmosGateway) tokenRates() (interface{}, error) {
 		tokenAliasGRPCResponse.Data[index].Data.StakeCap = utils.ConvertRate(tokenRate.Data.StakeCap)
 		tokenAliasGRPCResponse.Data[index].Data.StakeMin = utils.ConvertRate(tokenRate.Data.StakeMin)
 
-		result.Data = append(result.Data, tokenAliasGRPCResponse.Data[index].Data)
+		result = append(result, tokenAliasGRPCResponse.Data[index].Data)
 	}
 
 	return result, nil
 }
 
-func (g *CosmosGateway) customPrefixes() (*types.CustomPrefixesResponse, error) {
+func (g *CosmosGateway) customPrefixes(ctx context.Context) (*types.CustomPrefixesResponse, error) {
 	var customPrefixesResponse = new(types.CustomPrefixesResponse)
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/custom_prefixes", nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/custom_prefixes", nil)
 	if err != nil {
 		logger.Logger.Error("[query-custom-prefixes] Create request failed", zap.Error(err))
 		return nil, err
@@ -85,7 +93,7 @@ func (g *CosmosGateway) customPrefixes() (*types.CustomPrefixesResponse, error)
 	return customPrefixesResponse, nil
 }
 
-func (g *CosmosGateway) tokenAliases(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) tokenAliases(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	tokenAliasGRPCResponse := types.TokenAliasesGRPCResponse{}
 	tokenAliasResponse := types.TokenAliasesResponse{}
 
@@ -112,7 +120,7 @@ func (g *CosmosGateway) tokenAliases(req types.InboundRequest) (interface{}, err
 		return nil, err
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/tokens/infos", nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/tokens/infos", nil)
 	if err != nil {
 		logger.Logger.Error("[query-token-aliases] Create request failed", zap.Error(err))
 		return nil, err
@@ -130,7 +138,7 @@ func (g *CosmosGateway) tokenAliases(req types.InboundRequest) (interface{}, err
 		return nil, err
 	}
 
-	prefixes, err := g.customPrefixes()
+	prefixes, err := g.customPrefixes(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-token-aliases] Failed to get custom prefixes", zap.Error(err))
 		return nil, err
@@ -160,7 +168,7 @@ func (g *CosmosGateway) tokenAliases(req types.InboundRequest) (interface{}, err
 	return tokenAliasResponse, nil
 }
 
-func (g *CosmosGateway) proposalsCount() (int, error) {
+func (g *CosmosGateway) proposalsCount(ctx context.Context) (int, error) {
 	var totalCount = 0
 	var response struct {
 		Pagination struct {
@@ -168,7 +176,7 @@ func (g *CosmosGateway) proposalsCount() (int, error) {
 		} `json:"pagination"`
 	}
 
-	gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/proposals", nil)
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/proposals", nil)
 	if err != nil {
 		logger.Logger.Error("[query-proposals-count] Create request failed", zap.Error(err))
 		return totalCount, err
@@ -198,13 +206,13 @@ func (g *CosmosGateway) proposalsCount() (int, error) {
 	return totalCount, nil
 }
 
-func (g *CosmosGateway) getProposals(req types.InboundRequest) ([]interface{}, error) {
+func (g *CosmosGateway) getProposals(ctx context.Context, req types.InboundRequest) ([]interface{}, error) {
 	var proposalsInterface []interface{}
 	limit := sekaitypes.PageIterationLimit - 1
 	offset := 0
 
 	for {
-		gatewayReq, err := http.NewRequestWithContext(g.context.Context, "GET", "/kira/gov/proposals", nil)
+		gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/gov/proposals", nil)
 		if err != nil {
 			logger.Logger.Error("[query-proposals] Create request failed", zap.Error(err))
 			return nil, err
@@ -242,7 +250,25 @@ func (g *CosmosGateway) getProposals(req types.InboundRequest) ([]interface{}, e
 	return proposalsInterface, nil
 }
 
-func (g *CosmosGateway) proposals(req types.InboundRequest) (interface{}, error) {
+// ProposalStatuses maps every proposal ID to its current vote result, it feeds
+// the proposal status subscriptions.
+func (g *CosmosGateway) ProposalStatuses(ctx context.Context) (map[string]string, error) {
+	proposals, err := g.getProposals(ctx, types.InboundRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(proposals))
+	for _, p := range proposals {
+		if proposal, ok := p.(types.Proposal); ok {
+			statuses[proposal.ProposalID] = proposal.Result
+		}
+	}
+
+	return statuses, nil
+}
+
+func (g *CosmosGateway) proposals(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	const cacheTTL = 300
 
 	var proposalsResponse = types.ProposalsResponse{
@@ -288,7 +314,7 @@ func (g *CosmosGateway) proposals(req types.InboundRequest) (interface{}, error)
 		}
 	}
 
-	count, err := g.proposalsCount()
+	count, err := g.proposalsCount(ctx)
 	if err != nil {
 		logger.Logger.Error("[query-proposals] Failed to count proposals", zap.Error(err))
 		return proposalsResponse, err
@@ -306,7 +332,7 @@ func (g *CosmosGateway) proposals(req types.InboundRequest) (interface{}, error)
 			}
 		}
 
-		newProposals, err := g.getProposals(req)
+		newProposals, err := g.getProposals(ctx, req)
 		if err != nil {
 			logger.Logger.Error("[query-proposals] Failed to get new proposals", zap.Error(err))
 			return proposalsResponse, err
@@ -421,7 +447,7 @@ func (g *CosmosGateway) proposals(req types.InboundRequest) (interface{}, error)
 	return proposalsResponse, nil
 }
 
-func (g *CosmosGateway) faucet(req types.InboundRequest) (interface{}, error) {
+func (g *CosmosGateway) faucet(ctx context.Context, req types.InboundRequest) (interface{}, error) {
 	request := types.FaucetRequest{}
 
 	jsonData, err := json.Marshal(req.Payload)
@@ -437,9 +463,9 @@ func (g *CosmosGateway) faucet(req types.InboundRequest) (interface{}, error) {
 	}
 
 	if request.Claim == "" && request.Token == "" {
-		faucetAddress := sdk.AccAddress(g.PubKey.Address().Bytes()).String()
+		faucetAddress := g.signer.Address()
 
-		balances, err := g.balances(req, faucetAddress)
+		balances, err := g.balances(ctx, req, faucetAddress)
 		if err != nil {
 			logger.Logger.Error("[query-faucet] Failed to get faucet balance", zap.Error(err))
 			return nil, err
@@ -452,250 +478,24 @@ func (g *CosmosGateway) faucet(req types.InboundRequest) (interface{}, error) {
 
 		return info, nil
 	} else if request.Claim != "" && request.Token != "" {
-		return g.processFaucet(req)
-	} else {
-		err = errors.New("[query-faucet] both claim and token parameters are required")
-	}
-
-	return nil, nil
-}
-
-func (g *CosmosGateway) processFaucet(req types.InboundRequest) (interface{}, error) {
-	request := types.FaucetRequest{}
-
-	jsonData, err := json.Marshal(req.Payload)
-	if err != nil {
-		logger.Logger.Error("[query-faucet] Invalid request format", zap.Error(err))
-		return nil, err
-	}
-
-	err = json.Unmarshal(jsonData, &request)
-	if err != nil {
-		logger.Logger.Error("[query-faucet] Invalid request format", zap.Error(err))
-		return nil, err
-	}
-
-	result, err := g.storage.Read("cosmos_faucet", map[string]interface{}{"address": request.Claim}, &adapter.Options{Sort: map[string]interface{}{"timestamp": -1}}, []string{})
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to get faucet history", zap.Any("CAddress", request.Claim), zap.Error(err))
-		return nil, err
-	}
-
-	if len(result.Result) > 0 {
-		lastTimeFloat, ok := result.Result[0]["timestamp"]
-		if !ok {
-			err = errors.New("[faucet] Invalid faucet history response")
-			logger.Logger.Error("[faucet] Invalid faucet history response", zap.Any("Address", request.Claim))
-			return nil, err
-		}
-
-		lastTime, ok := lastTimeFloat.(float64)
-		if !ok {
-			err = errors.New("[faucet] Invalid faucet history response")
-			logger.Logger.Error("[faucet] Invalid faucet history response", zap.Any("Address", request.Claim))
-			return nil, err
-		}
-
-		left := (int64(lastTime) + g.config.Faucet.TimeLimit) - time.Now().UTC().Unix()
-		if left > 0 {
-			err = errors.New(fmt.Sprintf("[faucet] Claim time left: %d", left))
-			logger.Logger.Error("[faucet] Claim time left", zap.Any("Address", request.Claim), zap.Any("Time left", left))
-			return nil, err
-		}
+		return g.faucetService.Claim(ctx, request)
 	}
 
-	faucetAddress := sdk.AccAddress(g.PubKey.Address().Bytes()).String()
-
-	faucetBalances, err := g.balances(req, faucetAddress)
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to get faucet balance", zap.Error(err))
-		return nil, err
-	}
-
-	claimBalances, err := g.balances(req, request.Claim)
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to get faucet balance", zap.Error(err))
-		return nil, err
-	}
+	err = apierror.New(apierror.InvalidArgument, "[query-faucet] both claim and token parameters are required")
+	logger.Logger.Error("[query-faucet] Invalid request", zap.Error(err))
 
-	availableAmount := new(big.Int)
-	availableAmount.SetString("0", 10)
-
-	for _, balance := range faucetBalances.Balances {
-		if balance.Denom == request.Token {
-			availableAmount.Set(balance.Amount.BigInt())
-		}
-	}
-
-	claimAmount := new(big.Int)
-	claimAmount.SetString("0", 10)
-	for _, balance := range claimBalances.Balances {
-		if balance.Denom == request.Token {
-			claimAmount.Set(balance.Amount.BigInt())
-		}
-	}
-
-	faucetAmount := new(big.Int)
-	faucetAmountInt64, ok := g.config.Faucet.FaucetAmounts[request.Token]
-	if !ok {
-		err = errors.New("[faucet] Failed to get faucet amount from the configuration")
-		logger.Logger.Error("[faucet] Failed to get faucet amount from the configuration")
-		return nil, err
-	}
-	faucetAmount.SetInt64(faucetAmountInt64)
-
-	faucetMinimumAmount := new(big.Int)
-	faucetMinimumAmountInt64, ok := g.config.Faucet.FaucetMinimumAmounts[request.Token]
-	if !ok {
-		err = errors.New("[faucet] Failed to get faucet minimum amount from the configuration")
-		logger.Logger.Error("[faucet] Failed to get faucet minimum amount from the configuration")
-		return nil, err
-	}
-	faucetMinimumAmount.SetInt64(faucetMinimumAmountInt64)
-
-	feeInt64, ok := g.config.Faucet.FeeAmounts[request.Token]
-	if !ok {
-		err = errors.New("[faucet] Failed to get fee amount from the configuration")
-		logger.Logger.Error("[faucet] Failed to get fee amount from the configuration")
-		return nil, err
-	}
-
-	if faucetAmount.Cmp(claimAmount) <= 0 {
-		err = errors.New("[faucet] No need to send tokens: faucetAmount <= claimAmount")
-		logger.Logger.Error("[faucet] No need to send tokens: faucetAmount <= claimAmount")
-		return nil, err
-	}
-
-	claimingAmount := new(big.Int)
-	claimingAmount.SetString("0", 10)
-	claimingAmount = claimingAmount.Sub(faucetAmount, claimAmount)
-	if claimingAmount.Cmp(faucetMinimumAmount) <= 0 {
-		err = errors.New("[faucet] No need to send tokens: faucetAmount <= claimAmount")
-		logger.Logger.Error("[faucet] No need to send tokens: faucetAmount <= claimAmount")
-		return nil, err
-	}
-
-	remainingAmount := new(big.Int)
-	remainingAmount.SetString("0", 10)
-	remainingAmount = remainingAmount.Sub(availableAmount, faucetMinimumAmount)
-	if claimingAmount.Cmp(remainingAmount) > 0 {
-		err = errors.New("[faucet] Not enough tokens: faucetAmount-claimAmount > availableAmount-faucetMininumAmount")
-		logger.Logger.Error("[faucet] Not enough tokens: faucetAmount-claimAmount > availableAmount-faucetMininumAmount")
-		return nil, err
-	}
-
-	accountInfo, err := g.account(faucetAddress)
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to get account info", zap.Error(err))
-		return nil, err
-	}
-
-	accountNumber, err := strconv.ParseUint(accountInfo.AccountNumber, 10, 64)
-	if err != nil {
-		logger.Logger.Error("[faucet] Invalid account response format", zap.Error(err))
-		return nil, err
-	}
-
-	sequence, err := strconv.ParseUint(accountInfo.Sequence, 10, 64)
-	if err != nil {
-		logger.Logger.Error("[faucet] Invalid account response format", zap.Error(err))
-		return nil, err
-	}
-
-	status, err := g.status()
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to get node status", zap.Error(err))
-		return nil, err
-	}
-
-	msgSend := &bank.MsgSend{
-		FromAddress: faucetAddress,
-		ToAddress:   request.Claim,
-		Amount:      sdk.NewCoins(sdk.NewCoin(request.Token, sdk.NewIntFromBigInt(claimingAmount))),
-	}
-
-	txBuilder := g.txConfig.NewTxBuilder()
-
-	err = txBuilder.SetMsgs(msgSend)
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to set tx msgs", zap.Error(err))
-		return nil, err
-	}
-
-	txBuilder.SetFeeAmount(sdk.NewCoins(sdk.NewCoin(request.Token, sdk.NewInt(feeInt64))))
-	txBuilder.SetGasLimit(200000)
-	txBuilder.SetMemo("Faucet Transfer")
-
-	signMode := g.txConfig.SignModeHandler().DefaultMode()
-
-	signerData := authsigning.SignerData{
-		Address:       faucetAddress,
-		ChainID:       status.NodeInfo.Network,
-		AccountNumber: accountNumber,
-		Sequence:      sequence,
-		PubKey:        g.PubKey,
-	}
-
-	signBytes, err := g.txConfig.SignModeHandler().GetSignBytes(
-		signMode,
-		signerData,
-		txBuilder.GetTx(),
-	)
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to get sign bytes", zap.Error(err))
-		return nil, err
-	}
-
-	sig, _, err := g.kRing.Sign(g.kName, signBytes)
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to sign transaction", zap.Error(err))
-		return nil, err
-	}
-
-	sigData := signing.SingleSignatureData{
-		SignMode:  signMode,
-		Signature: sig,
-	}
-
-	sigV2 := signing.SignatureV2{
-		PubKey:   g.PubKey,
-		Data:     &sigData,
-		Sequence: signerData.Sequence,
-	}
+	return nil, err
+}
 
-	err = txBuilder.SetSignatures(sigV2)
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to set signatures", zap.Error(err))
-		return nil, err
-	}
+// faucetChallenge issues the proof of work the claim field has to solve before
+// claiming. It stores the challenge, so it is only served on POST.
+func (g *CosmosGateway) faucetChallenge(req types.InboundRequest) (interface{}, error) {
+	address, _ := req.Payload["claim"].(string)
 
-	txBytes, err := g.txConfig.TxEncoder()(txBuilder.GetTx())
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to encode transaction", zap.Error(err))
+	if _, err := accAddress(address); err != nil {
+		logger.Logger.Error("[faucet] Invalid claim address", zap.Error(err))
 		return nil, err
 	}
 
-	tHash, err := g.txs(types.InboundRequest{
-		Method: "POST",
-		Payload: map[string]interface{}{
-			"tx":   txBytes,
-			"mode": "sync",
-		},
-	})
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to write faucet claim to database", zap.Error(err))
-		return tHash, err
-	}
-
-	_, err = g.storage.Create("cosmos_faucet", []interface{}{map[string]interface{}{
-		"address":   request.Claim,
-		"timestamp": time.Now().UTC().Unix(),
-		"amount":    claimingAmount.String(),
-		"token":     request.Token,
-	}})
-	if err != nil {
-		logger.Logger.Error("[faucet] Failed to write faucet claim to database", zap.Error(err))
-	}
-
-	return tHash, nil
+	return g.faucetService.guard.Challenge(address)
 }
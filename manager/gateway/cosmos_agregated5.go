@@ -0,0 +1,326 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	sekaitypes "github.com/KiraCore/sekai/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-interx-manager/utils"
+)
+
+type pageRequest struct {
+	Limit      int `json:"limit,string,omitempty"`
+	Offset     int `json:"offset,string,omitempty"`
+	CountTotal int `json:"count_total,string,omitempty"`
+}
+
+// page returns the bounds of the requested window over total items together
+// with the pagination block to report, if any. A negative offset counts from
+// the first item.
+func (r pageRequest) page(total int) (int, int, *types.Pagination) {
+	var pagination *types.Pagination
+	if r.CountTotal > 0 {
+		pagination = &types.Pagination{Total: total}
+	}
+
+	if r.Limit <= 0 {
+		return 0, total, pagination
+	}
+
+	from := max(r.Offset, 0)
+	if from > total {
+		from = total
+	}
+
+	to := from + r.Limit
+	if to > total {
+		to = total
+	}
+
+	return from, to, pagination
+}
+
+func (g *CosmosGateway) baskets(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	type BasketsRequest struct {
+		pageRequest
+		Tokens          interface{} `json:"tokens,omitempty"`
+		DerivativesOnly bool        `json:"derivatives_only,string,omitempty"`
+	}
+
+	request := BasketsRequest{
+		pageRequest: pageRequest{Limit: sekaitypes.PageIterationLimit - 1},
+	}
+
+	jsonData, err := json.Marshal(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(jsonData, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := g.tokenInfos(ctx)
+	if err != nil {
+		logger.Logger.Error("[query-baskets] Getting token rates failed", zap.Error(err))
+		return nil, err
+	}
+
+	rates := make(map[string]types.TokenAlias, len(tokens))
+	denoms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		rates[token.Denom] = token
+		denoms = append(denoms, token.Denom)
+	}
+
+	// sekai ignores derivatives_only unless tokens is set, so an unfiltered
+	// query asks for baskets holding any registered token instead.
+	filter := strings.Join(cast.ToStringSlice(request.Tokens), ",")
+	if filter == "" {
+		filter = strings.Join(denoms, ",")
+	}
+
+	if filter == "" {
+		return types.BasketsResponse{Baskets: []types.Basket{}}, nil
+	}
+
+	path := fmt.Sprintf("/kira/basket/token_baskets/%s/%t", url.PathEscape(filter), request.DerivativesOnly)
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		logger.Logger.Error("[query-baskets] Create request failed", zap.Error(err))
+		return nil, err
+	}
+
+	respBody, err := g.grpcProxy.ServeGRPC(gatewayReq)
+	if err != nil {
+		logger.Logger.Error("[query-baskets] Serve request failed", zap.Error(err))
+		return nil, err
+	}
+
+	grpcResponse := types.BasketsGRPCResponse{}
+
+	err = json.Unmarshal(respBody, &grpcResponse)
+	if err != nil {
+		logger.Logger.Error("[query-baskets] Invalid response format", zap.Error(err))
+		return nil, err
+	}
+
+	from, to, pagination := request.page(len(grpcResponse.Baskets))
+
+	response := types.BasketsResponse{
+		Baskets:    grpcResponse.Baskets[from:to],
+		Pagination: pagination,
+	}
+
+	for i := range response.Baskets {
+		basket := &response.Baskets[i]
+
+		basket.Denom = fmt.Sprintf("b%s/%s", basket.ID, basket.Suffix)
+		basket.SwapFee = utils.ConvertRate(basket.SwapFee)
+		basket.SlipppageFeeMin = utils.ConvertRate(basket.SlipppageFeeMin)
+		basket.TokensCap = utils.ConvertRate(basket.TokensCap)
+
+		if rate, found := rates[basket.Denom]; found {
+			basket.Rate = &rate
+		}
+
+		for j := range basket.Tokens {
+			token := &basket.Tokens[j]
+			token.Weight = utils.ConvertRate(token.Weight)
+
+			if rate, found := rates[token.Denom]; found {
+				token.Rate = &rate
+			}
+		}
+	}
+
+	return response, nil
+}
+
+func (g *CosmosGateway) dapps(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	type DappsRequest struct {
+		pageRequest
+		Status string `json:"status,omitempty"`
+	}
+
+	request := DappsRequest{
+		pageRequest: pageRequest{Limit: sekaitypes.PageIterationLimit - 1},
+	}
+
+	jsonData, err := json.Marshal(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(jsonData, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/layer2/all_dapps", nil)
+	if err != nil {
+		logger.Logger.Error("[query-dapps] Create request failed", zap.Error(err))
+		return nil, err
+	}
+
+	respBody, err := g.grpcProxy.ServeGRPC(gatewayReq)
+	if err != nil {
+		logger.Logger.Error("[query-dapps] Serve request failed", zap.Error(err))
+		return nil, err
+	}
+
+	grpcResponse := struct {
+		Dapps []json.RawMessage `json:"dapps"`
+	}{}
+
+	err = json.Unmarshal(respBody, &grpcResponse)
+	if err != nil {
+		logger.Logger.Error("[query-dapps] Invalid response format", zap.Error(err))
+		return nil, err
+	}
+
+	dapps := []types.DappInfo{}
+	for _, raw := range grpcResponse.Dapps {
+		dapp := types.DappInfo{Dapp: raw}
+
+		err = json.Unmarshal(raw, &dapp)
+		if err != nil {
+			logger.Logger.Error("[query-dapps] Invalid dapp format", zap.Error(err))
+			return nil, err
+		}
+
+		if request.Status != "" && !strings.EqualFold(dapp.Status, request.Status) {
+			continue
+		}
+
+		dapps = append(dapps, dapp)
+	}
+
+	from, to, pagination := request.page(len(dapps))
+
+	response := types.DappsResponse{
+		Dapps:      dapps[from:to],
+		Pagination: pagination,
+	}
+
+	for i := range response.Dapps {
+		dapp := &response.Dapps[i]
+
+		registrar, err := g.executionRegistrar(ctx, dapp.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		dapp.ExecutionRegistrar = registrar
+		if registrar != nil && registrar.CurrSession != nil {
+			dapp.SessionStatus = registrar.CurrSession.Status
+			dapp.Leader = registrar.CurrSession.Leader
+		}
+	}
+
+	return response, nil
+}
+
+func (g *CosmosGateway) executionRegistrar(ctx context.Context, name string) (*types.ExecutionRegistrar, error) {
+	gatewayReq, err := http.NewRequestWithContext(ctx, "GET", "/kira/layer2/execution_registrar/"+url.PathEscape(name), nil)
+	if err != nil {
+		logger.Logger.Error("[query-execution-registrar] Create request failed", zap.Error(err))
+		return nil, err
+	}
+
+	respBody, err := g.grpcProxy.ServeGRPC(gatewayReq)
+	if err != nil {
+		logger.Logger.Error("[query-execution-registrar] Serve request failed", zap.Error(err), zap.String("dapp", name))
+		return nil, err
+	}
+
+	response := types.ExecutionRegistrarResponse{}
+
+	err = json.Unmarshal(respBody, &response)
+	if err != nil {
+		logger.Logger.Error("[query-execution-registrar] Invalid response format", zap.Error(err))
+		return nil, err
+	}
+
+	return response.ExecutionRegistrar, nil
+}
+
+func (g *CosmosGateway) custody(ctx context.Context, address string) (interface{}, error) {
+	addr := addressParam(address)
+
+	response := types.CustodyResponse{Address: address}
+
+	queries := []struct {
+		path   string
+		field  string
+		target *json.RawMessage
+	}{
+		{"/kira/custody/custody_settings/", "custodySettings", &response.Settings},
+		{"/kira/custody/custody_custodians/", "custodyCustodians", &response.Custodians},
+		{"/kira/custody/custody_white_list/", "custodyWhiteList", &response.WhiteList},
+		{"/kira/custody/custody_limits/", "custodyLimits", &response.Limits},
+		{"/kira/custody/custody_limits_status/", "custodyStatuses", &response.LimitsStatus},
+		{"/kira/custody/custody_pool/", "transactions", &response.Pool},
+	}
+
+	for _, query := range queries {
+		gatewayReq, err := http.NewRequestWithContext(ctx, "GET", query.path+addr, nil)
+		if err != nil {
+			logger.Logger.Error("[query-custody] Create request failed", zap.Error(err))
+			return nil, err
+		}
+
+		respBody, err := g.grpcProxy.ServeGRPC(gatewayReq)
+		if err != nil {
+			logger.Logger.Error("[query-custody] Serve request failed", zap.Error(err), zap.String("path", query.path))
+			return nil, err
+		}
+
+		fields := map[string]json.RawMessage{}
+
+		err = json.Unmarshal(respBody, &fields)
+		if err != nil {
+			logger.Logger.Error("[query-custody] Invalid response format", zap.Error(err))
+			return nil, err
+		}
+
+		*query.target = fields[query.field]
+	}
+
+	return response, nil
+}
+
+// accAddress parses a bech32 account address given by the caller.
+func accAddress(address string) (sdk.AccAddress, error) {
+	accAddr, err := sdk.AccAddressFromBech32(address)
+	if err != nil {
+		return nil, apierror.Newf(apierror.InvalidArgument, "invalid address %q: %v", address, err)
+	}
+
+	return accAddr, nil
+}
+
+// addressParam converts a bech32 address into the base64 form the gateway
+// expects for bytes path parameters. Anything else is passed through as is.
+func addressParam(address string) string {
+	accAddr, err := sdk.AccAddressFromBech32(address)
+	if err != nil {
+		return address
+	}
+
+	return base64.URLEncoding.EncodeToString(accAddr)
+}
@@ -0,0 +1,42 @@
+package gateway
+
+import "testing"
+
+func TestPageRequestPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  pageRequest
+		total    int
+		from, to int
+		counted  bool
+	}{
+		{name: "no limit", request: pageRequest{}, total: 5, from: 0, to: 5},
+		{name: "negative limit", request: pageRequest{Limit: -1, Offset: 2}, total: 5, from: 0, to: 5},
+		{name: "first page", request: pageRequest{Limit: 2}, total: 5, from: 0, to: 2},
+		{name: "last page", request: pageRequest{Limit: 2, Offset: 4}, total: 5, from: 4, to: 5},
+		{name: "offset past the end", request: pageRequest{Limit: 2, Offset: 9}, total: 5, from: 5, to: 5},
+		{name: "negative offset", request: pageRequest{Limit: 1, Offset: -1}, total: 5, from: 0, to: 1},
+		{name: "negative offset of an empty list", request: pageRequest{Limit: 1, Offset: -3}, total: 0, from: 0, to: 0},
+		{name: "count total", request: pageRequest{Limit: 1, CountTotal: 1}, total: 5, from: 0, to: 1, counted: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from, to, pagination := test.request.page(test.total)
+			if from != test.from || to != test.to {
+				t.Fatalf("page(%d) = [%d:%d], want [%d:%d]", test.total, from, to, test.from, test.to)
+			}
+
+			if (pagination != nil) != test.counted {
+				t.Fatalf("pagination = %v, want counted %v", pagination, test.counted)
+			}
+
+			if pagination != nil && pagination.Total != test.total {
+				t.Fatalf("pagination total = %d, want %d", pagination.Total, test.total)
+			}
+
+			items := make([]int, test.total)
+			_ = items[from:to]
+		})
+	}
+}
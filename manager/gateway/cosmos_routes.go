@@ -0,0 +1,406 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// grpcGatewayPrefixes are the path prefixes of the gRPC gateway handlers
+// registered by registerHandlers.
+var grpcGatewayPrefixes = []string{
+	"/cosmos/auth",
+	"/cosmos/bank",
+	"/cosmos/evidence",
+	"/cosmos/tx",
+	"/kira/basket",
+	"/kira/bridge",
+	"/kira/collectives",
+	"/kira/custody",
+	"/kira/distributor",
+	"/kira/gov",
+	"/kira/layer2",
+	"/kira/multistaking",
+	"/kira/recovery",
+	"/kira/slashing",
+	"/kira/spending",
+	"/kira/staking",
+	"/kira/tokens",
+	"/kira/ubi",
+	"/kira/upgrade",
+}
+
+// tendermintMethods are the Tendermint RPC methods served on
+// /tendermint/{method}. Only reads are allowed, the unsafe and peer managing
+// methods and the ones that broadcast stay on the node.
+var tendermintMethods = map[string]bool{
+	"abci_info":           true,
+	"abci_query":          true,
+	"block":               true,
+	"block_by_hash":       true,
+	"block_results":       true,
+	"block_search":        true,
+	"blockchain":          true,
+	"commit":              true,
+	"consensus_params":    true,
+	"genesis":             true,
+	"genesis_chunked":     true,
+	"header":              true,
+	"header_by_hash":      true,
+	"health":              true,
+	"net_info":            true,
+	"num_unconfirmed_txs": true,
+	"status":              true,
+	"tx":                  true,
+	"tx_search":           true,
+	"unconfirmed_txs":     true,
+	"validators":          true,
+}
+
+// routes is the single place where KIRA endpoints are declared. Paths under
+// the gRPC gateway prefixes that are not listed explicitly fall through to
+// the gRPC gateway, any other path is unknown.
+func (g *CosmosGateway) routes() []Route {
+	routes := []Route{
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/accounts/{address}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.account(ctx, params["address"])
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/balances/{address}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.balances(ctx, req, params["address"])
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/gov/identity_records/{address}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.identityRecords(ctx, params["address"])
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/gov/identity_verify_requests_by_approver/{address}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.identityVerifyRequestsByApprover(ctx, req, params["address"])
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/gov/identity_verify_requests_by_requester/{address}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.identityVerifyRequestsByRequester(ctx, req, params["address"])
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/gov/proposal/{id}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				req.Path = "/kira/gov/proposals/" + params["id"]
+				return g.proxy(ctx, req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/kira/gov/proposals",
+			CacheTTL:  5 * time.Second,
+			RateClass: RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.proposals(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/gov/execution_fee",
+			Historical: true,
+			CacheTTL:   time.Minute,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.executionFee(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/gov/network_properties",
+			Historical: true,
+			CacheTTL:   time.Minute,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.networkProperties(ctx)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/delegations",
+			Historical: true,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.delegations(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/undelegations",
+			Historical: true,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.undelegations(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/staking-pool",
+			Historical: true,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.stakingPool(ctx, req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/kira/status",
+			CacheTTL:  time.Second,
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.status(ctx)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/tokens/rates",
+			Historical: true,
+			CacheTTL:   time.Minute,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.tokenRates(ctx)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/tokens/aliases",
+			Historical: true,
+			CacheTTL:   time.Minute,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.tokenAliases(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/baskets",
+			Historical: true,
+			CacheTTL:   5 * time.Second,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.baskets(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/layer2/dapps",
+			Historical: true,
+			CacheTTL:   5 * time.Second,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.dapps(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/custody/{address}",
+			Historical: true,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.custody(ctx, params["address"])
+			},
+		},
+		{
+			// custody and bridge queries take the address as bytes, accept bech32 too
+			Method:     http.MethodGet,
+			Path:       "/kira/custody/{query}/{address}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				req.Path = "/kira/custody/" + params["query"] + "/" + addressParam(params["address"])
+				return g.proxy(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/kira/bridge/{query}/{address}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				req.Path = "/kira/bridge/" + params["query"] + "/" + addressParam(params["address"])
+				return g.proxy(ctx, req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/kira/faucet",
+			RateClass: RateClassTx,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.faucet(ctx, req)
+			},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/kira/faucet/challenge",
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.faucetChallenge(req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/kira/faucet/claims/{id}",
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.faucetService.Lookup(params["id"])
+			},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/kira/txs",
+			RateClass: RateClassTx,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.txs(ctx, req)
+			},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/kira/txs/simulate",
+			RateClass: RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.simulateTx(ctx, req)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/dashboard",
+			Historical: true,
+			CacheTTL:   5 * time.Second,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.dashboard(ctx)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/status",
+			CacheTTL:  time.Second,
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.statusAPI(ctx)
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/valopers",
+			Historical: true,
+			CacheTTL:   5 * time.Second,
+			RateClass:  RateClassAggregate,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.validators(ctx, req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/transactions",
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.transactions(req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/transactions/{hash}",
+			CacheTTL:  10 * time.Second,
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.txByHash(params["hash"])
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/blocks",
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.blocks(req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/blocks/{height}",
+			CacheTTL:  time.Hour,
+			Immutable: true,
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.blockById(ctx, req, params["height"])
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/blocks/{height}/transactions",
+			CacheTTL:  10 * time.Second,
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.txByBlock(req, params["height"])
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/tendermint",
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.tendermint(ctx, req)
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/tendermint/{method}",
+			RateClass: RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				if !tendermintMethods[params["method"]] {
+					return nil, fmt.Errorf("%w: %s", ErrRouteNotFound, req.Path)
+				}
+
+				req.Path = "/" + params["method"]
+				return g.tendermint(ctx, req)
+			},
+		},
+	}
+
+	for _, prefix := range grpcGatewayPrefixes {
+		routes = append(routes, Route{
+			Path:       prefix + "/{path...}",
+			Historical: true,
+			RateClass:  RateClassDefault,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return g.proxy(ctx, req)
+			},
+		})
+	}
+
+	return routes
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestCosmosRoutes(t *testing.T) {
+	router, err := NewRouter((&CosmosGateway{}).routes()...)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		route  string
+		err    error
+	}{
+		{method: http.MethodGet, path: "/kira/gov/proposals", route: "/kira/gov/proposals"},
+		{method: http.MethodGet, path: "/kira/gov/voters/1", route: "/kira/gov/{path...}"},
+		{method: http.MethodPost, path: "/cosmos/tx/v1beta1/txs", route: "/cosmos/tx/{path...}"},
+		{method: http.MethodGet, path: "/cosmos/bank/v1beta1/supply", route: "/cosmos/bank/{path...}"},
+		{method: http.MethodGet, path: "/kira/unknown", err: ErrRouteNotFound},
+		{method: http.MethodGet, path: "/cosmos/staking/v1beta1/validators", err: ErrRouteNotFound},
+		{method: http.MethodGet, path: "/tendermint", route: "/tendermint"},
+		{method: http.MethodGet, path: "/tendermint/status", route: "/tendermint/{method}"},
+		{method: http.MethodPost, path: "/tendermint/status", err: ErrMethodNotAllowed},
+		{method: http.MethodGet, path: "/tendermint/status/extra", err: ErrRouteNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			route, _, err := router.Match(test.method, test.path)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Match error = %v, want %v", err, test.err)
+			}
+
+			if test.err == nil && route.Path != test.route {
+				t.Fatalf("Match = %s, want %s", route.Path, test.route)
+			}
+		})
+	}
+}
+
+func TestTendermintUnsafeMethods(t *testing.T) {
+	router, err := NewRouter((&CosmosGateway{}).routes()...)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	for _, method := range []string{"dial_peers", "dial_seeds", "unsafe_flush_mempool", "broadcast_tx_sync"} {
+		path := "/tendermint/" + method
+
+		route, params, err := router.Match(http.MethodGet, path)
+		if err != nil {
+			t.Fatalf("Match %s: %v", path, err)
+		}
+
+		// refused before any node is called
+		_, err = route.Handler(context.Background(), types.InboundRequest{Method: http.MethodGet, Path: path}, params)
+		if !errors.Is(err, ErrRouteNotFound) {
+			t.Fatalf("%s = %v, want %v", path, err, ErrRouteNotFound)
+		}
+	}
+}
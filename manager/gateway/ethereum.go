@@ -1,10 +1,10 @@
 package gateway
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"github.com/KiraCore/sai-service/service"
-	"github.com/spf13/cast"
+	"fmt"
 	"math/big"
 	"strconv"
 	"strings"
@@ -15,36 +15,108 @@ import (
 
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-service/service"
 )
 
+// defaultQuorumMethods are the reads answered by a provider majority when
+// ethereum.quorum.size is above 1 and no method list is configured.
+var defaultQuorumMethods = []string{"eth_getBalance", "eth_call"}
+
 type EthereumGateway struct {
 	*BaseGateway
-	storage    types.Storage
-	rpcProxies map[string]*jsonrpc2.RPCClient
+	storage       types.Storage
+	chains        map[string]*evmChain
+	quorumMethods map[string]struct{}
+	cache         *ResponseCache
+	cacheTTL      time.Duration
+	timeouts      Timeouts
+	maxBatchSize  int
+	batchWorkers  int
+	streams       map[string]*evmStream
+	stop          context.CancelFunc
 }
 
 var _ types.Gateway = (*EthereumGateway)(nil)
 
-func newJsonRPCClients(chains map[string]string) map[string]*jsonrpc2.RPCClient {
-	proxies := map[string]*jsonrpc2.RPCClient{}
+func newEVMChains(chains map[string][]string, quorum int, policies map[string]types.EVMPolicy, breakers *Breakers) map[string]*evmChain {
+	result := map[string]*evmChain{}
 
-	for chainId, url := range chains {
-		proxies[chainId] = jsonrpc2.NewRPCClient(url)
+	for chainId, urls := range chains {
+		pool := NewEVMPool(chainId, urls, quorum, breakers, "ethereum/"+chainId)
+		result[chainId] = newEVMChain(chainId, pool, evmPolicyFor(policies, chainId), quorum, breakers)
 	}
 
-	return proxies
+	return result
 }
 
-func NewEthereumGateway(ctx *service.Context, chains map[string]string, storage types.Storage, retryAttempts int, retryDelay time.Duration, rateLimit int) (*EthereumGateway, error) {
-	return &EthereumGateway{
-		BaseGateway: NewBaseGateway(ctx, retryAttempts, retryDelay, rateLimit),
-		rpcProxies:  newJsonRPCClients(chains),
-		storage:     storage,
-	}, nil
+func NewEthereumGateway(ctx *service.Context, chains map[string][]string, quorum int, quorumMethods []string, config types.EVMConfig, timeouts Timeouts, storage types.Storage, retry types.RetryConfig, breakers *Breakers, rateLimit int) (*EthereumGateway, error) {
+	if len(quorumMethods) == 0 {
+		quorumMethods = defaultQuorumMethods
+	}
+
+	cache, err := NewResponseCache(types.CacheConfig{Backend: config.Cache.Backend, Size: config.Cache.Size}, storage)
+	if err != nil {
+		logger.Logger.Error("NewEthereumGateway", zap.Error(err))
+		return nil, err
+	}
+
+	cacheTTL := time.Duration(config.Cache.TTL) * time.Second
+	if cacheTTL <= 0 {
+		cacheTTL = evmDefaultCacheTTL
+	}
+
+	maxBatchSize := config.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = evmMaxBatchSize
+	}
+
+	batchWorkers := config.BatchWorkers
+	if batchWorkers <= 0 {
+		batchWorkers = evmBatchWorkers
+	}
+
+	gateway := &EthereumGateway{
+		BaseGateway:   NewBaseGateway(ctx, retry, breakers, rateLimit),
+		chains:        newEVMChains(chains, quorum, config.Policies, breakers),
+		quorumMethods: make(map[string]struct{}, len(quorumMethods)),
+		storage:       storage,
+		cache:         cache,
+		cacheTTL:      cacheTTL,
+		timeouts:      timeouts,
+		maxBatchSize:  maxBatchSize,
+		batchWorkers:  batchWorkers,
+	}
+
+	for _, method := range quorumMethods {
+		gateway.quorumMethods[method] = struct{}{}
+	}
+
+	streamCtx, stop := context.WithCancel(ctx.Context)
+	gateway.stop = stop
+	go cache.Run(streamCtx)
+	gateway.streams = map[string]*evmStream{}
+	for chainId, url := range config.WS {
+		if _, ok := gateway.chains[chainId]; !ok {
+			logger.Logger.Warn("NewEthereumGateway - websocket of an unknown chain", zap.String("chain", chainId))
+			continue
+		}
+
+		gateway.streams[chainId] = newEVMStream(streamCtx, chainId, url)
+	}
+
+	return gateway, nil
 }
 
-func (g *EthereumGateway) Handle(data []byte) (interface{}, error) {
-	var req types.InboundRequest
+// Handle serves two kinds of paths: "/{chain}" takes a standard JSON-RPC 2.0
+// body, single or batch, and "/{chain}/{method}" is the legacy form where the
+// payload is the only param, with "/{chain}/status" as the node summary.
+// Every upstream call is bounded by the timeout of its own method.
+func (g *EthereumGateway) Handle(ctx context.Context, data []byte, metadata types.RequestMetadata) (interface{}, error) {
+	var req struct {
+		Method  string          `json:"method"`
+		Path    string          `json:"path"`
+		Payload json.RawMessage `json:"payload"`
+	}
 
 	if err := json.Unmarshal(data, &req); err != nil {
 		logger.Logger.Error("EthereumGateway - Handle", zap.Error(err))
@@ -57,63 +129,161 @@ func (g *EthereumGateway) Handle(data []byte) (interface{}, error) {
 		return nil, err
 	}
 
-	client, ok := g.rpcProxies[chainId]
+	chain, ok := g.chains[chainId]
 	if !ok {
-		err = errors.New("chain not found")
+		err = fmt.Errorf("%w: chain %s", ErrRouteNotFound, chainId)
 		logger.Logger.Error("EthereumGateway - Handle", zap.Error(err))
 		return nil, err
 	}
 
-	switch req.Path {
-	case "/status":
-		{
-			return g.retry.Do(func() (interface{}, error) {
-				if err := g.rateLimit.Wait(g.context.Context); err != nil {
-					logger.Logger.Error("EthereumGateway - Handle", zap.Error(err))
-					return nil, err
-				}
-				return g.status(client, chainId)
-			})
-		}
+	payload := rawPayload(req.Payload)
+	ctx = types.WithRequestMetadata(ctx, metadata)
+
+	switch method {
+	case "":
+		return g.handleJSONRPC(ctx, chain, payload), nil
+	case "status":
+		ctx, cancel := withTimeout(ctx, g.timeouts.For(method, 0))
+		defer cancel()
+
+		return g.retry.For(method, true).Do(ctx, func() (interface{}, error) {
+			if err := g.rateLimit.Wait(ctx); err != nil {
+				logger.Logger.Error("EthereumGateway - Handle", zap.Error(err))
+				return nil, err
+			}
+			return g.status(ctx, chain.pool, chainId)
+		})
 	}
 
-	return g.retry.Do(func() (interface{}, error) {
-		if err := g.rateLimit.Wait(g.context.Context); err != nil {
+	var params map[string]interface{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &params); err != nil {
 			logger.Logger.Error("EthereumGateway - Handle", zap.Error(err))
 			return nil, err
 		}
-		return client.Call(method, req.Payload)
+	}
+
+	return g.forward(ctx, chain, method, params)
+}
+
+// rawPayload unwraps a body the proxy forwarded as a string because it was not
+// sent as application/json.
+func rawPayload(payload json.RawMessage) json.RawMessage {
+	var body string
+	if err := json.Unmarshal(payload, &body); err == nil {
+		return json.RawMessage(body)
+	}
+
+	return payload
+}
+
+// forward sends a call through the chain policy: methods it does not allow are
+// refused, immutable answers come from the cache, the call waits on the rate
+// class of the method and old block reads go to the archive providers.
+func (g *EthereumGateway) forward(ctx context.Context, chain *evmChain, method string, params ...interface{}) (*jsonrpc2.RPCResponse, error) {
+	if !chain.allowed(method) {
+		err := fmt.Errorf("%w: %s on %s", ErrMethodNotAllowed, method, chain.id)
+		logger.Logger.Error("EthereumGateway - forward", zap.Error(err))
+		return nil, err
+	}
+
+	var cacheKey string
+	if g.cache != nil && chain.cacheable(method) {
+		paramsBytes, err := json.Marshal(params)
+		if err == nil {
+			cacheKey = "evm:" + chain.id + ":" + method + ":" + string(paramsBytes)
+		}
+	}
+
+	if cacheKey != "" {
+		if cached, ok := g.cache.Get(cacheKey); ok {
+			var result interface{}
+			decoder := json.NewDecoder(bytes.NewReader(cached))
+			decoder.UseNumber()
+			if err := decoder.Decode(&result); err == nil {
+				return &jsonrpc2.RPCResponse{JSONRPC: "2.0", Result: result}, nil
+			}
+		}
+	}
+
+	ctx, cancel := withTimeout(ctx, g.timeouts.For(method, 0))
+	defer cancel()
+
+	client := chain.client(ctx, method, params)
+
+	idempotent := chain.rateClassOf(method) != RateClassTx
+
+	response, err := g.retry.For(method, idempotent).Do(ctx, func() (interface{}, error) {
+		if err := chain.rateLimiter(method, g.rateLimit).Wait(ctx); err != nil {
+			logger.Logger.Error("EthereumGateway - forward", zap.Error(err), zap.String("method", method))
+			return nil, err
+		}
+		return g.call(ctx, client, method, params...)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	rpcResponse := response.(*jsonrpc2.RPCResponse)
+
+	if cacheKey != "" && rpcResponse.Error == nil && chain.immutable(ctx, method, params, rpcResponse.Result) {
+		if resultBytes, err := json.Marshal(rpcResponse.Result); err == nil {
+			g.cache.Set(cacheKey, resultBytes, g.cacheTTL, true)
+		}
+	}
+
+	return rpcResponse, nil
+}
+
+// call answers the configured critical reads through a provider quorum.
+func (g *EthereumGateway) call(ctx context.Context, client *EVMPool, method string, params ...interface{}) (*jsonrpc2.RPCResponse, error) {
+	if _, ok := g.quorumMethods[method]; ok {
+		return client.QuorumCall(ctx, method, params...)
+	}
+
+	return client.Call(ctx, method, params...)
 }
 
 func (g *EthereumGateway) Close() {
+	if g.stop != nil {
+		g.stop()
+	}
 
+	for _, stream := range g.streams {
+		stream.Close()
+	}
 }
 
+// convert splits "/{chain}" and "/{chain}/{method}", the "/ethereum" prefix is
+// optional so that direct manager requests can keep the public path.
 func (g *EthereumGateway) convert(originalPath string) (chainId, method string, err error) {
-	paths := strings.Split(originalPath, "/")
-	if len(paths) < 3 {
-		return "", "", err
+	path := strings.Trim(strings.TrimPrefix(originalPath, "/ethereum"), "/")
+
+	paths := strings.Split(path, "/")
+	if paths[0] == "" || len(paths) > 2 {
+		return "", "", fmt.Errorf("%w: %s", ErrRouteNotFound, originalPath)
 	}
 
-	return paths[2], paths[3], nil
-}
+	if len(paths) == 2 {
+		method = paths[1]
+	}
 
-func (g *EthereumGateway) status(client *jsonrpc2.RPCClient, chainId string) (interface{}, error) {
-	chains := cast.ToStringMapString(g.context.GetConfig("ethereum.nodes", map[string]string{}))
+	return paths[0], method, nil
+}
 
+func (g *EthereumGateway) status(ctx context.Context, client *EVMPool, chainId string) (interface{}, error) {
 	var response = types.EVMStatus{}
 
-	response.NodeInfo.RPCAddress = chains[chainId]
+	response.NodeInfo.RPCAddress = client.URL()
 
-	data, err := client.Call("eth_chainId")
+	data, err := client.Call(ctx, "eth_chainId")
 	if err != nil {
 		return nil, err
 	}
 
 	response.NodeInfo.Network, _ = strconv.ParseUint((chainId)[2:], 16, 64)
 
-	data, err = client.Call("web3_clientVersion")
+	data, err = client.Call(ctx, "web3_clientVersion")
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +293,7 @@ func (g *EthereumGateway) status(client *jsonrpc2.RPCClient, chainId string) (in
 	}
 	response.NodeInfo.Version.Web3 = clientVersion
 
-	data, err = client.Call("net_version")
+	data, err = client.Call(ctx, "net_version")
 	if err != nil {
 		return nil, err
 	}
@@ -133,7 +303,7 @@ func (g *EthereumGateway) status(client *jsonrpc2.RPCClient, chainId string) (in
 	}
 	response.NodeInfo.Version.Net = netVersion
 
-	data, err = client.Call("eth_protocolVersion")
+	data, err = client.Call(ctx, "eth_protocolVersion")
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +313,7 @@ func (g *EthereumGateway) status(client *jsonrpc2.RPCClient, chainId string) (in
 	}
 	response.NodeInfo.Version.Protocol = protocolVersion
 
-	data, err = client.Call("eth_syncing")
+	data, err = client.Call(ctx, "eth_syncing")
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +328,7 @@ func (g *EthereumGateway) status(client *jsonrpc2.RPCClient, chainId string) (in
 		Number    string `json:"number"`
 		Timestamp string `json:"timestamp"`
 	})
-	data, err = client.Call("eth_getBlockByNumber", "latest", true)
+	data, err = client.Call(ctx, "eth_getBlockByNumber", "latest", true)
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +345,7 @@ func (g *EthereumGateway) status(client *jsonrpc2.RPCClient, chainId string) (in
 		Number    string `json:"number"`
 		Timestamp string `json:"timestamp"`
 	})
-	data, err = client.Call("eth_getBlockByNumber", "earliest", true)
+	data, err = client.Call(ctx, "eth_getBlockByNumber", "earliest", true)
 	if err != nil {
 		return nil, err
 	}
@@ -187,7 +357,7 @@ func (g *EthereumGateway) status(client *jsonrpc2.RPCClient, chainId string) (in
 	response.SyncInfo.EarliestBlockHeight, _ = strconv.ParseUint((earliestBlock.Number)[2:], 16, 64)
 	response.SyncInfo.EarliestBlockTime, _ = strconv.ParseUint((earliestBlock.Timestamp)[2:], 16, 64)
 
-	data, err = client.Call("eth_gasPrice")
+	data, err = client.Call(ctx, "eth_gasPrice")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	jsonrpc2 "github.com/KeisukeYamashita/go-jsonrpc"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+var jsonRPCNullID = json.RawMessage("null")
+
+// jsonRPCUpstreamError is the message of every -32603 answer. The cause is
+// only logged, it can name a provider URL and the API key in it.
+const jsonRPCUpstreamError = "upstream error"
+
+// handleJSONRPC serves a standard JSON-RPC 2.0 body, a single request or a
+// batch, so that stock Ethereum tooling can use /api/ethereum/{chain} as its
+// endpoint. Errors are reported in the envelope, never as a failed call.
+// Batches hold at most max_batch_size requests and run batch_workers at a time.
+func (g *EthereumGateway) handleJSONRPC(ctx context.Context, chain *evmChain, body json.RawMessage) interface{} {
+	body = bytes.TrimSpace(body)
+
+	if !json.Valid(body) {
+		return jsonRPCError(jsonRPCNullID, types.EVMRPCParseError, "parse error")
+	}
+
+	if body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return jsonRPCError(jsonRPCNullID, types.EVMRPCInvalidRequest, "invalid request")
+		}
+
+		if len(batch) == 0 {
+			return jsonRPCError(jsonRPCNullID, types.EVMRPCInvalidRequest, "empty batch")
+		}
+
+		if len(batch) > g.maxBatchSize {
+			return jsonRPCError(jsonRPCNullID, types.EVMRPCInvalidRequest, fmt.Sprintf("batch too large, at most %d requests", g.maxBatchSize))
+		}
+
+		responses := make([]*types.EVMRPCResponse, len(batch))
+
+		// the request itself paid for the first element, the others are charged
+		// one by one
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for range min(g.batchWorkers, len(batch)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					responses[i] = g.jsonRPCCall(ctx, chain, batch[i], i > 0)
+				}
+			}()
+		}
+
+		for i := range batch {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+
+		// notifications get no response, a batch of only notifications none at all
+		result := make([]*types.EVMRPCResponse, 0, len(responses))
+		for _, response := range responses {
+			if response != nil {
+				result = append(result, response)
+			}
+		}
+
+		if len(result) == 0 {
+			return nil
+		}
+
+		return result
+	}
+
+	response := g.jsonRPCCall(ctx, chain, body, false)
+	if response == nil {
+		return nil
+	}
+
+	return response
+}
+
+// jsonRPCCall forwards one request and answers with its own id, it returns nil
+// for notifications. With charge the call is admitted on its own against the
+// quota of the caller first.
+func (g *EthereumGateway) jsonRPCCall(ctx context.Context, chain *evmChain, raw json.RawMessage, charge bool) *types.EVMRPCResponse {
+	var request types.EVMRPCRequest
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return jsonRPCError(jsonRPCNullID, types.EVMRPCInvalidRequest, "invalid request")
+	}
+
+	id := request.ID
+	notification := len(id) == 0
+	if notification {
+		id = jsonRPCNullID
+	}
+
+	if request.Jsonrpc != "2.0" || request.Method == "" {
+		return jsonRPCError(id, types.EVMRPCInvalidRequest, "invalid request")
+	}
+
+	var params []interface{}
+	if len(request.Params) > 0 && !bytes.Equal(request.Params, jsonRPCNullID) {
+		decoder := json.NewDecoder(bytes.NewReader(request.Params))
+		decoder.UseNumber()
+		if err := decoder.Decode(&params); err != nil {
+			return jsonRPCError(id, types.EVMRPCInvalidParams, "params must be an array")
+		}
+	}
+
+	if charge {
+		if err := types.Admit(ctx); err != nil {
+			if notification {
+				return nil
+			}
+
+			return jsonRPCError(id, types.EVMRPCLimitExceeded, err.Error())
+		}
+	}
+
+	response, err := g.forward(ctx, chain, request.Method, params...)
+
+	if notification {
+		return nil
+	}
+
+	if errors.Is(err, ErrMethodNotAllowed) {
+		return jsonRPCError(id, types.EVMRPCMethodNotFound, "method not allowed")
+	}
+
+	if err != nil {
+		logger.Logger.Error("EthereumGateway - jsonRPCCall", zap.String("method", request.Method), zap.Error(err))
+		return jsonRPCError(id, types.EVMRPCInternalError, jsonRPCUpstreamError)
+	}
+
+	return jsonRPCResponse(id, response)
+}
+
+func jsonRPCResponse(id json.RawMessage, upstream *jsonrpc2.RPCResponse) *types.EVMRPCResponse {
+	if upstream.Error != nil {
+		return &types.EVMRPCResponse{
+			Jsonrpc: "2.0",
+			ID:      id,
+			Error: &types.EVMRPCError{
+				Code:    upstream.Error.Code,
+				Message: upstream.Error.Message,
+				Data:    upstream.Error.Data,
+			},
+		}
+	}
+
+	result, err := json.Marshal(upstream.Result)
+	if err != nil {
+		logger.Logger.Error("EthereumGateway - jsonRPCResponse", zap.Error(err))
+		return jsonRPCError(id, types.EVMRPCInternalError, jsonRPCUpstreamError)
+	}
+
+	return &types.EVMRPCResponse{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+}
+
+func jsonRPCError(id json.RawMessage, code int, message string) *types.EVMRPCResponse {
+	return &types.EVMRPCResponse{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Error: &types.EVMRPCError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,394 @@
+package gateway
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	RateClassHeavy = "heavy"
+
+	evmPolicyDefault    = "default"
+	evmHeadRefresh      = 5 * time.Second
+	evmArchiveDepth     = 128
+	evmDefaultCacheTTL  = 24 * time.Hour
+	evmMaxBatchSize     = 100
+	evmBatchWorkers     = 10
+	evmBlockHashLength  = 66
+	evmHexPrefix        = "0x"
+	evmBlockTagEarliest = "earliest"
+)
+
+// evmAllow and evmDeny apply when a policy leaves them empty: the public
+// namespaces without the calls that would use the provider's own accounts.
+var (
+	evmAllow = []string{"eth_*", "net_*", "web3_*"}
+	evmDeny  = []string{"eth_accounts", "eth_sign", "eth_signTransaction", "eth_signTypedData*", "eth_sendTransaction"}
+)
+
+var evmRateClasses = map[string]string{
+	"eth_call":               RateClassHeavy,
+	"eth_estimateGas":        RateClassHeavy,
+	"eth_getLogs":            RateClassHeavy,
+	"eth_getBlockReceipts":   RateClassHeavy,
+	"eth_sendRawTransaction": RateClassTx,
+}
+
+// evmBlockParams is the position of the block parameter of the methods reading
+// the chain at a given block.
+var evmBlockParams = map[string]int{
+	"eth_getBalance":                          1,
+	"eth_getCode":                             1,
+	"eth_getTransactionCount":                 1,
+	"eth_call":                                1,
+	"eth_estimateGas":                         1,
+	"eth_getStorageAt":                        2,
+	"eth_getProof":                            2,
+	"eth_getBlockByNumber":                    0,
+	"eth_getBlockTransactionCountByNumber":    0,
+	"eth_getTransactionByBlockNumberAndIndex": 0,
+	"eth_getUncleByBlockNumberAndIndex":       0,
+	"eth_getUncleCountByBlockNumber":          0,
+	"eth_getBlockReceipts":                    0,
+}
+
+// evmStaticMethods never change for a chain, evmHashMethods address a block by
+// hash and evmTxMethods a transaction by hash, those are immutable once the
+// block holding the transaction is finalized.
+var (
+	evmStaticMethods = map[string]struct{}{
+		"eth_chainId": {},
+		"net_version": {},
+	}
+	evmHashMethods = map[string]struct{}{
+		"eth_getBlockByHash":                    {},
+		"eth_getBlockTransactionCountByHash":    {},
+		"eth_getTransactionByBlockHashAndIndex": {},
+		"eth_getUncleByBlockHashAndIndex":       {},
+		"eth_getUncleCountByBlockHash":          {},
+	}
+	evmTxMethods = map[string]struct{}{
+		"eth_getTransactionByHash":  {},
+		"eth_getTransactionReceipt": {},
+	}
+)
+
+// evmChain is a configured chain: its providers, the archive providers serving
+// old blocks, the method policy and the last head and finalized heights seen.
+type evmChain struct {
+	id         string
+	pool       *EVMPool
+	archive    *EVMPool
+	allow      []string
+	deny       []string
+	rateClass  map[string]string
+	rateLimits map[string]*RateLimiter
+
+	archiveDepth  int64
+	finalityDepth int64
+
+	mu         sync.Mutex
+	head       int64
+	finalized  int64
+	updated    time.Time
+	refreshing bool
+}
+
+func newEVMChain(id string, pool *EVMPool, policy types.EVMPolicy, quorum int, breakers *Breakers) *evmChain {
+	chain := &evmChain{
+		id:            id,
+		pool:          pool,
+		allow:         policy.Allow,
+		deny:          policy.Deny,
+		rateClass:     policy.RateClasses,
+		rateLimits:    map[string]*RateLimiter{},
+		archiveDepth:  policy.ArchiveDepth,
+		finalityDepth: policy.FinalityDepth,
+	}
+
+	if len(chain.allow) == 0 {
+		chain.allow = evmAllow
+	}
+	if len(chain.deny) == 0 {
+		chain.deny = evmDeny
+	}
+	if chain.archiveDepth <= 0 {
+		chain.archiveDepth = evmArchiveDepth
+	}
+	if len(policy.Archive) > 0 {
+		chain.archive = NewEVMPool(id, policy.Archive, quorum, breakers, "ethereum/"+id+"/archive")
+	}
+
+	for class, limit := range policy.RateLimits {
+		chain.rateLimits[class] = NewRateLimiter(limit)
+	}
+
+	return chain
+}
+
+// evmPolicyFor returns the policy of the chain, or the "default" one.
+func evmPolicyFor(policies map[string]types.EVMPolicy, chain string) types.EVMPolicy {
+	if policy, ok := policies[chain]; ok {
+		return policy
+	}
+
+	return policies[evmPolicyDefault]
+}
+
+func (c *evmChain) allowed(method string) bool {
+	return !matchMethod(c.deny, method) && matchMethod(c.allow, method)
+}
+
+// rateClassOf returns the class the chain policy gives method, or its default.
+func (c *evmChain) rateClassOf(method string) string {
+	if class, ok := c.rateClass[method]; ok {
+		return class
+	}
+	if class, ok := evmRateClasses[method]; ok {
+		return class
+	}
+
+	return RateClassDefault
+}
+
+func (c *evmChain) rateLimiter(method string, fallback *RateLimiter) *RateLimiter {
+	if limiter, ok := c.rateLimits[c.rateClassOf(method)]; ok {
+		return limiter
+	}
+
+	return fallback
+}
+
+// client returns the archive providers for reads of blocks older than
+// archiveDepth behind the head, the regular ones otherwise.
+func (c *evmChain) client(ctx context.Context, method string, params []interface{}) *EVMPool {
+	if c.archive == nil {
+		return c.pool
+	}
+
+	block, ok := evmRequestBlock(method, params)
+	if !ok {
+		return c.pool
+	}
+
+	head, _ := c.heights(ctx)
+	if head > 0 && block < head-c.archiveDepth {
+		return c.archive
+	}
+
+	return c.pool
+}
+
+// immutable tells whether the answer to the call can be cached for good.
+func (c *evmChain) immutable(ctx context.Context, method string, params []interface{}, result interface{}) bool {
+	if result == nil {
+		return false
+	}
+
+	if _, ok := evmStaticMethods[method]; ok {
+		return true
+	}
+
+	if _, ok := evmHashMethods[method]; ok {
+		return true
+	}
+
+	if method == "eth_getBlockReceipts" && len(params) > 0 {
+		if hash, ok := params[0].(string); ok && len(hash) == evmBlockHashLength {
+			return true
+		}
+	}
+
+	if _, ok := evmTxMethods[method]; ok {
+		tx, ok := result.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		block, ok := parseBlockNumber(tx["blockNumber"])
+		return ok && c.isFinalized(ctx, block)
+	}
+
+	if method == "eth_getLogs" {
+		filter, ok := evmLogFilter(params)
+		if !ok {
+			return false
+		}
+
+		if _, ok := filter["blockHash"]; ok {
+			return true
+		}
+
+		to, ok := parseBlockNumber(filter["toBlock"])
+		return ok && c.isFinalized(ctx, to)
+	}
+
+	if index, ok := evmBlockParams[method]; ok && index < len(params) {
+		block, ok := parseBlockNumber(params[index])
+		return ok && c.isFinalized(ctx, block)
+	}
+
+	return false
+}
+
+// cacheable tells whether the call may have a cached answer.
+func (c *evmChain) cacheable(method string) bool {
+	if method == "eth_getLogs" {
+		return true
+	}
+
+	for _, methods := range []map[string]struct{}{evmStaticMethods, evmHashMethods, evmTxMethods} {
+		if _, ok := methods[method]; ok {
+			return true
+		}
+	}
+
+	_, ok := evmBlockParams[method]
+	return ok
+}
+
+func (c *evmChain) isFinalized(ctx context.Context, block int64) bool {
+	_, finalized := c.heights(ctx)
+	return block <= finalized
+}
+
+// heights returns the head and finalized block numbers, refreshed at most
+// every evmHeadRefresh. Chains without the "finalized" tag count the blocks
+// finalityDepth behind the head as final, or none when it is not set. One
+// caller refreshes them without holding the lock, the others get the cached
+// values meanwhile.
+func (c *evmChain) heights(ctx context.Context) (int64, int64) {
+	c.mu.Lock()
+	if c.refreshing || time.Since(c.updated) < evmHeadRefresh {
+		head, finalized := c.head, c.finalized
+		c.mu.Unlock()
+		return head, finalized
+	}
+	c.refreshing = true
+	c.mu.Unlock()
+
+	head, finalized, ok := c.fetchHeights(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.refreshing = false
+	c.updated = time.Now()
+	if ok {
+		c.head, c.finalized = head, finalized
+	}
+
+	return c.head, c.finalized
+}
+
+// fetchHeights asks the providers for the head and finalized block numbers.
+func (c *evmChain) fetchHeights(ctx context.Context) (int64, int64, bool) {
+	response, err := c.pool.Call(ctx, "eth_blockNumber")
+	if err != nil || response.Error != nil {
+		logger.Logger.Error("evmChain - heights", zap.String("chain", c.id), zap.Error(err))
+		return 0, 0, false
+	}
+
+	head, ok := parseBlockNumber(response.Result)
+	if !ok {
+		return 0, 0, false
+	}
+
+	var finalized int64
+	if c.finalityDepth > 0 && head > c.finalityDepth {
+		finalized = head - c.finalityDepth
+	}
+
+	response, err = c.pool.Call(ctx, "eth_getBlockByNumber", "finalized", false)
+	if err == nil && response.Error == nil {
+		if block, ok := response.Result.(map[string]interface{}); ok {
+			if number, ok := parseBlockNumber(block["number"]); ok {
+				finalized = number
+			}
+		}
+	}
+
+	return head, finalized, true
+}
+
+// evmRequestBlock is the lowest block number the call reads, block tags other
+// than "earliest" follow the head and have none.
+func evmRequestBlock(method string, params []interface{}) (int64, bool) {
+	if method == "eth_getLogs" {
+		filter, ok := evmLogFilter(params)
+		if !ok {
+			return 0, false
+		}
+
+		return parseBlockNumber(filter["fromBlock"])
+	}
+
+	index, ok := evmBlockParams[method]
+	if !ok || index >= len(params) {
+		return 0, false
+	}
+
+	return parseBlockNumber(params[index])
+}
+
+func evmLogFilter(params []interface{}) (map[string]interface{}, bool) {
+	if len(params) == 0 {
+		return nil, false
+	}
+
+	filter, ok := params[0].(map[string]interface{})
+	return filter, ok
+}
+
+// parseBlockNumber reads a hex quantity, the "earliest" tag or an EIP-1898
+// {"blockNumber": ...} object.
+func parseBlockNumber(value interface{}) (int64, bool) {
+	switch block := value.(type) {
+	case string:
+		if block == evmBlockTagEarliest {
+			return 0, true
+		}
+
+		if !strings.HasPrefix(block, evmHexPrefix) || len(block) == evmBlockHashLength {
+			return 0, false
+		}
+
+		number, err := strconv.ParseInt(block[len(evmHexPrefix):], 16, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return number, true
+	case map[string]interface{}:
+		return parseBlockNumber(block["blockNumber"])
+	}
+
+	return 0, false
+}
+
+// matchMethod reports whether method is one of patterns, a pattern ending with
+// "*" matches every method with that prefix.
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(method, prefix) {
+				return true
+			}
+			continue
+		}
+
+		if pattern == method {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,324 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jsonrpc2 "github.com/KeisukeYamashita/go-jsonrpc"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+)
+
+var ErrNoQuorum = apierror.New(apierror.Upstream, "providers did not agree on a result")
+
+const (
+	evmMaxBackoff = time.Minute
+	// evmProviderTimeout bounds a provider call when the caller has no deadline.
+	evmProviderTimeout = 30 * time.Second
+)
+
+// rate limit codes returned by public providers: Infura/geth "limit exceeded",
+// Alchemy/QuickNode "too many requests", and plain HTTP 429 mirrored in the body
+var evmRateLimitCodes = map[int]struct{}{
+	-32005: {},
+	-32029: {},
+	-32090: {},
+	429:    {},
+}
+
+// evmProvider is one RPC endpoint of a chain. latency is a moving average of
+// its successful calls, failed calls put it aside for an increasing backoff
+// and enough of them in a row open its circuit breaker. url may carry an API
+// key, it is never logged or returned: name and target stand for it.
+type evmProvider struct {
+	url          string
+	name         string
+	target       string
+	breaker      *CircuitBreaker
+	latency      atomic.Int64
+	failures     atomic.Int32
+	backoffUntil atomic.Int64
+}
+
+func (p *evmProvider) available(now time.Time) bool {
+	return p.backoffUntil.Load() <= now.UnixNano()
+}
+
+func (p *evmProvider) succeeded(elapsed time.Duration) {
+	p.failures.Store(0)
+	p.backoffUntil.Store(0)
+
+	previous := p.latency.Load()
+	if previous == 0 {
+		p.latency.Store(int64(elapsed))
+		return
+	}
+
+	p.latency.Store((previous*4 + int64(elapsed)) / 5)
+}
+
+func (p *evmProvider) failed(err error) {
+	failures := p.failures.Add(1)
+
+	backoff := time.Second << min(failures-1, 6)
+	if backoff > evmMaxBackoff {
+		backoff = evmMaxBackoff
+	}
+
+	p.backoffUntil.Store(time.Now().Add(backoff).UnixNano())
+	logger.Logger.Warn("EVMPool - provider failed", zap.String("provider", p.name), zap.String("target", p.target), zap.Duration("backoff", backoff), zap.Error(err))
+}
+
+// EVMPool holds the RPC providers of one chain. Calls go to the fastest
+// available provider and fail over to the next one on transport errors and
+// rate limits, quorum calls ask several providers and keep the majority answer.
+type EVMPool struct {
+	chain     string
+	client    *http.Client
+	providers []*evmProvider
+	quorum    int
+	requestID atomic.Uint64
+}
+
+// NewEVMPool names the breaker of each provider after prefix and its index,
+// provider URLs often carry an API key.
+func NewEVMPool(chain string, urls []string, quorum int, breakers *Breakers, prefix string) *EVMPool {
+	pool := &EVMPool{
+		chain:  chain,
+		client: &http.Client{Timeout: evmProviderTimeout},
+		quorum: quorum,
+	}
+
+	for i, providerURL := range urls {
+		name := fmt.Sprintf("%s/%d", prefix, i)
+		target := upstreamTarget(providerURL)
+
+		pool.providers = append(pool.providers, &evmProvider{
+			url:     providerURL,
+			name:    name,
+			target:  target,
+			breaker: breakers.Get(name, target),
+		})
+	}
+
+	return pool
+}
+
+// parseEVMNodes accepts both a single URL and a list of URLs per chain.
+func parseEVMNodes(nodes map[string]interface{}) map[string][]string {
+	chains := map[string][]string{}
+
+	for chain, value := range nodes {
+		switch urls := value.(type) {
+		case string:
+			chains[chain] = []string{urls}
+		default:
+			chains[chain] = cast.ToStringSlice(urls)
+		}
+	}
+
+	return chains
+}
+
+// ordered returns the available providers fastest first, never measured ones
+// before the others so that they get a latency, followed by those in backoff
+// as a last resort.
+func (p *EVMPool) ordered() []*evmProvider {
+	now := time.Now()
+
+	var available, backoff []*evmProvider
+	for _, provider := range p.providers {
+		if provider.available(now) {
+			available = append(available, provider)
+		} else {
+			backoff = append(backoff, provider)
+		}
+	}
+
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].latency.Load() < available[j].latency.Load()
+	})
+	sort.SliceStable(backoff, func(i, j int) bool {
+		return backoff[i].backoffUntil.Load() < backoff[j].backoffUntil.Load()
+	})
+
+	return append(available, backoff...)
+}
+
+// Call sends the request to the providers in order until one answers. RPC
+// errors other than rate limits are answers and are returned as they are.
+func (p *EVMPool) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc2.RPCResponse, error) {
+	var lastErr error = apierror.Newf(apierror.Unavailable, "chain %s has no provider", p.chain)
+
+	for _, provider := range p.ordered() {
+		response, err := p.callProvider(ctx, provider, method, params...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		return response, nil
+	}
+
+	return nil, apierror.Wrap(apierror.Upstream, lastErr)
+}
+
+// QuorumCall sends the request to p.quorum providers at once and returns the
+// answer more than half of them agree on.
+func (p *EVMPool) QuorumCall(ctx context.Context, method string, params ...interface{}) (*jsonrpc2.RPCResponse, error) {
+	providers := p.ordered()
+	if p.quorum <= 1 || len(providers) < 2 {
+		return p.Call(ctx, method, params...)
+	}
+
+	size := p.quorum
+	if size > len(providers) {
+		size = len(providers)
+	}
+
+	responses := make([]*jsonrpc2.RPCResponse, size)
+
+	var wg sync.WaitGroup
+	for i, provider := range providers[:size] {
+		wg.Add(1)
+		go func(i int, provider *evmProvider) {
+			defer wg.Done()
+			responses[i], _ = p.callProvider(ctx, provider, method, params...)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	votes := map[string]int{}
+	answers := map[string]*jsonrpc2.RPCResponse{}
+	for _, response := range responses {
+		if response == nil {
+			continue
+		}
+
+		key, err := json.Marshal(struct {
+			Result interface{}        `json:"result"`
+			Error  *jsonrpc2.RPCError `json:"error"`
+		}{response.Result, response.Error})
+		if err != nil {
+			continue
+		}
+
+		votes[string(key)]++
+		answers[string(key)] = response
+	}
+
+	for key, count := range votes {
+		if count*2 > size {
+			return answers[key], nil
+		}
+	}
+
+	logger.Logger.Error("EVMPool - QuorumCall", zap.String("chain", p.chain), zap.String("method", method), zap.Int("quorum", size), zap.Any("votes", votes))
+
+	return nil, fmt.Errorf("%w: %s on %s", ErrNoQuorum, method, p.chain)
+}
+
+func (p *EVMPool) callProvider(ctx context.Context, provider *evmProvider, method string, params ...interface{}) (*jsonrpc2.RPCResponse, error) {
+	if err := provider.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	response, err := p.send(ctx, provider, method, params...)
+	if err == nil && response.Error != nil {
+		if _, limited := evmRateLimitCodes[response.Error.Code]; limited {
+			err = response.Error
+		}
+	}
+
+	// a cancelled caller says nothing about the provider
+	if err != nil && ctx.Err() != nil {
+		provider.breaker.Done(ctx.Err())
+		return nil, ctx.Err()
+	}
+
+	provider.breaker.Done(err)
+
+	if err != nil {
+		provider.failed(err)
+		return nil, err
+	}
+
+	provider.succeeded(time.Since(start))
+
+	return response, nil
+}
+
+// send posts one JSON-RPC request, params are omitted when there are none.
+func (p *EVMPool) send(ctx context.Context, provider *evmProvider, method string, params ...interface{}) (*jsonrpc2.RPCResponse, error) {
+	request := jsonrpc2.RPCRequest{
+		JSONRPC: "2.0",
+		ID:      uint(p.requestID.Add(1)),
+		Method:  method,
+	}
+	if len(params) > 0 {
+		request.Params = params
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Accept", "application/json")
+
+	httpResponse, err := p.client.Do(httpRequest)
+	if err != nil {
+		// the error names the URL, with the API key it may carry
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = provider.target
+		}
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	response := &jsonrpc2.RPCResponse{}
+	decoder := json.NewDecoder(httpResponse.Body)
+	decoder.UseNumber()
+	if err = decoder.Decode(response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// URL returns the scheme and host of the provider the next call would use.
+func (p *EVMPool) URL() string {
+	providers := p.ordered()
+	if len(providers) == 0 {
+		return ""
+	}
+
+	return providers[0].target
+}
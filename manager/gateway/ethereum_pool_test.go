@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestEVMPoolHidesProviderURL(t *testing.T) {
+	// a closed server, so that the call fails in transport
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	const secret = "0123456789abcdef"
+	pool := NewEVMPool("0x1", []string{server.URL + "/v3/" + secret}, 1, NewBreakers(types.BreakerConfig{}), "ethereum/0x1")
+
+	_, err := pool.Call(context.Background(), "eth_chainId")
+	if err == nil {
+		t.Fatal("call to a closed provider succeeded")
+	}
+
+	if strings.Contains(err.Error(), secret) {
+		t.Fatalf("error %q shows the provider key", err)
+	}
+
+	if url := pool.URL(); url != server.URL {
+		t.Fatalf("URL() = %q, want %q", url, server.URL)
+	}
+}
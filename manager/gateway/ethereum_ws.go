@@ -0,0 +1,672 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	evmWSWriteWait        = 10 * time.Second
+	evmWSPongWait         = 60 * time.Second
+	evmWSPingInterval     = 30 * time.Second
+	evmWSRequestTimeout   = 10 * time.Second
+	evmWSMaxBackoff       = 30 * time.Second
+	evmWSMaxMessage       = 1 << 20
+	evmWSClientBuffer     = 256
+	evmWSMaxSubscriptions = 32
+)
+
+var evmSubscriptionKinds = map[string]struct{}{
+	"newHeads":               {},
+	"logs":                   {},
+	"newPendingTransactions": {},
+}
+
+var errUpstreamClosed = errors.New("upstream websocket closed")
+
+// evmSubscription is one upstream subscription shared by every client
+// subscribing with the same params. upstream is empty while it is being
+// (re)subscribed, ready is closed once the first eth_subscribe answered.
+type evmSubscription struct {
+	key      string
+	params   []interface{}
+	upstream string
+	clients  map[*evmWSClient]string
+	ready    chan struct{}
+	err      error
+}
+
+// evmWSClient is one downstream connection, messages are queued on send and a
+// client that lets the queue fill up is dropped.
+type evmWSClient struct {
+	send          chan []byte
+	done          chan struct{}
+	once          sync.Once
+	subscriptions map[string]*evmSubscription
+}
+
+func (c *evmWSClient) push(message []byte) {
+	select {
+	case c.send <- message:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+func (c *evmWSClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// evmStream holds the upstream WebSocket of a chain. It is dialed on the first
+// subscription and, when it drops, dialed again and every live subscription
+// is made again under the same client ids.
+type evmStream struct {
+	ctx   context.Context
+	chain string
+	url   string
+
+	mu            sync.Mutex
+	conn          *websocket.Conn
+	subscriptions map[string]*evmSubscription
+	byUpstream    map[string]*evmSubscription
+	pending       map[uint64]chan *types.EVMRPCResponse
+	reconnecting  bool
+
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+}
+
+func newEVMStream(ctx context.Context, chain, url string) *evmStream {
+	return &evmStream{
+		ctx:           ctx,
+		chain:         chain,
+		url:           url,
+		subscriptions: map[string]*evmSubscription{},
+		byUpstream:    map[string]*evmSubscription{},
+		pending:       map[uint64]chan *types.EVMRPCResponse{},
+	}
+}
+
+func (s *evmStream) connect() (*websocket.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		return s.conn, nil
+	}
+
+	dialCtx, cancel := context.WithTimeout(s.ctx, evmWSRequestTimeout)
+	defer cancel()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(dialCtx, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.conn = conn
+	go s.read(conn)
+
+	logger.Logger.Info("evmStream - connected", zap.String("chain", s.chain))
+
+	return conn, nil
+}
+
+// request sends a JSON-RPC call upstream and waits for its answer.
+func (s *evmStream) request(method string, params ...interface{}) (json.RawMessage, error) {
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	id := s.nextID.Add(1)
+	reply := make(chan *types.EVMRPCResponse, 1)
+
+	s.mu.Lock()
+	s.pending[id] = reply
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	s.writeMu.Lock()
+	conn.SetWriteDeadline(time.Now().Add(evmWSWriteWait))
+	err = conn.WriteJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+	s.writeMu.Unlock()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case response, ok := <-reply:
+		if !ok {
+			return nil, errUpstreamClosed
+		}
+		if response.Error != nil {
+			return nil, response.Error
+		}
+		return response.Result, nil
+	case <-time.After(evmWSRequestTimeout):
+		return nil, fmt.Errorf("%s timed out on %s", method, s.chain)
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func (s *evmStream) read(conn *websocket.Conn) {
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			s.disconnected(conn, err)
+			return
+		}
+
+		var incoming struct {
+			ID     *uint64              `json:"id"`
+			Method string               `json:"method"`
+			Result json.RawMessage      `json:"result"`
+			Error  *types.EVMRPCError   `json:"error"`
+			Params *evmSubscriptionItem `json:"params"`
+		}
+		if err := json.Unmarshal(message, &incoming); err != nil {
+			logger.Logger.Error("evmStream - read", zap.String("chain", s.chain), zap.Error(err))
+			continue
+		}
+
+		if incoming.Method == "eth_subscription" && incoming.Params != nil {
+			s.dispatch(incoming.Params)
+			continue
+		}
+
+		if incoming.ID == nil {
+			continue
+		}
+
+		s.mu.Lock()
+		reply, ok := s.pending[*incoming.ID]
+		s.mu.Unlock()
+
+		if ok {
+			reply <- &types.EVMRPCResponse{Result: incoming.Result, Error: incoming.Error}
+		}
+	}
+}
+
+type evmSubscriptionItem struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// dispatch relays an upstream notification to every client of the
+// subscription under the client's own subscription id.
+func (s *evmStream) dispatch(item *evmSubscriptionItem) {
+	s.mu.Lock()
+	sub, ok := s.byUpstream[item.Subscription]
+	var clients map[*evmWSClient]string
+	if ok {
+		clients = make(map[*evmWSClient]string, len(sub.clients))
+		for client, id := range sub.clients {
+			clients[client] = id
+		}
+	}
+	s.mu.Unlock()
+
+	for client, id := range clients {
+		message, err := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "eth_subscription",
+			"params":  &evmSubscriptionItem{Subscription: id, Result: item.Result},
+		})
+		if err != nil {
+			logger.Logger.Error("evmStream - dispatch", zap.Error(err))
+			return
+		}
+
+		client.push(message)
+	}
+}
+
+func (s *evmStream) disconnected(conn *websocket.Conn, err error) {
+	conn.Close()
+
+	s.mu.Lock()
+	if s.conn != conn {
+		s.mu.Unlock()
+		return
+	}
+
+	s.conn = nil
+	for id, reply := range s.pending {
+		close(reply)
+		delete(s.pending, id)
+	}
+	s.byUpstream = map[string]*evmSubscription{}
+	for _, sub := range s.subscriptions {
+		sub.upstream = ""
+	}
+
+	resubscribe := len(s.subscriptions) > 0 && !s.reconnecting
+	if resubscribe {
+		s.reconnecting = true
+	}
+	s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	logger.Logger.Warn("evmStream - disconnected", zap.String("chain", s.chain), zap.Error(err))
+
+	if resubscribe {
+		go s.resubscribe()
+	}
+}
+
+// resubscribe makes every subscription without an upstream id again, backing
+// off while the node cannot be reached.
+func (s *evmStream) resubscribe() {
+	defer func() {
+		s.mu.Lock()
+		s.reconnecting = false
+		s.mu.Unlock()
+	}()
+
+	backoff := time.Second
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		s.mu.Lock()
+		var missing []*evmSubscription
+		for _, sub := range s.subscriptions {
+			if sub.upstream == "" {
+				missing = append(missing, sub)
+			}
+		}
+		s.mu.Unlock()
+
+		if len(missing) == 0 {
+			return
+		}
+
+		failed := false
+		for _, sub := range missing {
+			if err := s.subscribeUpstream(sub); err != nil {
+				logger.Logger.Error("evmStream - resubscribe", zap.String("chain", s.chain), zap.String("subscription", sub.key), zap.Error(err))
+				failed = true
+				break
+			}
+		}
+
+		if !failed {
+			return
+		}
+
+		backoff = min(backoff*2, evmWSMaxBackoff)
+	}
+}
+
+func (s *evmStream) subscribeUpstream(sub *evmSubscription) error {
+	result, err := s.request("eth_subscribe", sub.params...)
+	if err != nil {
+		return err
+	}
+
+	var upstream string
+	if err := json.Unmarshal(result, &upstream); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	live := s.subscriptions[sub.key] == sub
+	if live {
+		sub.upstream = upstream
+		s.byUpstream[upstream] = sub
+	}
+	s.mu.Unlock()
+
+	if !live {
+		go s.request("eth_unsubscribe", upstream)
+	}
+
+	return nil
+}
+
+// subscribe attaches the client to the upstream subscription for params,
+// making it when no other client holds it yet.
+func (s *evmStream) subscribe(client *evmWSClient, params []interface{}) (string, error) {
+	keyBytes, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	key := string(keyBytes)
+
+	id, err := newEVMSubscriptionID()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	if len(client.subscriptions) >= evmWSMaxSubscriptions {
+		s.mu.Unlock()
+		return "", errors.New("too many subscriptions")
+	}
+
+	sub, ok := s.subscriptions[key]
+	if !ok {
+		sub = &evmSubscription{
+			key:     key,
+			params:  params,
+			clients: map[*evmWSClient]string{},
+			ready:   make(chan struct{}),
+		}
+		s.subscriptions[key] = sub
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		sub.err = s.subscribeUpstream(sub)
+		if sub.err != nil {
+			s.mu.Lock()
+			if s.subscriptions[key] == sub {
+				delete(s.subscriptions, key)
+			}
+			s.mu.Unlock()
+		}
+		close(sub.ready)
+	}
+
+	<-sub.ready
+	if sub.err != nil {
+		return "", sub.err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscriptions[key] != sub {
+		return "", errUpstreamClosed
+	}
+
+	// the connection may have gone while waiting, unsubscribeAll already ran
+	select {
+	case <-client.done:
+		return "", errors.New("client disconnected")
+	default:
+	}
+
+	sub.clients[client] = id
+	client.subscriptions[id] = sub
+
+	return id, nil
+}
+
+// unsubscribe detaches the client and drops the upstream subscription once
+// no client is left on it.
+func (s *evmStream) unsubscribe(client *evmWSClient, id string) bool {
+	s.mu.Lock()
+	sub, ok := client.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+
+	delete(client.subscriptions, id)
+	delete(sub.clients, client)
+
+	var upstream string
+	if len(sub.clients) == 0 && s.subscriptions[sub.key] == sub {
+		delete(s.subscriptions, sub.key)
+		delete(s.byUpstream, sub.upstream)
+		upstream = sub.upstream
+	}
+	s.mu.Unlock()
+
+	if upstream != "" {
+		go func() {
+			if _, err := s.request("eth_unsubscribe", upstream); err != nil {
+				logger.Logger.Error("evmStream - unsubscribe", zap.String("chain", s.chain), zap.Error(err))
+			}
+		}()
+	}
+
+	return true
+}
+
+func (s *evmStream) unsubscribeAll(client *evmWSClient) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(client.subscriptions))
+	for id := range client.subscriptions {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		s.unsubscribe(client, id)
+	}
+}
+
+func (s *evmStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+func newEVMSubscriptionID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return "0x" + hex.EncodeToString(id), nil
+}
+
+// ServeHTTP serves /ethereum/{chain}/ws. eth_subscribe and eth_unsubscribe
+// are multiplexed over the chain's upstream WebSocket, every other request is
+// answered like a JSON-RPC call over HTTP. The connection is admitted by the
+// subscriptions server, every message and eth_subscribe is charged to the
+// caller through the admission of the request context.
+func (g *EthereumGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	chainId, method, err := g.convert(r.URL.Path)
+	if err != nil || method != "ws" {
+		http.NotFound(w, r)
+		return
+	}
+
+	chain, ok := g.chains[chainId]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	stream, ok := g.streams[chainId]
+	if !ok {
+		http.Error(w, "websocket is not configured for chain "+chainId, http.StatusNotFound)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		// the public proxy already applies CORS for every origin
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Logger.Error("EthereumGateway - ServeHTTP", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	client := &evmWSClient{
+		send:          make(chan []byte, evmWSClientBuffer),
+		done:          make(chan struct{}),
+		subscriptions: map[string]*evmSubscription{},
+	}
+	defer stream.unsubscribeAll(client)
+	defer client.close()
+
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		g.readWS(r.Context(), conn, chain, stream, client)
+	}()
+
+	ticker := time.NewTicker(evmWSPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-readerDone:
+			return
+		case <-client.done:
+			conn.SetWriteDeadline(time.Now().Add(evmWSWriteWait))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "subscriber is too slow"))
+			return
+		case message := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(evmWSWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(evmWSWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (g *EthereumGateway) readWS(ctx context.Context, conn *websocket.Conn, chain *evmChain, stream *evmStream, client *evmWSClient) {
+	conn.SetReadLimit(evmWSMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(evmWSPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(evmWSPongWait))
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var response interface{}
+
+		var request types.EVMRPCRequest
+		message = bytes.TrimSpace(message)
+		if len(message) > 0 && message[0] == '{' && json.Unmarshal(message, &request) == nil &&
+			(request.Method == "eth_subscribe" || request.Method == "eth_unsubscribe") {
+			if reply := g.subscriptionCall(ctx, chain, stream, client, &request); reply != nil {
+				response = reply
+			}
+		} else if err := types.Admit(ctx); err != nil {
+			// every message pays like an HTTP request, handleJSONRPC charges the
+			// elements of a batch after the first
+			id := request.ID
+			if len(id) == 0 {
+				id = jsonRPCNullID
+			}
+			response = jsonRPCError(id, types.EVMRPCLimitExceeded, err.Error())
+		} else {
+			response = g.handleJSONRPC(ctx, chain, message)
+		}
+
+		if response == nil {
+			continue
+		}
+
+		responseBytes, err := json.Marshal(response)
+		if err != nil {
+			logger.Logger.Error("EthereumGateway - readWS", zap.Error(err))
+			continue
+		}
+
+		client.push(responseBytes)
+
+		select {
+		case <-client.done:
+			return
+		default:
+		}
+	}
+}
+
+// subscriptionCall answers eth_subscribe and eth_unsubscribe, it returns nil
+// for notifications. Every eth_subscribe is charged to the caller of ctx.
+func (g *EthereumGateway) subscriptionCall(ctx context.Context, chain *evmChain, stream *evmStream, client *evmWSClient, request *types.EVMRPCRequest) *types.EVMRPCResponse {
+	id := request.ID
+	if len(id) == 0 {
+		return nil
+	}
+
+	if !chain.allowed(request.Method) {
+		return jsonRPCError(id, types.EVMRPCMethodNotFound, "method not allowed")
+	}
+
+	var params []interface{}
+	if err := json.Unmarshal(request.Params, &params); err != nil || len(params) == 0 {
+		return jsonRPCError(id, types.EVMRPCInvalidParams, "params must be a non-empty array")
+	}
+
+	if request.Method == "eth_unsubscribe" {
+		subscription, _ := params[0].(string)
+		result, _ := json.Marshal(stream.unsubscribe(client, strings.ToLower(subscription)))
+		return &types.EVMRPCResponse{Jsonrpc: "2.0", ID: id, Result: result}
+	}
+
+	kind, _ := params[0].(string)
+	if _, ok := evmSubscriptionKinds[kind]; !ok {
+		return jsonRPCError(id, types.EVMRPCInvalidParams, fmt.Sprintf("unsupported subscription: %v", params[0]))
+	}
+
+	if err := types.Admit(ctx); err != nil {
+		return jsonRPCError(id, types.EVMRPCLimitExceeded, err.Error())
+	}
+
+	subscription, err := stream.subscribe(client, params)
+	if err != nil {
+		logger.Logger.Error("EthereumGateway - subscriptionCall", zap.String("chain", chain.id), zap.Error(err))
+
+		var rpcErr *types.EVMRPCError
+		if errors.As(err, &rpcErr) {
+			return &types.EVMRPCResponse{Jsonrpc: "2.0", ID: id, Error: rpcErr}
+		}
+		return jsonRPCError(id, types.EVMRPCInternalError, jsonRPCUpstreamError)
+	}
+
+	result, _ := json.Marshal(subscription)
+	return &types.EVMRPCResponse{Jsonrpc: "2.0", ID: id, Result: result}
+}
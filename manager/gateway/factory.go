@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"go.uber.org/zap"
-	"time"
 
 	saiService "github.com/KiraCore/sai-service/service"
 	"github.com/spf13/cast"
@@ -14,62 +13,125 @@ import (
 )
 
 type GatewayFactory struct {
-	context *saiService.Context
-	storage types.Storage
+	context  *saiService.Context
+	storage  types.Storage
+	breakers *Breakers
+	cosmos   *CosmosGateway
 }
 
+// NewGatewayFactory puts the storage behind its circuit breaker, the gateways
+// it creates register the breakers of their own upstreams in Breakers.
 func NewGatewayFactory(context *saiService.Context, storage types.Storage) *GatewayFactory {
+	var breakerConfig types.BreakerConfig
+	if err := decodeConfig(context, "breakers", &breakerConfig); err != nil {
+		logger.Logger.Error("GatewayFactory - invalid breakers configuration", zap.Error(err))
+	}
+
+	breakers := NewBreakers(breakerConfig)
+
 	return &GatewayFactory{
-		context: context,
-		storage: storage,
+		context:  context,
+		storage:  newBreakerStorage(storage, breakers.Get("storage", upstreamTarget(cast.ToString(context.GetConfig("storage.url", ""))))),
+		breakers: breakers,
+	}
+}
+
+func (f *GatewayFactory) Breakers() *Breakers {
+	return f.breakers
+}
+
+// decodeConfig fills value from a configuration section.
+func decodeConfig(context *saiService.Context, key string, value interface{}) error {
+	configBytes, err := json.Marshal(context.GetConfig(key, map[string]interface{}{}))
+	if err != nil {
+		return err
 	}
+
+	return json.Unmarshal(configBytes, value)
+}
+
+// retryConfig reads the retry section of a gateway, the former retries key
+// still sets the attempts.
+func (f *GatewayFactory) retryConfig(gateway string) types.RetryConfig {
+	var retry types.RetryConfig
+	if err := decodeConfig(f.context, gateway+".retry", &retry); err != nil {
+		logger.Logger.Error("GatewayFactory - invalid retry configuration", zap.String("gateway", gateway), zap.Error(err))
+	}
+
+	if retry.Attempts == 0 {
+		retry.Attempts = cast.ToInt(f.context.GetConfig(gateway+".retries", 1))
+	}
+
+	return retry
 }
 
 func (f *GatewayFactory) CreateGateway(gatewayType string) (types.Gateway, error) {
 	switch gatewayType {
 	case "ethereum":
+		var evmConfig types.EVMConfig
+
+		configBytes, err := json.Marshal(f.context.GetConfig("ethereum", evmConfig))
+		if err != nil {
+			logger.Logger.Error("Invalid ethereum configuration format")
+			return nil, err
+		}
+
+		err = json.Unmarshal(configBytes, &evmConfig)
+		if err != nil {
+			logger.Logger.Error("Invalid ethereum configuration format")
+			return nil, err
+		}
+
 		return NewEthereumGateway(
 			f.context,
-			cast.ToStringMapString(f.context.GetConfig("ethereum.nodes", map[string]string{})),
+			parseEVMNodes(cast.ToStringMap(f.context.GetConfig("ethereum.nodes", map[string]interface{}{}))),
+			cast.ToInt(f.context.GetConfig("ethereum.quorum.size", 1)),
+			cast.ToStringSlice(f.context.GetConfig("ethereum.quorum.methods", []string{})),
+			evmConfig,
+			NewTimeouts(
+				cast.ToInt(f.context.GetConfig("ethereum.timeout", 30)),
+				cast.ToStringMapInt(f.context.GetConfig("ethereum.timeouts", map[string]int{})),
+			),
 			f.storage,
-			cast.ToInt(f.context.GetConfig("ethereum.retries", 1)),
-			time.Duration(cast.ToInt64(f.context.GetConfig("ethereum.retry_delay", 10))),
+			f.retryConfig("ethereum"),
+			f.breakers,
 			cast.ToInt(f.context.GetConfig("ethereum.rate_limit", 10)),
 		)
 	case "cosmos":
-		var cosmosConfig types.CosmosConfig
-
-		configBytes, err := json.Marshal(f.context.GetConfig("cosmos", cosmosConfig))
+		cosmos, err := f.cosmosGateway()
 		if err != nil {
-			logger.Logger.Error("Invalid cosmos configuration format")
 			return nil, err
 		}
 
-		err = json.Unmarshal(configBytes, &cosmosConfig)
+		return cosmos, nil
+	case "rosetta":
+		cosmos, err := f.cosmosGateway()
 		if err != nil {
-			logger.Logger.Error("Invalid cosmos configuration format")
 			return nil, err
 		}
 
-		return NewCosmosGateway(
-			f.context,
-			f.storage,
-			cosmosConfig,
-		)
+		return NewRosettaGateway(cosmos)
 	case "bitcoin":
 		return NewBitcoinGateway(
 			f.context,
 			cast.ToString(f.context.GetConfig("bitcoin.url", "")),
-			cast.ToInt(f.context.GetConfig("bitcoin.retries", 1)),
-			time.Duration(cast.ToInt64(f.context.GetConfig("bitcoin.retry_delay", 10))),
+			f.storage,
+			cast.ToString(f.context.GetConfig("bitcoin.collection", "bitcoin")),
+			cast.ToStringSlice(f.context.GetConfig("bitcoin.methods", []string{})),
+			NewTimeouts(
+				cast.ToInt(f.context.GetConfig("bitcoin.timeout", 30)),
+				cast.ToStringMapInt(f.context.GetConfig("bitcoin.timeouts", map[string]int{})),
+			),
+			f.retryConfig("bitcoin"),
+			f.breakers,
 			cast.ToInt(f.context.GetConfig("bitcoin.rate_limit", 10)),
 		)
 	case "storage":
 		return NewStorageGateway(
 			f.context,
 			f.storage,
-			cast.ToInt(f.context.GetConfig("storage.retries", 1)),
-			time.Duration(cast.ToInt64(f.context.GetConfig("storage.retry_delay", 10))),
+			f.retryConfig("storage"),
+			f.breakers,
 			cast.ToInt(f.context.GetConfig("storage.rate_limit", 10)),
 		)
 	default:
@@ -79,3 +141,41 @@ func (f *GatewayFactory) CreateGateway(gatewayType string) (types.Gateway, error
 		return nil, err
 	}
 }
+
+// cosmosGateway is shared between the cosmos and rosetta gateways so that both
+// use one gRPC connection, cache and set of rate limits.
+func (f *GatewayFactory) cosmosGateway() (*CosmosGateway, error) {
+	if f.cosmos != nil {
+		return f.cosmos, nil
+	}
+
+	var cosmosConfig types.CosmosConfig
+
+	configBytes, err := json.Marshal(f.context.GetConfig("cosmos", cosmosConfig))
+	if err != nil {
+		logger.Logger.Error("Invalid cosmos configuration format")
+		return nil, err
+	}
+
+	err = json.Unmarshal(configBytes, &cosmosConfig)
+	if err != nil {
+		logger.Logger.Error("Invalid cosmos configuration format")
+		return nil, err
+	}
+
+	cosmosConfig.Retry = f.retryConfig("cosmos")
+
+	cosmos, err := NewCosmosGateway(
+		f.context,
+		f.storage,
+		cosmosConfig,
+		f.breakers,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	f.cosmos = cosmos
+
+	return cosmos, nil
+}
@@ -0,0 +1,741 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/faucet"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/signer"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	faucetClaimsCollection = "cosmos_faucet_claims"
+
+	defaultFaucetQueue          = 100
+	defaultFaucetBatchSize      = 10
+	defaultFaucetBatchWindow    = 500 * time.Millisecond
+	defaultFaucetGasLimit       = 200000
+	defaultFaucetGasPerOutput   = 20000
+	defaultFaucetConfirmTimeout = 60 * time.Second
+
+	faucetConfirmPoll = time.Second
+	faucetUnknownPoll = 30 * time.Second
+)
+
+// errFaucetTxUnknown marks a broadcast the node did not answer, the
+// transaction may still be committed.
+var errFaucetTxUnknown = errors.New("[faucet] broadcast outcome unknown")
+
+// faucetSequence is the faucet account as the worker sees it. It is loaded from
+// the chain on first use, after a key rotation and whenever a broadcast may
+// have left the local sequence behind, then incremented for every accepted
+// transaction.
+type faucetSequence struct {
+	address       string
+	accountNumber uint64
+	sequence      uint64
+	chainID       string
+	loaded        bool
+}
+
+// faucetTx is a broadcast transaction, signed by address with sequence.
+type faucetTx struct {
+	hash     string
+	address  string
+	sequence uint64
+}
+
+// faucetJob is owned by the worker once queued, the claimer gets a copy of the
+// claim through result when it is broadcast or has failed.
+type faucetJob struct {
+	claim  types.FaucetClaim
+	result chan types.FaucetClaim
+}
+
+// faucetService accepts claims and dispenses them from a single worker, which
+// owns the faucet sequence so that no two transactions are signed with the same
+// one. Queued claims are batched into one MsgMultiSend. The guard decides which
+// claims are accepted, the amounts not committed yet are tracked in memory so
+// that concurrent claims do not overdraw the faucet.
+type faucetService struct {
+	chain    faucetChain
+	keys     faucetKeys
+	storage  types.Storage
+	txConfig client.TxConfig
+	timeout  time.Duration
+	config   types.FaucetConfig
+	guard    *faucet.Guard
+	queue    chan *faucetJob
+	sequence faucetSequence
+
+	mu       sync.Mutex
+	reserved sdk.Coins
+}
+
+func newFaucetService(gateway *CosmosGateway, config types.FaucetConfig) (*faucetService, error) {
+	if config.Queue <= 0 {
+		config.Queue = defaultFaucetQueue
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultFaucetBatchSize
+	}
+
+	if config.GasLimit == 0 {
+		config.GasLimit = defaultFaucetGasLimit
+	}
+
+	if config.GasPerOutput == 0 {
+		config.GasPerOutput = defaultFaucetGasPerOutput
+	}
+
+	guard, err := faucet.NewGuard(config.Protection, config.TimeLimit, gateway.storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &faucetService{
+		chain:    cosmosFaucetChain{gateway: gateway},
+		keys:     gateway.signer,
+		storage:  gateway.storage,
+		txConfig: gateway.txConfig,
+		timeout:  gateway.timeouts.Default,
+		config:   config,
+		guard:    guard,
+		queue:    make(chan *faucetJob, config.Queue),
+	}, nil
+}
+
+func (s *faucetService) address() string {
+	return s.keys.Address()
+}
+
+func (s *faucetService) batchWindow() time.Duration {
+	if s.config.BatchWindow <= 0 {
+		return defaultFaucetBatchWindow
+	}
+
+	return time.Duration(s.config.BatchWindow) * time.Millisecond
+}
+
+func (s *faucetService) confirmTimeout() time.Duration {
+	if s.config.ConfirmTimeout <= 0 {
+		return defaultFaucetConfirmTimeout
+	}
+
+	return time.Duration(s.config.ConfirmTimeout) * time.Second
+}
+
+// Claim has the guard check a claim, queues it and waits until it is broadcast
+// or ctx ends. The claim is returned in the state it reached, its id can be
+// looked up later.
+func (s *faucetService) Claim(ctx context.Context, request types.FaucetRequest) (*types.FaucetClaim, error) {
+	if _, err := accAddress(request.Claim); err != nil {
+		logger.Logger.Error("[faucet] Invalid claim address", zap.Error(err))
+		return nil, err
+	}
+
+	denoms := faucetDenoms(request.Token)
+	if len(denoms) == 0 {
+		return nil, apierror.New(apierror.InvalidArgument, "[faucet] No token to claim")
+	}
+
+	metadata := types.RequestMetadataFromContext(ctx)
+	claim := faucet.Claim{
+		ID:          uuid.New().String(),
+		Address:     request.Claim,
+		IP:          metadata.Origin,
+		Identity:    metadata.Identity,
+		PowNonce:    request.PowNonce,
+		PowSolution: request.PowSolution,
+		Captcha:     request.Captcha,
+	}
+
+	end, err := s.guard.Begin(claim)
+	if err != nil {
+		logger.Logger.Error("[faucet] Claim rejected", zap.String("Address", request.Claim), zap.Error(err))
+		return nil, err
+	}
+
+	job, err := s.accept(ctx, claim, denoms)
+	end()
+	if err != nil {
+		return nil, err
+	}
+
+	queued := job.claim
+
+	select {
+	case s.queue <- job:
+	default:
+		s.release(job.claim.Coins)
+		s.guard.Forget(job.claim.ID)
+		s.update(&job.claim, types.FaucetClaimFailed, "", "faucet queue is full")
+		err = apierror.New(apierror.ResourceExhausted, "[faucet] Faucet queue is full").WithRetryAfter(s.batchWindow())
+		logger.Logger.Error("[faucet] Faucet queue is full", zap.String("Address", request.Claim))
+		return nil, err
+	}
+
+	select {
+	case claim := <-job.result:
+		if claim.Status == types.FaucetClaimFailed {
+			return nil, apierror.Newf(apierror.Unavailable, "[faucet] Claim %s failed: %s", claim.ID, claim.Error)
+		}
+		return &claim, nil
+	case <-ctx.Done():
+		return &queued, nil
+	}
+}
+
+// accept runs the guard checks, sets the coins aside and records the claim.
+func (s *faucetService) accept(ctx context.Context, claim faucet.Claim, denoms []string) (*faucetJob, error) {
+	err := s.guard.Check(ctx, claim)
+	if err != nil {
+		logger.Logger.Error("[faucet] Claim rejected", zap.String("Address", claim.Address), zap.Error(err))
+		return nil, err
+	}
+
+	claim.Coins, err = s.claimingCoins(ctx, claim.Address, denoms)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.fee(claim.Coins); err != nil {
+		logger.Logger.Error("[faucet] Failed to get fee amount from the configuration", zap.Error(err))
+		return nil, err
+	}
+
+	if err = s.reserve(ctx, claim.Coins); err != nil {
+		return nil, err
+	}
+
+	if err = s.guard.Record(claim); err != nil {
+		s.release(claim.Coins)
+		logger.Logger.Error("[faucet] Claim rejected", zap.String("Address", claim.Address), zap.Error(err))
+		return nil, err
+	}
+
+	now := time.Now().UTC().Unix()
+	job := &faucetJob{
+		claim: types.FaucetClaim{
+			ID:        claim.ID,
+			Address:   claim.Address,
+			Coins:     claim.Coins,
+			Status:    types.FaucetClaimQueued,
+			Identity:  claim.Identity,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		result: make(chan types.FaucetClaim, 1),
+	}
+
+	if _, err = s.storage.Create(faucetClaimsCollection, []interface{}{job.claim}); err != nil {
+		s.release(claim.Coins)
+		s.guard.Forget(claim.ID)
+		logger.Logger.Error("[faucet] Failed to write faucet claim to database", zap.Error(err))
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Lookup returns a claim by id.
+func (s *faucetService) Lookup(id string) (*types.FaucetClaim, error) {
+	result, err := s.storage.Read(faucetClaimsCollection, map[string]interface{}{"id": id}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get faucet claim", zap.String("ID", id), zap.Error(err))
+		return nil, err
+	}
+
+	if len(result.Result) == 0 {
+		return nil, apierror.Newf(apierror.NotFound, "[faucet] Claim %s not found", id)
+	}
+
+	claim := new(types.FaucetClaim)
+	if err = remarshal(result.Result[0], claim); err != nil {
+		logger.Logger.Error("[faucet] Invalid faucet claim record", zap.String("ID", id), zap.Error(err))
+		return nil, err
+	}
+
+	return claim, nil
+}
+
+// faucetDenoms splits a comma separated token list, dropping blanks and repeats.
+func faucetDenoms(tokens string) []string {
+	var denoms []string
+	seen := map[string]bool{}
+
+	for _, denom := range strings.Split(tokens, ",") {
+		denom = strings.TrimSpace(denom)
+		if denom == "" || seen[denom] {
+			continue
+		}
+
+		seen[denom] = true
+		denoms = append(denoms, denom)
+	}
+
+	return denoms
+}
+
+// claimingCoins tops the claim address up to the faucet amount of every denom.
+// Denoms the address already holds enough of are left out.
+func (s *faucetService) claimingCoins(ctx context.Context, address string, denoms []string) (sdk.Coins, error) {
+	held, err := s.chain.balances(ctx, address)
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get claim balance", zap.Error(err))
+		return nil, err
+	}
+
+	coins := sdk.NewCoins()
+
+	for _, denom := range denoms {
+		faucetAmount, ok := s.config.FaucetAmounts[denom]
+		if !ok {
+			err = apierror.Newf(apierror.InvalidArgument, "[faucet] Token %s is not dispensed", denom)
+			logger.Logger.Error("[faucet] Failed to get faucet amount from the configuration", zap.String("Token", denom))
+			return nil, err
+		}
+
+		faucetMinimumAmount, ok := s.config.FaucetMinimumAmounts[denom]
+		if !ok {
+			err = apierror.New(apierror.Internal, "[faucet] Failed to get faucet minimum amount from the configuration")
+			logger.Logger.Error("[faucet] Failed to get faucet minimum amount from the configuration", zap.String("Token", denom))
+			return nil, err
+		}
+
+		claimingAmount := sdk.NewInt(faucetAmount).Sub(held.AmountOf(denom))
+		if !claimingAmount.GT(sdk.NewInt(faucetMinimumAmount)) {
+			continue
+		}
+
+		coins = coins.Add(sdk.NewCoin(denom, claimingAmount))
+	}
+
+	if coins.Empty() {
+		err = apierror.New(apierror.FailedPrecondition, "[faucet] No need to send tokens: faucetAmount <= claimAmount")
+		logger.Logger.Error("[faucet] No need to send tokens: faucetAmount <= claimAmount", zap.String("Address", address))
+		return nil, err
+	}
+
+	return coins, nil
+}
+
+// reserve sets coins aside until the claim is committed or fails, the faucet
+// keeps its minimum amount of every denom.
+func (s *faucetService) reserve(ctx context.Context, coins sdk.Coins) error {
+	available, err := s.chain.balances(ctx, s.address())
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get faucet balance", zap.Error(err))
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, coin := range coins {
+		remaining := available.AmountOf(coin.Denom).
+			Sub(s.reserved.AmountOf(coin.Denom)).
+			Sub(sdk.NewInt(s.config.FaucetMinimumAmounts[coin.Denom]))
+
+		if coin.Amount.GT(remaining) {
+			err = apierror.Newf(apierror.Unavailable, "[faucet] Not enough tokens: %s", coin.Denom)
+			logger.Logger.Error("[faucet] Not enough tokens: faucetAmount-claimAmount > availableAmount-faucetMininumAmount", zap.String("Token", coin.Denom))
+			return err
+		}
+	}
+
+	s.reserved = s.reserved.Add(coins...)
+
+	return nil
+}
+
+func (s *faucetService) release(coins sdk.Coins) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reserved = s.reserved.Sub(coins...)
+}
+
+// fee is paid in fee_denom, or in the first dispensed denom when it is unset.
+func (s *faucetService) fee(coins sdk.Coins) (sdk.Coins, error) {
+	denom := s.config.FeeDenom
+	if denom == "" {
+		denom = coins[0].Denom
+	}
+
+	amount, ok := s.config.FeeAmounts[denom]
+	if !ok {
+		return nil, apierror.Newf(apierror.Internal, "[faucet] Failed to get fee amount of %s from the configuration", denom)
+	}
+
+	return sdk.NewCoins(sdk.NewCoin(denom, sdk.NewInt(amount))), nil
+}
+
+// run collects up to batch_size claims, waiting at most batch_window after the
+// first one, and sends them together until ctx ends.
+func (s *faucetService) run(ctx context.Context) {
+	for {
+		var batch []*faucetJob
+
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			batch = append(batch, job)
+		}
+
+		timer := time.NewTimer(s.batchWindow())
+
+	collect:
+		for len(batch) < s.config.BatchSize {
+			select {
+			case job := <-s.queue:
+				batch = append(batch, job)
+			case <-timer.C:
+				break collect
+			case <-ctx.Done():
+				timer.Stop()
+				s.fail(batch, ctx.Err())
+				return
+			}
+		}
+
+		timer.Stop()
+		s.send(ctx, batch)
+	}
+}
+
+// send broadcasts a batch. Its claims fail only when the transaction surely
+// was not accepted: it could not be signed, or the node rejected it. When the
+// node could not answer it may have the transaction, so the claims become
+// unknown and are settled by confirm like any broadcast.
+func (s *faucetService) send(ctx context.Context, batch []*faucetJob) {
+	broadcastCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	status, reason := types.FaucetClaimBroadcast, ""
+
+	tx, err := s.broadcast(broadcastCtx, batch)
+	switch {
+	case errors.Is(err, errFaucetTxUnknown):
+		logger.Logger.Warn("[faucet] Faucet claims broadcast with an unknown outcome", zap.String("Hash", tx.hash), zap.Error(err))
+		status, reason = types.FaucetClaimUnknown, err.Error()
+	case err != nil:
+		logger.Logger.Error("[faucet] Failed to broadcast faucet claims", zap.Int("Claims", len(batch)), zap.Error(err))
+		s.fail(batch, err)
+		return
+	}
+
+	for _, job := range batch {
+		s.update(&job.claim, status, tx.hash, reason)
+		job.result <- job.claim
+	}
+
+	go s.confirm(ctx, tx, batch)
+}
+
+func (s *faucetService) fail(batch []*faucetJob, cause error) {
+	for _, job := range batch {
+		s.release(job.claim.Coins)
+		s.guard.Forget(job.claim.ID)
+		s.update(&job.claim, types.FaucetClaimFailed, job.claim.TxHash, cause.Error())
+		job.result <- job.claim
+	}
+}
+
+// broadcast signs and sends one MsgMultiSend paying every claim of the batch.
+// A sequence mismatch signs again once with the sequence the node expects.
+// When the node does not answer, the transaction is returned with an error
+// wrapping errFaucetTxUnknown and its sequence is taken as used, a mismatch
+// on the next batch corrects it.
+func (s *faucetService) broadcast(ctx context.Context, batch []*faucetJob) (*faucetTx, error) {
+	key := s.keys.Key()
+	faucetAddress := sdk.AccAddress(key.PubKey().Address()).String()
+	total := sdk.NewCoins()
+	outputs := make([]bank.Output, 0, len(batch))
+
+	for _, job := range batch {
+		total = total.Add(job.claim.Coins...)
+		outputs = append(outputs, bank.NewOutput(sdk.MustAccAddressFromBech32(job.claim.Address), job.claim.Coins))
+	}
+
+	fee, err := s.fee(total)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := bank.NewMsgMultiSend([]bank.Input{bank.NewInput(sdk.MustAccAddressFromBech32(faucetAddress), total)}, outputs)
+	gasLimit := s.config.GasLimit + s.config.GasPerOutput*uint64(len(outputs))
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if !s.sequence.loaded || s.sequence.address != faucetAddress {
+			if err = s.loadSequence(ctx, faucetAddress); err != nil {
+				return nil, err
+			}
+		}
+
+		txBytes, err := s.sign(key, faucetAddress, msg, fee, gasLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		tx := &faucetTx{hash: txHash(txBytes), address: faucetAddress, sequence: s.sequence.sequence}
+
+		response, err := s.chain.broadcastTx(ctx, txBytes)
+		if err != nil {
+			s.sequence.sequence++
+			return tx, fmt.Errorf("%w: %w", errFaucetTxUnknown, err)
+		}
+
+		if response.Code == 0 {
+			s.sequence.sequence++
+			return tx, nil
+		}
+
+		if response.Codespace == sdkerrors.ErrWrongSequence.Codespace() && response.Code == sdkerrors.ErrWrongSequence.ABCICode() {
+			logger.Logger.Warn("[faucet] Account sequence mismatch", zap.Uint64("Sequence", s.sequence.sequence), zap.String("Log", response.Log))
+
+			// the account query may be cached, the node tells the sequence it wants
+			if expected, ok := expectedSequence(response.Log); ok {
+				s.sequence.sequence = expected
+			} else {
+				s.sequence.loaded = false
+			}
+			continue
+		}
+
+		return nil, apierror.Newf(apierror.FailedPrecondition, "[faucet] Transaction rejected: %s", response.Log)
+	}
+
+	return nil, apierror.New(apierror.Unavailable, "[faucet] Account sequence mismatch")
+}
+
+var wrongSequenceLog = regexp.MustCompile(`expected (\d+), got \d+`)
+
+// expectedSequence reads the sequence the node expects from the log of an
+// ErrWrongSequence: "account sequence mismatch, expected 5, got 4: ...".
+func expectedSequence(log string) (uint64, bool) {
+	match := wrongSequenceLog.FindStringSubmatch(log)
+	if match == nil {
+		return 0, false
+	}
+
+	sequence, err := strconv.ParseUint(match[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return sequence, true
+}
+
+func (s *faucetService) loadSequence(ctx context.Context, faucetAddress string) error {
+	accountNumber, sequence, err := s.chain.account(ctx, faucetAddress)
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get account info", zap.Error(err))
+		return err
+	}
+
+	chainID, err := s.chain.chainID(ctx)
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get node status", zap.Error(err))
+		return err
+	}
+
+	s.sequence = faucetSequence{
+		address:       faucetAddress,
+		accountNumber: accountNumber,
+		sequence:      sequence,
+		chainID:       chainID,
+		loaded:        true,
+	}
+
+	return nil
+}
+
+func (s *faucetService) sign(key signer.Key, faucetAddress string, msg sdk.Msg, fee sdk.Coins, gasLimit uint64) ([]byte, error) {
+	txBuilder := s.txConfig.NewTxBuilder()
+
+	err := txBuilder.SetMsgs(msg)
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to set tx msgs", zap.Error(err))
+		return nil, err
+	}
+
+	txBuilder.SetFeeAmount(fee)
+	txBuilder.SetGasLimit(gasLimit)
+	txBuilder.SetMemo("Faucet Transfer")
+
+	signMode := s.txConfig.SignModeHandler().DefaultMode()
+
+	signerData := authsigning.SignerData{
+		Address:       faucetAddress,
+		ChainID:       s.sequence.chainID,
+		AccountNumber: s.sequence.accountNumber,
+		Sequence:      s.sequence.sequence,
+		PubKey:        key.PubKey(),
+	}
+
+	signBytes, err := s.txConfig.SignModeHandler().GetSignBytes(signMode, signerData, txBuilder.GetTx())
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to get sign bytes", zap.Error(err))
+		return nil, err
+	}
+
+	sig, err := key.Sign(signBytes)
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to sign transaction", zap.Error(err))
+		return nil, err
+	}
+
+	err = txBuilder.SetSignatures(signing.SignatureV2{
+		PubKey:   key.PubKey(),
+		Data:     &signing.SingleSignatureData{SignMode: signMode, Signature: sig},
+		Sequence: signerData.Sequence,
+	})
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to set signatures", zap.Error(err))
+		return nil, err
+	}
+
+	txBytes, err := s.txConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to encode transaction", zap.Error(err))
+		return nil, err
+	}
+
+	return txBytes, nil
+}
+
+// confirm waits for the transaction to be committed and settles its claims.
+// Claims whose transaction failed lose their cooldown entry so that they can
+// be claimed again. A transaction not seen within confirm_timeout may still be
+// committed: its claims become unknown and keep their coins and cooldown, and
+// it is looked for less often until it is committed, fails, or the faucet
+// sequence moves past it without it.
+func (s *faucetService) confirm(ctx context.Context, tx *faucetTx, batch []*faucetJob) {
+	status, reason := s.poll(ctx, tx, faucetConfirmPoll, time.Now().Add(s.confirmTimeout()))
+
+	if status == "" && ctx.Err() == nil {
+		logger.Logger.Warn("[faucet] Faucet claims not committed in time", zap.String("Hash", tx.hash), zap.Duration("Timeout", s.confirmTimeout()))
+
+		for _, job := range batch {
+			s.update(&job.claim, types.FaucetClaimUnknown, tx.hash, fmt.Sprintf("not committed within %s", s.confirmTimeout()))
+		}
+
+		status, reason = s.poll(ctx, tx, faucetUnknownPoll, time.Time{})
+	}
+
+	// the service is stopping, the claims stay as they are
+	if status == "" {
+		return
+	}
+
+	for _, job := range batch {
+		s.release(job.claim.Coins)
+		s.update(&job.claim, status, tx.hash, reason)
+
+		if status == types.FaucetClaimFailed {
+			s.guard.Forget(job.claim.ID)
+		}
+	}
+
+	if status == types.FaucetClaimFailed {
+		logger.Logger.Error("[faucet] Faucet claims failed", zap.String("Hash", tx.hash), zap.String("Reason", reason))
+	}
+}
+
+// poll looks tx up every interval until it is settled, deadline passes or ctx
+// ends, and returns an empty status when it is not settled. A zero deadline
+// never passes.
+func (s *faucetService) poll(ctx context.Context, tx *faucetTx, interval time.Duration, deadline time.Time) (string, string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for deadline.IsZero() || time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return "", ""
+		case <-ticker.C:
+		}
+
+		status, reason, err := s.txStatus(ctx, tx.hash)
+		if err == nil {
+			return status, reason
+		}
+
+		if !s.sequencePassed(ctx, tx) {
+			continue
+		}
+
+		// the tx may have been committed since it was looked up, it is only
+		// dropped when the chain says it does not have it
+		status, reason, err = s.txStatus(ctx, tx.hash)
+		switch {
+		case err == nil:
+			return status, reason
+		case apierror.From(err).Code == apierror.NotFound:
+			return types.FaucetClaimFailed, fmt.Sprintf("dropped, sequence %d was used by another transaction", tx.sequence)
+		}
+	}
+
+	return "", ""
+}
+
+// txStatus reports the result of a committed transaction, it fails while the
+// chain does not know it or cannot be asked.
+func (s *faucetService) txStatus(ctx context.Context, hash string) (string, string, error) {
+	result, err := s.chain.txResult(ctx, hash)
+	if err != nil {
+		return "", "", err
+	}
+
+	if result.Code != 0 {
+		return types.FaucetClaimFailed, result.Log, nil
+	}
+
+	return types.FaucetClaimCommitted, "", nil
+}
+
+// sequencePassed tells whether the account that signed tx has used its
+// sequence, so tx can no longer be committed if it is not already.
+func (s *faucetService) sequencePassed(ctx context.Context, tx *faucetTx) bool {
+	_, sequence, err := s.chain.account(ctx, tx.address)
+
+	return err == nil && sequence > tx.sequence
+}
+
+func (s *faucetService) update(claim *types.FaucetClaim, status, hash, reason string) {
+	claim.Status = status
+	claim.TxHash = hash
+	claim.Error = reason
+	claim.UpdatedAt = time.Now().UTC().Unix()
+
+	_, err := s.storage.Update(faucetClaimsCollection, map[string]interface{}{"id": claim.ID}, map[string]interface{}{
+		"status":     claim.Status,
+		"tx_hash":    claim.TxHash,
+		"error":      claim.Error,
+		"updated_at": claim.UpdatedAt,
+	})
+	if err != nil {
+		logger.Logger.Error("[faucet] Failed to update faucet claim", zap.String("ID", claim.ID), zap.Error(err))
+	}
+}
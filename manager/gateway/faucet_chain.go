@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/KiraCore/sai-interx-manager/signer"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// faucetChain is what the faucet asks the chain, served by the cosmos gateway.
+type faucetChain interface {
+	balances(ctx context.Context, address string) (sdk.Coins, error)
+	account(ctx context.Context, address string) (accountNumber, sequence uint64, err error)
+	chainID(ctx context.Context) (string, error)
+	// broadcastTx fails when the node could not be asked or did not answer,
+	// the node may have the transaction all the same.
+	broadcastTx(ctx context.Context, txBytes []byte) (*faucetBroadcast, error)
+	// txResult fails with NotFound while the chain does not have the tx.
+	txResult(ctx context.Context, hash string) (*faucetBroadcast, error)
+}
+
+// faucetBroadcast is the CheckTx or DeliverTx result of a transaction.
+type faucetBroadcast struct {
+	Code      uint32 `json:"code"`
+	Codespace string `json:"codespace"`
+	Log       string `json:"log"`
+}
+
+// faucetKeys holds the key the faucet signs with.
+type faucetKeys interface {
+	Key() signer.Key
+	Address() string
+}
+
+type cosmosFaucetChain struct {
+	gateway *CosmosGateway
+}
+
+func (c cosmosFaucetChain) balances(ctx context.Context, address string) (sdk.Coins, error) {
+	response, err := c.gateway.balances(ctx, types.InboundRequest{}, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdk.NewCoins(response.Balances...), nil
+}
+
+func (c cosmosFaucetChain) account(ctx context.Context, address string) (uint64, uint64, error) {
+	accountInfo, err := c.gateway.account(ctx, address)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	accountNumber, err := strconv.ParseUint(accountInfo.AccountNumber, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sequence, err := strconv.ParseUint(accountInfo.Sequence, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return accountNumber, sequence, nil
+}
+
+func (c cosmosFaucetChain) chainID(ctx context.Context) (string, error) {
+	status, err := c.gateway.status(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return status.NodeInfo.Network, nil
+}
+
+func (c cosmosFaucetChain) broadcastTx(ctx context.Context, txBytes []byte) (*faucetBroadcast, error) {
+	result, err := c.gateway.txs(ctx, types.InboundRequest{
+		Method: "POST",
+		Payload: map[string]interface{}{
+			"tx":   base64.StdEncoding.EncodeToString(txBytes),
+			"mode": "sync",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(faucetBroadcast)
+	if err = remarshal(result, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c cosmosFaucetChain) txResult(ctx context.Context, hash string) (*faucetBroadcast, error) {
+	result, err := c.gateway.makeTendermintRPCRequest(ctx, "/tx", "hash=0x"+hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		TxResult faucetBroadcast `json:"tx_result"`
+	}
+
+	if err = remarshal(result, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.TxResult, nil
+}
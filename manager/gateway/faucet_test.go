@@ -0,0 +1,444 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/faucet"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/signer"
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	m.Run()
+}
+
+type testKey struct {
+	*secp256k1.PrivKey
+}
+
+func (k testKey) PubKey() *secp256k1.PubKey {
+	return k.PrivKey.PubKey().(*secp256k1.PubKey)
+}
+
+type testKeys struct {
+	key signer.Key
+}
+
+func (k testKeys) Key() signer.Key {
+	return k.key
+}
+
+func (k testKeys) Address() string {
+	return sdk.AccAddress(k.key.PubKey().Address()).String()
+}
+
+// fakeChain answers the faucet from fixed balances and an account sequence,
+// broadcasts are answered by the queued results in order.
+type fakeChain struct {
+	mu         sync.Mutex
+	balance    sdk.Coins
+	sequence   uint64
+	accounts   int
+	broadcasts []func(txBytes []byte) (*faucetBroadcast, error)
+	sent       []string
+	results    map[string]*faucetBroadcast
+}
+
+func (c *fakeChain) balances(ctx context.Context, address string) (sdk.Coins, error) {
+	return c.balance, nil
+}
+
+func (c *fakeChain) account(ctx context.Context, address string) (uint64, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.accounts++
+
+	return 1, c.sequence, nil
+}
+
+func (c *fakeChain) chainID(ctx context.Context) (string, error) {
+	return "testnet-1", nil
+}
+
+func (c *fakeChain) broadcastTx(ctx context.Context, txBytes []byte) (*faucetBroadcast, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent = append(c.sent, txHash(txBytes))
+	next := c.broadcasts[0]
+	c.broadcasts = c.broadcasts[1:]
+
+	return next(txBytes)
+}
+
+func (c *fakeChain) txResult(ctx context.Context, hash string) (*faucetBroadcast, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if result, ok := c.results[hash]; ok {
+		return result, nil
+	}
+
+	return nil, apierror.New(apierror.NotFound, "tx not found")
+}
+
+// claimStorage keeps faucet claims by id and remembers the history entries
+// the guard forgets.
+type claimStorage struct {
+	mu        sync.Mutex
+	claims    map[string]map[string]interface{}
+	forgotten []string
+}
+
+func (s *claimStorage) Create(collection string, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, document := range cast.ToSlice(document) {
+		claim := decodedClaim(document)
+		s.claims[cast.ToString(claim["id"])] = claim
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *claimStorage) Read(collection string, criteria map[string]interface{}, options *adapter.Options, fields []string) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *claimStorage) Upsert(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *claimStorage) Update(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if claim, ok := s.claims[cast.ToString(criteria["id"])]; ok {
+		for key, value := range document.(map[string]interface{}) {
+			claim[key] = value
+		}
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *claimStorage) Delete(collection string, criteria map[string]interface{}) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if collection == faucet.HistoryCollection {
+		s.forgotten = append(s.forgotten, cast.ToString(criteria["claim_id"]))
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *claimStorage) status(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return cast.ToString(s.claims[id]["status"])
+}
+
+func decodedClaim(document interface{}) map[string]interface{} {
+	claim := map[string]interface{}{}
+	remarshal(document, &claim)
+
+	return claim
+}
+
+// testTxConfig encodes bank sends signed with secp256k1 keys.
+func testTxConfig() client.TxConfig {
+	registry := codectypes.NewInterfaceRegistry()
+	registry.RegisterInterface("types.PubKey", (*cryptotypes.PubKey)(nil), &secp256k1.PubKey{})
+	registry.RegisterInterface("types.Msg", (*sdk.Msg)(nil), &banktypes.MsgSend{}, &banktypes.MsgMultiSend{})
+
+	return authtx.NewTxConfig(codec.NewProtoCodec(registry), authtx.DefaultSignModes)
+}
+
+func newTestFaucet(t *testing.T, chain *fakeChain) (*faucetService, *claimStorage) {
+	t.Helper()
+
+	storage := &claimStorage{claims: map[string]map[string]interface{}{}}
+
+	guard, err := faucet.NewGuard(types.FaucetProtection{}, 0, storage)
+	if err != nil {
+		t.Fatalf("NewGuard: %v", err)
+	}
+
+	return &faucetService{
+		chain:    chain,
+		keys:     testKeys{key: testKey{secp256k1.GenPrivKey()}},
+		storage:  storage,
+		txConfig: testTxConfig(),
+		timeout:  time.Second,
+		config: types.FaucetConfig{
+			FaucetAmounts:        map[string]int64{"ukex": 500},
+			FaucetMinimumAmounts: map[string]int64{"ukex": 100},
+			FeeAmounts:           map[string]int64{"ukex": 10},
+			GasLimit:             defaultFaucetGasLimit,
+			GasPerOutput:         defaultFaucetGasPerOutput,
+		},
+		guard: guard,
+	}, storage
+}
+
+// queueClaim records a claim of coins the way accept does and returns its job.
+func queueClaim(t *testing.T, s *faucetService, coins sdk.Coins) *faucetJob {
+	t.Helper()
+
+	if err := s.reserve(context.Background(), coins); err != nil {
+		t.Fatalf("reserve(%s): %v", coins, err)
+	}
+
+	job := &faucetJob{
+		claim: types.FaucetClaim{
+			ID:      uuid.New().String(),
+			Address: sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String(),
+			Coins:   coins,
+			Status:  types.FaucetClaimQueued,
+		},
+		result: make(chan types.FaucetClaim, 1),
+	}
+
+	if _, err := s.storage.Create(faucetClaimsCollection, []interface{}{job.claim}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	return job
+}
+
+func accepted(txBytes []byte) (*faucetBroadcast, error) {
+	return &faucetBroadcast{}, nil
+}
+
+func TestFaucetReserve(t *testing.T) {
+	ukex := func(amount int64) sdk.Coins {
+		return sdk.NewCoins(sdk.NewInt64Coin("ukex", amount))
+	}
+
+	tests := []struct {
+		name     string
+		reserved sdk.Coins
+		released sdk.Coins
+		coins    sdk.Coins
+		ok       bool
+	}{
+		{name: "within the balance", coins: ukex(900), ok: true},
+		{name: "into the minimum amount", coins: ukex(901)},
+		{name: "beside a reservation", reserved: ukex(600), coins: ukex(300), ok: true},
+		{name: "past a reservation", reserved: ukex(600), coins: ukex(301)},
+		{name: "after a release", reserved: ukex(600), released: ukex(600), coins: ukex(900), ok: true},
+		{name: "denom the faucet lacks", coins: sdk.NewCoins(sdk.NewInt64Coin("samolean", 1))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, _ := newTestFaucet(t, &fakeChain{balance: ukex(1000)})
+
+			if !test.reserved.Empty() {
+				if err := s.reserve(context.Background(), test.reserved); err != nil {
+					t.Fatalf("reserve(%s): %v", test.reserved, err)
+				}
+			}
+			s.release(test.released)
+
+			err := s.reserve(context.Background(), test.coins)
+			if test.ok != (err == nil) {
+				t.Fatalf("reserve(%s) = %v, want ok %v", test.coins, err, test.ok)
+			}
+
+			if err != nil && apierror.From(err).Code != apierror.Unavailable {
+				t.Fatalf("reserve(%s) code = %s, want %s", test.coins, apierror.From(err).Code, apierror.Unavailable)
+			}
+		})
+	}
+}
+
+func TestFaucetSendRejected(t *testing.T) {
+	chain := &fakeChain{
+		balance: sdk.NewCoins(sdk.NewInt64Coin("ukex", 1000)),
+		broadcasts: []func([]byte) (*faucetBroadcast, error){
+			func([]byte) (*faucetBroadcast, error) {
+				return &faucetBroadcast{Code: 5, Codespace: "sdk", Log: "insufficient funds"}, nil
+			},
+		},
+	}
+	s, storage := newTestFaucet(t, chain)
+	job := queueClaim(t, s, sdk.NewCoins(sdk.NewInt64Coin("ukex", 400)))
+
+	s.send(context.Background(), []*faucetJob{job})
+
+	claim := <-job.result
+	if claim.Status != types.FaucetClaimFailed || storage.status(claim.ID) != types.FaucetClaimFailed {
+		t.Fatalf("claim status = %s, stored %s, want %s", claim.Status, storage.status(claim.ID), types.FaucetClaimFailed)
+	}
+
+	if !s.reserved.Empty() {
+		t.Fatalf("reserved = %s after a rejected batch, want none", s.reserved)
+	}
+
+	if len(storage.forgotten) != 1 || storage.forgotten[0] != claim.ID {
+		t.Fatalf("forgotten = %v, want [%s]", storage.forgotten, claim.ID)
+	}
+
+	if s.sequence.sequence != 0 {
+		t.Fatalf("sequence = %d after a rejected batch, want 0", s.sequence.sequence)
+	}
+}
+
+func TestFaucetSendUnknown(t *testing.T) {
+	chain := &fakeChain{
+		balance: sdk.NewCoins(sdk.NewInt64Coin("ukex", 1000)),
+		broadcasts: []func([]byte) (*faucetBroadcast, error){
+			func([]byte) (*faucetBroadcast, error) {
+				return nil, errors.New("connection reset by peer")
+			},
+		},
+		results: map[string]*faucetBroadcast{},
+	}
+	s, storage := newTestFaucet(t, chain)
+	job := queueClaim(t, s, sdk.NewCoins(sdk.NewInt64Coin("ukex", 400)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.send(ctx, []*faucetJob{job})
+	// stops confirm before its first look up, the claims stay unknown
+	cancel()
+
+	claim := <-job.result
+	if claim.Status != types.FaucetClaimUnknown || storage.status(claim.ID) != types.FaucetClaimUnknown {
+		t.Fatalf("claim status = %s, stored %s, want %s", claim.Status, storage.status(claim.ID), types.FaucetClaimUnknown)
+	}
+
+	if len(chain.sent) != 1 || claim.TxHash != chain.sent[0] {
+		t.Fatalf("claim hash = %s, want the broadcast tx %v", claim.TxHash, chain.sent)
+	}
+
+	s.mu.Lock()
+	reserved := s.reserved
+	s.mu.Unlock()
+
+	if !reserved.IsEqual(claim.Coins) {
+		t.Fatalf("reserved = %s while the outcome is unknown, want %s", reserved, claim.Coins)
+	}
+
+	if len(storage.forgotten) != 0 {
+		t.Fatalf("forgotten = %v while the outcome is unknown, want none", storage.forgotten)
+	}
+
+	if s.sequence.sequence != 1 {
+		t.Fatalf("sequence = %d after an unknown broadcast, want 1", s.sequence.sequence)
+	}
+
+	chain.mu.Lock()
+	chain.results[claim.TxHash] = &faucetBroadcast{}
+	chain.mu.Unlock()
+
+	tx := &faucetTx{hash: claim.TxHash, address: s.address(), sequence: 0}
+	if status, _ := s.poll(context.Background(), tx, time.Millisecond, time.Time{}); status != types.FaucetClaimCommitted {
+		t.Fatalf("poll status = %q, want %s", status, types.FaucetClaimCommitted)
+	}
+}
+
+func TestFaucetBroadcastWrongSequence(t *testing.T) {
+	wrongSequence := func([]byte) (*faucetBroadcast, error) {
+		return &faucetBroadcast{
+			Code:      sdkerrors.ErrWrongSequence.ABCICode(),
+			Codespace: sdkerrors.ErrWrongSequence.Codespace(),
+			Log:       "account sequence mismatch, expected 7, got 3: incorrect account sequence",
+		}, nil
+	}
+
+	chain := &fakeChain{
+		balance:    sdk.NewCoins(sdk.NewInt64Coin("ukex", 1000)),
+		sequence:   3,
+		broadcasts: []func([]byte) (*faucetBroadcast, error){wrongSequence, accepted},
+	}
+	s, _ := newTestFaucet(t, chain)
+	job := queueClaim(t, s, sdk.NewCoins(sdk.NewInt64Coin("ukex", 400)))
+
+	tx, err := s.broadcast(context.Background(), []*faucetJob{job})
+	if err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+
+	if tx.sequence != 7 || s.sequence.sequence != 8 {
+		t.Fatalf("tx sequence = %d, next %d, want 7 and 8", tx.sequence, s.sequence.sequence)
+	}
+
+	if chain.accounts != 1 {
+		t.Fatalf("account read %d times, want once", chain.accounts)
+	}
+}
+
+func TestFaucetPoll(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   *faucetBroadcast
+		sequence uint64
+		status   string
+	}{
+		{name: "committed", result: &faucetBroadcast{}, status: types.FaucetClaimCommitted},
+		{name: "failed in the block", result: &faucetBroadcast{Code: 5, Log: "insufficient funds"}, status: types.FaucetClaimFailed},
+		{name: "dropped", sequence: 5, status: types.FaucetClaimFailed},
+		{name: "pending", sequence: 4, status: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := &fakeChain{sequence: test.sequence, results: map[string]*faucetBroadcast{}}
+			if test.result != nil {
+				chain.results["HASH"] = test.result
+			}
+			s, _ := newTestFaucet(t, chain)
+
+			tx := &faucetTx{hash: "HASH", address: s.address(), sequence: 4}
+			status, _ := s.poll(context.Background(), tx, time.Millisecond, time.Now().Add(20*time.Millisecond))
+			if status != test.status {
+				t.Fatalf("poll status = %q, want %q", status, test.status)
+			}
+		})
+	}
+}
+
+func TestExpectedSequence(t *testing.T) {
+	tests := []struct {
+		log      string
+		sequence uint64
+		ok       bool
+	}{
+		{log: "account sequence mismatch, expected 12, got 9: incorrect account sequence", sequence: 12, ok: true},
+		{log: "incorrect account sequence"},
+		{log: "account sequence mismatch, expected 99999999999999999999999, got 1: incorrect account sequence"},
+	}
+
+	for _, test := range tests {
+		sequence, ok := expectedSequence(test.log)
+		if sequence != test.sequence || ok != test.ok {
+			t.Fatalf("expectedSequence(%q) = %d, %v, want %d, %v", test.log, sequence, ok, test.sequence, test.ok)
+		}
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
+	"github.com/KiraCore/sai-interx-manager/apierror"
 	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
 	"github.com/KiraCore/sai-service/service"
 	"go.uber.org/zap"
 	"io"
@@ -18,16 +19,18 @@ type BaseGateway struct {
 	client    *http.Client
 	rateLimit *RateLimiter
 	retry     *Retrier
+	breakers  *Breakers
 }
 
-func NewBaseGateway(ctx *service.Context, retryAttempts int, retryDelay time.Duration, rateLimit int) *BaseGateway {
+func NewBaseGateway(ctx *service.Context, retry types.RetryConfig, breakers *Breakers, rateLimit int) *BaseGateway {
 	return &BaseGateway{
 		context: ctx,
 		client: &http.Client{
 			Timeout: time.Second * 30,
 		},
 		rateLimit: NewRateLimiter(rateLimit),
-		retry:     NewRetrier(retryAttempts, retryDelay),
+		retry:     NewRetrier(retry),
+		breakers:  breakers,
 	}
 }
 
@@ -59,7 +62,7 @@ func (g *BaseGateway) makeSaiRequest(ctx context.Context, url string, payload in
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		err = errors.New("non-200 status code: " + resp.Status)
+		err = apierror.Newf(apierror.Upstream, "non-200 status code: %s", resp.Status)
 		logger.Logger.Error("BaseGateway - makeRequest", zap.Error(err))
 		return nil, err
 	}
@@ -100,7 +103,7 @@ func (g *BaseGateway) makeRequest(ctx context.Context, method, url string, paylo
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		err = errors.New("non-200 status code: " + resp.Status)
+		err = apierror.Newf(apierror.Upstream, "non-200 status code: %s", resp.Status)
 		logger.Logger.Error("BaseGateway - makeRequest", zap.Error(err))
 		return nil, err
 	}
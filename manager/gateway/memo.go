@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+)
+
+const blockHeightMetadata = "Grpc-Metadata-X-Cosmos-Block-Height"
+
+type blockHeightKey struct{}
+
+// withBlockHeight pins every gRPC query issued with the returned context to the
+// given height through x-cosmos-block-height metadata.
+func withBlockHeight(ctx context.Context, height int64) context.Context {
+	return context.WithValue(ctx, blockHeightKey{}, height)
+}
+
+func blockHeightFromContext(ctx context.Context) (int64, bool) {
+	height, ok := ctx.Value(blockHeightKey{}).(int64)
+	return height, ok && height > 0
+}
+
+func parseBlockHeight(value interface{}) (int64, error) {
+	height, err := strconv.ParseInt(fmt.Sprintf("%v", value), 10, 64)
+	if err != nil || height <= 0 {
+		return 0, apierror.Newf(apierror.InvalidArgument, "invalid height: %v", value)
+	}
+
+	return height, nil
+}
+
+type memoCall struct {
+	done   chan struct{}
+	height int64
+	value  interface{}
+	err    error
+}
+
+// heightMemo computes each aggregate at most once per block height. Concurrent
+// callers asking for the same height wait for the first computation, which
+// is detached from the caller that started it so that its cancellation does
+// not fail the others, and bounded by timeout instead. The computation is
+// pinned to the height of its key, so a block committed meanwhile does not
+// leak into a result served as the previous one.
+type heightMemo struct {
+	mu      sync.Mutex
+	entries map[string]*memoCall
+	timeout time.Duration
+}
+
+func newHeightMemo(timeout time.Duration) *heightMemo {
+	return &heightMemo{
+		entries: make(map[string]*memoCall),
+		timeout: timeout,
+	}
+}
+
+func (m *heightMemo) Do(ctx context.Context, name string, height int64, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if height <= 0 {
+		return fn(ctx)
+	}
+
+	key := fmt.Sprintf("%s@%d", name, height)
+
+	m.mu.Lock()
+	call, ok := m.entries[key]
+	if !ok {
+		call = &memoCall{done: make(chan struct{}), height: height}
+		m.entries[key] = call
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		go m.compute(ctx, key, call, fn)
+	}
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *heightMemo) compute(ctx context.Context, key string, call *memoCall, fn func(ctx context.Context) (interface{}, error)) {
+	ctx, cancel := withTimeout(withBlockHeight(context.WithoutCancel(ctx), call.height), m.timeout)
+	defer cancel()
+
+	call.value, call.err = fn(ctx)
+	close(call.done)
+
+	if call.err != nil {
+		m.mu.Lock()
+		delete(m.entries, key)
+		m.mu.Unlock()
+	}
+}
+
+// Prune drops aggregates computed for heights below latest, including pinned
+// historical snapshots which are kept by the response cache instead.
+func (m *heightMemo) Prune(latest int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, call := range m.entries {
+		if call.height < latest {
+			delete(m.entries, key)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeightMemoOncePerHeight(t *testing.T) {
+	memo := newHeightMemo(time.Second)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	compute := func(ctx context.Context) (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "result", nil
+	}
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make(chan interface{}, callers)
+
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value, err := memo.Do(context.Background(), "dashboard", 10, compute)
+			if err != nil {
+				t.Errorf("Do: %v", err)
+			}
+			results <- value
+		}()
+	}
+
+	// callers that come after the computation ended get its result as well
+	for {
+		memo.mu.Lock()
+		started := len(memo.entries)
+		memo.mu.Unlock()
+		if started == 1 && calls.Load() == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	wg.Wait()
+	close(results)
+
+	for value := range results {
+		if value != "result" {
+			t.Fatalf("Do = %v, want result", value)
+		}
+	}
+
+	if _, err := memo.Do(context.Background(), "dashboard", 10, compute); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("computed %d times for one height, want 1", calls.Load())
+	}
+
+	if _, err := memo.Do(context.Background(), "dashboard", 11, compute); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("computed %d times for two heights, want 2", calls.Load())
+	}
+}
+
+func TestHeightMemoDetachedPinned(t *testing.T) {
+	memo := newHeightMemo(time.Second)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	heights := make(chan int64, 1)
+
+	compute := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+
+		height, _ := blockHeightFromContext(ctx)
+		heights <- height
+
+		return "result", ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := memo.Do(ctx, "dashboard", 10, compute)
+		done <- err
+	}()
+
+	<-started
+
+	// a second caller waits for the computation the first one started
+	waiter := make(chan interface{}, 1)
+	go func() {
+		value, _ := memo.Do(context.Background(), "dashboard", 10, compute)
+		waiter <- value
+	}()
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do of the cancelled caller = %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+
+	if value := <-waiter; value != "result" {
+		t.Fatalf("Do of the waiting caller = %v, want result", value)
+	}
+
+	if height := <-heights; height != 10 {
+		t.Fatalf("computation pinned to height %d, want 10", height)
+	}
+}
+
+func TestHeightMemoErrorsNotKept(t *testing.T) {
+	memo := newHeightMemo(time.Second)
+
+	failing := errors.New("node down")
+	if _, err := memo.Do(context.Background(), "dashboard", 10, func(ctx context.Context) (interface{}, error) {
+		return nil, failing
+	}); !errors.Is(err, failing) {
+		t.Fatalf("Do = %v, want %v", err, failing)
+	}
+
+	value, err := memo.Do(context.Background(), "dashboard", 10, func(ctx context.Context) (interface{}, error) {
+		return "result", nil
+	})
+	if err != nil || value != "result" {
+		t.Fatalf("Do after a failure = %v, %v, want result", value, err)
+	}
+}
+
+func TestHeightMemoPrune(t *testing.T) {
+	memo := newHeightMemo(time.Second)
+
+	for _, height := range []int64{9, 10, 11} {
+		if _, err := memo.Do(context.Background(), "dashboard", height, func(ctx context.Context) (interface{}, error) {
+			return height, nil
+		}); err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+	}
+
+	memo.Prune(11)
+
+	if len(memo.entries) != 1 || memo.entries["dashboard@11"] == nil {
+		t.Fatalf("entries after pruning below 11 = %v, want only dashboard@11", memo.entries)
+	}
+}
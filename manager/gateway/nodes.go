@@ -0,0 +1,292 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-service/service"
+)
+
+var ErrNoHealthyNode = apierror.New(apierror.Unavailable, "no healthy sekai node")
+
+// sekaiNode is one backend of the pool: a gRPC-gateway mux over its own
+// connection and the Tendermint RPC address of the same node, each behind its
+// circuit breaker. earliest is the lowest height the node still serves, learnt
+// from /status.
+type sekaiNode struct {
+	name        string
+	tendermint  string
+	archive     bool
+	conn        *grpc.ClientConn
+	mux         *runtime.ServeMux
+	grpcBreaker *CircuitBreaker
+	rpcBreaker  *CircuitBreaker
+	healthy     atomic.Bool
+	height      atomic.Int64
+	earliest    atomic.Int64
+}
+
+// hasHeight reports whether the node keeps the state of height, a node that was
+// never probed is assumed to.
+func (n *sekaiNode) hasHeight(height int64) bool {
+	earliest := n.earliest.Load()
+	return height <= 0 || earliest <= 0 || earliest <= height
+}
+
+type requiredHeightKey struct{}
+
+// withRequiredHeight routes the requests issued with the returned context to a
+// node holding height without pinning gRPC queries to it.
+func withRequiredHeight(ctx context.Context, height int64) context.Context {
+	return context.WithValue(ctx, requiredHeightKey{}, height)
+}
+
+// routeHeight is the height a request needs: the pinned or required height of
+// ctx, otherwise the height query parameter of Tendermint calls, 0 for the tip.
+func routeHeight(ctx context.Context, query string) int64 {
+	if height, ok := blockHeightFromContext(ctx); ok {
+		return height
+	}
+
+	if height, ok := ctx.Value(requiredHeightKey{}).(int64); ok && height > 0 {
+		return height
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return 0
+	}
+
+	height, err := strconv.ParseInt(values.Get("height"), 10, 64)
+	if err != nil || height <= 0 {
+		return 0
+	}
+
+	return height
+}
+
+// NodePool spreads requests over the healthy sekai nodes. Nodes are probed
+// through Tendermint /status and ejected while unreachable or catching up,
+// requests failing on a node's transport are retried on the next one.
+type NodePool struct {
+	nodes    []*sekaiNode
+	next     atomic.Uint64
+	client   *http.Client
+	interval time.Duration
+}
+
+func newNodePool(ctx *service.Context, config types.CosmosConfig, breakers *Breakers) (*NodePool, error) {
+	nodesConfig := config.Nodes
+	if len(nodesConfig) == 0 && config.Node.JsonRpc != "" {
+		nodesConfig = []types.CosmosNode{config.Node}
+	}
+
+	if len(nodesConfig) == 0 {
+		return nil, errors.New("cosmos: no sekai node configured")
+	}
+
+	interval := time.Duration(config.HealthCheck.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	timeout := time.Duration(config.HealthCheck.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	pool := &NodePool{
+		client:   &http.Client{Timeout: timeout},
+		interval: interval,
+	}
+
+	for _, nodeConfig := range nodesConfig {
+		node, err := newSekaiNode(ctx, nodeConfig, breakers)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+
+		pool.nodes = append(pool.nodes, node)
+	}
+
+	pool.probeAll(ctx.Context)
+
+	return pool, nil
+}
+
+// newSekaiNode dials without blocking, a node that is down at startup is only
+// ejected until the health check sees it again.
+func newSekaiNode(ctx *service.Context, config types.CosmosNode, breakers *Breakers) (*sekaiNode, error) {
+	conn, err := grpc.DialContext(
+		ctx.Context,
+		config.JsonRpc,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gRPC server: %v", err)
+	}
+
+	mux := runtime.NewServeMux(runtime.WithRoutingErrorHandler(routingErrorHandler))
+
+	if err := registerHandlers(ctx, mux, conn); err != nil {
+		conn.Close()
+		logger.Logger.Error("newSekaiNode", zap.Error(err))
+		return nil, err
+	}
+
+	name := config.Name
+	if name == "" {
+		name = config.JsonRpc
+	}
+
+	return &sekaiNode{
+		name:        name,
+		tendermint:  config.Tendermint,
+		archive:     config.Archive,
+		conn:        conn,
+		mux:         mux,
+		grpcBreaker: breakers.Get("sekai-grpc/"+name, config.JsonRpc),
+		rpcBreaker:  breakers.Get("sekai-rpc/"+name, upstreamTarget(config.Tendermint)),
+	}, nil
+}
+
+// candidates returns the nodes to try for a request needing height (0 for the
+// tip), in turn within each tier: healthy pruned nodes holding the height, then
+// healthy archive nodes holding it, so archive nodes only serve what the others
+// cannot. When no healthy node holds the height the remaining healthy nodes
+// are tried, and when none is healthy every node is, the health view may be
+// stale and trying beats failing outright.
+func (p *NodePool) candidates(height int64) []*sekaiNode {
+	start := int(p.next.Add(1) % uint64(len(p.nodes)))
+
+	var pruned, archive, rest, all []*sekaiNode
+	for i := range p.nodes {
+		node := p.nodes[(start+i)%len(p.nodes)]
+		all = append(all, node)
+
+		switch {
+		case !node.healthy.Load():
+		case !node.hasHeight(height):
+			rest = append(rest, node)
+		case node.archive:
+			archive = append(archive, node)
+		default:
+			pruned = append(pruned, node)
+		}
+	}
+
+	if len(pruned)+len(archive) > 0 {
+		return append(pruned, archive...)
+	}
+
+	if len(rest) > 0 {
+		return rest
+	}
+
+	return all
+}
+
+// eject takes a node out of rotation until the next successful probe.
+func (p *NodePool) eject(node *sekaiNode, err error) {
+	if node.healthy.Swap(false) {
+		logger.Logger.Warn("NodePool - node ejected", zap.String("node", node.name), zap.Error(err))
+	}
+}
+
+func (p *NodePool) watch(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.probeAll(ctx)
+		}
+	}
+}
+
+func (p *NodePool) probeAll(ctx context.Context) {
+	for _, node := range p.nodes {
+		err := p.probe(ctx, node)
+		if err != nil {
+			p.eject(node, err)
+			continue
+		}
+
+		if !node.healthy.Swap(true) {
+			logger.Logger.Info("NodePool - node healthy",
+				zap.String("node", node.name),
+				zap.Int64("earliest", node.earliest.Load()),
+				zap.Int64("height", node.height.Load()))
+		}
+	}
+}
+
+// probe checks that the node answers /status, is not catching up and that its
+// gRPC connection is not failing.
+func (p *NodePool) probe(ctx context.Context, node *sekaiNode) error {
+	node.conn.Connect()
+	if node.conn.GetState() == connectivity.TransientFailure {
+		return errors.New("gRPC connection failing")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, node.tendermint+"/status", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result types.KiraStatus `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return err
+	}
+
+	if response.Result.SyncInfo.CatchingUp {
+		return errors.New("node is catching up")
+	}
+
+	height, err := strconv.ParseInt(response.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	node.height.Store(height)
+
+	if earliest, err := strconv.ParseInt(response.Result.SyncInfo.EarliestBlockHeight, 10, 64); err == nil {
+		node.earliest.Store(earliest)
+	}
+
+	return nil
+}
+
+func (p *NodePool) Close() {
+	for _, node := range p.nodes {
+		node.conn.Close()
+	}
+}
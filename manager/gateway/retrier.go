@@ -1,42 +1,161 @@
 package gateway
 
 import (
-	"github.com/KiraCore/sai-interx-manager/logger"
-	"go.uber.org/zap"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
 	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 2 * time.Second
 )
 
 type RetryFunc func() (interface{}, error)
 
+// RetryPolicy retries transient failures with exponential backoff and full
+// jitter. Attempts counts the first call, 1 disables retries.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func newRetryPolicy(config types.RetryConfig, fallback RetryPolicy) RetryPolicy {
+	policy := fallback
+
+	if config.Attempts > 0 {
+		policy.Attempts = config.Attempts
+	}
+	if config.BaseDelay > 0 {
+		policy.BaseDelay = time.Duration(config.BaseDelay) * time.Millisecond
+	}
+	if config.MaxDelay > 0 {
+		policy.MaxDelay = time.Duration(config.MaxDelay) * time.Millisecond
+	}
+
+	return policy
+}
+
+// Retrier holds the retry policy of a gateway and its per route overrides.
 type Retrier struct {
-	attempts int
-	delay    time.Duration
+	policy RetryPolicy
+	routes map[string]RetryPolicy
 }
 
-func NewRetrier(attempts int, delay time.Duration) *Retrier {
-	return &Retrier{
-		attempts: attempts,
-		delay:    delay,
+func NewRetrier(config types.RetryConfig) *Retrier {
+	retrier := &Retrier{
+		policy: newRetryPolicy(config, RetryPolicy{
+			Attempts:  1,
+			BaseDelay: defaultRetryBaseDelay,
+			MaxDelay:  defaultRetryMaxDelay,
+		}),
+		routes: make(map[string]RetryPolicy, len(config.Routes)),
+	}
+
+	for route, routeConfig := range config.Routes {
+		retrier.routes[route] = newRetryPolicy(routeConfig, retrier.policy)
 	}
+
+	return retrier
 }
 
-func (r *Retrier) Do(fn RetryFunc) (interface{}, error) {
+// For returns the policy of a route. Calls that are not idempotent, such as tx
+// broadcasts, are made once unless the route is configured otherwise.
+func (r *Retrier) For(route string, idempotent bool) RetryPolicy {
+	if policy, ok := r.routes[route]; ok {
+		return policy
+	}
+
+	policy := r.policy
+	if !idempotent {
+		policy.Attempts = 1
+	}
+
+	return policy
+}
+
+// Do calls fn until it succeeds, fails with an error that is not transient or
+// runs out of attempts. It never sleeps past the deadline of ctx.
+func (p RetryPolicy) Do(ctx context.Context, fn RetryFunc) (interface{}, error) {
 	var lastError error
 
-	for i := 0; i < r.attempts; i++ {
+	for attempt := 0; ; attempt++ {
 		result, err := fn()
 		if err == nil {
 			return result, nil
-		} else {
-			lastError = err
+		}
+		lastError = err
+
+		if attempt+1 >= p.Attempts || !transient(err) || ctx.Err() != nil {
+			break
+		}
+
+		delay := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
 		}
 
-		if i < r.attempts-1 {
-			time.Sleep(r.delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, lastError
+		case <-timer.C:
 		}
 	}
 
-	logger.Logger.Error("Retrier - all retry attempts failed", zap.Error(lastError))
+	if p.Attempts > 1 {
+		logger.Logger.Error("Retrier - giving up", zap.Error(lastError))
+	}
 
 	return nil, lastError
 }
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 30 && p.BaseDelay<<attempt < p.MaxDelay {
+		delay = p.BaseDelay << attempt
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// transient tells whether err may go away on its own: transport failures and
+// unavailable or failing upstreams. Open circuits, caller errors and the
+// caller's own cancellation are final.
+func transient(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	switch apierror.From(err).Code {
+	case apierror.Unavailable, apierror.Upstream:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestRetrierFor(t *testing.T) {
+	retrier := NewRetrier(types.RetryConfig{
+		Attempts: 3,
+		Routes: map[string]types.RetryConfig{
+			"/kira/txs/simulate": {Attempts: 2},
+		},
+	})
+
+	tests := []struct {
+		route      string
+		idempotent bool
+		attempts   int
+	}{
+		{route: "/kira/status", idempotent: true, attempts: 3},
+		{route: "/kira/txs", idempotent: false, attempts: 1},
+		{route: "/kira/txs/simulate", idempotent: false, attempts: 2},
+	}
+
+	for _, test := range tests {
+		if policy := retrier.For(test.route, test.idempotent); policy.Attempts != test.attempts {
+			t.Fatalf("For(%s, %v) = %d attempts, want %d", test.route, test.idempotent, policy.Attempts, test.attempts)
+		}
+	}
+
+	if policy := NewRetrier(types.RetryConfig{}).For("/kira/status", true); policy.Attempts != 1 {
+		t.Fatalf("default policy = %d attempts, want 1", policy.Attempts)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3}
+
+	tests := []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{name: "success", calls: 1},
+		{name: "transient", err: apierror.New(apierror.Unavailable, "node down"), calls: 3},
+		{name: "final", err: ErrRouteNotFound, calls: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			_, err := policy.Do(context.Background(), func() (interface{}, error) {
+				calls++
+				return nil, test.err
+			})
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Do = %v, want %v", err, test.err)
+			}
+			if calls != test.calls {
+				t.Fatalf("%d calls, want %d", calls, test.calls)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	policy.Do(ctx, func() (interface{}, error) {
+		calls++
+		return nil, apierror.New(apierror.Unavailable, "node down")
+	})
+	if calls != 1 {
+		t.Fatalf("%d calls after the caller went away, want 1", calls)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: apierror.New(apierror.Unavailable, "node down"), want: true},
+		{err: apierror.New(apierror.Upstream, "bad gateway"), want: true},
+		{err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{err: io.ErrUnexpectedEOF, want: true},
+		{err: fmt.Errorf("%w: sekai", ErrCircuitOpen), want: false},
+		{err: context.Canceled, want: false},
+		{err: context.DeadlineExceeded, want: false},
+		{err: ErrRouteNotFound, want: false},
+		{err: apierror.New(apierror.InvalidArgument, "bad address"), want: false},
+	}
+
+	for _, test := range tests {
+		if got := transient(test.err); got != test.want {
+			t.Fatalf("transient(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestCosmosHandleTxNotRetried(t *testing.T) {
+	calls := map[string]int{}
+	handler := func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+		calls[req.Path]++
+		return nil, apierror.New(apierror.Unavailable, "node down")
+	}
+
+	router, err := NewRouter(
+		Route{Method: http.MethodPost, Path: "/kira/txs", RateClass: RateClassTx, Handler: handler},
+		Route{Method: http.MethodGet, Path: "/kira/status", Handler: handler},
+	)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	g := &CosmosGateway{
+		BaseGateway: &BaseGateway{
+			rateLimit: NewRateLimiter(1000),
+			retry:     NewRetrier(types.RetryConfig{Attempts: 3, BaseDelay: 1, MaxDelay: 1}),
+		},
+		router: router,
+	}
+
+	for _, request := range []types.InboundRequest{
+		{Method: http.MethodPost, Path: "/kira/txs"},
+		{Method: http.MethodGet, Path: "/kira/status"},
+	} {
+		data, _ := json.Marshal(request)
+		if _, err := g.Handle(context.Background(), data, types.RequestMetadata{}); err == nil {
+			t.Fatalf("%s %s succeeded", request.Method, request.Path)
+		}
+	}
+
+	if calls["/kira/txs"] != 1 {
+		t.Fatalf("tx broadcast made %d times, want 1", calls["/kira/txs"])
+	}
+	if calls["/kira/status"] != 3 {
+		t.Fatalf("status query made %d times, want 3", calls["/kira/status"])
+	}
+}
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	RosettaBlockchain = "kira"
+	RosettaVersion    = "1.4.13"
+
+	rosettaOperationTransfer = "transfer"
+	rosettaStatusSuccess     = "SUCCESS"
+	rosettaCurveSecp256k1    = "secp256k1"
+	rosettaSignatureEcdsa    = "ecdsa"
+)
+
+var (
+	ErrRosettaInvalidNetwork       = &types.RosettaError{Code: 1, Message: "Invalid network identifier"}
+	ErrRosettaInvalidRequest       = &types.RosettaError{Code: 2, Message: "Invalid request"}
+	ErrRosettaUnavailable          = &types.RosettaError{Code: 3, Message: "Node unavailable", Retriable: true}
+	ErrRosettaBlockNotFound        = &types.RosettaError{Code: 4, Message: "Block not found", Retriable: true}
+	ErrRosettaTxNotFound           = &types.RosettaError{Code: 5, Message: "Transaction not found", Retriable: true}
+	ErrRosettaUnsupportedOperation = &types.RosettaError{Code: 6, Message: "Unsupported operation"}
+	ErrRosettaInvalidTransaction   = &types.RosettaError{Code: 7, Message: "Invalid transaction"}
+	ErrRosettaBroadcastFailed      = &types.RosettaError{Code: 8, Message: "Broadcast failed"}
+
+	rosettaErrors = []*types.RosettaError{
+		ErrRosettaInvalidNetwork,
+		ErrRosettaInvalidRequest,
+		ErrRosettaUnavailable,
+		ErrRosettaBlockNotFound,
+		ErrRosettaTxNotFound,
+		ErrRosettaUnsupportedOperation,
+		ErrRosettaInvalidTransaction,
+		ErrRosettaBroadcastFailed,
+	}
+)
+
+// RosettaGateway serves the Coinbase Rosetta Data and Construction APIs on top
+// of the cosmos gateway, sharing its gRPC proxy, storage and rate limits.
+type RosettaGateway struct {
+	cosmos *CosmosGateway
+	router *Router
+
+	mu      sync.Mutex
+	chainID string
+}
+
+var _ types.Gateway = (*RosettaGateway)(nil)
+
+func NewRosettaGateway(cosmos *CosmosGateway) (*RosettaGateway, error) {
+	gateway := &RosettaGateway{
+		cosmos: cosmos,
+	}
+
+	router, err := NewRouter(gateway.routes()...)
+	if err != nil {
+		logger.Logger.Error("NewRosettaGateway", zap.Error(err))
+		return nil, err
+	}
+
+	gateway.router = router
+
+	return gateway, nil
+}
+
+func (g *RosettaGateway) routes() []Route {
+	handle := func(path string, rateClass string, handler func(ctx context.Context, req types.InboundRequest) (interface{}, error)) Route {
+		return Route{
+			Method:    http.MethodPost,
+			Path:      path,
+			RateClass: rateClass,
+			Handler: func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+				return handler(ctx, req)
+			},
+		}
+	}
+
+	return []Route{
+		handle("/network/list", RateClassDefault, g.networkList),
+		handle("/network/status", RateClassDefault, g.networkStatus),
+		handle("/network/options", RateClassDefault, g.networkOptions),
+		handle("/block", RateClassAggregate, g.block),
+		handle("/block/transaction", RateClassDefault, g.blockTransaction),
+		handle("/account/balance", RateClassDefault, g.accountBalance),
+		handle("/mempool", RateClassDefault, g.mempool),
+		handle("/mempool/transaction", RateClassDefault, g.mempoolTransaction),
+		handle("/construction/derive", RateClassDefault, g.constructionDerive),
+		handle("/construction/preprocess", RateClassDefault, g.constructionPreprocess),
+		handle("/construction/metadata", RateClassDefault, g.constructionMetadata),
+		handle("/construction/payloads", RateClassDefault, g.constructionPayloads),
+		handle("/construction/combine", RateClassDefault, g.constructionCombine),
+		handle("/construction/parse", RateClassDefault, g.constructionParse),
+		handle("/construction/hash", RateClassDefault, g.constructionHash),
+		handle("/construction/submit", RateClassTx, g.constructionSubmit),
+	}
+}
+
+func (g *RosettaGateway) Handle(ctx context.Context, data []byte, metadata types.RequestMetadata) (interface{}, error) {
+	var req types.InboundRequest
+
+	if err := json.Unmarshal(data, &req); err != nil {
+		logger.Logger.Error("RosettaGateway - Handle - Unmarshal request failed", zap.Error(err))
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	path := strings.TrimPrefix(req.Path, "/rosetta")
+
+	route, params, err := g.router.Match(req.Method, path)
+	if err != nil {
+		logger.Logger.Error("RosettaGateway - Handle - No route", zap.Error(err), zap.String("method", req.Method), zap.String("path", req.Path))
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(types.WithRequestMetadata(ctx, metadata), g.cosmos.timeouts.For("/rosetta"+route.Path, route.Timeout))
+	defer cancel()
+
+	if err := g.cosmos.rateLimiter(route.RateClass).Wait(ctx); err != nil {
+		logger.Logger.Error("RosettaGateway - Handle - Rate limit exceeded", zap.Error(err), zap.String("class", route.RateClass))
+		return nil, err
+	}
+
+	return route.Handler(ctx, req, params)
+}
+
+func (g *RosettaGateway) Close() {}
+
+// rosettaError returns a copy of base carrying the underlying cause in details.
+func rosettaError(base *types.RosettaError, err error) *types.RosettaError {
+	result := *base
+	if err != nil {
+		result.Details = map[string]interface{}{"error": err.Error()}
+	}
+
+	return &result
+}
+
+// RosettaErrorResponse extracts the Rosetta error object that has to be sent
+// back to the client in place of the usual error envelope.
+func RosettaErrorResponse(err error) (*types.RosettaError, bool) {
+	var rosettaErr *types.RosettaError
+	if errors.As(err, &rosettaErr) {
+		return rosettaErr, true
+	}
+
+	return nil, false
+}
+
+// decodeRequest reads the request body and checks the network identifier when
+// the request carries one.
+func (g *RosettaGateway) decodeRequest(ctx context.Context, req types.InboundRequest, target interface{}) error {
+	jsonData, err := json.Marshal(req.Payload)
+	if err != nil {
+		return rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	if err = json.Unmarshal(jsonData, target); err != nil {
+		return rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	var network types.NetworkRequest
+	if err = json.Unmarshal(jsonData, &network); err != nil {
+		return rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	return g.validateNetwork(ctx, network.NetworkIdentifier)
+}
+
+func (g *RosettaGateway) validateNetwork(ctx context.Context, network types.NetworkIdentifier) error {
+	chainID, err := g.network(ctx)
+	if err != nil {
+		return err
+	}
+
+	if network.Blockchain != RosettaBlockchain || network.Network != chainID {
+		return rosettaError(ErrRosettaInvalidNetwork, fmt.Errorf("expected %s/%s", RosettaBlockchain, chainID))
+	}
+
+	return nil
+}
+
+// network returns the chain id, which never changes for a running node.
+func (g *RosettaGateway) network(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.chainID != "" {
+		return g.chainID, nil
+	}
+
+	status, err := g.cosmos.status(ctx)
+	if err != nil {
+		logger.Logger.Error("[rosetta-network] Failed to get node status", zap.Error(err))
+		return "", rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	g.chainID = status.NodeInfo.Network
+
+	return g.chainID, nil
+}
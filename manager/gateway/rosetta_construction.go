@@ -0,0 +1,558 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const rosettaDefaultGasLimit = 200000
+
+// rosettaTxMetadata travels from /construction/metadata to /construction/payloads.
+type rosettaTxMetadata struct {
+	AccountNumber uint64 `json:"account_number,string"`
+	Sequence      uint64 `json:"sequence,string"`
+	ChainID       string `json:"chain_id"`
+	Memo          string `json:"memo,omitempty"`
+	GasLimit      uint64 `json:"gas_limit,string"`
+	Fee           string `json:"fee"`
+}
+
+func (g *RosettaGateway) constructionDerive(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionDeriveRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	pubKey, err := rosettaPubKey(request.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.ConstructionDeriveResponse{
+		AccountIdentifier: types.AccountIdentifier{Address: sdk.AccAddress(pubKey.Address()).String()},
+	}, nil
+}
+
+func (g *RosettaGateway) constructionPreprocess(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionPreprocessRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	_, sender, err := operationMsgs(request.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	options := map[string]interface{}{"sender": sender}
+
+	if memo, ok := request.Metadata["memo"]; ok {
+		options["memo"] = cast.ToString(memo)
+	}
+
+	if gasLimit, ok := request.Metadata["gas_limit"]; ok {
+		options["gas_limit"] = strconv.FormatUint(cast.ToUint64(gasLimit), 10)
+	}
+
+	if fee, ok := request.Metadata["fee"]; ok {
+		if _, err := sdk.ParseCoinsNormalized(cast.ToString(fee)); err != nil {
+			return nil, rosettaError(ErrRosettaInvalidRequest, err)
+		}
+		options["fee"] = cast.ToString(fee)
+	}
+
+	return types.ConstructionPreprocessResponse{
+		Options:            options,
+		RequiredPublicKeys: []types.AccountIdentifier{{Address: sender}},
+	}, nil
+}
+
+func (g *RosettaGateway) constructionMetadata(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionMetadataRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	sender := cast.ToString(request.Options["sender"])
+	if sender == "" {
+		return nil, rosettaError(ErrRosettaInvalidRequest, errors.New("sender option is required"))
+	}
+
+	account, err := g.cosmos.account(ctx, sender)
+	if err != nil {
+		logger.Logger.Error("[rosetta-construction-metadata] Failed to get account info", zap.Error(err))
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	chainID, err := g.network(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := rosettaTxMetadata{
+		ChainID:  chainID,
+		Memo:     cast.ToString(request.Options["memo"]),
+		GasLimit: cast.ToUint64(request.Options["gas_limit"]),
+		Fee:      cast.ToString(request.Options["fee"]),
+	}
+
+	if metadata.AccountNumber, err = strconv.ParseUint(account.AccountNumber, 10, 64); err != nil {
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	if metadata.Sequence, err = strconv.ParseUint(account.Sequence, 10, 64); err != nil {
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	if metadata.GasLimit == 0 {
+		metadata.GasLimit = rosettaDefaultGasLimit
+	}
+
+	if metadata.Fee == "" {
+		fee, err := g.suggestedFee(ctx)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Fee = fee.String()
+	}
+
+	fee, err := sdk.ParseCoinsNormalized(metadata.Fee)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	response := types.ConstructionMetadataResponse{}
+
+	if err = remarshal(metadata, &response.Metadata); err != nil {
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	for _, coin := range fee {
+		response.SuggestedFee = append(response.SuggestedFee, *rosettaAmount(coin.Amount.String(), coin.Denom))
+	}
+
+	return response, nil
+}
+
+// suggestedFee is the larger of the network minimum fee and the execution fee
+// of a bank send, paid in the default denom.
+func (g *RosettaGateway) suggestedFee(ctx context.Context) (sdk.Coin, error) {
+	prefixes, err := g.cosmos.customPrefixes(ctx)
+	if err != nil {
+		logger.Logger.Error("[rosetta-suggested-fee] Failed to get default denom", zap.Error(err))
+		return sdk.Coin{}, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	properties, err := g.cosmos.networkProperties(ctx)
+	if err != nil {
+		logger.Logger.Error("[rosetta-suggested-fee] Failed to get network properties", zap.Error(err))
+		return sdk.Coin{}, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	amount, ok := new(big.Int).SetString(properties.(*types.NetworkPropertiesResponse).Properties.MinTxFee, 10)
+	if !ok {
+		amount = big.NewInt(0)
+	}
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, http.MethodGet, "/kira/gov/execution_fee/"+bank.TypeMsgSend, nil)
+	if err == nil {
+		var executionFee struct {
+			Fee struct {
+				ExecutionFee string `json:"executionFee"`
+			} `json:"fee"`
+		}
+
+		if respBody, err := g.cosmos.grpcProxy.ServeGRPC(gatewayReq); err == nil && json.Unmarshal(respBody, &executionFee) == nil {
+			if fee, ok := new(big.Int).SetString(executionFee.Fee.ExecutionFee, 10); ok && fee.Cmp(amount) > 0 {
+				amount = fee
+			}
+		}
+	}
+
+	return sdk.NewCoin(prefixes.DefaultDenom, sdk.NewIntFromBigInt(amount)), nil
+}
+
+func (g *RosettaGateway) constructionPayloads(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionPayloadsRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	msgs, sender, err := operationMsgs(request.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata rosettaTxMetadata
+	if err = remarshal(request.Metadata, &metadata); err != nil {
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	fee, err := sdk.ParseCoinsNormalized(metadata.Fee)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	var pubKey *secp256k1.PubKey
+	for _, key := range request.PublicKeys {
+		candidate, err := rosettaPubKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if sdk.AccAddress(candidate.Address()).String() == sender {
+			pubKey = candidate
+		}
+	}
+
+	if pubKey == nil {
+		return nil, rosettaError(ErrRosettaInvalidRequest, fmt.Errorf("public key for %s is missing", sender))
+	}
+
+	txConfig := g.cosmos.txConfig
+	txBuilder := txConfig.NewTxBuilder()
+
+	if err = txBuilder.SetMsgs(msgs...); err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	txBuilder.SetFeeAmount(fee)
+	txBuilder.SetGasLimit(metadata.GasLimit)
+	txBuilder.SetMemo(metadata.Memo)
+
+	// Direct sign bytes cover the signer infos, so the public key and sequence
+	// are committed now and only the signature is filled in by combine.
+	err = txBuilder.SetSignatures(signing.SignatureV2{
+		PubKey:   pubKey,
+		Data:     &signing.SingleSignatureData{SignMode: signing.SignMode_SIGN_MODE_DIRECT},
+		Sequence: metadata.Sequence,
+	})
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	signBytes, err := txConfig.SignModeHandler().GetSignBytes(
+		signing.SignMode_SIGN_MODE_DIRECT,
+		authsigning.SignerData{
+			Address:       sender,
+			ChainID:       metadata.ChainID,
+			AccountNumber: metadata.AccountNumber,
+			Sequence:      metadata.Sequence,
+			PubKey:        pubKey,
+		},
+		txBuilder.GetTx(),
+	)
+	if err != nil {
+		logger.Logger.Error("[rosetta-construction-payloads] Failed to get sign bytes", zap.Error(err))
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	txBytes, err := txConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		logger.Logger.Error("[rosetta-construction-payloads] Failed to encode transaction", zap.Error(err))
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	digest := sha256.Sum256(signBytes)
+
+	return types.ConstructionPayloadsResponse{
+		UnsignedTransaction: hex.EncodeToString(txBytes),
+		Payloads: []types.SigningPayload{{
+			AccountIdentifier: &types.AccountIdentifier{Address: sender},
+			HexBytes:          hex.EncodeToString(digest[:]),
+			SignatureType:     rosettaSignatureEcdsa,
+		}},
+	}, nil
+}
+
+func (g *RosettaGateway) constructionCombine(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionCombineRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	tx, err := g.decodeTx(request.UnsignedTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	txBuilder, err := g.cosmos.txConfig.WrapTxBuilder(tx)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	placeholders, err := tx.(authsigning.SigVerifiableTx).GetSignaturesV2()
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	signatures := make([]signing.SignatureV2, 0, len(placeholders))
+	for _, placeholder := range placeholders {
+		signature, found := findSignature(request.Signatures, placeholder.PubKey.Bytes())
+		if !found {
+			return nil, rosettaError(ErrRosettaInvalidRequest, fmt.Errorf("signature for %X is missing", placeholder.PubKey.Bytes()))
+		}
+
+		signatures = append(signatures, signing.SignatureV2{
+			PubKey:   placeholder.PubKey,
+			Data:     &signing.SingleSignatureData{SignMode: signing.SignMode_SIGN_MODE_DIRECT, Signature: signature},
+			Sequence: placeholder.Sequence,
+		})
+	}
+
+	if err = txBuilder.SetSignatures(signatures...); err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	txBytes, err := g.cosmos.txConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	return types.ConstructionCombineResponse{SignedTransaction: hex.EncodeToString(txBytes)}, nil
+}
+
+func findSignature(signatures []types.Signature, pubKey []byte) ([]byte, bool) {
+	for _, signature := range signatures {
+		key, err := hex.DecodeString(signature.PublicKey.HexBytes)
+		if err != nil || string(key) != string(pubKey) {
+			continue
+		}
+
+		sig, err := hex.DecodeString(signature.HexBytes)
+		if err != nil {
+			return nil, false
+		}
+
+		return sig, true
+	}
+
+	return nil, false
+}
+
+func (g *RosettaGateway) constructionParse(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionParseRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	txBytes, err := hex.DecodeString(request.Transaction)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	return g.parseTx(txBytes, request.Signed)
+}
+
+func (g *RosettaGateway) parseTx(txBytes []byte, signed bool) (*types.ConstructionParseResponse, error) {
+	tx, err := g.cosmos.txConfig.TxDecoder()(txBytes)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	operations, err := msgOperations(tx.GetMsgs(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &types.ConstructionParseResponse{
+		Operations: operations,
+		Metadata:   map[string]interface{}{},
+	}
+
+	if feeTx, ok := tx.(sdk.FeeTx); ok {
+		response.Metadata["fee"] = feeTx.GetFee().String()
+		response.Metadata["gas_limit"] = strconv.FormatUint(feeTx.GetGas(), 10)
+	}
+
+	if memoTx, ok := tx.(sdk.TxWithMemo); ok && memoTx.GetMemo() != "" {
+		response.Metadata["memo"] = memoTx.GetMemo()
+	}
+
+	if !signed {
+		return response, nil
+	}
+
+	signatures, err := tx.(authsigning.SigVerifiableTx).GetSignaturesV2()
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	for _, signature := range signatures {
+		response.AccountIdentifierSigners = append(response.AccountIdentifierSigners, types.AccountIdentifier{
+			Address: sdk.AccAddress(signature.PubKey.Address()).String(),
+		})
+	}
+
+	return response, nil
+}
+
+func (g *RosettaGateway) constructionHash(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionHashRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	txBytes, err := hex.DecodeString(request.SignedTransaction)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	return types.TransactionIdentifierResponse{
+		TransactionIdentifier: types.TransactionIdentifier{Hash: txHash(txBytes)},
+	}, nil
+}
+
+func (g *RosettaGateway) constructionSubmit(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.ConstructionHashRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	txBytes, err := hex.DecodeString(request.SignedTransaction)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	if _, err = g.cosmos.txConfig.TxDecoder()(txBytes); err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	result, err := g.cosmos.txs(ctx, types.InboundRequest{
+		Method: http.MethodPost,
+		Payload: map[string]interface{}{
+			"tx": base64.StdEncoding.EncodeToString(txBytes),
+		},
+	})
+	if err != nil {
+		logger.Logger.Error("[rosetta-construction-submit] Broadcast failed", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	var broadcast struct {
+		Code int    `json:"code"`
+		Log  string `json:"log"`
+		Hash string `json:"hash"`
+	}
+
+	if err = remarshal(result, &broadcast); err != nil {
+		return nil, rosettaError(ErrRosettaBroadcastFailed, err)
+	}
+
+	if broadcast.Code != 0 {
+		return nil, rosettaError(ErrRosettaBroadcastFailed, errors.New(broadcast.Log))
+	}
+
+	return types.TransactionIdentifierResponse{
+		TransactionIdentifier: types.TransactionIdentifier{Hash: txHash(txBytes)},
+	}, nil
+}
+
+func (g *RosettaGateway) decodeTx(encoded string) (sdk.Tx, error) {
+	txBytes, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	tx, err := g.cosmos.txConfig.TxDecoder()(txBytes)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaInvalidTransaction, err)
+	}
+
+	return tx, nil
+}
+
+// operationMsgs pairs every debit with the credit that follows it into a bank
+// send. All debits must come from one account, which becomes the only signer.
+func operationMsgs(operations []types.Operation) ([]sdk.Msg, string, error) {
+	var (
+		msgs   []sdk.Msg
+		sender string
+		debit  *types.Operation
+	)
+
+	for i := range operations {
+		operation := &operations[i]
+
+		if operation.Type != rosettaOperationTransfer || operation.Account == nil || operation.Amount == nil {
+			return nil, "", rosettaError(ErrRosettaUnsupportedOperation, fmt.Errorf("operation %d", operation.OperationIdentifier.Index))
+		}
+
+		value, ok := new(big.Int).SetString(operation.Amount.Value, 10)
+		if !ok || value.Sign() == 0 {
+			return nil, "", rosettaError(ErrRosettaInvalidRequest, fmt.Errorf("invalid amount %q", operation.Amount.Value))
+		}
+
+		if value.Sign() < 0 {
+			if debit != nil {
+				return nil, "", rosettaError(ErrRosettaInvalidRequest, errors.New("debit without matching credit"))
+			}
+
+			if sender != "" && sender != operation.Account.Address {
+				return nil, "", rosettaError(ErrRosettaUnsupportedOperation, errors.New("multiple senders"))
+			}
+
+			sender = operation.Account.Address
+			debit = operation
+			continue
+		}
+
+		if debit == nil || debit.Amount.Currency.Symbol != operation.Amount.Currency.Symbol ||
+			new(big.Int).Neg(value).String() != debit.Amount.Value {
+			return nil, "", rosettaError(ErrRosettaInvalidRequest, errors.New("credit does not match debit"))
+		}
+
+		if _, err := sdk.AccAddressFromBech32(sender); err != nil {
+			return nil, "", rosettaError(ErrRosettaInvalidRequest, err)
+		}
+
+		if _, err := sdk.AccAddressFromBech32(operation.Account.Address); err != nil {
+			return nil, "", rosettaError(ErrRosettaInvalidRequest, err)
+		}
+
+		msgs = append(msgs, &bank.MsgSend{
+			FromAddress: sender,
+			ToAddress:   operation.Account.Address,
+			Amount:      sdk.NewCoins(sdk.NewCoin(operation.Amount.Currency.Symbol, sdk.NewIntFromBigInt(value))),
+		})
+		debit = nil
+	}
+
+	if debit != nil || len(msgs) == 0 {
+		return nil, "", rosettaError(ErrRosettaInvalidRequest, errors.New("operations must be debit and credit pairs"))
+	}
+
+	return msgs, sender, nil
+}
+
+func rosettaPubKey(key types.PublicKey) (*secp256k1.PubKey, error) {
+	if key.CurveType != rosettaCurveSecp256k1 {
+		return nil, rosettaError(ErrRosettaInvalidRequest, fmt.Errorf("unsupported curve %s", key.CurveType))
+	}
+
+	keyBytes, err := hex.DecodeString(key.HexBytes)
+	if err != nil || len(keyBytes) != secp256k1.PubKeySize {
+		return nil, rosettaError(ErrRosettaInvalidRequest, fmt.Errorf("invalid public key %s", key.HexBytes))
+	}
+
+	return &secp256k1.PubKey{Key: keyBytes}, nil
+}
@@ -0,0 +1,589 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+type indexedBlock struct {
+	BlockID struct {
+		Hash string `json:"hash"`
+	} `json:"block_id"`
+	Block struct {
+		Header struct {
+			Height      string    `json:"height"`
+			Time        time.Time `json:"time"`
+			LastBlockID struct {
+				Hash string `json:"hash"`
+			} `json:"last_block_id"`
+		} `json:"header"`
+	} `json:"block"`
+}
+
+type indexedTx struct {
+	Hash     string `json:"hash"`
+	TxResult struct {
+		Code    int    `json:"code"`
+		GasUsed string `json:"gas_used"`
+		Events  []struct {
+			Type       string `json:"type"`
+			Attributes []struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"attributes"`
+		} `json:"events"`
+	} `json:"tx_result"`
+}
+
+func (g *RosettaGateway) networkList(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	chainID, err := g.network(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NetworkListResponse{
+		NetworkIdentifiers: []types.NetworkIdentifier{{
+			Blockchain: RosettaBlockchain,
+			Network:    chainID,
+		}},
+	}, nil
+}
+
+func (g *RosettaGateway) networkStatus(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.NetworkRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	status, err := g.cosmos.status(ctx)
+	if err != nil {
+		logger.Logger.Error("[rosetta-network-status] Failed to get node status", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	latest, err := strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	earliest, err := strconv.ParseInt(status.SyncInfo.EarliestBlockHeight, 10, 64)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	latestTime, err := time.Parse(time.RFC3339Nano, status.SyncInfo.LatestBlockTime)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	oldest := types.BlockIdentifier{Index: earliest, Hash: status.SyncInfo.EarliestBlockHash}
+
+	response := types.NetworkStatusResponse{
+		CurrentBlockIdentifier: types.BlockIdentifier{Index: latest, Hash: status.SyncInfo.LatestBlockHash},
+		CurrentBlockTimestamp:  latestTime.UnixMilli(),
+		GenesisBlockIdentifier: oldest,
+		OldestBlockIdentifier:  &oldest,
+		SyncStatus: &types.RosettaSyncStatus{
+			CurrentIndex: latest,
+			Synced:       !status.SyncInfo.CatchingUp,
+		},
+		Peers: []types.RosettaPeer{},
+	}
+
+	netInfo, err := g.cosmos.makeTendermintRPCRequest(ctx, "/net_info", "")
+	if err != nil {
+		logger.Logger.Error("[rosetta-network-status] Failed to get peers", zap.Error(err))
+		return response, nil
+	}
+
+	var peers struct {
+		Peers []struct {
+			NodeInfo struct {
+				ID string `json:"id"`
+			} `json:"node_info"`
+		} `json:"peers"`
+	}
+
+	if err = remarshal(netInfo, &peers); err != nil {
+		logger.Logger.Error("[rosetta-network-status] Invalid net_info format", zap.Error(err))
+		return response, nil
+	}
+
+	for _, peer := range peers.Peers {
+		response.Peers = append(response.Peers, types.RosettaPeer{PeerID: peer.NodeInfo.ID})
+	}
+
+	return response, nil
+}
+
+func (g *RosettaGateway) networkOptions(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.NetworkRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	status, err := g.cosmos.status(ctx)
+	if err != nil {
+		logger.Logger.Error("[rosetta-network-options] Failed to get node status", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	errs := make([]types.RosettaError, 0, len(rosettaErrors))
+	for _, e := range rosettaErrors {
+		errs = append(errs, *e)
+	}
+
+	return types.NetworkOptionsResponse{
+		Version: types.RosettaVersion{
+			RosettaVersion: RosettaVersion,
+			NodeVersion:    status.NodeInfo.Version,
+		},
+		Allow: types.RosettaAllow{
+			OperationStatuses: []types.OperationStatus{
+				{Status: rosettaStatusSuccess, Successful: true},
+			},
+			OperationTypes:          []string{rosettaOperationTransfer},
+			Errors:                  errs,
+			HistoricalBalanceLookup: true,
+			MempoolCoins:            false,
+		},
+	}, nil
+}
+
+func (g *RosettaGateway) block(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.BlockRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	criteria := map[string]interface{}{}
+	options := &adapter.Options{
+		Limit:           1,
+		Sort:            map[string]interface{}{"block.header.height": -1},
+		NumericOrdering: true,
+	}
+
+	if request.BlockIdentifier.Index != nil {
+		criteria["block.header.height"] = strconv.FormatInt(*request.BlockIdentifier.Index, 10)
+	}
+
+	if request.BlockIdentifier.Hash != "" {
+		criteria["block_id.hash"] = strings.ToUpper(request.BlockIdentifier.Hash)
+	}
+
+	if len(criteria) == 0 {
+		criteria["_id"] = map[string]interface{}{"$ne": nil}
+	}
+
+	response, err := g.cosmos.storage.Read("cosmos_blocks", criteria, options, []string{})
+	if err != nil {
+		logger.Logger.Error("[rosetta-block] Failed to get block", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	if len(response.Result) == 0 {
+		return nil, rosettaError(ErrRosettaBlockNotFound, nil)
+	}
+
+	var indexed indexedBlock
+	if err = remarshal(response.Result[0], &indexed); err != nil {
+		logger.Logger.Error("[rosetta-block] Invalid block format", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	height, err := strconv.ParseInt(indexed.Block.Header.Height, 10, 64)
+	if err != nil {
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	block := &types.RosettaBlock{
+		BlockIdentifier: types.BlockIdentifier{Index: height, Hash: indexed.BlockID.Hash},
+		ParentBlockIdentifier: types.BlockIdentifier{
+			Index: height - 1,
+			Hash:  indexed.Block.Header.LastBlockID.Hash,
+		},
+		Timestamp:    indexed.Block.Header.Time.UnixMilli(),
+		Transactions: []types.RosettaTransaction{},
+	}
+
+	if block.ParentBlockIdentifier.Hash == "" {
+		block.ParentBlockIdentifier = block.BlockIdentifier
+	}
+
+	txs, err := g.indexedTxs(map[string]interface{}{"height": indexed.Block.Header.Height})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tx := range txs {
+		block.Transactions = append(block.Transactions, tx.rosetta())
+	}
+
+	return types.BlockResponse{Block: block}, nil
+}
+
+func (g *RosettaGateway) blockTransaction(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.BlockTransactionRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	txs, err := g.indexedTxs(map[string]interface{}{
+		"height": strconv.FormatInt(request.BlockIdentifier.Index, 10),
+		"hash":   strings.ToUpper(request.TransactionIdentifier.Hash),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(txs) == 0 {
+		return nil, rosettaError(ErrRosettaTxNotFound, nil)
+	}
+
+	return types.BlockTransactionResponse{Transaction: txs[0].rosetta()}, nil
+}
+
+func (g *RosettaGateway) indexedTxs(criteria map[string]interface{}) ([]indexedTx, error) {
+	options := &adapter.Options{
+		Sort:            map[string]interface{}{"index": 1},
+		NumericOrdering: true,
+	}
+
+	response, err := g.cosmos.storage.Read("cosmos_txs", criteria, options, []string{})
+	if err != nil {
+		logger.Logger.Error("[rosetta-txs] Failed to get transactions", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	var txs []indexedTx
+	if err = remarshal(response.Result, &txs); err != nil {
+		logger.Logger.Error("[rosetta-txs] Invalid transaction format", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	return txs, nil
+}
+
+// rosetta describes the balance changes of an indexed transaction. Operations
+// come from transfer events, which also cover the fee paid by failed txs.
+func (tx indexedTx) rosetta() types.RosettaTransaction {
+	operations := []types.Operation{}
+
+	for _, event := range tx.TxResult.Events {
+		if event.Type != "transfer" {
+			continue
+		}
+
+		var sender, recipient, amount string
+		for _, attribute := range event.Attributes {
+			switch attribute.Key {
+			case "sender":
+				sender = attribute.Value
+			case "recipient":
+				recipient = attribute.Value
+			case "amount":
+				amount = attribute.Value
+			}
+		}
+
+		coins, err := sdk.ParseCoinsNormalized(amount)
+		if err != nil || sender == "" || recipient == "" {
+			continue
+		}
+
+		operations = transferOperations(operations, sender, recipient, coins, rosettaStatusSuccess)
+	}
+
+	return types.RosettaTransaction{
+		TransactionIdentifier: types.TransactionIdentifier{Hash: tx.Hash},
+		Operations:            operations,
+		Metadata: map[string]interface{}{
+			"code":     tx.TxResult.Code,
+			"gas_used": tx.TxResult.GasUsed,
+		},
+	}
+}
+
+// transferOperations appends a debit and a related credit for every coin moved.
+func transferOperations(operations []types.Operation, from, to string, coins sdk.Coins, status string) []types.Operation {
+	for _, coin := range coins {
+		debit := int64(len(operations))
+
+		operations = append(operations,
+			types.Operation{
+				OperationIdentifier: types.OperationIdentifier{Index: debit},
+				Type:                rosettaOperationTransfer,
+				Status:              status,
+				Account:             &types.AccountIdentifier{Address: from},
+				Amount:              rosettaAmount("-"+coin.Amount.String(), coin.Denom),
+			},
+			types.Operation{
+				OperationIdentifier: types.OperationIdentifier{Index: debit + 1},
+				RelatedOperations:   []types.OperationIdentifier{{Index: debit}},
+				Type:                rosettaOperationTransfer,
+				Status:              status,
+				Account:             &types.AccountIdentifier{Address: to},
+				Amount:              rosettaAmount(coin.Amount.String(), coin.Denom),
+			},
+		)
+	}
+
+	return operations
+}
+
+// rosettaAmount expresses values in base denom units, hence zero decimals.
+func rosettaAmount(value, denom string) *types.Amount {
+	return &types.Amount{
+		Value:    value,
+		Currency: types.Currency{Symbol: denom},
+	}
+}
+
+func (g *RosettaGateway) accountBalance(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.AccountBalanceRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	if _, err := sdk.AccAddressFromBech32(request.AccountIdentifier.Address); err != nil {
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	blockID, err := g.blockIdentifier(ctx, request.BlockIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = withBlockHeight(ctx, blockID.Index)
+	path := "/cosmos/bank/v1beta1/balances/" + request.AccountIdentifier.Address + "?pagination.limit=1000"
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		logger.Logger.Error("[rosetta-account-balance] Create request failed", zap.Error(err))
+		return nil, rosettaError(ErrRosettaInvalidRequest, err)
+	}
+
+	respBody, err := g.cosmos.grpcProxy.ServeGRPC(gatewayReq)
+	if err != nil {
+		logger.Logger.Error("[rosetta-account-balance] Serve request failed", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	var balances types.QueryBalancesResponse
+	if err = json.Unmarshal(respBody, &balances); err != nil {
+		logger.Logger.Error("[rosetta-account-balance] Invalid response format", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	held := map[string]string{}
+	for _, balance := range balances.Balances {
+		held[balance.Denom] = balance.Amount
+	}
+
+	response := types.AccountBalanceResponse{
+		BlockIdentifier: blockID,
+		Balances:        []types.Amount{},
+	}
+
+	if len(request.Currencies) > 0 {
+		for _, currency := range request.Currencies {
+			value, ok := held[currency.Symbol]
+			if !ok {
+				value = "0"
+			}
+			response.Balances = append(response.Balances, *rosettaAmount(value, currency.Symbol))
+		}
+
+		return response, nil
+	}
+
+	for _, balance := range balances.Balances {
+		response.Balances = append(response.Balances, *rosettaAmount(balance.Amount, balance.Denom))
+	}
+
+	return response, nil
+}
+
+// blockIdentifier resolves a partial identifier against the node, defaulting
+// to the latest block.
+func (g *RosettaGateway) blockIdentifier(ctx context.Context, partial *types.PartialBlockIdentifier) (types.BlockIdentifier, error) {
+	var query, url string
+
+	switch {
+	case partial != nil && partial.Index != nil:
+		url, query = "/block", fmt.Sprintf("height=%d", *partial.Index)
+	case partial != nil && partial.Hash != "":
+		url, query = "/block_by_hash", "hash=0x"+strings.TrimPrefix(partial.Hash, "0x")
+	default:
+		status, err := g.cosmos.status(ctx)
+		if err != nil {
+			logger.Logger.Error("[rosetta-block-identifier] Failed to get node status", zap.Error(err))
+			return types.BlockIdentifier{}, rosettaError(ErrRosettaUnavailable, err)
+		}
+
+		height, err := strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+		if err != nil {
+			return types.BlockIdentifier{}, rosettaError(ErrRosettaUnavailable, err)
+		}
+
+		return types.BlockIdentifier{Index: height, Hash: status.SyncInfo.LatestBlockHash}, nil
+	}
+
+	result, err := g.cosmos.makeTendermintRPCRequest(ctx, url, query)
+	if err != nil {
+		return types.BlockIdentifier{}, rosettaError(ErrRosettaBlockNotFound, err)
+	}
+
+	var block struct {
+		BlockID struct {
+			Hash string `json:"hash"`
+		} `json:"block_id"`
+		Block struct {
+			Header struct {
+				Height string `json:"height"`
+			} `json:"header"`
+		} `json:"block"`
+	}
+
+	if err = remarshal(result, &block); err != nil {
+		return types.BlockIdentifier{}, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	height, err := strconv.ParseInt(block.Block.Header.Height, 10, 64)
+	if err != nil {
+		return types.BlockIdentifier{}, rosettaError(ErrRosettaBlockNotFound, err)
+	}
+
+	if partial.Hash != "" && !strings.EqualFold(strings.TrimPrefix(partial.Hash, "0x"), block.BlockID.Hash) {
+		return types.BlockIdentifier{}, rosettaError(ErrRosettaBlockNotFound, errors.New("hash does not match index"))
+	}
+
+	return types.BlockIdentifier{Index: height, Hash: block.BlockID.Hash}, nil
+}
+
+func (g *RosettaGateway) mempool(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.NetworkRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	txs, err := g.unconfirmedTxs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := types.RosettaMempoolResponse{TransactionIdentifiers: []types.TransactionIdentifier{}}
+	for hash := range txs {
+		response.TransactionIdentifiers = append(response.TransactionIdentifiers, types.TransactionIdentifier{Hash: hash})
+	}
+
+	return response, nil
+}
+
+func (g *RosettaGateway) mempoolTransaction(ctx context.Context, req types.InboundRequest) (interface{}, error) {
+	var request types.MempoolTransactionRequest
+	if err := g.decodeRequest(ctx, req, &request); err != nil {
+		return nil, err
+	}
+
+	txs, err := g.unconfirmedTxs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := strings.ToUpper(request.TransactionIdentifier.Hash)
+
+	txBytes, found := txs[hash]
+	if !found {
+		return nil, rosettaError(ErrRosettaTxNotFound, nil)
+	}
+
+	parsed, err := g.parseTx(txBytes, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.MempoolTransactionResponse{
+		Transaction: types.RosettaTransaction{
+			TransactionIdentifier: types.TransactionIdentifier{Hash: hash},
+			Operations:            parsed.Operations,
+			Metadata:              parsed.Metadata,
+		},
+	}, nil
+}
+
+// unconfirmedTxs returns the raw mempool transactions keyed by their hash.
+func (g *RosettaGateway) unconfirmedTxs(ctx context.Context) (map[string][]byte, error) {
+	result, err := g.cosmos.makeTendermintRPCRequest(ctx, "/unconfirmed_txs", "limit=100")
+	if err != nil {
+		logger.Logger.Error("[rosetta-mempool] Failed to get unconfirmed txs", zap.Error(err))
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	var mempool struct {
+		Txs []string `json:"txs"`
+	}
+
+	if err = remarshal(result, &mempool); err != nil {
+		return nil, rosettaError(ErrRosettaUnavailable, err)
+	}
+
+	txs := make(map[string][]byte, len(mempool.Txs))
+	for _, encoded := range mempool.Txs {
+		txBytes, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		txs[txHash(txBytes)] = txBytes
+	}
+
+	return txs, nil
+}
+
+func txHash(txBytes []byte) string {
+	hash := sha256.Sum256(txBytes)
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+// msgOperations converts bank sends, the only messages the construction API
+// builds, into operations.
+func msgOperations(msgs []sdk.Msg, status string) ([]types.Operation, error) {
+	operations := []types.Operation{}
+
+	for _, msg := range msgs {
+		send, ok := msg.(*bank.MsgSend)
+		if !ok {
+			return nil, rosettaError(ErrRosettaUnsupportedOperation, fmt.Errorf("message %s", sdk.MsgTypeURL(msg)))
+		}
+
+		operations = transferOperations(operations, send.FromAddress, send.ToAddress, send.Amount, status)
+	}
+
+	return operations, nil
+}
+
+func remarshal(from interface{}, to interface{}) error {
+	jsonData, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(jsonData, to)
+}
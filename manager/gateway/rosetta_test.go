@@ -0,0 +1,335 @@
+package gateway
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const rosettaTestNetwork = "testnet-1"
+
+func newTestRosetta(t *testing.T) *RosettaGateway {
+	t.Helper()
+
+	g, err := NewRosettaGateway(&CosmosGateway{
+		BaseGateway: &BaseGateway{
+			rateLimit: NewRateLimiter(1000),
+			retry:     NewRetrier(types.RetryConfig{}),
+		},
+		txConfig: testTxConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewRosettaGateway: %v", err)
+	}
+	g.chainID = rosettaTestNetwork
+
+	return g
+}
+
+// rosettaCall sends request, with the test network identifier, to path.
+func rosettaCall(t *testing.T, g *RosettaGateway, path string, request map[string]interface{}, response interface{}) error {
+	t.Helper()
+
+	if _, ok := request["network_identifier"]; !ok {
+		request["network_identifier"] = types.NetworkIdentifier{Blockchain: RosettaBlockchain, Network: rosettaTestNetwork}
+	}
+
+	var payload map[string]interface{}
+	if err := remarshal(request, &payload); err != nil {
+		t.Fatalf("remarshal: %v", err)
+	}
+
+	data, err := json.Marshal(types.InboundRequest{Method: http.MethodPost, Path: "/rosetta" + path, Payload: payload})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	result, err := g.Handle(context.Background(), data, types.RequestMetadata{})
+	if err != nil {
+		return err
+	}
+
+	if err = remarshal(result, response); err != nil {
+		t.Fatalf("remarshal %s response: %v", path, err)
+	}
+
+	return nil
+}
+
+func transfer(index int64, address, value string) types.Operation {
+	return types.Operation{
+		OperationIdentifier: types.OperationIdentifier{Index: index},
+		Type:                rosettaOperationTransfer,
+		Account:             &types.AccountIdentifier{Address: address},
+		Amount:              rosettaAmount(value, "ukex"),
+	}
+}
+
+func TestRosettaOperationMsgs(t *testing.T) {
+	alice := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String()
+	bob := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String()
+	carol := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String()
+
+	unsupported := transfer(0, alice, "-10")
+	unsupported.Type = "stake"
+
+	tests := []struct {
+		name       string
+		operations []types.Operation
+		sends      int
+		err        *types.RosettaError
+	}{
+		{name: "pair", operations: []types.Operation{transfer(0, alice, "-10"), transfer(1, bob, "10")}, sends: 1},
+		{name: "two pairs", operations: []types.Operation{transfer(0, alice, "-10"), transfer(1, bob, "10"), transfer(2, alice, "-5"), transfer(3, carol, "5")}, sends: 2},
+		{name: "debit alone", operations: []types.Operation{transfer(0, alice, "-10")}, err: ErrRosettaInvalidRequest},
+		{name: "credit first", operations: []types.Operation{transfer(0, bob, "10"), transfer(1, alice, "-10")}, err: ErrRosettaInvalidRequest},
+		{name: "amounts differ", operations: []types.Operation{transfer(0, alice, "-10"), transfer(1, bob, "9")}, err: ErrRosettaInvalidRequest},
+		{name: "zero", operations: []types.Operation{transfer(0, alice, "0"), transfer(1, bob, "0")}, err: ErrRosettaInvalidRequest},
+		{name: "two senders", operations: []types.Operation{transfer(0, alice, "-10"), transfer(1, bob, "10"), transfer(2, carol, "-5"), transfer(3, bob, "5")}, err: ErrRosettaUnsupportedOperation},
+		{name: "other operation", operations: []types.Operation{unsupported, transfer(1, bob, "10")}, err: ErrRosettaUnsupportedOperation},
+		{name: "bad address", operations: []types.Operation{transfer(0, alice, "-10"), transfer(1, "kira1bad", "10")}, err: ErrRosettaInvalidRequest},
+		{name: "none", err: ErrRosettaInvalidRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msgs, sender, err := operationMsgs(test.operations)
+			if test.err != nil {
+				rosettaErr, ok := RosettaErrorResponse(err)
+				if !ok || rosettaErr.Code != test.err.Code {
+					t.Fatalf("operationMsgs = %v, want code %d", err, test.err.Code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("operationMsgs: %v", err)
+			}
+
+			if sender != alice || len(msgs) != test.sends {
+				t.Fatalf("operationMsgs = %d sends from %s, want %d from %s", len(msgs), sender, test.sends, alice)
+			}
+
+			send := msgs[0].(*bank.MsgSend)
+			if send.FromAddress != alice || send.ToAddress != bob || send.Amount.String() != "10ukex" {
+				t.Fatalf("first send = %s to %s of %s, want %s to %s of 10ukex", send.FromAddress, send.ToAddress, send.Amount, alice, bob)
+			}
+		})
+	}
+}
+
+func TestIndexedTxRosetta(t *testing.T) {
+	var tx indexedTx
+	err := json.Unmarshal([]byte(`{
+		"hash": "ABCD",
+		"tx_result": {
+			"code": 5,
+			"gas_used": "1200",
+			"events": [
+				{"type": "message", "attributes": [{"key": "sender", "value": "kira1a"}]},
+				{"type": "transfer", "attributes": [
+					{"key": "recipient", "value": "kira1fee"},
+					{"key": "sender", "value": "kira1a"},
+					{"key": "amount", "value": "100ukex"}
+				]},
+				{"type": "transfer", "attributes": [
+					{"key": "recipient", "value": "kira1b"},
+					{"key": "sender", "value": "kira1a"},
+					{"key": "amount", "value": "5samolean,7ukex"}
+				]},
+				{"type": "transfer", "attributes": [{"key": "amount", "value": "1ukex"}]}
+			]
+		}
+	}`), &tx)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	transaction := tx.rosetta()
+
+	if transaction.TransactionIdentifier.Hash != "ABCD" || transaction.Metadata["code"] != 5 || transaction.Metadata["gas_used"] != "1200" {
+		t.Fatalf("transaction = %+v, want ABCD with code 5 and 1200 gas used", transaction)
+	}
+
+	want := []struct {
+		address string
+		value   string
+		denom   string
+	}{
+		{"kira1a", "-100", "ukex"},
+		{"kira1fee", "100", "ukex"},
+		{"kira1a", "-5", "samolean"},
+		{"kira1b", "5", "samolean"},
+		{"kira1a", "-7", "ukex"},
+		{"kira1b", "7", "ukex"},
+	}
+
+	if len(transaction.Operations) != len(want) {
+		t.Fatalf("%d operations, want %d", len(transaction.Operations), len(want))
+	}
+
+	for i, operation := range transaction.Operations {
+		if operation.OperationIdentifier.Index != int64(i) || operation.Status != rosettaStatusSuccess ||
+			operation.Account.Address != want[i].address || operation.Amount.Value != want[i].value ||
+			operation.Amount.Currency.Symbol != want[i].denom {
+			t.Fatalf("operation %d = %+v %+v %+v, want %+v", i, operation, operation.Account, operation.Amount, want[i])
+		}
+
+		// every credit points at its debit
+		if i%2 == 1 && (len(operation.RelatedOperations) != 1 || operation.RelatedOperations[0].Index != int64(i-1)) {
+			t.Fatalf("credit %d relates to %v, want %d", i, operation.RelatedOperations, i-1)
+		}
+	}
+}
+
+func TestRosettaConstruction(t *testing.T) {
+	g := newTestRosetta(t)
+
+	key := secp256k1.GenPrivKey()
+	publicKey := types.PublicKey{HexBytes: hex.EncodeToString(key.PubKey().Bytes()), CurveType: rosettaCurveSecp256k1}
+	recipient := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String()
+
+	var derived types.ConstructionDeriveResponse
+	if err := rosettaCall(t, g, "/construction/derive", map[string]interface{}{"public_key": publicKey}, &derived); err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+
+	sender := derived.AccountIdentifier.Address
+	if sender != sdk.AccAddress(key.PubKey().Address()).String() {
+		t.Fatalf("derived %s, want the address of the key", sender)
+	}
+
+	operations := []types.Operation{transfer(0, sender, "-10"), transfer(1, recipient, "10")}
+
+	var payloads types.ConstructionPayloadsResponse
+	err := rosettaCall(t, g, "/construction/payloads", map[string]interface{}{
+		"operations":  operations,
+		"public_keys": []types.PublicKey{publicKey},
+		"metadata": map[string]interface{}{
+			"account_number": "7",
+			"sequence":       "3",
+			"chain_id":       rosettaTestNetwork,
+			"gas_limit":      "200000",
+			"fee":            "100ukex",
+			"memo":           "rosetta",
+		},
+	}, &payloads)
+	if err != nil {
+		t.Fatalf("payloads: %v", err)
+	}
+
+	if len(payloads.Payloads) != 1 || payloads.Payloads[0].AccountIdentifier.Address != sender || payloads.Payloads[0].SignatureType != rosettaSignatureEcdsa {
+		t.Fatalf("payloads = %+v, want one ecdsa payload for %s", payloads.Payloads, sender)
+	}
+
+	var unsigned types.ConstructionParseResponse
+	if err = rosettaCall(t, g, "/construction/parse", map[string]interface{}{"transaction": payloads.UnsignedTransaction}, &unsigned); err != nil {
+		t.Fatalf("parse unsigned: %v", err)
+	}
+
+	if len(unsigned.Operations) != 2 || unsigned.Operations[0].Amount.Value != "-10" || unsigned.Operations[1].Account.Address != recipient {
+		t.Fatalf("parsed operations = %+v, want the transfer of 10 to %s", unsigned.Operations, recipient)
+	}
+	if unsigned.Metadata["fee"] != "100ukex" || unsigned.Metadata["gas_limit"] != "200000" || unsigned.Metadata["memo"] != "rosetta" {
+		t.Fatalf("parsed metadata = %v", unsigned.Metadata)
+	}
+	if len(unsigned.AccountIdentifierSigners) != 0 {
+		t.Fatalf("unsigned transaction has signers %v", unsigned.AccountIdentifierSigners)
+	}
+
+	// combine only attaches the signature, the node verifies it on broadcast
+	digest, err := hex.DecodeString(payloads.Payloads[0].HexBytes)
+	if err != nil || len(digest) != 32 {
+		t.Fatalf("payload %q is not a sha256 digest", payloads.Payloads[0].HexBytes)
+	}
+
+	signature, err := key.Sign(digest)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	var combined types.ConstructionCombineResponse
+	err = rosettaCall(t, g, "/construction/combine", map[string]interface{}{
+		"unsigned_transaction": payloads.UnsignedTransaction,
+		"signatures": []types.Signature{{
+			SigningPayload: payloads.Payloads[0],
+			PublicKey:      publicKey,
+			SignatureType:  rosettaSignatureEcdsa,
+			HexBytes:       hex.EncodeToString(signature),
+		}},
+	}, &combined)
+	if err != nil {
+		t.Fatalf("combine: %v", err)
+	}
+
+	var signed types.ConstructionParseResponse
+	if err = rosettaCall(t, g, "/construction/parse", map[string]interface{}{"transaction": combined.SignedTransaction, "signed": true}, &signed); err != nil {
+		t.Fatalf("parse signed: %v", err)
+	}
+
+	if len(signed.AccountIdentifierSigners) != 1 || signed.AccountIdentifierSigners[0].Address != sender {
+		t.Fatalf("signers = %v, want %s", signed.AccountIdentifierSigners, sender)
+	}
+
+	var hash types.TransactionIdentifierResponse
+	if err = rosettaCall(t, g, "/construction/hash", map[string]interface{}{"signed_transaction": combined.SignedTransaction}, &hash); err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	txBytes, _ := hex.DecodeString(combined.SignedTransaction)
+	if hash.TransactionIdentifier.Hash != txHash(txBytes) {
+		t.Fatalf("hash = %s, want %s", hash.TransactionIdentifier.Hash, txHash(txBytes))
+	}
+
+	// a signature of another key is missing
+	other := secp256k1.GenPrivKey()
+	err = rosettaCall(t, g, "/construction/combine", map[string]interface{}{
+		"unsigned_transaction": payloads.UnsignedTransaction,
+		"signatures": []types.Signature{{
+			PublicKey: types.PublicKey{HexBytes: hex.EncodeToString(other.PubKey().Bytes()), CurveType: rosettaCurveSecp256k1},
+			HexBytes:  hex.EncodeToString(signature),
+		}},
+	}, &combined)
+	if rosettaErr, ok := RosettaErrorResponse(err); !ok || rosettaErr.Code != ErrRosettaInvalidRequest.Code {
+		t.Fatalf("combine with the signature of another key = %v, want code %d", err, ErrRosettaInvalidRequest.Code)
+	}
+}
+
+func TestRosettaHandle(t *testing.T) {
+	g := newTestRosetta(t)
+
+	var list types.NetworkListResponse
+	if err := rosettaCall(t, g, "/network/list", map[string]interface{}{}, &list); err != nil {
+		t.Fatalf("network list: %v", err)
+	}
+	if len(list.NetworkIdentifiers) != 1 || list.NetworkIdentifiers[0].Network != rosettaTestNetwork {
+		t.Fatalf("networks = %v, want %s", list.NetworkIdentifiers, rosettaTestNetwork)
+	}
+
+	err := rosettaCall(t, g, "/construction/derive", map[string]interface{}{
+		"network_identifier": types.NetworkIdentifier{Blockchain: RosettaBlockchain, Network: "other-1"},
+	}, &struct{}{})
+	if rosettaErr, ok := RosettaErrorResponse(err); !ok || rosettaErr.Code != ErrRosettaInvalidNetwork.Code {
+		t.Fatalf("request for another network = %v, want code %d", err, ErrRosettaInvalidNetwork.Code)
+	}
+
+	err = rosettaCall(t, g, "/construction/derive", map[string]interface{}{
+		"public_key": types.PublicKey{HexBytes: "00", CurveType: "edwards25519"},
+	}, &struct{}{})
+	if rosettaErr, ok := RosettaErrorResponse(err); !ok || rosettaErr.Code != ErrRosettaInvalidRequest.Code {
+		t.Fatalf("unsupported curve = %v, want code %d", err, ErrRosettaInvalidRequest.Code)
+	}
+
+	if err = rosettaCall(t, g, "/unknown", map[string]interface{}{}, &struct{}{}); !errors.Is(err, ErrRouteNotFound) {
+		t.Fatalf("unknown route = %v, want %v", err, ErrRouteNotFound)
+	}
+}
@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	RateClassDefault   = "default"
+	RateClassAggregate = "aggregate"
+	RateClassTx        = "tx"
+)
+
+var (
+	ErrRouteNotFound    = apierror.New(apierror.NotFound, "route not found")
+	ErrMethodNotAllowed = apierror.New(apierror.MethodNotAllowed, "method not allowed")
+)
+
+type RouteParams map[string]string
+
+type RouteHandler func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error)
+
+// Route declares a single endpoint. Path segments written as {name} capture one
+// segment, a trailing {name...} captures the rest of the path. An empty Method
+// accepts any HTTP method. Immutable responses survive new blocks and only
+// expire by CacheTTL. Historical routes accept a height parameter that pins
+// their gRPC queries to that block. Timeout overrides the gateway default.
+type Route struct {
+	Method     string
+	Path       string
+	Handler    RouteHandler
+	CacheTTL   time.Duration
+	Immutable  bool
+	Historical bool
+	RateClass  string
+	Timeout    time.Duration
+
+	segments []string
+}
+
+type Router struct {
+	routes []*Route
+}
+
+func NewRouter(routes ...Route) (*Router, error) {
+	router := &Router{}
+
+	for _, route := range routes {
+		if err := router.Add(route); err != nil {
+			return nil, err
+		}
+	}
+
+	return router, nil
+}
+
+func (r *Router) Add(route Route) error {
+	if route.Handler == nil {
+		return fmt.Errorf("route %s %s has no handler", route.Method, route.Path)
+	}
+
+	route.segments = splitPath(route.Path)
+
+	for i, segment := range route.segments {
+		if isWildcard(segment) && i != len(route.segments)-1 {
+			return fmt.Errorf("route %s: wildcard must be the last segment", route.Path)
+		}
+	}
+
+	if route.RateClass == "" {
+		route.RateClass = RateClassDefault
+	}
+
+	r.routes = append(r.routes, &route)
+
+	return nil
+}
+
+// Match picks the most specific route for the path. Literal segments win over
+// parameters and parameters win over wildcards, so /kira/gov/proposals is
+// preferred to /kira/gov/{path...} regardless of declaration order.
+func (r *Router) Match(method, path string) (*Route, RouteParams, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	segments := splitPath(path)
+
+	var (
+		best       *Route
+		bestParams RouteParams
+		bestScore  = -1
+		pathFound  bool
+	)
+
+	for _, route := range r.routes {
+		params, score, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+
+		pathFound = true
+
+		if route.Method != "" && !strings.EqualFold(route.Method, method) {
+			continue
+		}
+
+		if score > bestScore {
+			best, bestParams, bestScore = route, params, score
+		}
+	}
+
+	if best == nil {
+		if pathFound {
+			return nil, nil, fmt.Errorf("%w: %s %s", ErrMethodNotAllowed, method, path)
+		}
+		return nil, nil, fmt.Errorf("%w: %s", ErrRouteNotFound, path)
+	}
+
+	return best, bestParams, nil
+}
+
+func (route *Route) match(segments []string) (RouteParams, int, bool) {
+	params := RouteParams{}
+	score := 0
+
+	for i, pattern := range route.segments {
+		if isWildcard(pattern) {
+			if i >= len(segments) {
+				return nil, 0, false
+			}
+			params[pattern[1:len(pattern)-4]] = strings.Join(segments[i:], "/")
+			return params, score, true
+		}
+
+		if i >= len(segments) {
+			return nil, 0, false
+		}
+
+		if isParam(pattern) {
+			params[pattern[1:len(pattern)-1]] = segments[i]
+			score += 1
+			continue
+		}
+
+		if pattern != segments[i] {
+			return nil, 0, false
+		}
+
+		score += 2
+	}
+
+	if len(segments) != len(route.segments) {
+		return nil, 0, false
+	}
+
+	return params, score, true
+}
+
+// Timeouts bounds requests by route path or method. Routes holds the
+// configured overrides, then the timeout declared by the route applies, then
+// Default. A zero timeout leaves the caller's deadline alone.
+type Timeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+func NewTimeouts(defaultSeconds int, routes map[string]int) Timeouts {
+	timeouts := Timeouts{
+		Default: time.Duration(defaultSeconds) * time.Second,
+		Routes:  make(map[string]time.Duration, len(routes)),
+	}
+
+	for route, seconds := range routes {
+		timeouts.Routes[route] = time.Duration(seconds) * time.Second
+	}
+
+	return timeouts
+}
+
+func (t Timeouts) For(route string, declared time.Duration) time.Duration {
+	if timeout, ok := t.Routes[route]; ok {
+		return timeout
+	}
+
+	if declared > 0 {
+		return declared
+	}
+
+	return t.Default
+}
+
+// withTimeout is context.WithTimeout that leaves ctx unbounded for a zero
+// timeout, an earlier deadline of ctx always wins.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+
+	return strings.Split(path, "/")
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func isWildcard(segment string) bool {
+	return isParam(segment) && strings.HasSuffix(segment, "...}")
+}
@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func nopHandler(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+	return nil, nil
+}
+
+func TestRouterMatch(t *testing.T) {
+	router, err := NewRouter(
+		Route{Path: "/kira/{path...}", Handler: nopHandler},
+		Route{Method: http.MethodPost, Path: "/kira/txs", Handler: nopHandler},
+		Route{Method: http.MethodGet, Path: "/kira/txs/{hash}", Handler: nopHandler},
+		Route{Method: http.MethodGet, Path: "/kira/txs/pending", Handler: nopHandler},
+		Route{Method: http.MethodGet, Path: "/blocks/{height}/transactions", Handler: nopHandler},
+		Route{Method: http.MethodGet, Path: "/status", Handler: nopHandler},
+	)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		params RouteParams
+		err    error
+	}{
+		{name: "literal over wildcard", method: http.MethodPost, path: "/kira/txs", route: "/kira/txs"},
+		{name: "wildcard when the literal has another method", method: http.MethodGet, path: "/kira/txs", route: "/kira/{path...}", params: RouteParams{"path": "txs"}},
+		{name: "param over wildcard", method: http.MethodGet, path: "/kira/txs/0xabc", route: "/kira/txs/{hash}", params: RouteParams{"hash": "0xabc"}},
+		{name: "literal over param", method: http.MethodGet, path: "/kira/txs/pending", route: "/kira/txs/pending"},
+		{name: "wildcard takes the rest", method: http.MethodGet, path: "/kira/gov/proposals/1", route: "/kira/{path...}", params: RouteParams{"path": "gov/proposals/1"}},
+		{name: "param in the middle", method: http.MethodGet, path: "/blocks/10/transactions", route: "/blocks/{height}/transactions", params: RouteParams{"height": "10"}},
+		{name: "trailing slash", method: http.MethodGet, path: "/status/", route: "/status"},
+		{name: "empty method is GET", path: "/status", route: "/status"},
+		{name: "method is case insensitive", method: "get", path: "/status", route: "/status"},
+		{name: "wildcard needs a segment", method: http.MethodGet, path: "/kira", err: ErrRouteNotFound},
+		{name: "unknown path", method: http.MethodGet, path: "/unknown", err: ErrRouteNotFound},
+		{name: "extra segment", method: http.MethodGet, path: "/blocks/10/transactions/1", err: ErrRouteNotFound},
+		{name: "known path with another method", method: http.MethodPost, path: "/status", err: ErrMethodNotAllowed},
+		{name: "param route with another method", method: http.MethodDelete, path: "/blocks/10/transactions", err: ErrMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route, params, err := router.Match(test.method, test.path)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Match error = %v, want %v", err, test.err)
+			}
+			if test.err != nil {
+				return
+			}
+
+			if route.Path != test.route {
+				t.Fatalf("Match = %s, want %s", route.Path, test.route)
+			}
+
+			for key, value := range test.params {
+				if params[key] != value {
+					t.Fatalf("param %s = %q, want %q", key, params[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterAdd(t *testing.T) {
+	if _, err := NewRouter(Route{Path: "/kira/{path...}/tail", Handler: nopHandler}); err == nil {
+		t.Fatal("wildcard before the last segment accepted")
+	}
+
+	if _, err := NewRouter(Route{Path: "/kira"}); err == nil {
+		t.Fatal("route without handler accepted")
+	}
+
+	router, err := NewRouter(Route{Path: "/kira", Handler: nopHandler})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	if router.routes[0].RateClass != RateClassDefault {
+		t.Fatalf("rate class = %q, want %q", router.routes[0].RateClass, RateClassDefault)
+	}
+}
+
+func TestTimeoutsFor(t *testing.T) {
+	timeouts := NewTimeouts(30, map[string]int{"/dashboard": 60, "/kira/status": 0})
+
+	tests := []struct {
+		route    string
+		declared time.Duration
+		want     time.Duration
+	}{
+		{route: "/dashboard", declared: 5 * time.Second, want: time.Minute},
+		{route: "/kira/status", declared: 5 * time.Second, want: 0},
+		{route: "/blocks", declared: 5 * time.Second, want: 5 * time.Second},
+		{route: "/blocks", want: 30 * time.Second},
+	}
+
+	for _, test := range tests {
+		if got := timeouts.For(test.route, test.declared); got != test.want {
+			t.Fatalf("For(%s, %s) = %s, want %s", test.route, test.declared, got, test.want)
+		}
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := withTimeout(context.Background(), 0)
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("zero timeout set a deadline")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Fatal("cancel did not end the context")
+	}
+
+	ctx, cancel = withTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Fatalf("deadline = %s, %v, want within a minute", deadline, ok)
+	}
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	ctx, cancel = withTimeout(parent, time.Minute)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); time.Until(deadline) > time.Second {
+		t.Fatalf("deadline in %s, want the earlier deadline of the caller", time.Until(deadline))
+	}
+}
+
+func TestCosmosHandle(t *testing.T) {
+	type seen struct {
+		deadline time.Duration
+		height   int64
+		params   RouteParams
+	}
+
+	handler := func(ctx context.Context, req types.InboundRequest, params RouteParams) (interface{}, error) {
+		var result seen
+		if deadline, ok := ctx.Deadline(); ok {
+			result.deadline = time.Until(deadline)
+		}
+		result.height, _ = blockHeightFromContext(ctx)
+		result.params = params
+
+		return result, nil
+	}
+
+	router, err := NewRouter(
+		Route{Method: http.MethodGet, Path: "/kira/accounts/{address}", Historical: true, Handler: handler},
+		Route{Method: http.MethodGet, Path: "/kira/status", Timeout: 50 * time.Millisecond, Handler: handler},
+		Route{Method: http.MethodGet, Path: "/dashboard", Timeout: 50 * time.Millisecond, Handler: handler},
+		Route{Method: http.MethodGet, Path: "/blocks", Handler: handler},
+	)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	g := &CosmosGateway{
+		BaseGateway: &BaseGateway{
+			rateLimit: NewRateLimiter(1000),
+			retry:     NewRetrier(types.RetryConfig{}),
+		},
+		router:   router,
+		timeouts: NewTimeouts(0, map[string]int{"/dashboard": 60}),
+	}
+
+	handle := func(method, path string, payload map[string]interface{}) (seen, error) {
+		data, err := json.Marshal(types.InboundRequest{Method: method, Path: path, Payload: payload})
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+
+		result, err := g.Handle(context.Background(), data, types.RequestMetadata{})
+		if err != nil {
+			return seen{}, err
+		}
+
+		return result.(seen), nil
+	}
+
+	if _, err := handle(http.MethodGet, "/unknown", nil); !errors.Is(err, ErrRouteNotFound) {
+		t.Fatalf("unknown path = %v, want %v", err, ErrRouteNotFound)
+	}
+
+	if _, err := handle(http.MethodPost, "/kira/status", nil); !errors.Is(err, ErrMethodNotAllowed) {
+		t.Fatalf("wrong method = %v, want %v", err, ErrMethodNotAllowed)
+	}
+
+	result, err := handle(http.MethodGet, "/kira/status", nil)
+	if err != nil || result.deadline <= 0 || result.deadline > 50*time.Millisecond {
+		t.Fatalf("route timeout = %s, %v, want up to 50ms", result.deadline, err)
+	}
+
+	result, err = handle(http.MethodGet, "/dashboard", nil)
+	if err != nil || result.deadline <= 50*time.Millisecond || result.deadline > time.Minute {
+		t.Fatalf("configured timeout = %s, %v, want the minute of the configuration", result.deadline, err)
+	}
+
+	result, err = handle(http.MethodGet, "/blocks", nil)
+	if err != nil || result.deadline != 0 {
+		t.Fatalf("route without timeout got a deadline in %s, %v", result.deadline, err)
+	}
+
+	result, err = handle(http.MethodGet, "/kira/accounts/kira1abc", map[string]interface{}{"height": "42"})
+	if err != nil || result.height != 42 || result.params["address"] != "kira1abc" {
+		t.Fatalf("historical route = %+v, %v, want pinned to 42 for kira1abc", result, err)
+	}
+
+	if _, err = handle(http.MethodGet, "/kira/accounts/kira1abc", map[string]interface{}{"height": "-1"}); apierror.From(err).Code != apierror.InvalidArgument {
+		t.Fatalf("invalid height = %v, want %s", err, apierror.InvalidArgument)
+	}
+
+	result, err = handle(http.MethodGet, "/blocks", map[string]interface{}{"height": "42"})
+	if err != nil || result.height != 0 {
+		t.Fatalf("height pinned the route that is not historical to %d, %v", result.height, err)
+	}
+}
@@ -0,0 +1,306 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	sekaitypes "github.com/KiraCore/sekai/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	defaultGasAdjustment = 1.3
+	// sekai runs transactions on a zero gas meter, so the gas used is not a
+	// useful limit on its own
+	defaultSimulateMinGas = 200000
+)
+
+// simulateTx runs a transaction through the node without broadcasting it and
+// prices it. The tx is base64 protobuf or the JSON of sekaid --generate-only.
+// Unsigned transactions get empty signatures, and the current sequence of
+// their signers when they have no signer infos. A fee below what the chain
+// asks for is raised for the simulation, the ante handler would reject it
+// before the messages run.
+func (g *CosmosGateway) simulateTx(ctx context.Context, req types.InboundRequest) (*types.SimulateTxResponse, error) {
+	txBytes, err := g.simulateTxBytes(req.Payload["tx"])
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Invalid transaction", zap.Error(err))
+		return nil, apierror.Wrap(apierror.InvalidArgument, err)
+	}
+
+	tx, err := g.txConfig.TxDecoder()(txBytes)
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Invalid transaction", zap.Error(err))
+		return nil, apierror.Wrap(apierror.InvalidArgument, err)
+	}
+
+	response, err := g.txFee(ctx, tx.GetMsgs())
+	if err != nil {
+		return nil, err
+	}
+
+	txBytes, err = g.simulationTx(ctx, tx, txBytes, response.Fee)
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Failed to prepare transaction", zap.Error(err))
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{"tx_bytes": base64.StdEncoding.EncodeToString(txBytes)})
+	if err != nil {
+		return nil, err
+	}
+
+	gatewayReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "/cosmos/tx/v1beta1/simulate", bytes.NewReader(body))
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Create request failed", zap.Error(err))
+		return nil, err
+	}
+
+	respBody, err := g.grpcProxy.ServeGRPC(gatewayReq)
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Serve request failed", zap.Error(err))
+		return nil, err
+	}
+
+	var simulation struct {
+		GasInfo struct {
+			GasWanted uint64 `json:"gasWanted,string"`
+			GasUsed   uint64 `json:"gasUsed,string"`
+		} `json:"gasInfo"`
+		Result interface{} `json:"result"`
+	}
+
+	if err = json.Unmarshal(respBody, &simulation); err != nil {
+		logger.Logger.Error("[simulate-transaction] Invalid response format", zap.Error(err))
+		return nil, err
+	}
+
+	response.GasUsed = simulation.GasInfo.GasUsed
+	response.GasWanted = simulation.GasInfo.GasWanted
+	response.GasAdjustment, response.GasLimit = gasLimit(g.config.Simulate, simulation.GasInfo.GasUsed)
+	response.Result = simulation.Result
+
+	return response, nil
+}
+
+// gasLimit is gasUsed raised by the configured adjustment, and at least the
+// configured minimum gas.
+func gasLimit(config types.SimulateConfig, gasUsed uint64) (float64, uint64) {
+	adjustment := config.GasAdjustment
+	if adjustment <= 0 {
+		adjustment = defaultGasAdjustment
+	}
+
+	minGas := config.MinGas
+	if minGas == 0 {
+		minGas = defaultSimulateMinGas
+	}
+
+	limit := uint64(math.Ceil(float64(gasUsed) * adjustment))
+	if limit < minGas {
+		limit = minGas
+	}
+
+	return adjustment, limit
+}
+
+// simulateTxBytes reads the tx parameter: a base64 string, or an object holding
+// the JSON encoding of the transaction.
+func (g *CosmosGateway) simulateTxBytes(value interface{}) ([]byte, error) {
+	switch value := value.(type) {
+	case string:
+		return base64.StdEncoding.DecodeString(value)
+	case map[string]interface{}:
+		jsonData, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := g.txConfig.TxJSONDecoder()(jsonData)
+		if err != nil {
+			return nil, err
+		}
+
+		return g.txConfig.TxEncoder()(tx)
+	}
+
+	return nil, apierror.New(apierror.InvalidArgument, "[simulate-transaction] tx required")
+}
+
+// txFee prices msgs with the current network properties and execution fees.
+func (g *CosmosGateway) txFee(ctx context.Context, msgs []sdk.Msg) (*types.SimulateTxResponse, error) {
+	prefixes, err := g.customPrefixes(ctx)
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Failed to get default denom", zap.Error(err))
+		return nil, err
+	}
+
+	properties, err := g.networkProperties(ctx)
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Failed to get network properties", zap.Error(err))
+		return nil, err
+	}
+
+	fees, err := g.executionFees(ctx)
+	if err != nil {
+		logger.Logger.Error("[simulate-transaction] Failed to get execution fees", zap.Error(err))
+		return nil, err
+	}
+
+	return msgsFee(msgs, fees,
+		properties.(*types.NetworkPropertiesResponse).Properties.MinTxFee,
+		properties.(*types.NetworkPropertiesResponse).Properties.MaxTxFee,
+		prefixes.DefaultDenom,
+	)
+}
+
+// msgsFee prices msgs the way the sekai ante handler does: every message costs
+// the larger of its execution and failure fee, and the total is raised to the
+// network minimum fee. A total above the network maximum is refused.
+func msgsFee(msgs []sdk.Msg, fees map[string]msgExecutionFee, minTxFee, maxTxFee, denom string) (*types.SimulateTxResponse, error) {
+	response := &types.SimulateTxResponse{
+		MinTxFee: minTxFee,
+		MaxTxFee: maxTxFee,
+		Messages: []types.SimulateMsgFee{},
+	}
+
+	total := sdk.ZeroInt()
+	for _, msg := range msgs {
+		msgType := sekaitypes.MsgType(msg)
+		fee := fees[msgType]
+
+		response.Messages = append(response.Messages, types.SimulateMsgFee{
+			TypeURL:      sdk.MsgTypeURL(msg),
+			Type:         msgType,
+			ExecutionFee: strconv.FormatUint(fee.ExecutionFee, 10),
+			FailureFee:   strconv.FormatUint(fee.FailureFee, 10),
+		})
+
+		total = total.Add(sdk.NewIntFromUint64(max(fee.ExecutionFee, fee.FailureFee)))
+	}
+
+	if minFee, ok := sdk.NewIntFromString(minTxFee); ok && total.LT(minFee) {
+		total = minFee
+	}
+
+	if maxFee, ok := sdk.NewIntFromString(maxTxFee); ok && total.GT(maxFee) {
+		return nil, apierror.Newf(apierror.FailedPrecondition, "[simulate-transaction] Fee %s%s is above the network maximum %s", total, denom, maxTxFee)
+	}
+
+	response.Fee = sdk.NewCoins(sdk.NewCoin(denom, total))
+
+	return response, nil
+}
+
+type msgExecutionFee struct {
+	ExecutionFee uint64 `json:"executionFee,string"`
+	FailureFee   uint64 `json:"failureFee,string"`
+}
+
+// executionFees returns the execution fees set by governance by message type,
+// types without one are free.
+func (g *CosmosGateway) executionFees(ctx context.Context) (map[string]msgExecutionFee, error) {
+	gatewayReq, err := http.NewRequestWithContext(ctx, http.MethodGet, "/kira/gov/all_execution_fees", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := g.grpcProxy.ServeGRPC(gatewayReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Fees []struct {
+			TransactionType string `json:"transactionType"`
+			msgExecutionFee
+		} `json:"fees"`
+	}
+
+	if err = json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	fees := make(map[string]msgExecutionFee, len(result.Fees))
+	for _, fee := range result.Fees {
+		fees[fee.TransactionType] = fee.msgExecutionFee
+	}
+
+	return fees, nil
+}
+
+// simulationTx completes txBytes so the node can simulate it: one signer info
+// per signer, one signature per signer info and at least fee.
+func (g *CosmosGateway) simulationTx(ctx context.Context, tx sdk.Tx, txBytes []byte, fee sdk.Coins) ([]byte, error) {
+	var raw txtypes.TxRaw
+	if err := raw.Unmarshal(txBytes); err != nil {
+		return nil, apierror.Wrap(apierror.InvalidArgument, err)
+	}
+
+	var authInfo txtypes.AuthInfo
+	if err := authInfo.Unmarshal(raw.AuthInfoBytes); err != nil {
+		return nil, apierror.Wrap(apierror.InvalidArgument, err)
+	}
+
+	if len(authInfo.SignerInfos) == 0 {
+		sigTx, ok := tx.(authsigning.SigVerifiableTx)
+		if !ok {
+			return nil, apierror.New(apierror.InvalidArgument, "[simulate-transaction] Invalid transaction type")
+		}
+
+		for _, signer := range sigTx.GetSigners() {
+			authInfo.SignerInfos = append(authInfo.SignerInfos, &txtypes.SignerInfo{
+				ModeInfo: &txtypes.ModeInfo{
+					Sum: &txtypes.ModeInfo_Single_{Single: &txtypes.ModeInfo_Single{Mode: signing.SignMode_SIGN_MODE_DIRECT}},
+				},
+				Sequence: g.accountSequence(ctx, signer.String()),
+			})
+		}
+	}
+
+	if authInfo.Fee == nil {
+		authInfo.Fee = &txtypes.Fee{}
+	}
+
+	if !sdk.Coins(authInfo.Fee.Amount).IsAllGTE(fee) {
+		authInfo.Fee.Amount = fee
+	}
+
+	authInfoBytes, err := authInfo.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	raw.AuthInfoBytes = authInfoBytes
+	for len(raw.Signatures) < len(authInfo.SignerInfos) {
+		raw.Signatures = append(raw.Signatures, []byte{})
+	}
+
+	return raw.Marshal()
+}
+
+// accountSequence is the sequence of address, 0 for an account the chain does
+// not know yet, which the simulation then reports.
+func (g *CosmosGateway) accountSequence(ctx context.Context, address string) uint64 {
+	accountInfo, err := g.account(ctx, address)
+	if err != nil {
+		return 0
+	}
+
+	sequence, _ := strconv.ParseUint(accountInfo.Sequence, 10, 64)
+
+	return sequence
+}
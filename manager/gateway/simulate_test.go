@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestMsgsFee(t *testing.T) {
+	send := &bank.MsgSend{}
+	multiSend := &bank.MsgMultiSend{}
+
+	fees := map[string]msgExecutionFee{
+		bank.TypeMsgSend:      {ExecutionFee: 100, FailureFee: 40},
+		bank.TypeMsgMultiSend: {ExecutionFee: 10, FailureFee: 500},
+	}
+
+	tests := []struct {
+		name     string
+		msgs     []sdk.Msg
+		minTxFee string
+		maxTxFee string
+		fee      string
+		err      apierror.Code
+	}{
+		{name: "execution fee over failure fee", msgs: []sdk.Msg{send}, minTxFee: "1", maxTxFee: "10000", fee: "100ukex"},
+		{name: "failure fee over execution fee", msgs: []sdk.Msg{multiSend}, minTxFee: "1", maxTxFee: "10000", fee: "500ukex"},
+		{name: "fees add up", msgs: []sdk.Msg{send, send, multiSend}, minTxFee: "1", maxTxFee: "10000", fee: "700ukex"},
+		{name: "raised to the minimum", msgs: []sdk.Msg{send}, minTxFee: "250", maxTxFee: "10000", fee: "250ukex"},
+		{name: "no execution fee", msgs: []sdk.Msg{&bank.MsgUpdateParams{}}, minTxFee: "100", maxTxFee: "10000", fee: "100ukex"},
+		{name: "maximum is allowed", msgs: []sdk.Msg{send, multiSend}, minTxFee: "1", maxTxFee: "600", fee: "600ukex"},
+		{name: "above the maximum", msgs: []sdk.Msg{send, multiSend}, minTxFee: "1", maxTxFee: "599", err: apierror.FailedPrecondition},
+		{name: "properties not set", msgs: []sdk.Msg{send}, fee: "100ukex"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := msgsFee(test.msgs, fees, test.minTxFee, test.maxTxFee, "ukex")
+			if test.err != "" {
+				if apierror.From(err).Code != test.err {
+					t.Fatalf("msgsFee = %v, want %s", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("msgsFee: %v", err)
+			}
+
+			if response.Fee.String() != test.fee {
+				t.Fatalf("fee = %s, want %s", response.Fee, test.fee)
+			}
+
+			if len(response.Messages) != len(test.msgs) || response.MinTxFee != test.minTxFee || response.MaxTxFee != test.maxTxFee {
+				t.Fatalf("response = %+v, want %d messages between %s and %s", response, len(test.msgs), test.minTxFee, test.maxTxFee)
+			}
+		})
+	}
+
+	response, _ := msgsFee([]sdk.Msg{send}, fees, "1", "10000", "ukex")
+	if message := response.Messages[0]; message.TypeURL != sdk.MsgTypeURL(send) || message.Type != bank.TypeMsgSend ||
+		message.ExecutionFee != "100" || message.FailureFee != "40" {
+		t.Fatalf("message fee = %+v, want the fees of %s", message, bank.TypeMsgSend)
+	}
+}
+
+func TestGasLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     types.SimulateConfig
+		gasUsed    uint64
+		adjustment float64
+		limit      uint64
+	}{
+		{name: "default adjustment", gasUsed: 300000, adjustment: defaultGasAdjustment, limit: 390000},
+		{name: "rounded up", config: types.SimulateConfig{GasAdjustment: 1.5}, gasUsed: 200001, adjustment: 1.5, limit: 300002},
+		{name: "default minimum", gasUsed: 0, adjustment: defaultGasAdjustment, limit: defaultSimulateMinGas},
+		{name: "configured minimum", config: types.SimulateConfig{MinGas: 50000}, gasUsed: 10000, adjustment: defaultGasAdjustment, limit: 50000},
+		{name: "negative adjustment", config: types.SimulateConfig{GasAdjustment: -1}, gasUsed: 1000000, adjustment: defaultGasAdjustment, limit: 1300000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			adjustment, limit := gasLimit(test.config, test.gasUsed)
+			if adjustment != test.adjustment || limit != test.limit {
+				t.Fatalf("gasLimit = %v, %d, want %v, %d", adjustment, limit, test.adjustment, test.limit)
+			}
+		})
+	}
+}
+
+func TestSimulationTx(t *testing.T) {
+	signerInfo := &txtypes.SignerInfo{
+		ModeInfo: &txtypes.ModeInfo{
+			Sum: &txtypes.ModeInfo_Single_{Single: &txtypes.ModeInfo_Single{Mode: signing.SignMode_SIGN_MODE_DIRECT}},
+		},
+		Sequence: 3,
+	}
+
+	fee := sdk.NewCoins(sdk.NewInt64Coin("ukex", 100))
+
+	tests := []struct {
+		name string
+		fee  *txtypes.Fee
+		want string
+	}{
+		{name: "no fee", want: "100ukex"},
+		{name: "fee below", fee: &txtypes.Fee{Amount: sdk.NewCoins(sdk.NewInt64Coin("ukex", 10))}, want: "100ukex"},
+		{name: "other denom", fee: &txtypes.Fee{Amount: sdk.NewCoins(sdk.NewInt64Coin("stake", 1000))}, want: "100ukex"},
+		{name: "fee above is kept", fee: &txtypes.Fee{Amount: sdk.NewCoins(sdk.NewInt64Coin("ukex", 150))}, want: "150ukex"},
+	}
+
+	g := &CosmosGateway{}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authInfo := txtypes.AuthInfo{SignerInfos: []*txtypes.SignerInfo{signerInfo, signerInfo}, Fee: test.fee}
+			authInfoBytes, err := authInfo.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+
+			txBytes, err := (&txtypes.TxRaw{AuthInfoBytes: authInfoBytes}).Marshal()
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+
+			simulationBytes, err := g.simulationTx(context.Background(), nil, txBytes, fee)
+			if err != nil {
+				t.Fatalf("simulationTx: %v", err)
+			}
+
+			var raw txtypes.TxRaw
+			if err = raw.Unmarshal(simulationBytes); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			var simulated txtypes.AuthInfo
+			if err = simulated.Unmarshal(raw.AuthInfoBytes); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			if got := sdk.Coins(simulated.Fee.Amount).String(); got != test.want {
+				t.Fatalf("fee = %s, want %s", got, test.want)
+			}
+
+			// one empty signature per signer so that the node can count them
+			if len(raw.Signatures) != 2 || len(simulated.SignerInfos) != 2 || simulated.SignerInfos[0].Sequence != 3 {
+				t.Fatalf("%d signatures for %d signer infos, want 2 of each with the sequence kept", len(raw.Signatures), len(simulated.SignerInfos))
+			}
+		})
+	}
+}
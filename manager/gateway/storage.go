@@ -1,15 +1,16 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"github.com/KiraCore/sai-interx-manager/apierror"
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-service/service"
 	"github.com/spf13/cast"
 	"go.uber.org/zap"
-	"time"
 
 	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
 )
 
 type StorageGateway struct {
@@ -19,14 +20,14 @@ type StorageGateway struct {
 
 var _ types.Gateway = (*StorageGateway)(nil)
 
-func NewStorageGateway(ctx *service.Context, storage types.Storage, retryAttempts int, retryDelay time.Duration, rateLimit int) (*StorageGateway, error) {
+func NewStorageGateway(ctx *service.Context, storage types.Storage, retry types.RetryConfig, breakers *Breakers, rateLimit int) (*StorageGateway, error) {
 	return &StorageGateway{
-		BaseGateway: NewBaseGateway(ctx, retryAttempts, retryDelay, rateLimit),
+		BaseGateway: NewBaseGateway(ctx, retry, breakers, rateLimit),
 		storage:     storage,
 	}, nil
 }
 
-func (g *StorageGateway) Handle(data []byte) (interface{}, error) {
+func (g *StorageGateway) Handle(ctx context.Context, data []byte, metadata types.RequestMetadata) (interface{}, error) {
 	var req struct {
 		Method string                 `json:"method"`
 		Params map[string]interface{} `json:"params"`
@@ -37,8 +38,9 @@ func (g *StorageGateway) Handle(data []byte) (interface{}, error) {
 		return nil, err
 	}
 
-	return g.retry.Do(func() (interface{}, error) {
-		if err := g.rateLimit.Wait(g.context.Context); err != nil {
+	// only reads are safe to repeat
+	return g.retry.For(req.Method, req.Method == "read").Do(ctx, func() (interface{}, error) {
+		if err := g.rateLimit.Wait(ctx); err != nil {
 			return nil, err
 		}
 		switch req.Method {
@@ -67,7 +69,7 @@ func (g *StorageGateway) Handle(data []byte) (interface{}, error) {
 			return g.storage.Delete(cast.ToString(req.Params["collection"]), criteria)
 		}
 
-		err := errors.New("method not found")
+		err := apierror.Newf(apierror.NotFound, "method not found: %s", req.Method)
 		logger.Logger.Error("StorageGateway - Handle", zap.Error(err))
 
 		return nil, err
@@ -77,3 +79,57 @@ func (g *StorageGateway) Handle(data []byte) (interface{}, error) {
 func (g *StorageGateway) Close() {
 
 }
+
+// breakerStorage puts the storage service behind a circuit breaker shared by
+// every gateway using it.
+type breakerStorage struct {
+	storage types.Storage
+	breaker *CircuitBreaker
+}
+
+var _ types.Storage = (*breakerStorage)(nil)
+
+func newBreakerStorage(storage types.Storage, breaker *CircuitBreaker) *breakerStorage {
+	return &breakerStorage{storage: storage, breaker: breaker}
+}
+
+func (s *breakerStorage) call(fn func() (*adapter.SaiStorageResponse, error)) (*adapter.SaiStorageResponse, error) {
+	if err := s.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	result, err := fn()
+	s.breaker.Done(err)
+
+	return result, err
+}
+
+func (s *breakerStorage) Create(collection string, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return s.call(func() (*adapter.SaiStorageResponse, error) {
+		return s.storage.Create(collection, document)
+	})
+}
+
+func (s *breakerStorage) Read(collection string, criteria map[string]interface{}, options *adapter.Options, fields []string) (*adapter.SaiStorageResponse, error) {
+	return s.call(func() (*adapter.SaiStorageResponse, error) {
+		return s.storage.Read(collection, criteria, options, fields)
+	})
+}
+
+func (s *breakerStorage) Upsert(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return s.call(func() (*adapter.SaiStorageResponse, error) {
+		return s.storage.Upsert(collection, criteria, document)
+	})
+}
+
+func (s *breakerStorage) Update(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return s.call(func() (*adapter.SaiStorageResponse, error) {
+		return s.storage.Update(collection, criteria, document)
+	})
+}
+
+func (s *breakerStorage) Delete(collection string, criteria map[string]interface{}) (*adapter.SaiStorageResponse, error) {
+	return s.call(func() (*adapter.SaiStorageResponse, error) {
+		return s.storage.Delete(collection, criteria)
+	})
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cast"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/auth"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// handleAuth serves /auth: POST /auth/challenge issues the message to sign,
+// POST /auth/verify trades the signed message for a session token, and
+// GET or DELETE /auth/session reads or ends the session of the caller.
+func (is *InternalService) handleAuth(data []byte, metadata types.RequestMetadata) (interface{}, error) {
+	var req types.InboundRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, apierror.Wrap(apierror.InvalidArgument, err)
+	}
+
+	path := strings.Trim(strings.TrimPrefix(req.Path, "/auth"), "/")
+	method := strings.ToUpper(req.Method)
+
+	switch {
+	case path == "challenge" && method == http.MethodPost:
+		return is.auth.Challenge(cast.ToString(req.Payload["address"]))
+	case path == "verify" && method == http.MethodPost:
+		return is.auth.Verify(
+			cast.ToString(req.Payload["address"]),
+			cast.ToString(req.Payload["nonce"]),
+			cast.ToString(req.Payload["signature"]),
+			pubKeyValue(req.Payload["pub_key"]),
+		)
+	case path == "session" && (method == http.MethodGet || method == http.MethodDelete):
+		if metadata.Session == "" {
+			return nil, auth.ErrUnknownSession
+		}
+
+		if method == http.MethodGet {
+			return is.auth.Session(metadata.Session)
+		}
+
+		if err := is.auth.Logout(metadata.Session); err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"logged_out": true}, nil
+	}
+
+	return nil, apierror.Newf(apierror.NotFound, "route not found: %s %s", method, req.Path)
+}
+
+// pubKeyValue accepts the public key as a base64 string or as the amino
+// object wallets return, {"type": ..., "value": ...}.
+func pubKeyValue(value interface{}) string {
+	if object, ok := value.(map[string]interface{}); ok {
+		return cast.ToString(object["value"])
+	}
+
+	return cast.ToString(value)
+}
@@ -1,12 +1,18 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 
+	"github.com/spf13/cast"
 	"go.uber.org/zap"
 
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/gateway"
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-interx-manager/p2p"
+	"github.com/KiraCore/sai-interx-manager/types"
 	"github.com/KiraCore/sai-service/service"
 )
 
@@ -38,13 +44,23 @@ func (is *InternalService) NewHandler() service.Handler {
 				dataBytes, err := json.Marshal(data)
 				if err != nil {
 					logger.Logger.Error("EthereumAPI", zap.Error(err))
-					return nil, 500, err
+					return errorResponse(err, types.RequestMetadata{})
 				}
 
-				result, err := is.ethereumGateway.Handle(dataBytes)
+				ctx, cancel, metadata := is.requestContext(meta)
+				defer cancel()
+
+				if err := is.admit(&metadata); err != nil {
+					logger.Logger.Error("EthereumAPI", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				ctx = types.WithAdmission(ctx, func() error { return is.quota.Admit(metadata) })
+
+				result, err := is.ethereumGateway.Handle(ctx, dataBytes, metadata)
 				if err != nil {
 					logger.Logger.Error("EthereumAPI", zap.Error(err))
-					return nil, 500, err
+					return errorResponse(err, metadata)
 				}
 
 				return result, 200, nil
@@ -61,13 +77,21 @@ func (is *InternalService) NewHandler() service.Handler {
 				dataBytes, err := json.Marshal(data)
 				if err != nil {
 					logger.Logger.Error("CosmosAPI", zap.Error(err))
-					return nil, 500, err
+					return errorResponse(err, types.RequestMetadata{})
 				}
 
-				result, err := is.cosmosGateway.Handle(dataBytes)
+				ctx, cancel, metadata := is.requestContext(meta)
+				defer cancel()
+
+				if err := is.admit(&metadata); err != nil {
+					logger.Logger.Error("CosmosAPI", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				result, err := is.cosmosGateway.Handle(ctx, dataBytes, metadata)
 				if err != nil {
-					logger.Logger.Error("EthereumAPI", zap.Error(err))
-					return nil, 500, err
+					logger.Logger.Error("CosmosAPI", zap.Error(err))
+					return errorResponse(err, metadata)
 				}
 
 				return result, 200, nil
@@ -79,9 +103,33 @@ func (is *InternalService) NewHandler() service.Handler {
 		},
 		"rosetta": service.HandlerElement{
 			Name:        "RosettaAPI",
-			Description: "Proxy api endpoint for Rosetta",
+			Description: "Rosetta Data and Construction API for a cosmos network",
 			Function: func(data, meta interface{}) (interface{}, int, error) {
-				return nil, 500, nil
+				dataBytes, err := json.Marshal(data)
+				if err != nil {
+					logger.Logger.Error("RosettaAPI", zap.Error(err))
+					return errorResponse(err, types.RequestMetadata{})
+				}
+
+				ctx, cancel, metadata := is.requestContext(meta)
+				defer cancel()
+
+				if err := is.admit(&metadata); err != nil {
+					logger.Logger.Error("RosettaAPI", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				result, err := is.rosettaGateway.Handle(ctx, dataBytes, metadata)
+				if err != nil {
+					logger.Logger.Error("RosettaAPI", zap.Error(err))
+					// Rosetta clients expect the error object itself as the body
+					if rosettaErr, ok := gateway.RosettaErrorResponse(err); ok {
+						return rosettaErr, 500, nil
+					}
+					return errorResponse(err, metadata)
+				}
+
+				return result, 200, nil
 			},
 			Middlewares: []service.Middleware{
 				is.p2pServer.MetricsCollector().CreateMetricsMiddleware("metrics"),
@@ -89,16 +137,163 @@ func (is *InternalService) NewHandler() service.Handler {
 			},
 		},
 		"bitcoin": service.HandlerElement{
-			Name:        "RosettaAPI",
-			Description: "Proxy api endpoint for Rosetta",
+			Name:        "BitcoinAPI",
+			Description: "Proxy api endpoint for a bitcoin network",
 			Function: func(data, meta interface{}) (interface{}, int, error) {
-				return nil, 500, nil
+				dataBytes, err := json.Marshal(data)
+				if err != nil {
+					logger.Logger.Error("BitcoinAPI", zap.Error(err))
+					return errorResponse(err, types.RequestMetadata{})
+				}
+
+				ctx, cancel, metadata := is.requestContext(meta)
+				defer cancel()
+
+				if err := is.admit(&metadata); err != nil {
+					logger.Logger.Error("BitcoinAPI", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				result, err := is.bitcoinGateway.Handle(ctx, dataBytes, metadata)
+				if err != nil {
+					logger.Logger.Error("BitcoinAPI", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				return result, 200, nil
 			},
 			Middlewares: []service.Middleware{
 				is.p2pServer.MetricsCollector().CreateMetricsMiddleware("metrics"),
 				is.p2pServer.LoadBalancer().CreateLoadBalancerMiddleware("metrics"),
 			},
 		},
+		"health": service.HandlerElement{
+			Name:        "Health",
+			Description: "State of the circuit breakers of this node's upstreams",
+			Function: func(data, meta interface{}) (interface{}, int, error) {
+				upstreams := is.breakers.Status()
+
+				status := "ok"
+				for _, upstream := range upstreams {
+					if upstream.State != gateway.BreakerClosed {
+						status = "degraded"
+						break
+					}
+				}
+
+				return struct {
+					Status    string                `json:"status"`
+					NodeID    p2p.NodeID            `json:"node_id"`
+					Upstreams []types.BreakerStatus `json:"upstreams"`
+				}{
+					Status:    status,
+					NodeID:    is.p2pServer.PeerManager().GetPeerId(),
+					Upstreams: upstreams,
+				}, 200, nil
+			},
+		},
+		"cancel": service.HandlerElement{
+			Name:        "Cancel",
+			Description: "Cancels a request whose client went away, sent by the proxy or the peer that delegated it",
+			Function: func(data, meta interface{}) (interface{}, int, error) {
+				id := cast.ToString(cast.ToStringMap(data)["request_id"])
+
+				return struct {
+					Cancelled bool `json:"cancelled"`
+				}{
+					Cancelled: id != "" && (is.inflight.cancel(id) || is.p2pServer.LoadBalancer().Cancel(id)),
+				}, 200, nil
+			},
+		},
+		"auth": service.HandlerElement{
+			Name:        "Auth",
+			Description: "Wallet signature login: challenges, sessions and logout",
+			Function: func(data, meta interface{}) (interface{}, int, error) {
+				dataBytes, err := json.Marshal(data)
+				if err != nil {
+					logger.Logger.Error("Auth", zap.Error(err))
+					return errorResponse(err, types.RequestMetadata{})
+				}
+
+				_, cancel, metadata := is.requestContext(meta)
+				defer cancel()
+
+				// admitted without identify, a stale session must not keep its
+				// owner from logging in again
+				if err := is.quota.Admit(metadata); err != nil {
+					logger.Logger.Error("Auth", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				result, err := is.handleAuth(dataBytes, metadata)
+				if err != nil {
+					logger.Logger.Error("Auth", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				return result, 200, nil
+			},
+		},
+		"keys": service.HandlerElement{
+			Name:        "Keys",
+			Description: "API key issuance and the caller's quota usage",
+			Function: func(data, meta interface{}) (interface{}, int, error) {
+				dataBytes, err := json.Marshal(data)
+				if err != nil {
+					logger.Logger.Error("Keys", zap.Error(err))
+					return errorResponse(err, types.RequestMetadata{})
+				}
+
+				_, cancel, metadata := is.requestContext(meta)
+				defer cancel()
+
+				result, err := is.handleKeys(dataBytes, metadata)
+				if err != nil {
+					logger.Logger.Error("Keys", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				return result, 200, nil
+			},
+		},
+		"signer": service.HandlerElement{
+			Name:        "Signer",
+			Description: "Status and rotation of the faucet signing key, admin only",
+			Function: func(data, meta interface{}) (interface{}, int, error) {
+				dataBytes, err := json.Marshal(data)
+				if err != nil {
+					logger.Logger.Error("Signer", zap.Error(err))
+					return errorResponse(err, types.RequestMetadata{})
+				}
+
+				_, cancel, metadata := is.requestContext(meta)
+				defer cancel()
+
+				result, err := is.handleSigner(dataBytes, metadata)
+				if err != nil {
+					logger.Logger.Error("Signer", zap.Error(err))
+					return errorResponse(err, metadata)
+				}
+
+				return result, 200, nil
+			},
+		},
+		"notify": service.HandlerElement{
+			Name:        "Notify",
+			Description: "Ingests tx and block notifications from the cosmos indexer for subscriptions",
+			Function: func(data, meta interface{}) (interface{}, int, error) {
+				result, err := is.handleNotify(data, meta)
+				if err != nil {
+					logger.Logger.Error("Notify", zap.Error(err))
+					if errors.Is(err, errWrongNotifyToken) {
+						return errorResponse(err, types.RequestMetadata{})
+					}
+					return errorResponse(apierror.Wrap(apierror.InvalidArgument, err), types.RequestMetadata{})
+				}
+
+				return result, 200, nil
+			},
+		},
 		"default": service.HandlerElement{
 			Name:        "DefaultAPI",
 			Description: "Proxy default api endpoints",
@@ -112,3 +307,70 @@ func (is *InternalService) NewHandler() service.Handler {
 		},
 	}
 }
+
+// errorResponse answers a failed request with the error envelope instead of
+// the service's own error body, so that clients get a stable format and the
+// status that matches the error.
+func errorResponse(err error, metadata types.RequestMetadata) (interface{}, int, error) {
+	response, status := apierror.Response(err, metadata.TraceID)
+	return response, status, nil
+}
+
+// admit identifies the caller and spends one request of its quota.
+func (is *InternalService) admit(metadata *types.RequestMetadata) error {
+	if err := is.identify(metadata); err != nil {
+		return err
+	}
+
+	return is.quota.Admit(*metadata)
+}
+
+// identify sets the identity of a caller that sent a session token.
+func (is *InternalService) identify(metadata *types.RequestMetadata) error {
+	if metadata.Session == "" || !is.auth.Enabled() {
+		return nil
+	}
+
+	session, err := is.auth.Session(metadata.Session)
+	if err != nil {
+		return err
+	}
+
+	metadata.Identity = session.Address
+
+	return nil
+}
+
+// requestContext reads the caller metadata forwarded by the proxy. The origin
+// falls back to the address the request came from, and a deadline set by the
+// caller bounds the returned context. A request with a request id can be
+// cancelled through the "cancel" handler until the returned cancel is called.
+func (is *InternalService) requestContext(meta interface{}) (context.Context, context.CancelFunc, types.RequestMetadata) {
+	values := cast.ToStringMap(meta)
+
+	metadata := types.RequestMetadata{
+		APIKey:  cast.ToString(values["api_key"]),
+		Origin:  cast.ToString(values["origin"]),
+		TraceID: cast.ToString(values["trace_id"]),
+		Session: cast.ToString(values["session"]),
+	}
+
+	if metadata.Origin == "" {
+		metadata.Origin = cast.ToString(values["ip"])
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if deadline, err := cast.ToTimeE(values["deadline"]); err == nil && !deadline.IsZero() {
+		metadata.Deadline = deadline
+		ctx, cancel = context.WithDeadline(is.Context.Context, deadline)
+	} else {
+		ctx, cancel = context.WithCancel(is.Context.Context)
+	}
+
+	if id := cast.ToString(values["request_id"]); id != "" {
+		cancel = is.inflight.add(id, cancel)
+	}
+
+	return ctx, cancel, metadata
+}
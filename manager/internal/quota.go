@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cast"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/quota"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// handleKeys serves /keys: any caller reads its own limits and usage on
+// /keys/me, issuing, listing and revoking keys take the admin key.
+func (is *InternalService) handleKeys(data []byte, metadata types.RequestMetadata) (interface{}, error) {
+	var req types.InboundRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, apierror.Wrap(apierror.InvalidArgument, err)
+	}
+
+	path := strings.Trim(strings.TrimPrefix(req.Path, "/keys"), "/")
+	method := strings.ToUpper(req.Method)
+
+	if path == "me" && method == http.MethodGet {
+		if err := is.identify(&metadata); err != nil {
+			return nil, err
+		}
+
+		return is.quota.Usage(metadata)
+	}
+
+	if !is.quota.IsAdmin(metadata) {
+		return nil, quota.ErrAdminOnly
+	}
+
+	switch {
+	case path == "" && method == http.MethodGet:
+		return is.quota.Keys().List()
+	case path == "" && method == http.MethodPost:
+		key, record, err := is.quota.Issue(cast.ToString(req.Payload["name"]), cast.ToString(req.Payload["tier"]))
+		if err != nil {
+			return nil, err
+		}
+
+		record.Hash = ""
+
+		return struct {
+			Key string `json:"key"`
+			*types.APIKey
+		}{key, record}, nil
+	case path != "" && method == http.MethodDelete:
+		if err := is.quota.Keys().Revoke(path); err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"id": path, "revoked": true}, nil
+	}
+
+	return nil, apierror.Newf(apierror.NotFound, "route not found: %s %s", method, req.Path)
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"context"
+	"sync"
+)
+
+// inflight holds the cancel functions of the requests being served, by the
+// request id the proxy gave them, so that the proxy can cancel a request whose
+// client went away.
+type inflight struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// add registers cancel under id and returns the function that cancels the
+// request and forgets it.
+func (f *inflight) add(id string, cancel context.CancelFunc) context.CancelFunc {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cancels == nil {
+		f.cancels = map[string]context.CancelFunc{}
+	}
+	f.cancels[id] = cancel
+
+	return func() {
+		f.mu.Lock()
+		delete(f.cancels, id)
+		f.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel stops the request id, it reports whether the request was still
+// running.
+func (f *inflight) cancel(id string) bool {
+	f.mu.Lock()
+	cancel, ok := f.cancels[id]
+	delete(f.cancels, id)
+	f.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+
+	return ok
+}
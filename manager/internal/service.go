@@ -1,14 +1,20 @@
 package internal
 
 import (
+	"encoding/json"
+	"net/http"
 	"time"
 
 	"github.com/spf13/cast"
 
+	"github.com/KiraCore/sai-interx-manager/auth"
 	"github.com/KiraCore/sai-interx-manager/gateway"
 	"github.com/KiraCore/sai-interx-manager/p2p"
 	"github.com/KiraCore/sai-interx-manager/p2p/config"
 	"github.com/KiraCore/sai-interx-manager/p2p/net"
+	"github.com/KiraCore/sai-interx-manager/p2p/proto"
+	"github.com/KiraCore/sai-interx-manager/quota"
+	"github.com/KiraCore/sai-interx-manager/subscriptions"
 	"github.com/KiraCore/sai-interx-manager/types"
 	"github.com/KiraCore/sai-service/service"
 )
@@ -18,8 +24,19 @@ type InternalService struct {
 	cosmosGateway   types.Gateway
 	ethereumGateway types.Gateway
 	storageGateway  types.Gateway
+	rosettaGateway  types.Gateway
+	bitcoinGateway  types.Gateway
 	storage         types.Storage
+	breakers        *gateway.Breakers
+	quota           *quota.Quota
+	auth            *auth.Service
 	p2pServer       p2p.Network
+	inflight        inflight
+
+	subscriptionsHub    *subscriptions.Hub
+	subscriptionsServer *subscriptions.Server
+	subscriptionsToken  string
+	proposalWatcher     *subscriptions.ProposalWatcher
 }
 
 func (is *InternalService) Init() {
@@ -49,7 +66,28 @@ func (is *InternalService) Init() {
 		panic(err)
 	}
 
+	var quotaConfig types.QuotaConfig
+	configBytes, err := json.Marshal(is.Context.GetConfig("quota", quotaConfig))
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(configBytes, &quotaConfig); err != nil {
+		panic(err)
+	}
+	is.quota = quota.New(quotaConfig, is.storage, nodeID)
+
+	var authConfig types.AuthConfig
+	configBytes, err = json.Marshal(is.Context.GetConfig("auth", authConfig))
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(configBytes, &authConfig); err != nil {
+		panic(err)
+	}
+	is.auth = auth.New(authConfig, is.storage)
+
 	gatewayFactory := gateway.NewGatewayFactory(is.Context, is.storage)
+	is.breakers = gatewayFactory.Breakers()
 
 	is.cosmosGateway, err = gatewayFactory.CreateGateway("cosmos")
 	if err != nil {
@@ -63,6 +101,40 @@ func (is *InternalService) Init() {
 	if err != nil {
 		panic(err)
 	}
+	is.rosettaGateway, err = gatewayFactory.CreateGateway("rosetta")
+	if err != nil {
+		panic(err)
+	}
+	is.bitcoinGateway, err = gatewayFactory.CreateGateway("bitcoin")
+	if err != nil {
+		panic(err)
+	}
+
+	is.subscriptionsHub = subscriptions.NewHub(
+		cast.ToInt(is.Context.GetConfig("subscriptions.buffer", 256)),
+		cast.ToInt(is.Context.GetConfig("subscriptions.max_clients", 1000)),
+		cast.ToInt(is.Context.GetConfig("subscriptions.max_subscriptions", 32)),
+	)
+	is.subscriptionsToken = cast.ToString(is.Context.GetConfig("subscriptions.token", ""))
+
+	if source, ok := is.cosmosGateway.(subscriptions.ProposalSource); ok {
+		is.proposalWatcher = subscriptions.NewProposalWatcher(source)
+	}
+
+	if cast.ToBool(is.Context.GetConfig("subscriptions.enabled", false)) {
+		is.subscriptionsServer = subscriptions.NewServer(
+			is.subscriptionsHub,
+			cast.ToInt(is.Context.GetConfig("subscriptions.port", 8090)),
+		)
+		is.subscriptionsServer.SetAdmission(is.admitStream)
+
+		// eth_subscribe for EVM chains, /ethereum/{chain}/ws
+		if handler, ok := is.ethereumGateway.(http.Handler); ok {
+			is.subscriptionsServer.Handle("/ethereum/", handler)
+		}
+	}
+
+	is.p2pServer.PeerManager().RegisterHandler(string(proto.MessageTypeEvent), &eventHandler{is: is})
 }
 
 func (is *InternalService) Process() {
@@ -71,7 +143,16 @@ func (is *InternalService) Process() {
 		is.cosmosGateway.Close()
 		is.ethereumGateway.Close()
 		is.storageGateway.Close()
+		is.rosettaGateway.Close()
+		is.bitcoinGateway.Close()
 
 		panic(err)
 	}
+
+	if is.subscriptionsServer != nil {
+		go is.subscriptionsServer.Start()
+	}
+
+	go is.quota.Run(is.Context.Context)
+	go is.auth.Run(is.Context.Context)
 }
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/quota"
+	"github.com/KiraCore/sai-interx-manager/signer"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// handleSigner serves /signer, the faucet signing key, with the admin key:
+// its status on GET /signer and a new key on POST /signer/rotate. The
+// rotation answers with the previous address and the backup of its key, the
+// faucet funds stay there until an operator moves them.
+func (is *InternalService) handleSigner(data []byte, metadata types.RequestMetadata) (interface{}, error) {
+	var req types.InboundRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, apierror.Wrap(apierror.InvalidArgument, err)
+	}
+
+	if !is.quota.IsAdmin(metadata) {
+		return nil, quota.ErrAdminOnly
+	}
+
+	path := strings.Trim(strings.TrimPrefix(req.Path, "/signer"), "/")
+	method := strings.ToUpper(req.Method)
+
+	keys, err := is.signer()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case path == "" && method == http.MethodGet:
+		return keys.Status(), nil
+	case path == "rotate" && method == http.MethodPost:
+		rotation, err := keys.Rotate()
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Logger.Warn("Signer - rotated, move the faucet funds from the previous address",
+			zap.String("previous_address", rotation.PreviousAddress),
+			zap.String("address", rotation.Address),
+			zap.String("backup", rotation.Backup))
+
+		return rotation, nil
+	}
+
+	return nil, apierror.Newf(apierror.NotFound, "route not found: %s %s", method, req.Path)
+}
+
+// signer is the key the cosmos gateway signs faucet transactions with.
+func (is *InternalService) signer() (*signer.Signer, error) {
+	if owner, ok := is.cosmosGateway.(interface{ Signer() *signer.Signer }); ok {
+		return owner.Signer(), nil
+	}
+
+	return nil, apierror.New(apierror.Unavailable, "the cosmos gateway is not running")
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/p2p"
+	"github.com/KiraCore/sai-interx-manager/p2p/proto"
+	"github.com/KiraCore/sai-interx-manager/subscriptions"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+var errWrongNotifyToken = apierror.New(apierror.Unauthenticated, "wrong notify token")
+
+// eventHandler receives events gossiped by other managers.
+type eventHandler struct {
+	is *InternalService
+}
+
+func (h *eventHandler) HandleMessage(msg p2p.Message, from p2p.Peer) error {
+	var event types.SubscriptionEvent
+	if err := proto.UnmarshalPayload(msg.Payload(), &event); err != nil {
+		return err
+	}
+
+	var exclude p2p.NodeID
+	if from != nil {
+		exclude = from.ID()
+	}
+
+	h.is.publishEvent(&event, exclude)
+
+	return nil
+}
+
+// handleNotify ingests "notify" requests sent by the cosmos indexer notifier,
+// the payload carries either a tx or a block document.
+func (is *InternalService) handleNotify(data, meta interface{}) (interface{}, error) {
+	token := cast.ToString(cast.ToStringMap(meta)["token"])
+	if is.subscriptionsToken != "" && token != is.subscriptionsToken {
+		return nil, errWrongNotifyToken
+	}
+
+	payload := cast.ToStringMap(data)
+
+	if tx, ok := payload["tx"]; ok && tx != nil {
+		event, err := subscriptions.TxEventFromIndexer(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		is.publishEvent(event, "")
+	}
+
+	if block, ok := payload["block"]; ok && block != nil {
+		event, err := subscriptions.BlockEventFromIndexer(block)
+		if err != nil {
+			return nil, err
+		}
+
+		if is.publishEvent(event, "") && is.proposalWatcher != nil {
+			go is.checkProposals(event.Height)
+		}
+	}
+
+	return map[string]interface{}{"Status": "OK"}, nil
+}
+
+func (is *InternalService) checkProposals(height int64) {
+	events, err := is.proposalWatcher.Check(is.Context.Context, height)
+	if err != nil {
+		logger.Logger.Error("Subscriptions - checkProposals", zap.Error(err))
+		return
+	}
+
+	for _, event := range events {
+		is.publishEvent(event, "")
+	}
+}
+
+// publishEvent delivers the event to local subscribers and gossips it to the
+// other managers once, events already seen are dropped.
+func (is *InternalService) publishEvent(event *types.SubscriptionEvent, exclude p2p.NodeID) bool {
+	if !is.subscriptionsHub.Publish(event) {
+		return false
+	}
+
+	err := is.p2pServer.PeerManager().Broadcast(proto.NewMessage(proto.MessageTypeEvent, event), exclude)
+	if err != nil && event.Tx != nil && len(event.Tx.Messages) > 0 {
+		// large txs do not fit a datagram, peers get them without message bodies
+		compact := *event
+		compactTx := *event.Tx
+		compactTx.Messages = nil
+		compact.Tx = &compactTx
+
+		err = is.p2pServer.PeerManager().Broadcast(proto.NewMessage(proto.MessageTypeEvent, &compact), exclude)
+	}
+
+	if err != nil {
+		logger.Logger.Error("Subscriptions - publishEvent", zap.String("event", event.ID), zap.Error(fmt.Errorf("broadcast failed: %w", err)))
+	}
+
+	return true
+}
+
+// admitStream admits a WebSocket or SSE connection like a request of its
+// caller, as forwarded by the proxy, and charges the caller for every call and
+// subscription made over it through the connection context.
+func (is *InternalService) admitStream(r *http.Request) (context.Context, error) {
+	metadata := types.RequestMetadata{
+		APIKey:  r.Header.Get("X-API-Key"),
+		Origin:  r.Header.Get("X-Request-Origin"),
+		TraceID: r.Header.Get("X-Request-ID"),
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		metadata.Session = strings.TrimSpace(token)
+	}
+
+	if metadata.Origin == "" {
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			metadata.Origin = host
+		} else {
+			metadata.Origin = r.RemoteAddr
+		}
+	}
+
+	if err := is.admit(&metadata); err != nil {
+		logger.Logger.Error("Subscriptions - admitStream", zap.String("origin", metadata.Origin), zap.Error(err))
+		return nil, err
+	}
+
+	ctx := types.WithRequestMetadata(r.Context(), metadata)
+
+	return types.WithAdmission(ctx, func() error { return is.quota.Admit(metadata) }), nil
+}
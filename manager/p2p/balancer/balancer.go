@@ -2,16 +2,20 @@ package balancer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	saiService "github.com/KiraCore/sai-service/service"
+	"github.com/spf13/cast"
 	"go.uber.org/zap"
 
+	"github.com/KiraCore/sai-interx-manager/apierror"
 	"github.com/KiraCore/sai-interx-manager/logger"
 	"github.com/KiraCore/sai-interx-manager/p2p"
 	"github.com/KiraCore/sai-interx-manager/p2p/metrics"
@@ -19,10 +23,28 @@ import (
 	"github.com/KiraCore/sai-interx-manager/types"
 )
 
+const (
+	// peerTimeout bounds a delegated request that came without a deadline.
+	peerTimeout = 60 * time.Second
+	// cancelTimeout bounds forwarding a cancel to a peer.
+	cancelTimeout = 5 * time.Second
+)
+
 type LoadBalancer struct {
 	nodeID    p2p.NodeID
 	metrics   metrics.Collector
 	threshold float64
+	client    *http.Client
+
+	mu        sync.Mutex
+	delegated map[string]delegation
+}
+
+// delegation is a request sent to a peer, kept by its request id until the
+// peer answers so that a cancel reaches the peer.
+type delegation struct {
+	nodeID p2p.NodeID
+	cancel context.CancelFunc
 }
 
 func NewLoadBalancer(nodeID p2p.NodeID, metrics metrics.Collector, threshold float64) *LoadBalancer {
@@ -30,6 +52,8 @@ func NewLoadBalancer(nodeID p2p.NodeID, metrics metrics.Collector, threshold flo
 		nodeID:    nodeID,
 		metrics:   metrics,
 		threshold: threshold,
+		client:    &http.Client{Timeout: peerTimeout},
+		delegated: map[string]delegation{},
 	}
 }
 
@@ -45,6 +69,9 @@ func (lb *LoadBalancer) CreateLoadBalancerMiddleware(method string) func(next sa
 			metadataMap["X-From-Peer"] = true
 			metadataMap["X-Original-Node"] = lb.nodeID
 
+			ctx, cancel := lb.delegate(metadataMap, targetNodeID)
+			defer cancel()
+
 			request := types.SaiRequest{
 				Method:   method,
 				Data:     data,
@@ -53,13 +80,16 @@ func (lb *LoadBalancer) CreateLoadBalancerMiddleware(method string) func(next sa
 
 			jsonData, err := json.Marshal(request)
 			if err != nil {
-				return nil, http.StatusInternalServerError, errors.New("failed to marshal request data")
+				return failure(apierror.Wrap(apierror.Internal, err), metadataMap)
 			}
 
-			response, err := lb.ProxyRequest(jsonData, targetNodeID)
+			response, err := lb.ProxyRequest(ctx, jsonData, targetNodeID)
 			if err != nil {
 				logger.Logger.Error("loadBalancerMiddleware: error proxying request", zap.Error(err))
-				return nil, http.StatusInternalServerError, errors.New("failed to delegate request")
+				if ctx.Err() != nil {
+					return failure(apierror.From(ctx.Err()), metadataMap)
+				}
+				return failure(apierror.Newf(apierror.Unavailable, "failed to delegate request: %v", err), metadataMap)
 			}
 
 			defer response.Body.Close()
@@ -67,14 +97,14 @@ func (lb *LoadBalancer) CreateLoadBalancerMiddleware(method string) func(next sa
 			body, err := ioutil.ReadAll(response.Body)
 			if err != nil {
 				logger.Logger.Error("failed to read proxied response", zap.Error(err))
-				return nil, http.StatusInternalServerError, errors.New("failed to read proxied response")
+				return failure(apierror.Newf(apierror.Upstream, "failed to read proxied response: %v", err), metadataMap)
 			}
 
 			var result interface{}
 			err = json.Unmarshal(body, &result)
 			if err != nil {
 				logger.Logger.Error("failed to proxied proxied response", zap.Error(err))
-				return nil, http.StatusInternalServerError, errors.New("failed to parse proxied response")
+				return failure(apierror.Newf(apierror.Upstream, "failed to parse proxied response: %v", err), metadataMap)
 			}
 
 			return result, response.StatusCode, nil
@@ -84,6 +114,81 @@ func (lb *LoadBalancer) CreateLoadBalancerMiddleware(method string) func(next sa
 	}
 }
 
+// delegate bounds a request sent to targetNodeID by the deadline of the
+// caller, which is set in metadata when the caller sent none so that the peer
+// stops at the same time. A request with a request id is registered until the
+// returned cancel is called, to be cancelled through Cancel.
+func (lb *LoadBalancer) delegate(metadata map[string]interface{}, targetNodeID p2p.NodeID) (context.Context, context.CancelFunc) {
+	deadline, err := cast.ToTimeE(metadata["deadline"])
+	if err != nil || deadline.IsZero() {
+		deadline = time.Now().Add(peerTimeout)
+		metadata["deadline"] = deadline.UTC().Format(time.RFC3339Nano)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+
+	id := cast.ToString(metadata["request_id"])
+	if id == "" {
+		return ctx, cancel
+	}
+
+	lb.mu.Lock()
+	lb.delegated[id] = delegation{nodeID: targetNodeID, cancel: cancel}
+	lb.mu.Unlock()
+
+	return ctx, func() {
+		lb.mu.Lock()
+		delete(lb.delegated, id)
+		lb.mu.Unlock()
+		cancel()
+	}
+}
+
+// Cancel stops the request id if it was delegated to a peer and forwards the
+// cancel to that peer. It reports whether the request was still running.
+func (lb *LoadBalancer) Cancel(id string) bool {
+	lb.mu.Lock()
+	request, ok := lb.delegated[id]
+	delete(lb.delegated, id)
+	lb.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(types.SaiRequest{
+		Method:   "cancel",
+		Data:     map[string]interface{}{"request_id": id},
+		Metadata: map[string]interface{}{"X-From-Peer": true, "X-Original-Node": lb.nodeID},
+	})
+	if err == nil {
+		var response *http.Response
+		response, err = lb.ProxyRequest(ctx, jsonData, request.nodeID)
+		if err == nil {
+			response.Body.Close()
+		}
+	}
+	if err != nil {
+		logger.Logger.Error("Cancel", zap.String("node", string(request.nodeID)), zap.Error(err))
+	}
+
+	request.cancel()
+
+	return true
+}
+
+// failure answers with the error envelope, the way the handlers of the peer
+// would have.
+func failure(err error, metadata map[string]interface{}) (interface{}, int, error) {
+	traceID, _ := metadata["trace_id"].(string)
+	response, status := apierror.Response(err, traceID)
+
+	return response, status, nil
+}
+
 func (lb *LoadBalancer) ShouldHandleRequest() (bool, p2p.NodeID) {
 	localScore := lb.metrics.CalculateScore(lb.nodeID)
 	var scores = map[string]float64{}
@@ -100,7 +205,7 @@ func (lb *LoadBalancer) ShouldHandleRequest() (bool, p2p.NodeID) {
 	return p2p.NodeID(bestNodeID) == lb.nodeID, p2p.NodeID(bestNodeID)
 }
 
-func (lb *LoadBalancer) ProxyRequest(jsonData []byte, targetNodeID p2p.NodeID) (*http.Response, error) {
+func (lb *LoadBalancer) ProxyRequest(ctx context.Context, jsonData []byte, targetNodeID p2p.NodeID) (*http.Response, error) {
 	nodeInfo, exists := lb.metrics.GetNodeInfo(targetNodeID)
 	if !exists {
 		err := fmt.Errorf("node %s not found", targetNodeID)
@@ -114,13 +219,12 @@ func (lb *LoadBalancer) ProxyRequest(jsonData []byte, targetNodeID p2p.NodeID) (
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s:%d", address, nodeInfo.HttpPort), bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("http://%s:%d", address, nodeInfo.HttpPort), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 
-	client := http.Client{}
-	response, err := client.Do(req)
+	response, err := lb.client.Do(req)
 	if err != nil {
 		logger.Logger.Error("ProxyRequest", zap.Error(err))
 		return nil, err
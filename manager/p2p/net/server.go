@@ -18,6 +18,9 @@ import (
 	"github.com/KiraCore/sai-interx-manager/p2p/types"
 )
 
+// maxDatagramSize is the largest payload a single UDP datagram can carry.
+const maxDatagramSize = 65507
+
 type PeerManager struct {
 	nodeID           p2p.NodeID
 	address          string
@@ -410,7 +413,11 @@ func (pm *PeerManager) handleIncomingMessages() {
 			}
 		}
 
-		go pm.processUDPMessage(buffer[:n], addr)
+		// the buffer is reused by the next read, hand the goroutine its own copy
+		msgBytes := make([]byte, n)
+		copy(msgBytes, buffer[:n])
+
+		go pm.processUDPMessage(msgBytes, addr)
 	}
 }
 
@@ -492,7 +499,10 @@ func (pm *PeerManager) processUDPMessage(msgBytes []byte, fromAddr *net.UDPAddr)
 			return
 		}
 
+		pm.mutex.RLock()
 		handler, ok := pm.messageHandlers[msg.Type()]
+		pm.mutex.RUnlock()
+
 		if ok {
 			if err := handler.HandleMessage(&msg, peer); err != nil {
 				logger.Logger.Error("ERROR HANDLING MESSAGE",
@@ -902,6 +912,49 @@ func (pm *PeerManager) sendMetricsToPeers() {
 	wg.Wait()
 }
 
+func (pm *PeerManager) RegisterHandler(msgType string, handler p2p.MessageHandler) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	pm.messageHandlers[msgType] = handler
+}
+
+func (pm *PeerManager) Broadcast(msg p2p.Message, exclude p2p.NodeID) error {
+	msgBytes, err := json.Marshal(proto.NewMessage(proto.MessageType(msg.Type()), msg.Payload()))
+	if err != nil {
+		logger.Logger.Error("BROADCAST SERIALIZATION ERROR", zap.Error(err))
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	if len(msgBytes) > maxDatagramSize {
+		return fmt.Errorf("message of %d bytes exceeds UDP datagram limit", len(msgBytes))
+	}
+
+	pm.mutex.RLock()
+	activePeers := make([]*Peer, 0, len(pm.peers))
+	for nodeID, peer := range pm.peers {
+		if nodeID != exclude {
+			activePeers = append(activePeers, peer)
+		}
+	}
+	pm.mutex.RUnlock()
+
+	for _, peer := range activePeers {
+		udpAddr := peer.GetUDPAddr()
+		if udpAddr == nil {
+			continue
+		}
+
+		if _, err := pm.conn.WriteToUDP(msgBytes, udpAddr); err != nil {
+			logger.Logger.Error("BROADCAST SEND ERROR",
+				zap.String("peerID", string(peer.ID())),
+				zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
 func (pm *PeerManager) LocalPeers() map[p2p.NodeID]*Peer {
 	var localPeers = map[p2p.NodeID]*Peer{}
 
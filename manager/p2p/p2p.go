@@ -21,6 +21,10 @@ type PeerManager interface {
 	GetPeerId() NodeID
 	AddPeer(address string, remote bool) (Peer, error)
 	RemovePeer(id NodeID)
+	// Broadcast sends msg to every connected peer except the excluded one.
+	Broadcast(msg Message, exclude NodeID) error
+	// RegisterHandler routes messages of msgType received from known peers to handler.
+	RegisterHandler(msgType string, handler MessageHandler)
 }
 
 type Message interface {
@@ -42,6 +46,7 @@ type MetricsCollector interface {
 
 type LoadBalancer interface {
 	ShouldHandleRequest() (bool, NodeID)
+	Cancel(id string) bool
 	CreateLoadBalancerMiddleware(method string) func(next saiService.HandlerFunc, data interface{}, metadata interface{}) (interface{}, int, error)
 }
 
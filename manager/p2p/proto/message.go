@@ -11,6 +11,7 @@ const (
 	MessageTypeJoinRequest  MessageType = "join_request"
 	MessageTypeJoinResponse MessageType = "join_response"
 	MessageTypeMetrics      MessageType = "metrics"
+	MessageTypeEvent        MessageType = "event"
 )
 
 type Message struct {
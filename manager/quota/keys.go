@@ -0,0 +1,213 @@
+package quota
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	keyPrefix   = "ix_"
+	keyCacheTTL = time.Minute
+	// maxCachedKeys bounds the lookup cache, unknown keys are not cached while
+	// it is full.
+	maxCachedKeys = 10000
+)
+
+var ErrUnknownKey = apierror.New(apierror.Unauthenticated, "unknown or revoked API key")
+
+type cachedKey struct {
+	key     *types.APIKey
+	expires time.Time
+}
+
+// KeyStore keeps issued keys in sai-storage-mongo. Lookups are cached for a
+// minute, unknown keys included while the cache is not full, so a revoked key
+// stops working on every manager within that time.
+type KeyStore struct {
+	storage    types.Storage
+	collection string
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+func NewKeyStore(storage types.Storage, collection string) *KeyStore {
+	return &KeyStore{
+		storage:    storage,
+		collection: collection,
+		cache:      map[string]cachedKey{},
+	}
+}
+
+// Issue creates a key of tier and returns it with its record, the key is not
+// kept and cannot be shown again.
+func (s *KeyStore) Issue(name, tier string) (string, *types.APIKey, error) {
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := keyPrefix + secret
+	record := &types.APIKey{
+		ID:        id,
+		Hash:      hashKey(key),
+		Name:      name,
+		Tier:      tier,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err = s.storage.Upsert(s.collection, map[string]interface{}{"id": id}, record)
+	if err != nil {
+		logger.Logger.Error("KeyStore - Issue", zap.Error(err))
+		return "", nil, err
+	}
+
+	return key, record, nil
+}
+
+// Cached tells whether key was found valid by a lookup that did not expire.
+func (s *KeyStore) Cached(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.cache[hashKey(key)]
+
+	return ok && cached.key != nil && time.Now().Before(cached.expires)
+}
+
+// Lookup returns the record of a valid key, ErrUnknownKey otherwise.
+func (s *KeyStore) Lookup(key string) (*types.APIKey, error) {
+	hash := hashKey(key)
+
+	s.mu.Lock()
+	cached, ok := s.cache[hash]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		if cached.key == nil {
+			return nil, ErrUnknownKey
+		}
+		return cached.key, nil
+	}
+
+	response, err := s.storage.Read(s.collection, map[string]interface{}{"hash": hash}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("KeyStore - Lookup", zap.Error(err))
+		return nil, apierror.Wrap(apierror.Unavailable, err)
+	}
+
+	var record *types.APIKey
+	if len(response.Result) > 0 {
+		record = keyFromDocument(response.Result[0])
+		if record.Revoked {
+			record = nil
+		}
+	}
+
+	s.mu.Lock()
+	if record != nil || len(s.cache) < maxCachedKeys {
+		s.cache[hash] = cachedKey{key: record, expires: time.Now().Add(keyCacheTTL)}
+	}
+	s.mu.Unlock()
+
+	if record == nil {
+		return nil, ErrUnknownKey
+	}
+
+	return record, nil
+}
+
+// Sweep drops the lookups expired at now.
+func (s *KeyStore) Sweep(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, cached := range s.cache {
+		if !now.Before(cached.expires) {
+			delete(s.cache, hash)
+		}
+	}
+}
+
+// List returns every issued key without its hash.
+func (s *KeyStore) List() ([]*types.APIKey, error) {
+	response, err := s.storage.Read(s.collection, map[string]interface{}{}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("KeyStore - List", zap.Error(err))
+		return nil, err
+	}
+
+	keys := make([]*types.APIKey, 0, len(response.Result))
+	for _, document := range response.Result {
+		key := keyFromDocument(document)
+		key.Hash = ""
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (s *KeyStore) Revoke(id string) error {
+	response, err := s.storage.Read(s.collection, map[string]interface{}{"id": id}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("KeyStore - Revoke", zap.Error(err))
+		return err
+	}
+
+	if len(response.Result) == 0 {
+		return apierror.Newf(apierror.NotFound, "API key %s not found", id)
+	}
+
+	_, err = s.storage.Update(s.collection, map[string]interface{}{"id": id}, map[string]interface{}{"revoked": true})
+	if err != nil {
+		logger.Logger.Error("KeyStore - Revoke", zap.Error(err))
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, cast.ToString(response.Result[0]["hash"]))
+	s.mu.Unlock()
+
+	return nil
+}
+
+func keyFromDocument(document map[string]interface{}) *types.APIKey {
+	return &types.APIKey{
+		ID:        cast.ToString(document["id"]),
+		Hash:      cast.ToString(document["hash"]),
+		Name:      cast.ToString(document["name"]),
+		Tier:      cast.ToString(document["tier"]),
+		CreatedAt: cast.ToTime(document["created_at"]),
+		Revoked:   cast.ToBool(document["revoked"]),
+	}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		logger.Logger.Error("KeyStore - randomHex", zap.Error(err))
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,78 @@
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// idleBucket is how long the bucket of a client is kept without requests, a
+// full bucket is the same as a new one.
+const idleBucket = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// Buckets holds one token bucket per client so that a client spending its own
+// tokens does not slow down the others.
+type Buckets struct {
+	mu    sync.Mutex
+	items map[string]*bucket
+}
+
+func NewBuckets() *Buckets {
+	return &Buckets{items: map[string]*bucket{}}
+}
+
+// Take spends a token of the client's bucket. When none is left it returns how
+// long the client has to wait for one.
+func (b *Buckets) Take(client string, tier types.QuotaTier) (time.Duration, bool) {
+	if tier.Rate <= 0 {
+		return 0, true
+	}
+
+	burst := tier.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	now := time.Now()
+
+	b.mu.Lock()
+	item, ok := b.items[client]
+	if !ok || item.limiter.Limit() != rate.Limit(tier.Rate) || item.limiter.Burst() != burst {
+		item = &bucket{limiter: rate.NewLimiter(rate.Limit(tier.Rate), burst)}
+		b.items[client] = item
+	}
+	item.lastUsed = now
+	b.mu.Unlock()
+
+	reservation := item.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Second, false
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+
+	return 0, true
+}
+
+// Sweep drops the buckets of clients idle for a while.
+func (b *Buckets) Sweep() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for client, item := range b.items {
+		if time.Since(item.lastUsed) > idleBucket {
+			delete(b.items, client)
+		}
+	}
+}
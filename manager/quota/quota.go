@@ -0,0 +1,208 @@
+package quota
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	defaultCollection      = "api_keys"
+	defaultUsageCollection = "api_usage"
+	defaultTier            = "free"
+	defaultFlush           = 10 * time.Second
+
+	anonymousTier = "anonymous"
+	adminTier     = "admin"
+)
+
+var ErrAdminOnly = apierror.New(apierror.PermissionDenied, "admin API key required")
+
+// Quota admits the requests of each client within the token bucket and the
+// daily quota of its tier. Clients are API keys, then logged in wallets, then
+// the client address. The admin key is not limited. Logged in wallets are also
+// limited per client address so that new wallets do not add up, and keys not
+// known yet cost a request of the client address before they are looked up.
+type Quota struct {
+	config  types.QuotaConfig
+	keys    *KeyStore
+	buckets *Buckets
+	usage   *Usage
+	flush   time.Duration
+}
+
+func New(config types.QuotaConfig, storage types.Storage, node string) *Quota {
+	if config.Collection == "" {
+		config.Collection = defaultCollection
+	}
+	if config.UsageCollection == "" {
+		config.UsageCollection = defaultUsageCollection
+	}
+	if config.DefaultTier == "" {
+		config.DefaultTier = defaultTier
+	}
+	if config.SessionTier == "" {
+		config.SessionTier = config.DefaultTier
+	}
+
+	flush := time.Duration(config.Flush) * time.Second
+	if flush <= 0 {
+		flush = defaultFlush
+	}
+
+	return &Quota{
+		config:  config,
+		keys:    NewKeyStore(storage, config.Collection),
+		buckets: NewBuckets(),
+		usage:   NewUsage(storage, config.UsageCollection, node),
+		flush:   flush,
+	}
+}
+
+func (q *Quota) Enabled() bool {
+	return q.config.Enabled
+}
+
+// Admit spends one request of the caller. Exhausted limits are reported with
+// the time after which the caller may try again.
+func (q *Quota) Admit(metadata types.RequestMetadata) error {
+	if !q.config.Enabled {
+		return nil
+	}
+
+	if metadata.APIKey != "" && !q.IsAdmin(metadata) && !q.keys.Cached(metadata.APIKey) {
+		if err := q.take("ip:"+metadata.Origin, anonymousTier, q.config.Anonymous); err != nil {
+			return err
+		}
+	}
+
+	client, tierName, tier, err := q.client(metadata)
+	if err != nil {
+		return err
+	}
+
+	if tierName == adminTier {
+		return nil
+	}
+
+	if strings.HasPrefix(client, "user:") {
+		if err = q.take("session:"+metadata.Origin, tierName, tier); err != nil {
+			return err
+		}
+	}
+
+	return q.take(client, tierName, tier)
+}
+
+// take spends one request of the bucket and the daily quota of client.
+func (q *Quota) take(client, tierName string, tier types.QuotaTier) error {
+	if delay, ok := q.buckets.Take(client, tier); !ok {
+		return apierror.Newf(apierror.ResourceExhausted, "rate limit of tier %s exceeded", tierName).WithRetryAfter(delay)
+	}
+
+	if !q.usage.Add(client, tier.Daily) {
+		return apierror.Newf(apierror.ResourceExhausted, "daily quota of tier %s exceeded", tierName).
+			WithRetryAfter(time.Until(nextDay(time.Now())))
+	}
+
+	return nil
+}
+
+// Usage returns the limits of the caller and what it used of them today.
+func (q *Quota) Usage(metadata types.RequestMetadata) (*types.QuotaUsage, error) {
+	client, tierName, tier, err := q.client(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.QuotaUsage{
+		Client:  client,
+		Tier:    tierName,
+		Rate:    tier.Rate,
+		Burst:   tier.Burst,
+		Daily:   tier.Daily,
+		Used:    q.usage.Used(client),
+		ResetAt: nextDay(time.Now()),
+	}, nil
+}
+
+// IsAdmin tells whether the caller sent the admin key, no key is admin when it
+// is not configured.
+func (q *Quota) IsAdmin(metadata types.RequestMetadata) bool {
+	return q.config.AdminKey != "" &&
+		subtle.ConstantTimeCompare([]byte(metadata.APIKey), []byte(q.config.AdminKey)) == 1
+}
+
+// Issue creates a key of tier, the default tier when empty.
+func (q *Quota) Issue(name, tier string) (string, *types.APIKey, error) {
+	if tier == "" {
+		tier = q.config.DefaultTier
+	}
+
+	if _, ok := q.config.Tiers[tier]; !ok {
+		return "", nil, apierror.Newf(apierror.InvalidArgument, "unknown tier %s", tier)
+	}
+
+	return q.keys.Issue(name, tier)
+}
+
+func (q *Quota) Keys() *KeyStore {
+	return q.keys
+}
+
+// Run syncs the usage counters and drops idle buckets and expired key lookups
+// until ctx is done.
+func (q *Quota) Run(ctx context.Context) {
+	if !q.config.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(q.flush)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			q.usage.Sync()
+			return
+		case <-ticker.C:
+			q.usage.Sync()
+			q.buckets.Sweep()
+			q.keys.Sweep(time.Now())
+		}
+	}
+}
+
+func (q *Quota) client(metadata types.RequestMetadata) (string, string, types.QuotaTier, error) {
+	if metadata.APIKey == "" && metadata.Identity != "" {
+		return "user:" + metadata.Identity, q.config.SessionTier, q.config.Tiers[q.config.SessionTier], nil
+	}
+
+	if metadata.APIKey == "" {
+		return "ip:" + metadata.Origin, anonymousTier, q.config.Anonymous, nil
+	}
+
+	if q.IsAdmin(metadata) {
+		return adminTier, adminTier, types.QuotaTier{}, nil
+	}
+
+	key, err := q.keys.Lookup(metadata.APIKey)
+	if err != nil {
+		return "", "", types.QuotaTier{}, err
+	}
+
+	tier, ok := q.config.Tiers[key.Tier]
+	if !ok {
+		logger.Logger.Warn("Quota - key of an unknown tier", zap.String("key", key.ID), zap.String("tier", key.Tier))
+		return "key:" + key.ID, q.config.DefaultTier, q.config.Tiers[q.config.DefaultTier], nil
+	}
+
+	return "key:" + key.ID, key.Tier, tier, nil
+}
@@ -0,0 +1,262 @@
+package quota
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	m.Run()
+}
+
+// keyStorage serves the key documents it was given and counts the reads.
+type keyStorage struct {
+	mu        sync.Mutex
+	documents []map[string]interface{}
+	reads     int
+}
+
+func (s *keyStorage) Create(collection string, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *keyStorage) Read(collection string, criteria map[string]interface{}, options *adapter.Options, fields []string) (*adapter.SaiStorageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reads++
+
+	var result []map[string]interface{}
+	for _, document := range s.documents {
+		if hash, ok := criteria["hash"]; ok && document["hash"] != hash {
+			continue
+		}
+		result = append(result, document)
+	}
+
+	return &adapter.SaiStorageResponse{Status: "OK", Result: result, Count: len(result)}, nil
+}
+
+func (s *keyStorage) Upsert(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *keyStorage) Update(collection string, criteria map[string]interface{}, document interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *keyStorage) Delete(collection string, criteria map[string]interface{}) (*adapter.SaiStorageResponse, error) {
+	return &adapter.SaiStorageResponse{Status: "OK"}, nil
+}
+
+func (s *keyStorage) add(key, id, tier string) {
+	s.documents = append(s.documents, map[string]interface{}{"id": id, "hash": hashKey(key), "tier": tier})
+}
+
+func newTestQuota(storage *keyStorage) *Quota {
+	return New(types.QuotaConfig{
+		Enabled:     true,
+		AdminKey:    "admin",
+		DefaultTier: "free",
+		SessionTier: "wallet",
+		Anonymous:   types.QuotaTier{Rate: 1, Burst: 2},
+		Tiers: map[string]types.QuotaTier{
+			"free":   {Rate: 1, Burst: 5},
+			"pro":    {Rate: 100, Burst: 100},
+			"wallet": {Rate: 1, Burst: 3},
+		},
+	}, storage, "node")
+}
+
+func TestBucketsTake(t *testing.T) {
+	buckets := NewBuckets()
+	tier := types.QuotaTier{Rate: 20, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if _, ok := buckets.Take("client", tier); !ok {
+			t.Fatalf("take %d of the burst refused", i+1)
+		}
+	}
+
+	delay, ok := buckets.Take("client", tier)
+	if ok || delay <= 0 || delay > 50*time.Millisecond {
+		t.Fatalf("take past the burst = %s, %v, want refused with a delay up to 50ms", delay, ok)
+	}
+
+	if _, ok = buckets.Take("other", tier); !ok {
+		t.Fatal("take of another client refused")
+	}
+
+	time.Sleep(delay)
+
+	if _, ok = buckets.Take("client", tier); !ok {
+		t.Fatal("take after the refill delay refused")
+	}
+
+	if _, ok = buckets.Take("client", types.QuotaTier{}); !ok {
+		t.Fatal("take of a tier without rate refused")
+	}
+}
+
+func TestQuotaClient(t *testing.T) {
+	storage := &keyStorage{}
+	storage.add("ix_pro", "k1", "pro")
+	storage.add("ix_gone", "k2", "enterprise")
+	q := newTestQuota(storage)
+
+	tests := []struct {
+		name     string
+		metadata types.RequestMetadata
+		client   string
+		tier     string
+		err      error
+	}{
+		{name: "anonymous", metadata: types.RequestMetadata{Origin: "10.0.0.1"}, client: "ip:10.0.0.1", tier: anonymousTier},
+		{name: "session", metadata: types.RequestMetadata{Origin: "10.0.0.1", Identity: "kira1abc"}, client: "user:kira1abc", tier: "wallet"},
+		{name: "key", metadata: types.RequestMetadata{Origin: "10.0.0.1", APIKey: "ix_pro"}, client: "key:k1", tier: "pro"},
+		{name: "key before session", metadata: types.RequestMetadata{APIKey: "ix_pro", Identity: "kira1abc"}, client: "key:k1", tier: "pro"},
+		{name: "key of an unknown tier", metadata: types.RequestMetadata{APIKey: "ix_gone"}, client: "key:k2", tier: "free"},
+		{name: "admin", metadata: types.RequestMetadata{APIKey: "admin"}, client: adminTier, tier: adminTier},
+		{name: "unknown key", metadata: types.RequestMetadata{APIKey: "ix_random"}, err: ErrUnknownKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, tier, _, err := q.client(test.metadata)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("client error = %v, want %v", err, test.err)
+			}
+
+			if client != test.client || tier != test.tier {
+				t.Fatalf("client = %s of tier %s, want %s of tier %s", client, tier, test.client, test.tier)
+			}
+		})
+	}
+}
+
+func TestQuotaAdmitRateLimit(t *testing.T) {
+	q := newTestQuota(&keyStorage{})
+	metadata := types.RequestMetadata{Origin: "10.0.0.1"}
+
+	for i := 0; i < 2; i++ {
+		if err := q.Admit(metadata); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+
+	err := apierror.From(q.Admit(metadata))
+	if err.Code != apierror.ResourceExhausted || err.RetryAfter <= 0 || err.RetryAfter > time.Second {
+		t.Fatalf("request past the burst = %v retry after %s, want %s within a second", err, err.RetryAfter, apierror.ResourceExhausted)
+	}
+
+	if err := q.Admit(types.RequestMetadata{Origin: "10.0.0.2"}); err != nil {
+		t.Fatalf("request of another address: %v", err)
+	}
+}
+
+func TestQuotaAdmitDaily(t *testing.T) {
+	q := newTestQuota(&keyStorage{})
+	q.config.Anonymous = types.QuotaTier{Daily: 3}
+	metadata := types.RequestMetadata{Origin: "10.0.0.1"}
+
+	for i := 0; i < 3; i++ {
+		if err := q.Admit(metadata); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+
+	reset := time.Until(nextDay(time.Now()))
+	err := apierror.From(q.Admit(metadata))
+	if err.Code != apierror.ResourceExhausted {
+		t.Fatalf("request past the daily quota = %v, want %s", err, apierror.ResourceExhausted)
+	}
+
+	if err.RetryAfter <= 0 || err.RetryAfter > reset {
+		t.Fatalf("retry after = %s, want up to the next UTC day in %s", err.RetryAfter, reset)
+	}
+
+	usage, _ := q.Usage(metadata)
+	if usage.Used != 3 || usage.Daily != 3 {
+		t.Fatalf("usage = %d of %d, want 3 of 3", usage.Used, usage.Daily)
+	}
+}
+
+func TestQuotaAdmitUnknownKeys(t *testing.T) {
+	storage := &keyStorage{}
+	storage.add("ix_pro", "k1", "pro")
+	q := newTestQuota(storage)
+
+	for i, key := range []string{"ix_random1", "ix_random2"} {
+		if err := q.Admit(types.RequestMetadata{Origin: "10.0.0.1", APIKey: key}); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("random key %d = %v, want %v", i+1, err, ErrUnknownKey)
+		}
+	}
+
+	err := apierror.From(q.Admit(types.RequestMetadata{Origin: "10.0.0.1", APIKey: "ix_random3"}))
+	if err.Code != apierror.ResourceExhausted {
+		t.Fatalf("random key past the anonymous burst = %v, want %s", err, apierror.ResourceExhausted)
+	}
+
+	if storage.reads != 2 {
+		t.Fatalf("storage read %d times, want 2", storage.reads)
+	}
+
+	// a known key is charged to its own tier once it is cached
+	if err := q.Admit(types.RequestMetadata{Origin: "10.0.0.2", APIKey: "ix_pro"}); err != nil {
+		t.Fatalf("first request of a key: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := q.Admit(types.RequestMetadata{Origin: "10.0.0.2", APIKey: "ix_pro"}); err != nil {
+			t.Fatalf("request %d of a cached key: %v", i+2, err)
+		}
+	}
+}
+
+func TestQuotaAdmitSessionsPerOrigin(t *testing.T) {
+	q := newTestQuota(&keyStorage{})
+
+	for i, identity := range []string{"kira1a", "kira1b", "kira1c"} {
+		if err := q.Admit(types.RequestMetadata{Origin: "10.0.0.1", Identity: identity}); err != nil {
+			t.Fatalf("wallet %d: %v", i+1, err)
+		}
+	}
+
+	err := apierror.From(q.Admit(types.RequestMetadata{Origin: "10.0.0.1", Identity: "kira1d"}))
+	if err.Code != apierror.ResourceExhausted {
+		t.Fatalf("new wallet past the session burst of its address = %v, want %s", err, apierror.ResourceExhausted)
+	}
+
+	if err := q.Admit(types.RequestMetadata{Origin: "10.0.0.2", Identity: "kira1d"}); err != nil {
+		t.Fatalf("wallet from another address: %v", err)
+	}
+}
+
+func TestKeyStoreCache(t *testing.T) {
+	storage := &keyStorage{}
+	keys := NewKeyStore(storage, defaultCollection)
+
+	for i := 0; i < maxCachedKeys+10; i++ {
+		keys.Lookup("ix_random" + cast.ToString(i))
+	}
+
+	if len(keys.cache) != maxCachedKeys {
+		t.Fatalf("cache holds %d keys, want %d", len(keys.cache), maxCachedKeys)
+	}
+
+	keys.Sweep(time.Now().Add(keyCacheTTL))
+
+	if len(keys.cache) != 0 {
+		t.Fatalf("cache holds %d keys after they expired, want none", len(keys.cache))
+	}
+}
@@ -0,0 +1,157 @@
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const dayLayout = "2006-01-02"
+
+type usageDocument struct {
+	Client string `json:"client"`
+	Day    string `json:"day"`
+	Node   string `json:"node"`
+	Count  int64  `json:"count"`
+}
+
+// Usage counts the requests of every client per UTC day. Each manager stores
+// its own count in sai-storage-mongo and adds those of the other managers, as
+// of the last Sync, so daily quotas hold across the network up to the sync
+// interval.
+type Usage struct {
+	storage    types.Storage
+	collection string
+	node       string
+
+	mu      sync.Mutex
+	day     string
+	local   map[string]int64
+	remote  map[string]int64
+	dirty   map[string]struct{}
+	pending []usageDocument
+}
+
+func NewUsage(storage types.Storage, collection, node string) *Usage {
+	return &Usage{
+		storage:    storage,
+		collection: collection,
+		node:       node,
+		day:        today(),
+		local:      map[string]int64{},
+		remote:     map[string]int64{},
+		dirty:      map[string]struct{}{},
+	}
+}
+
+// Add counts a request of client unless it already used limit requests today,
+// 0 being no limit.
+func (u *Usage) Add(client string, limit int64) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollover()
+
+	if limit > 0 && u.local[client]+u.remote[client] >= limit {
+		return false
+	}
+
+	u.local[client]++
+	u.dirty[client] = struct{}{}
+
+	return true
+}
+
+func (u *Usage) Used(client string) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollover()
+
+	return u.local[client] + u.remote[client]
+}
+
+// Sync stores the counts of this manager that changed and reads those of the
+// others.
+func (u *Usage) Sync() {
+	u.mu.Lock()
+	u.rollover()
+	day := u.day
+	documents := u.pending
+	u.pending = nil
+	for client := range u.dirty {
+		documents = append(documents, usageDocument{Client: client, Day: day, Node: u.node, Count: u.local[client]})
+	}
+	u.dirty = map[string]struct{}{}
+	u.mu.Unlock()
+
+	for _, document := range documents {
+		_, err := u.storage.Upsert(u.collection, map[string]interface{}{
+			"client": document.Client,
+			"day":    document.Day,
+			"node":   document.Node,
+		}, document)
+		if err != nil {
+			logger.Logger.Error("Usage - Sync", zap.String("client", document.Client), zap.Error(err))
+
+			u.mu.Lock()
+			if document.Day == u.day {
+				u.dirty[document.Client] = struct{}{}
+			}
+			u.mu.Unlock()
+		}
+	}
+
+	response, err := u.storage.Read(u.collection, map[string]interface{}{"day": day}, nil, []string{})
+	if err != nil {
+		logger.Logger.Error("Usage - Sync", zap.Error(err))
+		return
+	}
+
+	remote := map[string]int64{}
+	for _, document := range response.Result {
+		if cast.ToString(document["node"]) == u.node {
+			continue
+		}
+		remote[cast.ToString(document["client"])] += cast.ToInt64(document["count"])
+	}
+
+	u.mu.Lock()
+	if u.day == day {
+		u.remote = remote
+	}
+	u.mu.Unlock()
+}
+
+// rollover starts a new day, the unsynced counts of the previous one are kept
+// for the next Sync. Callers hold mu.
+func (u *Usage) rollover() {
+	day := today()
+	if day == u.day {
+		return
+	}
+
+	for client := range u.dirty {
+		u.pending = append(u.pending, usageDocument{Client: client, Day: u.day, Node: u.node, Count: u.local[client]})
+	}
+
+	u.day = day
+	u.local = map[string]int64{}
+	u.remote = map[string]int64{}
+	u.dirty = map[string]struct{}{}
+}
+
+func today() string {
+	return time.Now().UTC().Format(dayLayout)
+}
+
+// nextDay is when the daily quotas reset.
+func nextDay(now time.Time) time.Time {
+	year, month, day := now.UTC().Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
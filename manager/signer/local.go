@@ -0,0 +1,279 @@
+package signer
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/go-bip39"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// localKey is a private key held in memory.
+type localKey struct {
+	priv *secp256k1.PrivKey
+}
+
+func (k *localKey) PubKey() *secp256k1.PubKey {
+	return k.priv.PubKey().(*secp256k1.PubKey)
+}
+
+func (k *localKey) Sign(msg []byte) ([]byte, error) {
+	return k.priv.Sign(msg)
+}
+
+// fromMnemonic derives the key of the first account of the KIRA BIP44 path.
+func fromMnemonic(mnemonic string) (*localKey, error) {
+	mnemonic = strings.TrimSpace(mnemonic)
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("signer: invalid mnemonic")
+	}
+
+	derived, err := hd.Secp256k1.Derive()(mnemonic, "", sdk.GetConfig().GetFullBIP44Path())
+	if err != nil {
+		return nil, err
+	}
+
+	return &localKey{priv: &secp256k1.PrivKey{Key: derived}}, nil
+}
+
+func newMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+
+	return bip39.NewMnemonic(entropy)
+}
+
+// writeSecret replaces path with data readable by the owner only.
+func writeSecret(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// copySecret copies src to dst readable by the owner only, it fails when dst
+// exists.
+func copySecret(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		os.Remove(dst)
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		file.Close()
+		os.Remove(dst)
+		return err
+	}
+
+	return file.Close()
+}
+
+// mnemonicBackend is the plaintext mnemonic file of older releases, created
+// on first start. Its mode is narrowed to the owner.
+type mnemonicBackend struct {
+	path string
+}
+
+func (b *mnemonicBackend) Load() (Key, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		mnemonic, err := newMnemonic()
+		if err != nil {
+			return nil, err
+		}
+
+		if err = writeSecret(b.path, []byte(mnemonic)); err != nil {
+			return nil, err
+		}
+
+		return fromMnemonic(mnemonic)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Chmod(b.path, 0600); err != nil {
+		logger.Logger.Warn("Signer - cannot restrict the mnemonic file", zap.String("path", b.path), zap.Error(err))
+	}
+
+	return fromMnemonic(string(data))
+}
+
+// keystoreBackend keeps the key in a file encrypted with a passphrase, in the
+// armored format of "sekaid keys export". On first start the key of the legacy
+// mnemonic file is imported if there is one, otherwise a new key is generated.
+type keystoreBackend struct {
+	path         string
+	passphrase   string
+	mnemonicFile string
+
+	mu sync.Mutex
+}
+
+func newKeystoreBackend(config types.SignerConfig) (*keystoreBackend, error) {
+	var passphrase string
+
+	if config.PassphraseFile != "" {
+		data, err := os.ReadFile(config.PassphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("signer: read passphrase: %w", err)
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	} else {
+		passphrase = os.Getenv(orDefault(config.PassphraseEnv, defaultPassphraseEnv))
+	}
+
+	if passphrase == "" {
+		return nil, errors.New("signer: the keystore backend needs a passphrase")
+	}
+
+	return &keystoreBackend{
+		path:         orDefault(config.Keystore, defaultKeystore),
+		passphrase:   passphrase,
+		mnemonicFile: orDefault(config.MnemonicFile, defaultMnemonicFile),
+	}, nil
+}
+
+func (b *keystoreBackend) Load() (Key, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b.create()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	priv, _, err := crypto.UnarmorDecryptPrivKey(string(data), b.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("signer: open keystore: %w", err)
+	}
+
+	secpPriv, ok := priv.(*secp256k1.PrivKey)
+	if !ok {
+		return nil, fmt.Errorf("signer: keystore holds a %s key, secp256k1 expected", priv.Type())
+	}
+
+	return &localKey{priv: secpPriv}, nil
+}
+
+func (b *keystoreBackend) create() (Key, error) {
+	var key *localKey
+
+	if data, err := os.ReadFile(b.mnemonicFile); err == nil {
+		if key, err = fromMnemonic(string(data)); err != nil {
+			return nil, err
+		}
+
+		logger.Logger.Warn("Signer - imported the legacy mnemonic file into the keystore, delete it once the keystore is backed up",
+			zap.String("mnemonic_file", b.mnemonicFile), zap.String("keystore", b.path))
+	} else {
+		key = &localKey{priv: secp256k1.GenPrivKey()}
+	}
+
+	if err := b.write(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Rotate generates a new key. The previous keystore is copied next to the new
+// one, suffixed with the rotation time in nanoseconds, so that its funds can be
+// recovered, and its path is returned. An existing backup is never overwritten.
+func (b *keystoreBackend) Rotate() (Key, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	backup := fmt.Sprintf("%s.%d", b.path, time.Now().UTC().UnixNano())
+	if err := copySecret(b.path, backup); errors.Is(err, os.ErrNotExist) {
+		backup = ""
+	} else if err != nil {
+		return nil, "", fmt.Errorf("signer: back up keystore: %w", err)
+	}
+
+	key := &localKey{priv: secp256k1.GenPrivKey()}
+	if err := b.write(key); err != nil {
+		return nil, "", err
+	}
+
+	logger.Logger.Info("Signer - keystore rotated", zap.String("previous", backup))
+
+	return key, backup, nil
+}
+
+func (b *keystoreBackend) write(key *localKey) error {
+	armor := crypto.EncryptArmorPrivKey(key.priv, b.passphrase, string(hd.Secp256k1Type))
+
+	return writeSecret(b.path, []byte(armor))
+}
+
+// fileBackend reads a mnemonic or a hex encoded private key from a mounted
+// secret on every load, replacing the secret rotates the key.
+type fileBackend struct {
+	path string
+}
+
+func (b *fileBackend) Load() (Key, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := strings.TrimSpace(string(data))
+	if strings.Contains(secret, " ") {
+		return fromMnemonic(secret)
+	}
+
+	keyBytes, err := hex.DecodeString(strings.TrimPrefix(secret, "0x"))
+	if err != nil || len(keyBytes) != secp256k1.PrivKeySize {
+		return nil, errors.New("signer: key file holds neither a mnemonic nor a hex secp256k1 private key")
+	}
+
+	return &localKey{priv: &secp256k1.PrivKey{Key: keyBytes}}, nil
+}
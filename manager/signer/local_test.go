@@ -0,0 +1,223 @@
+package signer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	m.Run()
+}
+
+func newTestKeystore(t *testing.T, passphrase string) (*keystoreBackend, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	passphraseFile := filepath.Join(dir, "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte(passphrase+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := newKeystoreBackend(types.SignerConfig{
+		Keystore:       filepath.Join(dir, "keystore.armor"),
+		PassphraseFile: passphraseFile,
+		MnemonicFile:   filepath.Join(dir, "mnemonic.data"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return backend, dir
+}
+
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, "keystore.armor.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return matches
+}
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	backend, dir := newTestKeystore(t, "correct horse")
+
+	created, err := backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(backend.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("keystore mode %o, want 600", info.Mode().Perm())
+	}
+
+	loaded, err := backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.PubKey().Equals(created.PubKey()) {
+		t.Fatal("loaded key differs from the created one")
+	}
+
+	rotated, backup, err := backend.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.PubKey().Equals(created.PubKey()) {
+		t.Fatal("rotation kept the key")
+	}
+
+	loaded, err = backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.PubKey().Equals(rotated.PubKey()) {
+		t.Fatal("loaded key differs from the rotated one")
+	}
+
+	previous := backups(t, dir)
+	if len(previous) != 1 {
+		t.Fatalf("%d backups, want 1", len(previous))
+	}
+	if previous[0] != backup {
+		t.Fatalf("backup at %s, Rotate returned %s", previous[0], backup)
+	}
+
+	data, err := os.ReadFile(previous[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	priv, _, err := crypto.UnarmorDecryptPrivKey(string(data), backend.passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.PubKey().Equals(created.PubKey()) {
+		t.Fatal("backup does not hold the previous key")
+	}
+
+	data = []byte("sign me")
+	signature, err := loaded.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotated.PubKey().VerifySignature(data, signature) {
+		t.Fatal("signature of the loaded key does not verify")
+	}
+}
+
+func TestKeystoreRotateKeepsEveryBackup(t *testing.T) {
+	backend, dir := newTestKeystore(t, "correct horse")
+
+	if _, err := backend.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := backend.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := len(backups(t, dir)); got != 3 {
+		t.Fatalf("%d backups after 3 rotations, want 3", got)
+	}
+}
+
+func TestKeystoreWrongPassphrase(t *testing.T) {
+	backend, _ := newTestKeystore(t, "correct horse")
+
+	if _, err := backend.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.passphrase = "battery staple"
+	if _, err := backend.Load(); err == nil {
+		t.Fatal("keystore opened with the wrong passphrase")
+	}
+}
+
+func TestKeystoreImportsMnemonic(t *testing.T) {
+	backend, _ := newTestKeystore(t, "correct horse")
+
+	mnemonic, err := newMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(backend.mnemonicFile, []byte(mnemonic), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := fromMnemonic(mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PubKey().Equals(want.PubKey()) {
+		t.Fatal("keystore does not hold the key of the mnemonic")
+	}
+}
+
+func TestCopySecret(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	if err := os.WriteFile(src, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copySecret(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte("secret")) {
+		t.Fatalf("copied %q", data)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("copy mode %o, want 600", info.Mode().Perm())
+	}
+
+	if err = os.WriteFile(src, []byte("other"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = copySecret(src, dst); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("got %v, want the existing copy kept", err)
+	}
+	if data, _ = os.ReadFile(dst); !bytes.Equal(data, []byte("secret")) {
+		t.Fatal("existing copy was overwritten")
+	}
+
+	if err = copySecret(filepath.Join(dir, "missing"), filepath.Join(dir, "other")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v, want not exist", err)
+	}
+}
@@ -0,0 +1,120 @@
+package signer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+)
+
+// remoteRequest and remoteResponse are the messages of the remote signer
+// protocol, one JSON object per line: {"method": "pub_key"} answered with the
+// compressed public key, {"method": "sign", "sign_bytes": ...} answered with
+// the 64 byte r||s signature of sha256(sign_bytes). Bytes are base64 and
+// failures are answered with error.
+type remoteRequest struct {
+	Method    string `json:"method"`
+	SignBytes []byte `json:"sign_bytes,omitempty"`
+}
+
+type remoteResponse struct {
+	PubKey    []byte `json:"pub_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// remoteBackend asks a signer process for the key, like tmkms does for
+// validators, so that the key never enters interx. A connection is opened per
+// call.
+type remoteBackend struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+func newRemoteBackend(address string, timeout time.Duration) (*remoteBackend, error) {
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		return &remoteBackend{network: "unix", address: strings.TrimPrefix(address, "unix://"), timeout: timeout}, nil
+	case strings.HasPrefix(address, "tcp://"):
+		return &remoteBackend{network: "tcp", address: strings.TrimPrefix(address, "tcp://"), timeout: timeout}, nil
+	}
+
+	return nil, fmt.Errorf("signer: remote address %q must start with unix:// or tcp://", address)
+}
+
+func (b *remoteBackend) call(request remoteRequest) (*remoteResponse, error) {
+	conn, err := net.DialTimeout(b.network, b.address, b.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("signer: dial remote signer: %w", err)
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(b.timeout)); err != nil {
+		return nil, err
+	}
+
+	if err = json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, fmt.Errorf("signer: send to remote signer: %w", err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("signer: read from remote signer: %w", err)
+	}
+
+	response := new(remoteResponse)
+	if err = json.Unmarshal(line, response); err != nil {
+		return nil, fmt.Errorf("signer: invalid remote signer response: %w", err)
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("signer: remote signer: %s", response.Error)
+	}
+
+	return response, nil
+}
+
+func (b *remoteBackend) Load() (Key, error) {
+	response, err := b.call(remoteRequest{Method: "pub_key"})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.PubKey) != secp256k1.PubKeySize {
+		return nil, fmt.Errorf("signer: remote signer sent a %d byte public key", len(response.PubKey))
+	}
+
+	return &remoteKey{backend: b, pubKey: &secp256k1.PubKey{Key: response.PubKey}}, nil
+}
+
+// remoteKey signs through the remote signer and checks every signature against
+// the public key it was loaded with, a signer that rotated in between fails
+// until the next reload.
+type remoteKey struct {
+	backend *remoteBackend
+	pubKey  *secp256k1.PubKey
+}
+
+func (k *remoteKey) PubKey() *secp256k1.PubKey {
+	return k.pubKey
+}
+
+func (k *remoteKey) Sign(msg []byte) ([]byte, error) {
+	response, err := k.backend.call(remoteRequest{Method: "sign", SignBytes: msg})
+	if err != nil {
+		return nil, err
+	}
+
+	if !k.pubKey.VerifySignature(msg, response.Signature) {
+		return nil, errors.New("signer: remote signature does not match the public key " + base64.StdEncoding.EncodeToString(k.pubKey.Key))
+	}
+
+	return response.Signature, nil
+}
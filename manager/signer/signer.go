@@ -0,0 +1,216 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	defaultMnemonicFile  = "mnemonic.data"
+	defaultKeystore      = "keystore.armor"
+	defaultPassphraseEnv = "INTERX_KEYSTORE_PASSPHRASE"
+	defaultTimeout       = 5 * time.Second
+)
+
+var ErrRotateUnsupported = apierror.New(apierror.FailedPrecondition, "the signer backend does not generate keys, rotate it at its source")
+
+// Key signs with one secp256k1 key. Keys do not change, a rotation replaces
+// the Key held by the Signer.
+type Key interface {
+	PubKey() *secp256k1.PubKey
+	Sign(msg []byte) ([]byte, error)
+}
+
+// Backend gives the current key of where it is kept. Load is called again on
+// every reload and returns the new key once it was rotated at the source.
+type Backend interface {
+	Load() (Key, error)
+}
+
+// Rotator is a backend that can replace its key with a new one. Rotate also
+// returns where the previous key was backed up, empty when there was none.
+type Rotator interface {
+	Rotate() (Key, string, error)
+}
+
+// Signer holds the key of a backend and swaps it when the backend rotates.
+// Callers take the Key once per signature so that the public key they put in
+// a transaction matches the key that signs it.
+type Signer struct {
+	name    string
+	backend Backend
+	reload  time.Duration
+
+	mu        sync.RWMutex
+	key       Key
+	rotatedAt time.Time
+}
+
+func New(config types.SignerConfig) (*Signer, error) {
+	var backend Backend
+	var err error
+
+	name := config.Backend
+	if name == "" {
+		name = "mnemonic"
+	}
+
+	switch name {
+	case "mnemonic":
+		backend = &mnemonicBackend{path: orDefault(config.MnemonicFile, defaultMnemonicFile)}
+	case "keystore":
+		backend, err = newKeystoreBackend(config)
+	case "file":
+		if config.KeyFile == "" {
+			return nil, fmt.Errorf("signer: key_file is required by the file backend")
+		}
+		backend = &fileBackend{path: config.KeyFile}
+	case "remote":
+		backend, err = newRemoteBackend(config.Remote, seconds(config.Timeout, defaultTimeout))
+	default:
+		return nil, fmt.Errorf("signer: unknown backend %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		name:    name,
+		backend: backend,
+		reload:  seconds(config.Reload, 0),
+		key:     key,
+	}, nil
+}
+
+func (s *Signer) Key() Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.key
+}
+
+// Address is the bech32 account address of the current key.
+func (s *Signer) Address() string {
+	return sdk.AccAddress(s.Key().PubKey().Address()).String()
+}
+
+func (s *Signer) Status() types.SignerStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := types.SignerStatus{
+		Backend: s.name,
+		Address: sdk.AccAddress(s.key.PubKey().Address()).String(),
+		PubKey:  s.key.PubKey().String(),
+	}
+	if !s.rotatedAt.IsZero() {
+		status.RotatedAt = s.rotatedAt.Unix()
+	}
+
+	return status
+}
+
+// Rotate makes the backend generate a new key. Backends whose keys are kept
+// elsewhere are reloaded instead, after the key was replaced at its source.
+func (s *Signer) Rotate() (types.SignerRotation, error) {
+	previous := s.Address()
+
+	rotator, ok := s.backend.(Rotator)
+	if !ok {
+		if _, err := s.Reload(); err != nil {
+			return types.SignerRotation{}, err
+		}
+
+		return types.SignerRotation{SignerStatus: s.Status(), PreviousAddress: previous}, nil
+	}
+
+	key, backup, err := rotator.Rotate()
+	if err != nil {
+		logger.Logger.Error("Signer - Rotate", zap.Error(err))
+		return types.SignerRotation{}, err
+	}
+
+	s.swap(key)
+
+	return types.SignerRotation{SignerStatus: s.Status(), PreviousAddress: previous, Backup: backup}, nil
+}
+
+// Reload loads the key of the backend again and reports whether it changed.
+func (s *Signer) Reload() (bool, error) {
+	key, err := s.backend.Load()
+	if err != nil {
+		logger.Logger.Error("Signer - Reload", zap.Error(err))
+		return false, err
+	}
+
+	if key.PubKey().Equals(s.Key().PubKey()) {
+		return false, nil
+	}
+
+	s.swap(key)
+
+	return true, nil
+}
+
+func (s *Signer) swap(key Key) {
+	s.mu.Lock()
+	previous := s.key
+	s.key = key
+	s.rotatedAt = time.Now().UTC()
+	s.mu.Unlock()
+
+	logger.Logger.Info("Signer - key rotated",
+		zap.String("from", sdk.AccAddress(previous.PubKey().Address()).String()),
+		zap.String("to", sdk.AccAddress(key.PubKey().Address()).String()))
+}
+
+// Watch reloads the key every reload seconds until ctx ends, it returns at
+// once when reloads are disabled.
+func (s *Signer) Watch(ctx context.Context) {
+	if s.reload <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.reload)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = s.Reload()
+		}
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func seconds(value int, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+
+	return time.Duration(value) * time.Second
+}
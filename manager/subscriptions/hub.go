@@ -0,0 +1,228 @@
+package subscriptions
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	TopicBlocks    = "blocks"
+	TopicTxs       = "txs"
+	TopicAddress   = "address"
+	TopicProposals = "proposals"
+
+	// seenLimit bounds each generation of the event ID set used to drop
+	// events that come back through another peer.
+	seenLimit = 10000
+)
+
+var (
+	ErrTooManyClients       = errors.New("too many subscription clients")
+	ErrTooManySubscriptions = errors.New("too many subscriptions")
+	ErrUnknownSubscription  = errors.New("unknown subscription")
+)
+
+type subscription struct {
+	topic  string
+	filter types.SubscriptionFilter
+}
+
+// Client is one WebSocket connection or SSE stream. Notifications are queued on
+// Events, a client that lets the queue fill up is dropped and Done is closed.
+type Client struct {
+	Events        chan *types.SubscriptionNotification
+	done          chan struct{}
+	once          sync.Once
+	subscriptions map[string]subscription
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+type Hub struct {
+	mutex            sync.RWMutex
+	clients          map[*Client]struct{}
+	bufferSize       int
+	maxClients       int
+	maxSubscriptions int
+
+	seenMutex    sync.Mutex
+	seen         map[string]struct{}
+	previousSeen map[string]struct{}
+}
+
+func NewHub(bufferSize, maxClients, maxSubscriptions int) *Hub {
+	return &Hub{
+		clients:          make(map[*Client]struct{}),
+		bufferSize:       bufferSize,
+		maxClients:       maxClients,
+		maxSubscriptions: maxSubscriptions,
+		seen:             make(map[string]struct{}),
+		previousSeen:     make(map[string]struct{}),
+	}
+}
+
+func (h *Hub) Register() (*Client, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.maxClients > 0 && len(h.clients) >= h.maxClients {
+		return nil, ErrTooManyClients
+	}
+
+	client := &Client{
+		Events:        make(chan *types.SubscriptionNotification, h.bufferSize),
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]subscription),
+	}
+	h.clients[client] = struct{}{}
+
+	return client, nil
+}
+
+func (h *Hub) Unregister(client *Client) {
+	h.mutex.Lock()
+	delete(h.clients, client)
+	h.mutex.Unlock()
+
+	client.close()
+}
+
+func (h *Hub) Subscribe(client *Client, topic string, filter types.SubscriptionFilter) (string, error) {
+	if err := validateSubscription(topic, filter); err != nil {
+		return "", err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.clients[client]; !ok {
+		return "", ErrUnknownSubscription
+	}
+
+	if h.maxSubscriptions > 0 && len(client.subscriptions) >= h.maxSubscriptions {
+		return "", ErrTooManySubscriptions
+	}
+
+	id := uuid.NewString()
+	client.subscriptions[id] = subscription{topic: topic, filter: filter}
+
+	return id, nil
+}
+
+func (h *Hub) Unsubscribe(client *Client, id string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := client.subscriptions[id]; !ok {
+		return ErrUnknownSubscription
+	}
+	delete(client.subscriptions, id)
+
+	return nil
+}
+
+// Publish delivers event to every matching subscription. It returns false when
+// the event ID was already published, so callers only gossip it once.
+func (h *Hub) Publish(event *types.SubscriptionEvent) bool {
+	if !h.markSeen(event.ID) {
+		return false
+	}
+
+	var slow []*Client
+
+	h.mutex.RLock()
+	for client := range h.clients {
+		for id, sub := range client.subscriptions {
+			if !sub.matches(event) {
+				continue
+			}
+
+			select {
+			case client.Events <- &types.SubscriptionNotification{Subscription: id, Event: event}:
+				continue
+			default:
+				slow = append(slow, client)
+			}
+			break
+		}
+	}
+	h.mutex.RUnlock()
+
+	for _, client := range slow {
+		h.Unregister(client)
+	}
+
+	return true
+}
+
+func (h *Hub) markSeen(id string) bool {
+	h.seenMutex.Lock()
+	defer h.seenMutex.Unlock()
+
+	if _, ok := h.seen[id]; ok {
+		return false
+	}
+	if _, ok := h.previousSeen[id]; ok {
+		return false
+	}
+
+	if len(h.seen) >= seenLimit {
+		h.previousSeen = h.seen
+		h.seen = make(map[string]struct{})
+	}
+	h.seen[id] = struct{}{}
+
+	return true
+}
+
+func validateSubscription(topic string, filter types.SubscriptionFilter) error {
+	switch topic {
+	case TopicBlocks, TopicTxs, TopicProposals:
+		return nil
+	case TopicAddress:
+		if filter.Address == "" {
+			return fmt.Errorf("address filter is required for the %s topic", topic)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown topic: %s", topic)
+	}
+}
+
+func (s subscription) matches(event *types.SubscriptionEvent) bool {
+	switch s.topic {
+	case TopicBlocks:
+		return event.Block != nil
+	case TopicTxs:
+		return event.Tx != nil && (s.filter.TypeUrl == "" || contains(event.Tx.TypeUrls, s.filter.TypeUrl))
+	case TopicAddress:
+		return event.Tx != nil && contains(event.Tx.Addresses, s.filter.Address)
+	case TopicProposals:
+		return event.Proposal != nil && (s.filter.ProposalID == "" || event.Proposal.ProposalID == s.filter.ProposalID)
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
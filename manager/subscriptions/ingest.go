@@ -0,0 +1,229 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+// indexerTx and indexerBlock mirror the documents the cosmos indexer posts
+// with its "notify" requests (worker/cosmos/sai-cosmos-indexer/internal/model).
+type indexerTx struct {
+	Timestamp time.Time `json:"timestamp"`
+	Hash      string    `json:"hash"`
+	Height    string    `json:"height"`
+	TxResult  struct {
+		Code      int    `json:"code"`
+		GasWanted string `json:"gas_wanted"`
+		GasUsed   string `json:"gas_used"`
+		Events    []struct {
+			Type       string `json:"type"`
+			Attributes []struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"attributes"`
+		} `json:"events"`
+	} `json:"tx_result"`
+	Messages []interface{} `json:"messages"`
+}
+
+type indexerBlock struct {
+	BlockId struct {
+		Hash string `json:"hash"`
+	} `json:"block_id"`
+	Block struct {
+		Header struct {
+			Height          string    `json:"height"`
+			Time            time.Time `json:"time"`
+			ProposerAddress string    `json:"proposer_address"`
+		} `json:"header"`
+		Data struct {
+			Txs []string `json:"txs"`
+		} `json:"data"`
+	} `json:"block"`
+}
+
+func TxEventFromIndexer(data interface{}) (*types.SubscriptionEvent, error) {
+	var tx indexerTx
+	if err := remarshal(data, &tx); err != nil {
+		return nil, err
+	}
+
+	if tx.Hash == "" {
+		return nil, fmt.Errorf("tx hash is missing")
+	}
+
+	height, _ := strconv.ParseInt(tx.Height, 10, 64)
+	addresses := make(map[string]struct{})
+	typeUrls := make([]string, 0, len(tx.Messages))
+
+	for _, msg := range tx.Messages {
+		if m, ok := msg.(map[string]interface{}); ok {
+			if typeUrl, ok := m["typeUrl"].(string); ok && !contains(typeUrls, typeUrl) {
+				typeUrls = append(typeUrls, typeUrl)
+			}
+		}
+		collectAddresses(msg, addresses)
+	}
+
+	for _, event := range tx.TxResult.Events {
+		for _, attribute := range event.Attributes {
+			collectAddresses(attribute.Value, addresses)
+			// Tendermint 0.34 base64 encodes attribute values
+			if decoded, err := base64.StdEncoding.DecodeString(attribute.Value); err == nil {
+				collectAddresses(string(decoded), addresses)
+			}
+		}
+	}
+
+	return &types.SubscriptionEvent{
+		ID:     "tx:" + tx.Hash,
+		Type:   types.SubscriptionEventTx,
+		Height: height,
+		Tx: &types.TxEvent{
+			Hash:      tx.Hash,
+			Height:    height,
+			Timestamp: tx.Timestamp,
+			Code:      tx.TxResult.Code,
+			GasWanted: tx.TxResult.GasWanted,
+			GasUsed:   tx.TxResult.GasUsed,
+			TypeUrls:  typeUrls,
+			Addresses: sortedKeys(addresses),
+			Messages:  tx.Messages,
+		},
+	}, nil
+}
+
+func BlockEventFromIndexer(data interface{}) (*types.SubscriptionEvent, error) {
+	var block indexerBlock
+	if err := remarshal(data, &block); err != nil {
+		return nil, err
+	}
+
+	height, err := strconv.ParseInt(block.Block.Header.Height, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block height: %w", err)
+	}
+
+	return &types.SubscriptionEvent{
+		ID:     "block:" + block.Block.Header.Height,
+		Type:   types.SubscriptionEventBlock,
+		Height: height,
+		Block: &types.BlockEvent{
+			Height:   height,
+			Hash:     block.BlockId.Hash,
+			Time:     block.Block.Header.Time,
+			Proposer: block.Block.Header.ProposerAddress,
+			NumTxs:   len(block.Block.Data.Txs),
+		},
+	}, nil
+}
+
+// ProposalSource is implemented by the cosmos gateway.
+type ProposalSource interface {
+	ProposalStatuses(ctx context.Context) (map[string]string, error)
+}
+
+// ProposalWatcher diffs proposal statuses on every new block. The first check
+// only records a baseline so a restart does not replay every proposal.
+type ProposalWatcher struct {
+	source   ProposalSource
+	mutex    sync.Mutex
+	statuses map[string]string
+	running  atomic.Bool
+}
+
+func NewProposalWatcher(source ProposalSource) *ProposalWatcher {
+	return &ProposalWatcher{source: source}
+}
+
+// Check returns an event for every proposal that appeared or changed status.
+// Calls made while a previous check is still running are skipped.
+func (w *ProposalWatcher) Check(ctx context.Context, height int64) ([]*types.SubscriptionEvent, error) {
+	if !w.running.CompareAndSwap(false, true) {
+		return nil, nil
+	}
+	defer w.running.Store(false)
+
+	statuses, err := w.source.ProposalStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var events []*types.SubscriptionEvent
+
+	if w.statuses != nil {
+		for id, status := range statuses {
+			previous, known := w.statuses[id]
+			if known && previous == status {
+				continue
+			}
+
+			events = append(events, &types.SubscriptionEvent{
+				ID:     "proposal:" + id + ":" + status,
+				Type:   types.SubscriptionEventProposal,
+				Height: height,
+				Proposal: &types.ProposalEvent{
+					ProposalID:     id,
+					Status:         status,
+					PreviousStatus: previous,
+				},
+			})
+		}
+	}
+
+	w.statuses = statuses
+
+	return events, nil
+}
+
+// collectAddresses walks decoded JSON and keeps every string that decodes as a
+// bech32 account or validator address.
+func collectAddresses(value interface{}, addresses map[string]struct{}) {
+	switch v := value.(type) {
+	case string:
+		if _, data, err := bech32.DecodeAndConvert(v); err == nil && (len(data) == 20 || len(data) == 32) {
+			addresses[v] = struct{}{}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectAddresses(item, addresses)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectAddresses(item, addresses)
+		}
+	}
+}
+
+func sortedKeys(values map[string]struct{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func remarshal(data interface{}, target interface{}) error {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(dataBytes, target)
+}
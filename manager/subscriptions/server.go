@@ -0,0 +1,308 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-interx-manager/apierror"
+	"github.com/KiraCore/sai-interx-manager/logger"
+	"github.com/KiraCore/sai-interx-manager/types"
+)
+
+const (
+	writeWait    = 10 * time.Second
+	pongWait     = 60 * time.Second
+	pingInterval = 30 * time.Second
+	sseKeepAlive = 15 * time.Second
+	maxWSMessage = 4096
+)
+
+// Admission admits a connection before it is served, like a request. The
+// returned context is the one of the connection, types.Admit on it charges the
+// caller for every subscription or call made over the connection.
+type Admission func(r *http.Request) (context.Context, error)
+
+// Server exposes the hub over WebSocket (/subscriptions/ws) and Server-Sent
+// Events (/subscriptions/events). It runs beside the sai-service HTTP server
+// because that one only speaks request/response.
+type Server struct {
+	hub      *Hub
+	mux      *http.ServeMux
+	server   *http.Server
+	upgrader websocket.Upgrader
+	admit    Admission
+}
+
+func NewServer(hub *Hub, port int) *Server {
+	s := &Server{
+		hub: hub,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// the public proxy already applies CORS for every origin
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/subscriptions/ws", s.handleWebSocket)
+	s.mux.HandleFunc("/subscriptions/events", s.handleSSE)
+
+	s.server = &http.Server{
+		Addr:    ":" + strconv.Itoa(port),
+		Handler: http.HandlerFunc(s.serve),
+	}
+
+	return s
+}
+
+// Handle mounts another streaming endpoint, such as the EVM WebSocket proxy,
+// before Start is called.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// SetAdmission has every connection admitted before Start is called.
+func (s *Server) SetAdmission(admit Admission) {
+	s.admit = admit
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if s.admit != nil {
+		ctx, err := s.admit(r)
+		if err != nil {
+			writeError(w, err, r.Header.Get("X-Request-ID"))
+			return
+		}
+
+		r = r.WithContext(ctx)
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// writeError answers a refused connection with the error envelope.
+func writeError(w http.ResponseWriter, err error, traceID string) {
+	envelope, status := apierror.Response(err, traceID)
+
+	w.Header().Set("Content-Type", "application/json")
+	if envelope.Error.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt(envelope.Error.RetryAfter, 10))
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(envelope)
+}
+
+func (s *Server) Start() error {
+	logger.Logger.Info("Starting subscriptions server", zap.String("address", s.server.Addr))
+
+	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Logger.Error("Subscriptions - Start", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		logger.Logger.Error("Subscriptions - Close", zap.Error(err))
+	}
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	client, err := s.hub.Register()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer s.hub.Unregister(client)
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Logger.Error("Subscriptions - WebSocket", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	replies := make(chan *types.SubscriptionResponse, 16)
+	readerDone := make(chan struct{})
+
+	go func() {
+		defer close(readerDone)
+		s.readRequests(r.Context(), conn, client, replies)
+	}()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-readerDone:
+			return
+		case <-client.Done():
+			s.writeClose(conn, websocket.ClosePolicyViolation, "subscriber is too slow")
+			return
+		case reply := <-replies:
+			if err := s.writeJSON(conn, reply); err != nil {
+				return
+			}
+		case notification := <-client.Events:
+			if err := s.writeJSON(conn, notification); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readRequests serves the requests of a WebSocket client, every subscription
+// is charged to the caller of ctx.
+func (s *Server) readRequests(ctx context.Context, conn *websocket.Conn, client *Client, replies chan<- *types.SubscriptionResponse) {
+	conn.SetReadLimit(maxWSMessage)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var req types.SubscriptionRequest
+		err := conn.ReadJSON(&req)
+		if _, ok := err.(*json.SyntaxError); !ok && err != nil {
+			return
+		}
+
+		reply := &types.SubscriptionResponse{ID: req.ID}
+
+		switch {
+		case err != nil:
+			reply.Error = "invalid request"
+		case req.Action == "subscribe":
+			if err := types.Admit(ctx); err != nil {
+				reply.Error = err.Error()
+				break
+			}
+
+			id, err := s.hub.Subscribe(client, req.Topic, req.Filter)
+			if err != nil {
+				reply.Error = err.Error()
+			} else {
+				reply.Subscription = id
+			}
+		case req.Action == "unsubscribe":
+			if err := s.hub.Unsubscribe(client, req.Subscription); err != nil {
+				reply.Error = err.Error()
+			} else {
+				reply.Subscription = req.Subscription
+			}
+		default:
+			reply.Error = fmt.Sprintf("unknown action: %s", req.Action)
+		}
+
+		select {
+		case replies <- reply:
+		case <-client.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) writeJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(v)
+}
+
+func (s *Server) writeClose(conn *websocket.Conn, code int, text string) {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, text))
+}
+
+// handleSSE streams one or more topics, e.g.
+// /subscriptions/events?topic=txs&topic=address&address=kira1...&type_url=/kira.gov.MsgVoteProposal
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	topics := query["topic"]
+	if len(topics) == 0 {
+		http.Error(w, "topic is required", http.StatusBadRequest)
+		return
+	}
+
+	filter := types.SubscriptionFilter{
+		TypeUrl:    query.Get("type_url"),
+		Address:    query.Get("address"),
+		ProposalID: query.Get("proposal_id"),
+	}
+
+	client, err := s.hub.Register()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer s.hub.Unregister(client)
+
+	// the connection paid for the first topic, the others are charged one by one
+	for i, topic := range topics {
+		if i > 0 {
+			if err := types.Admit(r.Context()); err != nil {
+				writeError(w, err, r.Header.Get("X-Request-ID"))
+				return
+			}
+		}
+
+		if _, err := s.hub.Subscribe(client, topic, filter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client.Done():
+			return
+		case notification := <-client.Events:
+			data, err := json.Marshal(notification)
+			if err != nil {
+				logger.Logger.Error("Subscriptions - SSE", zap.Error(err))
+				continue
+			}
+
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", notification.Event.ID, notification.Event.Type, data)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
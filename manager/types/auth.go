@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+// AuthConfig sets the wallet signature login. Challenges and sessions are kept
+// in storage so that any manager can verify and serve them. TTLs are seconds.
+type AuthConfig struct {
+	Enabled             bool   `json:"enabled"`
+	Domain              string `json:"domain"`
+	ChallengeCollection string `json:"challenge_collection"`
+	SessionCollection   string `json:"session_collection"`
+	ChallengeTTL        int    `json:"challenge_ttl"`
+	SessionTTL          int    `json:"session_ttl"`
+}
+
+// AuthChallenge is the message a wallet signs to prove it holds an address.
+type AuthChallenge struct {
+	Nonce     string    `json:"nonce"`
+	Address   string    `json:"address"`
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AuthSession is a login. Token is only shown when the session is created,
+// storage keeps its SHA-256 hash.
+type AuthSession struct {
+	Token     string    `json:"token,omitempty"`
+	Address   string    `json:"address"`
+	Scheme    string    `json:"scheme"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
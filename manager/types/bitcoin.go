@@ -0,0 +1,81 @@
+package types
+
+import "encoding/json"
+
+type BitcoinRPCRequest struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	ID      string      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type BitcoinRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *BitcoinRPCError) Error() string {
+	return e.Message
+}
+
+type BitcoinRPCResponse struct {
+	Result json.RawMessage  `json:"result"`
+	Error  *BitcoinRPCError `json:"error"`
+	ID     string           `json:"id"`
+}
+
+type BitcoinStatus struct {
+	NodeInfo struct {
+		Network    string `json:"network"`
+		RPCAddress string `json:"rpc_address"`
+		Version    struct {
+			Node       int    `json:"node"`
+			SubVersion string `json:"sub_version"`
+			Protocol   int    `json:"protocol"`
+		} `json:"version"`
+	} `json:"node_info"`
+	SyncInfo struct {
+		CatchingUp           bool    `json:"catching_up"`
+		Headers              uint64  `json:"headers"`
+		VerificationProgress float64 `json:"verification_progress"`
+		Pruned               bool    `json:"pruned"`
+		EarliestBlockHash    string  `json:"earliest_block_hash"`
+		EarliestBlockHeight  uint64  `json:"earliest_block_height"`
+		EarliestBlockTime    uint64  `json:"earliest_block_time"`
+		LatestBlockHash      string  `json:"latest_block_hash"`
+		LatestBlockHeight    uint64  `json:"latest_block_height"`
+		LatestBlockTime      uint64  `json:"latest_block_time"`
+	} `json:"sync_info"`
+	Mempool struct {
+		Size       uint64  `json:"size"`
+		Bytes      uint64  `json:"bytes"`
+		MinFeeRate float64 `json:"min_fee_rate"`
+	} `json:"mempool"`
+	// FeeEstimates maps a confirmation target in blocks to a fee rate in BTC/kvB.
+	FeeEstimates map[string]float64 `json:"fee_estimates"`
+}
+
+type BitcoinUtxo struct {
+	Txid        string `json:"txid"`
+	Vout        uint32 `json:"vout"`
+	Address     string `json:"address"`
+	Value       int64  `json:"value"`
+	Height      int64  `json:"height"`
+	Coinbase    bool   `json:"coinbase"`
+	Spent       bool   `json:"spent"`
+	SpentTxid   string `json:"spent_txid,omitempty"`
+	SpentHeight int64  `json:"spent_height,omitempty"`
+}
+
+type BitcoinBalanceResponse struct {
+	Address       string `json:"address"`
+	Balance       int64  `json:"balance"`
+	UtxoCount     int    `json:"utxo_count"`
+	IndexedHeight int64  `json:"indexed_height"`
+}
+
+type BitcoinUtxosResponse struct {
+	Utxos         []BitcoinUtxo `json:"utxos"`
+	IndexedHeight int64         `json:"indexed_height"`
+	Pagination    *Pagination   `json:"pagination,omitempty"`
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"time"
 
 	types2 "github.com/cometbft/cometbft/types"
@@ -359,29 +360,127 @@ type BalancesResponse struct {
 	Pagination *Pagination `json:"pagination,omitempty"`
 }
 
+// FaucetRequest claims Token for the Claim address, several tokens can be
+// claimed at once as a comma separated list.
 type FaucetRequest struct {
-	Claim string `json:"claim,omitempty"`
-	Token string `json:"token,omitempty"`
+	Claim       string `json:"claim,omitempty"`
+	Token       string `json:"token,omitempty"`
+	PowNonce    string `json:"pow_nonce,omitempty"`
+	PowSolution string `json:"pow_solution,omitempty"`
+	Captcha     string `json:"captcha,omitempty"`
+}
+
+// FaucetConfig sets what the faucet dispenses and how claims are batched.
+// BatchWindow is in milliseconds, ConfirmTimeout in seconds.
+type FaucetConfig struct {
+	FaucetAmounts        map[string]int64 `json:"faucet_amounts"`
+	FaucetMinimumAmounts map[string]int64 `json:"faucet_minimum_amounts"`
+	FeeAmounts           map[string]int64 `json:"fee_amounts"`
+	FeeDenom             string           `json:"fee_denom"`
+	TimeLimit            int64            `json:"time_limit,float64"`
+	Queue                int              `json:"queue"`
+	BatchSize            int              `json:"batch_size"`
+	BatchWindow          int              `json:"batch_window"`
+	GasLimit             uint64           `json:"gas_limit"`
+	GasPerOutput         uint64           `json:"gas_per_output"`
+	ConfirmTimeout       int              `json:"confirm_timeout"`
+	Protection           FaucetProtection `json:"protection"`
+}
+
+const (
+	FaucetClaimQueued    = "queued"
+	FaucetClaimBroadcast = "broadcast"
+	FaucetClaimCommitted = "committed"
+	FaucetClaimFailed    = "failed"
+	FaucetClaimUnknown   = "unknown"
+)
+
+// FaucetClaim is an accepted claim and how far it got: queued, broadcast in
+// TxHash, unknown when not committed in time, committed, or failed with Error.
+// Times are unix seconds.
+type FaucetClaim struct {
+	ID        string    `json:"id"`
+	Address   string    `json:"address"`
+	Coins     sdk.Coins `json:"coins"`
+	Status    string    `json:"status"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Identity  string    `json:"identity,omitempty"`
+	CreatedAt int64     `json:"created_at"`
+	UpdatedAt int64     `json:"updated_at"`
 }
 
 type CosmosConfig struct {
-	Node struct {
-		JsonRpc    string `json:"json_rpc"`
-		Tendermint string `json:"tendermint"`
-	}
-	TxModes map[string]bool `json:"tx_modes"`
-	Faucet  struct {
-		FaucetAmounts        map[string]int64 `json:"faucet_amounts"`
-		FaucetMinimumAmounts map[string]int64 `json:"faucet_minimum_amounts"`
-		FeeAmounts           map[string]int64 `json:"fee_amounts"`
-		TimeLimit            int64            `json:"time_limit,float64"`
-	}
-	GWTimeout   int    `json:"gw_timeout,float64"`
-	Interaction string `json:"interaction"`
-	Token       string `json:"token"`
-	Retries     int    `json:"retries,float64"`
-	RetryDelay  int    `json:"retry_delay,float64"`
-	RateLimit   int    `json:"rate_limit,float64"`
+	// Node is the single backend of older configs, it is used when Nodes is empty
+	Node        CosmosNode        `json:"node"`
+	Nodes       []CosmosNode      `json:"nodes"`
+	HealthCheck HealthCheckConfig `json:"health_check"`
+	TxModes     map[string]bool   `json:"tx_modes"`
+	Faucet      FaucetConfig      `json:"faucet"`
+	Signer      SignerConfig      `json:"signer"`
+	Simulate    SimulateConfig    `json:"simulate"`
+	GWTimeout   int               `json:"gw_timeout,float64"`
+	Timeouts    map[string]int    `json:"timeouts"`
+	Interaction string            `json:"interaction"`
+	Token       string            `json:"token"`
+	Retry       RetryConfig       `json:"retry"`
+	RateLimit   int               `json:"rate_limit,float64"`
+	RateLimits  map[string]int    `json:"rate_limits"`
+	Cache       CacheConfig       `json:"cache"`
+}
+
+// SimulateConfig sets the gas limit recommended by /kira/txs/simulate: the gas
+// used times GasAdjustment, never below MinGas.
+type SimulateConfig struct {
+	GasAdjustment float64 `json:"gas_adjustment"`
+	MinGas        uint64  `json:"min_gas"`
+}
+
+// SimulateTxResponse is the outcome of a simulated transaction. Fee is what
+// the sekai ante handler asks for: the network minimum fee or the execution
+// fees of the messages, whichever is larger, in the default denom.
+type SimulateTxResponse struct {
+	GasUsed       uint64           `json:"gas_used,string"`
+	GasWanted     uint64           `json:"gas_wanted,string"`
+	GasLimit      uint64           `json:"gas_limit,string"`
+	GasAdjustment float64          `json:"gas_adjustment"`
+	Fee           sdk.Coins        `json:"fee"`
+	MinTxFee      string           `json:"min_tx_fee"`
+	MaxTxFee      string           `json:"max_tx_fee"`
+	Messages      []SimulateMsgFee `json:"messages"`
+	Result        interface{}      `json:"result,omitempty"`
+}
+
+// SimulateMsgFee is the execution fee of one message, Type is the name fees
+// are registered under in /kira/gov/execution_fee.
+type SimulateMsgFee struct {
+	TypeURL      string `json:"type_url"`
+	Type         string `json:"type"`
+	ExecutionFee string `json:"execution_fee"`
+	FailureFee   string `json:"failure_fee"`
+}
+
+// CosmosNode is one sekai backend, its gRPC and Tendermint RPC endpoints.
+// Archive nodes only get the requests no pruned node can serve.
+type CosmosNode struct {
+	Name       string `json:"name"`
+	JsonRpc    string `json:"json_rpc"`
+	Tendermint string `json:"tendermint"`
+	Archive    bool   `json:"archive"`
+}
+
+type HealthCheckConfig struct {
+	Interval int `json:"interval,float64"`
+	Timeout  int `json:"timeout,float64"`
+}
+
+type CacheConfig struct {
+	Backend       string         `json:"backend"`
+	Size          int            `json:"size,float64"`
+	GRPCTTL       int            `json:"grpc_ttl,float64"`
+	TendermintTTL int            `json:"tendermint_ttl,float64"`
+	HeightPoll    int            `json:"height_poll,float64"`
+	Routes        map[string]int `json:"routes"`
 }
 
 type AccountInfo struct {
@@ -395,3 +494,89 @@ type AccountInfo struct {
 type AccountResponse struct {
 	Account *AccountInfo `json:"account"`
 }
+
+type BasketToken struct {
+	Denom     string      `json:"denom"`
+	Weight    string      `json:"weight"`
+	Amount    string      `json:"amount"`
+	Deposits  bool        `json:"deposits"`
+	Withdraws bool        `json:"withdraws"`
+	Swaps     bool        `json:"swaps"`
+	Rate      *TokenAlias `json:"rate,omitempty"`
+}
+
+type Basket struct {
+	ID              string        `json:"id"`
+	Denom           string        `json:"denom"`
+	Suffix          string        `json:"suffix"`
+	Description     string        `json:"description"`
+	Amount          string        `json:"amount"`
+	SwapFee         string        `json:"swapFee"`
+	SlipppageFeeMin string        `json:"slipppageFeeMin"`
+	TokensCap       string        `json:"tokensCap"`
+	LimitsPeriod    string        `json:"limitsPeriod"`
+	MintsMin        string        `json:"mintsMin"`
+	MintsMax        string        `json:"mintsMax"`
+	MintsDisabled   bool          `json:"mintsDisabled"`
+	BurnsMin        string        `json:"burnsMin"`
+	BurnsMax        string        `json:"burnsMax"`
+	BurnsDisabled   bool          `json:"burnsDisabled"`
+	SwapsMin        string        `json:"swapsMin"`
+	SwapsMax        string        `json:"swapsMax"`
+	SwapsDisabled   bool          `json:"swapsDisabled"`
+	Tokens          []BasketToken `json:"tokens"`
+	Surplus         []string      `json:"surplus"`
+	Rate            *TokenAlias   `json:"rate,omitempty"`
+}
+
+type BasketsGRPCResponse struct {
+	Baskets []Basket `json:"baskets"`
+}
+
+type BasketsResponse struct {
+	Baskets    []Basket    `json:"baskets"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type DappSession struct {
+	Leader     string `json:"leader"`
+	Start      string `json:"start"`
+	StatusHash string `json:"statusHash"`
+	Status     string `json:"status"`
+	Gateway    string `json:"gateway"`
+}
+
+type ExecutionRegistrar struct {
+	DappName    string       `json:"dappName"`
+	PrevSession *DappSession `json:"prevSession"`
+	CurrSession *DappSession `json:"currSession"`
+	NextSession *DappSession `json:"nextSession"`
+}
+
+type ExecutionRegistrarResponse struct {
+	ExecutionRegistrar *ExecutionRegistrar `json:"executionRegistrar"`
+}
+
+type DappInfo struct {
+	Name               string              `json:"name"`
+	Status             string              `json:"status"`
+	SessionStatus      string              `json:"session_status"`
+	Leader             string              `json:"leader"`
+	Dapp               json.RawMessage     `json:"dapp"`
+	ExecutionRegistrar *ExecutionRegistrar `json:"execution_registrar,omitempty"`
+}
+
+type DappsResponse struct {
+	Dapps      []DappInfo  `json:"dapps"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type CustodyResponse struct {
+	Address      string          `json:"address"`
+	Settings     json.RawMessage `json:"custody_settings"`
+	Custodians   json.RawMessage `json:"custody_custodians"`
+	WhiteList    json.RawMessage `json:"custody_white_list"`
+	Limits       json.RawMessage `json:"custody_limits"`
+	LimitsStatus json.RawMessage `json:"custody_limits_status"`
+	Pool         json.RawMessage `json:"custody_pool"`
+}
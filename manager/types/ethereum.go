@@ -0,0 +1,68 @@
+package types
+
+import "encoding/json"
+
+// JSON-RPC 2.0 error codes
+const (
+	EVMRPCParseError     = -32700
+	EVMRPCInvalidRequest = -32600
+	EVMRPCMethodNotFound = -32601
+	EVMRPCInvalidParams  = -32602
+	EVMRPCInternalError  = -32603
+	EVMRPCLimitExceeded  = -32005
+)
+
+type EVMRPCRequest struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type EVMRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *EVMRPCError) Error() string {
+	return e.Message
+}
+
+// EVMRPCResponse carries either Result or Error, Result is kept raw so that a
+// null result is still written.
+type EVMRPCResponse struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *EVMRPCError    `json:"error,omitempty"`
+}
+
+// EVMConfig is the part of the ethereum section decoded as a whole, nodes and
+// quorum are read on their own.
+type EVMConfig struct {
+	Policies     map[string]EVMPolicy `json:"policies"`
+	Cache        EVMCacheConfig       `json:"cache"`
+	WS           map[string]string    `json:"ws"`
+	MaxBatchSize int                  `json:"max_batch_size"`
+	BatchWorkers int                  `json:"batch_workers"`
+}
+
+// EVMPolicy shapes the RPC methods of one chain. Allow and Deny take method
+// names or namespaces such as "debug_*", Deny wins. Old block reads, more than
+// ArchiveDepth blocks behind the head, go to the Archive providers.
+type EVMPolicy struct {
+	Allow         []string          `json:"allow"`
+	Deny          []string          `json:"deny"`
+	RateClasses   map[string]string `json:"rate_classes"`
+	RateLimits    map[string]int    `json:"rate_limits"`
+	Archive       []string          `json:"archive"`
+	ArchiveDepth  int64             `json:"archive_depth"`
+	FinalityDepth int64             `json:"finality_depth"`
+}
+
+type EVMCacheConfig struct {
+	Backend string `json:"backend"`
+	Size    int    `json:"size"`
+	TTL     int    `json:"ttl"`
+}
@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+// FaucetProtection sets the checks a claim goes through before it is accepted.
+// Cooldowns are seconds between claims of the same client address, subnet or
+// logged in identity, 0 disables them. PowDifficulty is the number of leading
+// zero bits a proof of work has to reach, 0 disables it. DailyBudget caps what
+// is dispensed of each denom per UTC day.
+type FaucetProtection struct {
+	PowDifficulty       int              `json:"pow_difficulty"`
+	PowTTL              int              `json:"pow_ttl"`
+	ChallengeCollection string           `json:"challenge_collection"`
+	Captcha             CaptchaConfig    `json:"captcha"`
+	IPCooldown          int64            `json:"ip_cooldown"`
+	SubnetCooldown      int64            `json:"subnet_cooldown"`
+	SubnetV4            int              `json:"subnet_v4"`
+	SubnetV6            int              `json:"subnet_v6"`
+	IdentityCooldown    int64            `json:"identity_cooldown"`
+	DailyBudget         map[string]int64 `json:"daily_budget"`
+}
+
+// CaptchaConfig selects the captcha provider: hcaptcha, recaptcha, turnstile
+// or stub, which accepts Token only. URL overrides the provider's verify URL.
+type CaptchaConfig struct {
+	Provider string `json:"provider"`
+	Secret   string `json:"secret"`
+	URL      string `json:"url"`
+	Token    string `json:"token"`
+	Timeout  int    `json:"timeout"`
+}
+
+// FaucetChallenge is a proof of work to solve before claiming for Address: a
+// Solution such that sha256(Nonce + ":" + Address + ":" + Solution) starts
+// with Difficulty zero bits.
+type FaucetChallenge struct {
+	Nonce      string    `json:"nonce"`
+	Address    string    `json:"address"`
+	Algorithm  string    `json:"algorithm"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
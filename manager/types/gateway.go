@@ -1,7 +1,12 @@
 package types
 
+import (
+	"context"
+	"time"
+)
+
 type Gateway interface {
-	Handle(data []byte) (interface{}, error)
+	Handle(ctx context.Context, data []byte, metadata RequestMetadata) (interface{}, error)
 	Close()
 }
 
@@ -24,3 +29,72 @@ type RPCResponse struct {
 		Data    string  `json:"data"`
 	} `json:"error,omitempty"`
 }
+
+// RequestMetadata describes the caller of a request as forwarded by the proxy.
+// Deadline is zero when the caller did not set one. Identity is the address a
+// valid Session token was issued to, empty for anonymous callers.
+type RequestMetadata struct {
+	APIKey   string    `json:"api_key,omitempty"`
+	Origin   string    `json:"origin,omitempty"`
+	TraceID  string    `json:"trace_id,omitempty"`
+	Deadline time.Time `json:"deadline,omitempty"`
+	Session  string    `json:"-"`
+	Identity string    `json:"identity,omitempty"`
+}
+
+type requestMetadataKey struct{}
+
+// WithRequestMetadata makes metadata available to every handler running with
+// the returned context.
+func WithRequestMetadata(ctx context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, metadata)
+}
+
+func RequestMetadataFromContext(ctx context.Context) RequestMetadata {
+	metadata, _ := ctx.Value(requestMetadataKey{}).(RequestMetadata)
+	return metadata
+}
+
+type admissionKey struct{}
+
+// WithAdmission lets a gateway charge the caller for every call a request fans
+// out to, such as the elements of a JSON-RPC batch.
+func WithAdmission(ctx context.Context, admit func() error) context.Context {
+	return context.WithValue(ctx, admissionKey{}, admit)
+}
+
+// Admit charges the caller of ctx for one more call, a context without an
+// admission is free.
+func Admit(ctx context.Context) error {
+	if admit, ok := ctx.Value(admissionKey{}).(func() error); ok {
+		return admit()
+	}
+
+	return nil
+}
+
+// RetryConfig is the retry policy of a gateway, Routes overrides it per route
+// template or method. Delays are in milliseconds.
+type RetryConfig struct {
+	Attempts  int                    `json:"attempts"`
+	BaseDelay int                    `json:"base_delay"`
+	MaxDelay  int                    `json:"max_delay"`
+	Routes    map[string]RetryConfig `json:"routes,omitempty"`
+}
+
+// BreakerConfig sets when the circuit of an upstream opens: after Failures
+// consecutive failures, for Open seconds.
+type BreakerConfig struct {
+	Failures int `json:"failures"`
+	Open     int `json:"open"`
+}
+
+// BreakerStatus is the state of the circuit breaker of one upstream.
+type BreakerStatus struct {
+	Name      string     `json:"name"`
+	Target    string     `json:"target,omitempty"`
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
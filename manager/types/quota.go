@@ -0,0 +1,48 @@
+package types
+
+import "time"
+
+// QuotaConfig sets the limits of API clients. Requests with an API key get the
+// limits of the key's tier, logged in wallets those of SessionTier, the others
+// those of Anonymous per client address.
+type QuotaConfig struct {
+	Enabled         bool                 `json:"enabled"`
+	AdminKey        string               `json:"admin_key"`
+	Collection      string               `json:"collection"`
+	UsageCollection string               `json:"usage_collection"`
+	Flush           int                  `json:"flush"`
+	DefaultTier     string               `json:"default_tier"`
+	SessionTier     string               `json:"session_tier"`
+	Anonymous       QuotaTier            `json:"anonymous"`
+	Tiers           map[string]QuotaTier `json:"tiers"`
+}
+
+// QuotaTier is a token bucket of Rate requests per second holding Burst
+// requests, and a number of requests per UTC day. 0 means no limit.
+type QuotaTier struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+	Daily int64   `json:"daily"`
+}
+
+// APIKey is an issued key. Only the SHA-256 hash of the key is stored, the key
+// itself is shown once when it is issued.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash,omitempty"`
+	Name      string    `json:"name"`
+	Tier      string    `json:"tier"`
+	CreatedAt time.Time `json:"created_at"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// QuotaUsage is what a client used of its limits today.
+type QuotaUsage struct {
+	Client  string    `json:"client"`
+	Tier    string    `json:"tier"`
+	Rate    float64   `json:"rate"`
+	Burst   int       `json:"burst"`
+	Daily   int64     `json:"daily"`
+	Used    int64     `json:"used"`
+	ResetAt time.Time `json:"reset_at"`
+}
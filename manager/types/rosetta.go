@@ -0,0 +1,261 @@
+package types
+
+type RosettaError struct {
+	Code      int32                  `json:"code"`
+	Message   string                 `json:"message"`
+	Retriable bool                   `json:"retriable"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *RosettaError) Error() string {
+	if msg, ok := e.Details["error"].(string); ok {
+		return e.Message + ": " + msg
+	}
+
+	return e.Message
+}
+
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+type BlockIdentifier struct {
+	Index int64  `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+type PartialBlockIdentifier struct {
+	Index *int64 `json:"index,omitempty"`
+	Hash  string `json:"hash,omitempty"`
+}
+
+type TransactionIdentifier struct {
+	Hash string `json:"hash"`
+}
+
+type OperationIdentifier struct {
+	Index int64 `json:"index"`
+}
+
+type AccountIdentifier struct {
+	Address string `json:"address"`
+}
+
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int32  `json:"decimals"`
+}
+
+type Amount struct {
+	Value    string   `json:"value"`
+	Currency Currency `json:"currency"`
+}
+
+type Operation struct {
+	OperationIdentifier OperationIdentifier   `json:"operation_identifier"`
+	RelatedOperations   []OperationIdentifier `json:"related_operations,omitempty"`
+	Type                string                `json:"type"`
+	Status              string                `json:"status,omitempty"`
+	Account             *AccountIdentifier    `json:"account,omitempty"`
+	Amount              *Amount               `json:"amount,omitempty"`
+}
+
+type RosettaTransaction struct {
+	TransactionIdentifier TransactionIdentifier  `json:"transaction_identifier"`
+	Operations            []Operation            `json:"operations"`
+	Metadata              map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type RosettaBlock struct {
+	BlockIdentifier       BlockIdentifier      `json:"block_identifier"`
+	ParentBlockIdentifier BlockIdentifier      `json:"parent_block_identifier"`
+	Timestamp             int64                `json:"timestamp"`
+	Transactions          []RosettaTransaction `json:"transactions"`
+}
+
+type RosettaPeer struct {
+	PeerID string `json:"peer_id"`
+}
+
+type RosettaSyncStatus struct {
+	CurrentIndex int64 `json:"current_index"`
+	Synced       bool  `json:"synced"`
+}
+
+type RosettaVersion struct {
+	RosettaVersion    string `json:"rosetta_version"`
+	NodeVersion       string `json:"node_version"`
+	MiddlewareVersion string `json:"middleware_version,omitempty"`
+}
+
+type OperationStatus struct {
+	Status     string `json:"status"`
+	Successful bool   `json:"successful"`
+}
+
+type RosettaAllow struct {
+	OperationStatuses       []OperationStatus `json:"operation_statuses"`
+	OperationTypes          []string          `json:"operation_types"`
+	Errors                  []RosettaError    `json:"errors"`
+	HistoricalBalanceLookup bool              `json:"historical_balance_lookup"`
+	MempoolCoins            bool              `json:"mempool_coins"`
+}
+
+type PublicKey struct {
+	HexBytes  string `json:"hex_bytes"`
+	CurveType string `json:"curve_type"`
+}
+
+type SigningPayload struct {
+	AccountIdentifier *AccountIdentifier `json:"account_identifier,omitempty"`
+	HexBytes          string             `json:"hex_bytes"`
+	SignatureType     string             `json:"signature_type,omitempty"`
+}
+
+type Signature struct {
+	SigningPayload SigningPayload `json:"signing_payload"`
+	PublicKey      PublicKey      `json:"public_key"`
+	SignatureType  string         `json:"signature_type"`
+	HexBytes       string         `json:"hex_bytes"`
+}
+
+type NetworkRequest struct {
+	NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+}
+
+type NetworkListResponse struct {
+	NetworkIdentifiers []NetworkIdentifier `json:"network_identifiers"`
+}
+
+type NetworkStatusResponse struct {
+	CurrentBlockIdentifier BlockIdentifier    `json:"current_block_identifier"`
+	CurrentBlockTimestamp  int64              `json:"current_block_timestamp"`
+	GenesisBlockIdentifier BlockIdentifier    `json:"genesis_block_identifier"`
+	OldestBlockIdentifier  *BlockIdentifier   `json:"oldest_block_identifier,omitempty"`
+	SyncStatus             *RosettaSyncStatus `json:"sync_status,omitempty"`
+	Peers                  []RosettaPeer      `json:"peers"`
+}
+
+type NetworkOptionsResponse struct {
+	Version RosettaVersion `json:"version"`
+	Allow   RosettaAllow   `json:"allow"`
+}
+
+type BlockRequest struct {
+	NetworkIdentifier NetworkIdentifier      `json:"network_identifier"`
+	BlockIdentifier   PartialBlockIdentifier `json:"block_identifier"`
+}
+
+type BlockResponse struct {
+	Block *RosettaBlock `json:"block,omitempty"`
+}
+
+type BlockTransactionRequest struct {
+	NetworkIdentifier     NetworkIdentifier     `json:"network_identifier"`
+	BlockIdentifier       BlockIdentifier       `json:"block_identifier"`
+	TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+}
+
+type BlockTransactionResponse struct {
+	Transaction RosettaTransaction `json:"transaction"`
+}
+
+type AccountBalanceRequest struct {
+	NetworkIdentifier NetworkIdentifier       `json:"network_identifier"`
+	AccountIdentifier AccountIdentifier       `json:"account_identifier"`
+	BlockIdentifier   *PartialBlockIdentifier `json:"block_identifier,omitempty"`
+	Currencies        []Currency              `json:"currencies,omitempty"`
+}
+
+type AccountBalanceResponse struct {
+	BlockIdentifier BlockIdentifier `json:"block_identifier"`
+	Balances        []Amount        `json:"balances"`
+}
+
+type RosettaMempoolResponse struct {
+	TransactionIdentifiers []TransactionIdentifier `json:"transaction_identifiers"`
+}
+
+type MempoolTransactionRequest struct {
+	NetworkIdentifier     NetworkIdentifier     `json:"network_identifier"`
+	TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+}
+
+type MempoolTransactionResponse struct {
+	Transaction RosettaTransaction `json:"transaction"`
+}
+
+type ConstructionDeriveRequest struct {
+	NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+	PublicKey         PublicKey         `json:"public_key"`
+}
+
+type ConstructionDeriveResponse struct {
+	AccountIdentifier AccountIdentifier `json:"account_identifier"`
+}
+
+type ConstructionPreprocessRequest struct {
+	NetworkIdentifier NetworkIdentifier      `json:"network_identifier"`
+	Operations        []Operation            `json:"operations"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type ConstructionPreprocessResponse struct {
+	Options            map[string]interface{} `json:"options"`
+	RequiredPublicKeys []AccountIdentifier    `json:"required_public_keys"`
+}
+
+type ConstructionMetadataRequest struct {
+	NetworkIdentifier NetworkIdentifier      `json:"network_identifier"`
+	Options           map[string]interface{} `json:"options"`
+	PublicKeys        []PublicKey            `json:"public_keys,omitempty"`
+}
+
+type ConstructionMetadataResponse struct {
+	Metadata     map[string]interface{} `json:"metadata"`
+	SuggestedFee []Amount               `json:"suggested_fee,omitempty"`
+}
+
+type ConstructionPayloadsRequest struct {
+	NetworkIdentifier NetworkIdentifier      `json:"network_identifier"`
+	Operations        []Operation            `json:"operations"`
+	Metadata          map[string]interface{} `json:"metadata"`
+	PublicKeys        []PublicKey            `json:"public_keys"`
+}
+
+type ConstructionPayloadsResponse struct {
+	UnsignedTransaction string           `json:"unsigned_transaction"`
+	Payloads            []SigningPayload `json:"payloads"`
+}
+
+type ConstructionCombineRequest struct {
+	NetworkIdentifier   NetworkIdentifier `json:"network_identifier"`
+	UnsignedTransaction string            `json:"unsigned_transaction"`
+	Signatures          []Signature       `json:"signatures"`
+}
+
+type ConstructionCombineResponse struct {
+	SignedTransaction string `json:"signed_transaction"`
+}
+
+type ConstructionParseRequest struct {
+	NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+	Signed            bool              `json:"signed"`
+	Transaction       string            `json:"transaction"`
+}
+
+type ConstructionParseResponse struct {
+	Operations               []Operation            `json:"operations"`
+	AccountIdentifierSigners []AccountIdentifier    `json:"account_identifier_signers,omitempty"`
+	Metadata                 map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type ConstructionHashRequest struct {
+	NetworkIdentifier NetworkIdentifier `json:"network_identifier"`
+	SignedTransaction string            `json:"signed_transaction"`
+}
+
+type TransactionIdentifierResponse struct {
+	TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+}
@@ -0,0 +1,37 @@
+package types
+
+// SignerConfig selects where the key interx signs faucet transactions with is
+// kept. Backend is "mnemonic" (the legacy plaintext MnemonicFile), "keystore"
+// (Keystore encrypted with the passphrase read from PassphraseFile or the
+// PassphraseEnv variable), "file" (a mnemonic or hex private key mounted at
+// KeyFile) or "remote" (a signer listening on Remote, unix:// or tcp://).
+// Reload is the number of seconds between checks for a rotated key, Timeout
+// bounds remote calls, both in seconds.
+type SignerConfig struct {
+	Backend        string `json:"backend"`
+	MnemonicFile   string `json:"mnemonic_file"`
+	Keystore       string `json:"keystore"`
+	PassphraseFile string `json:"passphrase_file"`
+	PassphraseEnv  string `json:"passphrase_env"`
+	KeyFile        string `json:"key_file"`
+	Remote         string `json:"remote"`
+	Timeout        int    `json:"timeout"`
+	Reload         int    `json:"reload"`
+}
+
+// SignerStatus is the key currently in use.
+type SignerStatus struct {
+	Backend   string `json:"backend"`
+	Address   string `json:"address"`
+	PubKey    string `json:"pub_key"`
+	RotatedAt int64  `json:"rotated_at,omitempty"`
+}
+
+// SignerRotation is the key a rotation put in use and the one it replaced.
+// Funds are not moved, they stay at PreviousAddress. Backup is the copy of the
+// previous keystore, empty for backends whose keys are kept elsewhere.
+type SignerRotation struct {
+	SignerStatus
+	PreviousAddress string `json:"previous_address"`
+	Backup          string `json:"backup,omitempty"`
+}
@@ -122,3 +122,21 @@ func (s *storage) Delete(collection string, criteria map[string]interface{}) (*a
 
 	return result, nil
 }
+
+// Consume marks id of collection as used and tells whether this call did it.
+// sai-storage-mongo does not report how many documents a delete or an update
+// matched, so the mark is a document with _id set to id, which MongoDB keeps
+// unique across every manager: of concurrent calls only one inserts it. A
+// failed insert, whatever the reason, counts as already used.
+func Consume(storage Storage, collection, id string, expires int64) (bool, error) {
+	response, err := storage.Create(collection, []interface{}{map[string]interface{}{
+		"_id":     id,
+		"expires": expires,
+	}})
+	if err != nil {
+		logger.Logger.Error("Consume", zap.String("collection", collection), zap.Error(err))
+		return false, err
+	}
+
+	return response.Status != "NOK", nil
+}
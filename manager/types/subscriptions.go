@@ -0,0 +1,72 @@
+package types
+
+import "time"
+
+const (
+	SubscriptionEventBlock    = "block"
+	SubscriptionEventTx       = "tx"
+	SubscriptionEventProposal = "proposal"
+)
+
+// SubscriptionEvent is pushed to WebSocket/SSE subscribers and gossiped between
+// managers, exactly one of Block, Tx and Proposal is set depending on Type.
+type SubscriptionEvent struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	Height   int64          `json:"height"`
+	Block    *BlockEvent    `json:"block,omitempty"`
+	Tx       *TxEvent       `json:"tx,omitempty"`
+	Proposal *ProposalEvent `json:"proposal,omitempty"`
+}
+
+type BlockEvent struct {
+	Height   int64     `json:"height"`
+	Hash     string    `json:"hash"`
+	Time     time.Time `json:"time"`
+	Proposer string    `json:"proposer"`
+	NumTxs   int       `json:"num_txs"`
+}
+
+type TxEvent struct {
+	Hash      string        `json:"hash"`
+	Height    int64         `json:"height"`
+	Timestamp time.Time     `json:"timestamp"`
+	Code      int           `json:"code"`
+	GasWanted string        `json:"gas_wanted"`
+	GasUsed   string        `json:"gas_used"`
+	TypeUrls  []string      `json:"type_urls"`
+	Addresses []string      `json:"addresses"`
+	Messages  []interface{} `json:"messages,omitempty"`
+}
+
+type ProposalEvent struct {
+	ProposalID     string `json:"proposal_id"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+}
+
+type SubscriptionFilter struct {
+	TypeUrl    string `json:"type_url,omitempty"`
+	Address    string `json:"address,omitempty"`
+	ProposalID string `json:"proposal_id,omitempty"`
+}
+
+// SubscriptionRequest is a client message on the subscriptions WebSocket.
+type SubscriptionRequest struct {
+	ID           string             `json:"id,omitempty"`
+	Action       string             `json:"action"`
+	Topic        string             `json:"topic,omitempty"`
+	Filter       SubscriptionFilter `json:"filter"`
+	Subscription string             `json:"subscription,omitempty"`
+}
+
+type SubscriptionResponse struct {
+	ID           string `json:"id,omitempty"`
+	Subscription string `json:"subscription,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type SubscriptionNotification struct {
+	Subscription string             `json:"subscription"`
+	Event        *SubscriptionEvent `json:"event"`
+}
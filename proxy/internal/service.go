@@ -2,11 +2,20 @@ package internal
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rs/cors"
 	"github.com/spf13/cast"
@@ -18,12 +27,30 @@ import (
 )
 
 type InternalService struct {
-	Context  *service.Context
-	ProxyUrl string
+	Context           *service.Context
+	ProxyUrl          string
+	SubscriptionsUrl  string
+	TrustForwardedFor bool
+	TrustedHops       int
+	RequestTimeout    time.Duration
 }
 
+// defaultRequestTimeout bounds the requests that set no X-Request-Timeout, so
+// the manager always gets a deadline.
+const defaultRequestTimeout = 60
+
+// cancelTimeout bounds the notice sent to the manager when a client went away.
+const cancelTimeout = 5 * time.Second
+
 func (is *InternalService) Init() {
 	is.ProxyUrl = cast.ToString(is.Context.GetConfig("manager.url", ""))
+	is.SubscriptionsUrl = cast.ToString(is.Context.GetConfig("manager.subscriptions_url", ""))
+	is.TrustForwardedFor = cast.ToBool(is.Context.GetConfig("client.trust_forwarded_for", false))
+	is.TrustedHops = max(cast.ToInt(is.Context.GetConfig("client.trusted_hops", 1)), 1)
+	is.RequestTimeout = time.Duration(cast.ToFloat64(is.Context.GetConfig("client.request_timeout", defaultRequestTimeout)) * float64(time.Second))
+	if is.RequestTimeout <= 0 {
+		is.RequestTimeout = defaultRequestTimeout * time.Second
+	}
 }
 
 func (is *InternalService) Process() {
@@ -36,6 +63,16 @@ func (is *InternalService) StartHttpProxy() {
 	corsHandler := cors.AllowAll().Handler(handler)
 
 	http.Handle("/", corsHandler)
+
+	if is.SubscriptionsUrl != "" {
+		subscriptionsHandler, err := is.subscriptionsProxy()
+		if err != nil {
+			logger.Logger.Error("StartHttpProxy", zap.Error(err))
+		} else {
+			http.Handle("/api/subscriptions/", cors.AllowAll().Handler(subscriptionsHandler))
+			http.Handle("/api/ethereum/", ethereumWebSocket(cors.AllowAll().Handler(subscriptionsHandler), corsHandler))
+		}
+	}
 	logger.Logger.Info("Starting HTTP server on port", zap.Int("Port", port))
 
 	err := http.ListenAndServe(":"+strconv.Itoa(port), nil)
@@ -44,9 +81,51 @@ func (is *InternalService) StartHttpProxy() {
 	}
 }
 
+// subscriptionsProxy streams WebSocket and SSE subscriptions straight to the
+// manager, they cannot go through the request/response SaiRequest envelope.
+// The manager admits the connection against the quota of the client address
+// set in X-Request-Origin, the API key and the session token pass through.
+func (is *InternalService) subscriptionsProxy() (http.Handler, error) {
+	target, err := url.Parse(is.SubscriptionsUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		metadata, _ := requestMetadata(r, is.TrustForwardedFor, is.TrustedHops, is.RequestTimeout)
+		r.Header.Set("X-Request-Origin", metadata.Origin)
+		r.Header.Set("X-Request-ID", metadata.TraceID)
+
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/api")
+		director(r)
+	}
+	// flush SSE events as soon as they arrive
+	proxy.FlushInterval = -1
+
+	return proxy, nil
+}
+
+// ethereumWebSocket sends /api/ethereum/{chain}/ws upgrades to the manager's
+// streaming server and every other ethereum request through the envelope.
+func ethereumWebSocket(stream, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/ws") && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			stream.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (is *InternalService) handleHttpConnections(w http.ResponseWriter, r *http.Request) {
 	logger.Logger.Debug("handleHttpConnections", zap.Any("method", r.Method), zap.Any("path", r.URL.Path))
 
+	metadata, deadline := requestMetadata(r, is.TrustForwardedFor, is.TrustedHops, is.RequestTimeout)
+	w.Header().Set("X-Request-ID", metadata.TraceID)
+
 	var requestData interface{}
 
 	if r.Method == "GET" {
@@ -73,12 +152,14 @@ func (is *InternalService) handleHttpConnections(w http.ResponseWriter, r *http.
 
 		if err != nil {
 			logger.Logger.Error("handleHttpConnections", zap.Error(err))
-			http.Error(w, "Error reading request body", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "error reading request body", metadata.TraceID)
 			return
 		}
 
-		if r.Header.Get("Content-Type") == "application/json" {
-			var jsonData map[string]interface{}
+		// JSON-RPC batches are arrays and clients often add a charset
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/json" {
+			var jsonData interface{}
 			if err := json.Unmarshal(body, &jsonData); err == nil {
 				requestData = jsonData
 			} else {
@@ -103,34 +184,225 @@ func (is *InternalService) handleHttpConnections(w http.ResponseWriter, r *http.
 			Path:    path,
 			Payload: requestData,
 		},
+		Metadata: metadata,
 	}
 
-	response, err := is.SendProxyRequest(request)
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+
+	response, statusCode, err := is.SendProxyRequest(ctx, request)
 	if err != nil {
 		logger.Logger.Error("handleHttpConnections", zap.Error(err))
-		http.Error(w, "Error processing request", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, context.Canceled):
+			go is.cancelRequest(metadata.RequestID)
+			writeError(w, statusClientClosedRequest, "CANCELLED", "request cancelled", metadata.TraceID)
+		case errors.Is(err, context.DeadlineExceeded):
+			writeError(w, http.StatusGatewayTimeout, "DEADLINE_EXCEEDED", "manager did not answer in time", metadata.TraceID)
+		default:
+			writeError(w, http.StatusBadGateway, "UPSTREAM_ERROR", "manager unreachable", metadata.TraceID)
+		}
 		return
 	}
 
+	if statusCode >= http.StatusBadRequest {
+		response = errorEnvelope(w, response, statusCode, metadata.TraceID)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	w.Write(response)
 }
 
-func (is *InternalService) SendProxyRequest(r types.SaiRequest) ([]byte, error) {
+// statusClientClosedRequest is the non standard status used when the client
+// went away before the answer was ready.
+const statusClientClosedRequest = 499
+
+// errorCodes are the envelope codes of the statuses the service layer of the
+// manager answers with its own error body.
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "INVALID_ARGUMENT",
+	http.StatusUnauthorized:        "UNAUTHENTICATED",
+	http.StatusForbidden:           "PERMISSION_DENIED",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusMethodNotAllowed:    "METHOD_NOT_ALLOWED",
+	http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+	http.StatusBadGateway:          "UPSTREAM_ERROR",
+	http.StatusServiceUnavailable:  "UNAVAILABLE",
+	http.StatusGatewayTimeout:      "DEADLINE_EXCEEDED",
+	http.StatusInternalServerError: "INTERNAL",
+}
+
+// errorEnvelope passes the manager error envelope through, setting Retry-After
+// from it, and rewrites the {"Status": "NOK", "Error": ...} bodies the service
+// layer answers with into an envelope. Other bodies, such as Rosetta errors,
+// are left alone.
+func errorEnvelope(w http.ResponseWriter, body []byte, statusCode int, traceID string) []byte {
+	var envelope types.ErrorEnvelope
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error.Code != "" {
+		if envelope.Error.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.FormatInt(envelope.Error.RetryAfter, 10))
+		}
+		return body
+	}
+
+	var serviceError struct {
+		Status string `json:"Status"`
+		Error  string `json:"Error"`
+	}
+	if err := json.Unmarshal(body, &serviceError); err != nil || serviceError.Status != "NOK" {
+		return body
+	}
+
+	code, ok := errorCodes[statusCode]
+	if !ok {
+		code = "INTERNAL"
+	}
+
+	envelope = types.ErrorEnvelope{Error: types.ErrorBody{
+		Code:    code,
+		Status:  statusCode,
+		Message: serviceError.Error,
+		TraceID: traceID,
+	}}
+
+	response, err := json.Marshal(envelope)
+	if err != nil {
+		return body
+	}
+
+	return response
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code, message, traceID string) {
+	response, _ := json.Marshal(types.ErrorEnvelope{Error: types.ErrorBody{
+		Code:    code,
+		Status:  statusCode,
+		Message: message,
+		TraceID: traceID,
+	}})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(response)
+}
+
+// SendProxyRequest forwards the request to the manager, the call is dropped as
+// soon as ctx is done.
+func (is *InternalService) SendProxyRequest(ctx context.Context, r types.SaiRequest) ([]byte, int, error) {
 	reqData, err := json.Marshal(r)
 	if err != nil {
 		logger.Logger.Error("SendProxyRequest", zap.Error(err))
-		return nil, err
+		return nil, 0, err
 	}
 
-	resp, err := http.Post(is.ProxyUrl, "application/json", io.NopCloser(io.Reader(bytes.NewBuffer(reqData))))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, is.ProxyUrl, bytes.NewBuffer(reqData))
 	if err != nil {
 		logger.Logger.Error("SendProxyRequest", zap.Error(err))
-		return nil, err
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Logger.Error("SendProxyRequest", zap.Error(err))
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
-	return io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Logger.Error("SendProxyRequest", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// cancelRequest tells the manager to stop serving the request id, whose client
+// went away.
+func (is *InternalService) cancelRequest(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	request := types.SaiRequest{
+		Method: "cancel",
+		Data:   map[string]string{"request_id": id},
+	}
+
+	if _, _, err := is.SendProxyRequest(ctx, request); err != nil {
+		logger.Logger.Error("cancelRequest", zap.Error(err))
+	}
+}
+
+// requestMetadata reads the API key, the session token, the client address,
+// the trace id and the deadline of the request. A trace id is generated when the client sent none,
+// and every request gets a request id of its own to cancel it with.
+// X-Request-Timeout is a number of seconds, at most timeout, which is also
+// the timeout of requests that set none. X-Forwarded-For is only trusted
+// behind trustedHops reverse proxies that append to it, clients could pick
+// their address and escape the per address limits of the manager otherwise.
+func requestMetadata(r *http.Request, trustForwardedFor bool, trustedHops int, timeout time.Duration) (types.SaiMetadata, time.Time) {
+	metadata := types.SaiMetadata{
+		APIKey:    r.Header.Get("X-API-Key"),
+		TraceID:   r.Header.Get("X-Request-ID"),
+		RequestID: randomID(),
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		metadata.Session = strings.TrimSpace(token)
+	}
+
+	if origin := forwardedFor(r.Header.Values("X-Forwarded-For"), trustedHops); trustForwardedFor && origin != "" {
+		metadata.Origin = origin
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		metadata.Origin = host
+	} else {
+		metadata.Origin = r.RemoteAddr
+	}
+
+	if metadata.TraceID == "" {
+		metadata.TraceID = randomID()
+	}
+
+	if seconds, err := strconv.ParseFloat(r.Header.Get("X-Request-Timeout"), 64); err == nil && seconds > 0 {
+		timeout = min(timeout, time.Duration(seconds*float64(time.Second)))
+	}
+
+	deadline := time.Now().Add(timeout)
+	metadata.Deadline = deadline.UTC().Format(time.RFC3339Nano)
+
+	return metadata, deadline
+}
+
+// forwardedFor is the client address in X-Forwarded-For: every trusted proxy
+// appends the address it got the request from, so the client is the entry
+// trustedHops from the right. The entries left of it come from the client and
+// are ignored.
+func forwardedFor(headers []string, trustedHops int) string {
+	var entries []string
+	for _, header := range headers {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if len(entries) == 0 {
+		return ""
+	}
+
+	return entries[max(len(entries)-trustedHops, 0)]
+}
+
+func randomID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(id)
 }
 
 func determineMethod(path string) string {
@@ -148,5 +420,21 @@ func determineMethod(path string) string {
 		return "ethereum"
 	}
 
+	if path == "/health" {
+		return "health"
+	}
+
+	if path == "/keys" || strings.HasPrefix(path, "/keys/") {
+		return "keys"
+	}
+
+	if path == "/signer" || strings.HasPrefix(path, "/signer/") {
+		return "signer"
+	}
+
+	if strings.HasPrefix(path, "/auth/") {
+		return "auth"
+	}
+
 	return "cosmos"
 }
@@ -7,6 +7,32 @@ type SaiData struct {
 }
 
 type SaiRequest struct {
-	Method string      `json:"method"`
-	Data   interface{} `json:"data"`
+	Method   string      `json:"method"`
+	Data     interface{} `json:"data"`
+	Metadata SaiMetadata `json:"metadata"`
+}
+
+// SaiMetadata describes the caller to the manager, which only sees the proxy.
+type SaiMetadata struct {
+	APIKey    string `json:"api_key,omitempty"`
+	Origin    string `json:"origin,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+	Deadline  string `json:"deadline,omitempty"`
+	Session   string `json:"session,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ErrorEnvelope mirrors the manager error envelope (manager/apierror), the
+// proxy answers its own failures with it too.
+type ErrorEnvelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code       string                 `json:"code"`
+	Status     int                    `json:"status"`
+	Message    string                 `json:"message"`
+	RetryAfter int64                  `json:"retry_after,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	TraceID    string                 `json:"trace_id,omitempty"`
 }
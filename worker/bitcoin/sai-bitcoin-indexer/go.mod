@@ -0,0 +1,25 @@
+module github.com/KiraCore/saiBitcoinIndexer
+
+go 1.21
+
+require (
+	github.com/KiraCore/sai-service v1.0.5
+	github.com/KiraCore/sai-storage-mongo v1.1.9
+	github.com/json-iterator/go v1.1.12
+	github.com/spf13/cast v1.5.0
+	go.mongodb.org/mongo-driver v1.17.3
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/rs/cors v1.10.1 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/urfave/cli/v2 v2.27.1 // indirect
+	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/net v0.20.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
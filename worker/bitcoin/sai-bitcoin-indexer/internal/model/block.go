@@ -0,0 +1,68 @@
+package model
+
+import "encoding/json"
+
+type RPCRequest struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type RPCResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	ID     string          `json:"id"`
+}
+
+// BlockInfo is the getblock response with verbosity 2.
+type BlockInfo struct {
+	Hash              string `json:"hash"`
+	Height            int64  `json:"height"`
+	Time              int64  `json:"time"`
+	PreviousBlockHash string `json:"previousblockhash"`
+	Tx                []Tx   `json:"tx"`
+}
+
+type Tx struct {
+	Txid string `json:"txid"`
+	Vin  []struct {
+		Coinbase string `json:"coinbase"`
+		Txid     string `json:"txid"`
+		Vout     uint32 `json:"vout"`
+	} `json:"vin"`
+	Vout []struct {
+		Value        float64 `json:"value"`
+		N            uint32  `json:"n"`
+		ScriptPubKey struct {
+			Type      string   `json:"type"`
+			Address   string   `json:"address"`
+			Addresses []string `json:"addresses"`
+		} `json:"scriptPubKey"`
+	} `json:"vout"`
+}
+
+// Block is the document stored in <collection>_blocks.
+type Block struct {
+	Hash              string `json:"hash"`
+	Height            int64  `json:"height"`
+	Time              int64  `json:"time"`
+	PreviousBlockHash string `json:"previous_hash"`
+	TxCount           int    `json:"tx_count"`
+}
+
+// Utxo is the document stored in <collection>_utxos, values are in satoshi.
+type Utxo struct {
+	Txid     string `json:"txid"`
+	Vout     uint32 `json:"vout"`
+	Address  string `json:"address"`
+	Value    int64  `json:"value"`
+	Height   int64  `json:"height"`
+	Coinbase bool   `json:"coinbase"`
+	Spent    bool   `json:"spent"`
+}
@@ -0,0 +1,15 @@
+package model
+
+type ServiceConfig struct {
+	NodeAddress    string
+	Confirmations  int64
+	CollectionName string
+}
+
+type StorageConfig struct {
+	Token      string
+	Url        string
+	Email      string
+	Password   string
+	Collection string
+}
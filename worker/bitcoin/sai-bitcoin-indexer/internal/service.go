@@ -0,0 +1,624 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/spf13/cast"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+
+	saiService "github.com/KiraCore/sai-service/service"
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+	"github.com/KiraCore/saiBitcoinIndexer/internal/model"
+	"github.com/KiraCore/saiBitcoinIndexer/logger"
+	"github.com/KiraCore/saiBitcoinIndexer/utils"
+)
+
+const (
+	filePathAddresses   = "./addresses.json"
+	filePathLatestBlock = "./latest_handled_block"
+
+	satoshiPerBitcoin = 1e8
+)
+
+type IndexData struct {
+	Keys   []bson.M `bson:"keys" json:"keys"`
+	Unique bool     `bson:"unique" json:"unique"`
+}
+
+type InternalService struct {
+	mu            *sync.Mutex
+	Context       *saiService.Context
+	config        model.ServiceConfig
+	currentBlock  int64
+	addresses     map[string]struct{}
+	storageConfig model.StorageConfig
+	client        http.Client
+	indexed       bool
+}
+
+func (is *InternalService) Init() {
+	is.mu = &sync.Mutex{}
+	is.config = model.ServiceConfig{}
+	is.client = http.Client{Timeout: 30 * time.Second}
+
+	is.addresses = make(map[string]struct{})
+	is.config.NodeAddress = cast.ToString(is.Context.GetConfig("node_address", ""))
+	is.config.Confirmations = cast.ToInt64(is.Context.GetConfig("confirmations", 6))
+	is.config.CollectionName = cast.ToString(is.Context.GetConfig("storage.mongo_collection_name", "bitcoin"))
+	is.storageConfig = model.StorageConfig{
+		Token:      cast.ToString(is.Context.GetConfig("storage.token", "")),
+		Url:        cast.ToString(is.Context.GetConfig("storage.url", "")),
+		Email:      cast.ToString(is.Context.GetConfig("storage.email", "")),
+		Password:   cast.ToString(is.Context.GetConfig("storage.password", "")),
+		Collection: is.config.CollectionName,
+	}
+
+	if is.config.Confirmations < 1 {
+		is.config.Confirmations = 1
+	}
+
+	err := is.loadAddresses()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Logger.Error("loadAddresses", zap.Error(err))
+	}
+
+	fileBytes, err := os.ReadFile(filePathLatestBlock)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Logger.Error("can't read "+filePathLatestBlock, zap.Error(err))
+		}
+	} else {
+		latestHandledBlock, err := strconv.Atoi(strings.TrimSpace(string(fileBytes)))
+		if err != nil {
+			logger.Logger.Error("strconv.Atoi", zap.Error(err))
+		}
+
+		is.currentBlock = int64(latestHandledBlock)
+	}
+
+	startBlock := cast.ToInt64(is.Context.GetConfig("start_block", 0))
+	if is.currentBlock < startBlock {
+		is.currentBlock = startBlock
+	}
+}
+
+func (is *InternalService) ProcessIndexes() {
+	var newBlockIndexes = []IndexData{
+		{
+			Keys:   []bson.M{{"hash": 1}},
+			Unique: false,
+		},
+		{
+			Keys:   []bson.M{{"height": 1}},
+			Unique: false,
+		},
+	}
+
+	var newUtxoIndexes = []IndexData{
+		{
+			Keys:   []bson.M{{"txid": 1}, {"vout": 1}},
+			Unique: false,
+		},
+		{
+			Keys:   []bson.M{{"address": 1}, {"spent": 1}},
+			Unique: false,
+		},
+		{
+			Keys:   []bson.M{{"height": 1}},
+			Unique: false,
+		},
+	}
+
+	blockIndexes, err := is.getIndexes(is.config.CollectionName + "_blocks")
+	if err != nil {
+		logger.Logger.Error("getIndexes > blockIndexes", zap.Error(err))
+		return
+	}
+
+	if !hasRequiredIndexes(blockIndexes, newBlockIndexes) {
+		indexResp, err := is.createIndexes(is.config.CollectionName+"_blocks", newBlockIndexes)
+		if err != nil {
+			logger.Logger.Error("createIndexes > blockIndexes", zap.Error(err))
+			return
+		}
+
+		logger.Logger.Debug("ProcessIndexes", zap.Any("indexResp", indexResp))
+	}
+
+	utxoIndexes, err := is.getIndexes(is.config.CollectionName + "_utxos")
+	if err != nil {
+		logger.Logger.Error("getIndexes > utxoIndexes", zap.Error(err))
+		return
+	}
+
+	if !hasRequiredIndexes(utxoIndexes, newUtxoIndexes) {
+		indexResp, err := is.createIndexes(is.config.CollectionName+"_utxos", newUtxoIndexes)
+		if err != nil {
+			logger.Logger.Error("createIndexes > utxoIndexes", zap.Error(err))
+			return
+		}
+
+		logger.Logger.Debug("ProcessIndexes", zap.Any("indexResp", indexResp))
+	}
+
+	is.indexed = true
+}
+
+func hasRequiredIndexes(data interface{}, required []IndexData) bool {
+	resultStr := fmt.Sprintf("%v", data)
+
+	for _, req := range required {
+		found := false
+		for _, keyMap := range req.Keys {
+			for k := range keyMap {
+				indexName := k + "_1"
+				if strings.Contains(resultStr, indexName) {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Process follows the chain tip minus the configured number of confirmations,
+// so reorganisations shallower than that never reach the storage. Deeper ones
+// are rolled back and indexed again from the fork.
+func (is *InternalService) Process() {
+	sleepDuration := cast.ToDuration(is.Context.GetConfig("sleep_duration", 10))
+
+	for {
+		select {
+		case <-is.Context.Context.Done():
+			logger.Logger.Debug("saiBitcoinIndexer loop is done")
+			return
+		default:
+			blockCount, err := is.getBlockCount()
+			if err != nil {
+				logger.Logger.Error("getBlockCount", zap.Error(err))
+				time.Sleep(time.Second * sleepDuration)
+				continue
+			}
+
+			if is.currentBlock > blockCount-is.config.Confirmations+1 {
+				time.Sleep(time.Second * sleepDuration)
+				continue
+			}
+
+			err = is.handleBlock()
+			if errors.Is(err, errReorg) {
+				continue
+			}
+
+			if err != nil {
+				logger.Logger.Error("handleBlock", zap.Error(err))
+				time.Sleep(time.Second * sleepDuration)
+				continue
+			}
+
+			logger.Logger.Debug("handleBlock processed", zap.Any("block", is.currentBlock))
+
+			is.currentBlock += 1
+		}
+	}
+}
+
+func (is *InternalService) handleBlock() error {
+	blockInfo, err := is.getBlockInfo(is.currentBlock)
+	if err != nil {
+		logger.Logger.Error("handleBlock", zap.Error(err))
+		return err
+	}
+
+	if !is.indexed {
+		is.ProcessIndexes()
+	}
+
+	err = is.checkParent(blockInfo)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range blockInfo.Tx {
+		err = is.spendOutputs(tx, blockInfo.Height)
+		if err != nil {
+			logger.Logger.Error("handleBlock", zap.Error(err))
+			return err
+		}
+
+		err = is.sendUtxosToStorage(tx, blockInfo.Height)
+		if err != nil {
+			logger.Logger.Error("handleBlock", zap.Error(err))
+			return err
+		}
+	}
+
+	err = is.sendBlockToStorage(&model.Block{
+		Hash:              blockInfo.Hash,
+		Height:            blockInfo.Height,
+		Time:              blockInfo.Time,
+		PreviousBlockHash: blockInfo.PreviousBlockHash,
+		TxCount:           len(blockInfo.Tx),
+	})
+	if err != nil {
+		logger.Logger.Error("handleBlock", zap.Error(err))
+		return err
+	}
+
+	return is.rewriteLastHandledBlock(is.currentBlock)
+}
+
+// errReorg stops the handling of a block whose parent is not the stored one,
+// the index has been rolled back to the fork and is rebuilt from there.
+var errReorg = errors.New("chain reorganisation below the confirmation depth")
+
+// checkParent detects a reorganisation deeper than the confirmation window and
+// rolls the index back to the first height where it left the node's chain.
+func (is *InternalService) checkParent(block *model.BlockInfo) error {
+	if block.Height == 0 {
+		return nil
+	}
+
+	parent, err := is.readBlock(block.Height - 1)
+	if err != nil {
+		return err
+	}
+
+	if parent == nil || parent.Hash == block.PreviousBlockHash {
+		return nil
+	}
+
+	fork, err := is.forkHeight(block.Height - 1)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Error("checkParent - chain reorganisation below the confirmation depth, rolling back",
+		zap.Int64("height", block.Height),
+		zap.Int64("fork", fork),
+		zap.String("stored_parent", parent.Hash),
+		zap.String("parent", block.PreviousBlockHash),
+	)
+
+	if err = is.rollback(fork); err != nil {
+		return err
+	}
+
+	is.currentBlock = fork
+
+	return errReorg
+}
+
+// forkHeight walks down from height, which is known to be orphaned, to the
+// lowest stored block that is not on the node's chain.
+func (is *InternalService) forkHeight(height int64) (int64, error) {
+	for height > 0 {
+		stored, err := is.readBlock(height - 1)
+		if err != nil {
+			return 0, err
+		}
+
+		if stored == nil {
+			return height, nil
+		}
+
+		hash, err := is.getBlockHash(height - 1)
+		if err != nil {
+			return 0, err
+		}
+
+		if stored.Hash == hash {
+			return height, nil
+		}
+
+		height--
+	}
+
+	return 0, nil
+}
+
+// rollback forgets everything indexed at or above height: the blocks, the
+// outputs they created and the spending of older outputs. The last handled
+// block is rewritten first so that a failure half way is rolled back again.
+func (is *InternalService) rollback(height int64) error {
+	err := is.rewriteLastHandledBlock(height - 1)
+	if err != nil {
+		return err
+	}
+
+	above := map[string]interface{}{"$gte": height}
+
+	err = is.sendStorageRequest(adapter.Request{
+		Method: "update",
+		Data: adapter.UpdateRequest{
+			Select:     map[string]interface{}{"spent_height": above},
+			Collection: is.storageConfig.Collection + "_utxos",
+			Document: map[string]interface{}{
+				"$set":   map[string]interface{}{"spent": false},
+				"$unset": map[string]interface{}{"spent_txid": "", "spent_height": ""},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = is.sendStorageRequest(adapter.Request{
+		Method: "delete",
+		Data: adapter.DeleteRequest{
+			Select:     map[string]interface{}{"height": above},
+			Collection: is.storageConfig.Collection + "_utxos",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return is.sendStorageRequest(adapter.Request{
+		Method: "delete",
+		Data: adapter.DeleteRequest{
+			Select:     map[string]interface{}{"height": above},
+			Collection: is.storageConfig.Collection + "_blocks",
+		},
+	})
+}
+
+func (is *InternalService) sendStorageRequest(storageRequest adapter.Request) error {
+	bodyBytes, err := jsoniter.Marshal(&storageRequest)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+
+	return err
+}
+
+// tracked reports whether outputs to the address should be stored. An empty
+// address list means every address is indexed.
+func (is *InternalService) tracked(address string) bool {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if len(is.addresses) == 0 {
+		return true
+	}
+
+	_, ok := is.addresses[address]
+
+	return ok
+}
+
+// sendUtxosToStorage stores the outputs of the transaction, upserting by
+// outpoint keeps a block safe to handle again after a restart.
+func (is *InternalService) sendUtxosToStorage(tx model.Tx, height int64) error {
+	coinbase := len(tx.Vin) > 0 && tx.Vin[0].Coinbase != ""
+
+	for _, out := range tx.Vout {
+		address := out.ScriptPubKey.Address
+		if address == "" && len(out.ScriptPubKey.Addresses) == 1 {
+			address = out.ScriptPubKey.Addresses[0]
+		}
+
+		if address == "" || !is.tracked(address) {
+			continue
+		}
+
+		utxo := model.Utxo{
+			Txid:     tx.Txid,
+			Vout:     out.N,
+			Address:  address,
+			Value:    int64(math.Round(out.Value * satoshiPerBitcoin)),
+			Height:   height,
+			Coinbase: coinbase,
+		}
+
+		storageRequest := adapter.Request{
+			Method: "upsert",
+			Data: adapter.UpsertRequest{
+				Select: map[string]interface{}{
+					"txid": utxo.Txid,
+					"vout": utxo.Vout,
+				},
+				Collection: is.storageConfig.Collection + "_utxos",
+				Document:   utxo,
+			},
+		}
+
+		bodyBytes, err := jsoniter.Marshal(&storageRequest)
+		if err != nil {
+			return err
+		}
+
+		_, err = utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// spendOutputs marks every outpoint consumed by the transaction as spent with
+// a single update; outpoints that were never stored are simply not matched.
+func (is *InternalService) spendOutputs(tx model.Tx, height int64) error {
+	outpoints := make([]interface{}, 0, len(tx.Vin))
+	for _, in := range tx.Vin {
+		if in.Coinbase != "" {
+			continue
+		}
+
+		outpoints = append(outpoints, map[string]interface{}{
+			"txid": in.Txid,
+			"vout": in.Vout,
+		})
+	}
+
+	if len(outpoints) == 0 {
+		return nil
+	}
+
+	storageRequest := adapter.Request{
+		Method: "update",
+		Data: adapter.UpdateRequest{
+			Select: map[string]interface{}{
+				"$or": outpoints,
+			},
+			Collection: is.storageConfig.Collection + "_utxos",
+			Document: map[string]interface{}{
+				"$set": map[string]interface{}{
+					"spent":        true,
+					"spent_txid":   tx.Txid,
+					"spent_height": height,
+				},
+			},
+		},
+	}
+
+	bodyBytes, err := jsoniter.Marshal(&storageRequest)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+
+	return err
+}
+
+func (is *InternalService) sendBlockToStorage(block *model.Block) error {
+	storageRequest := adapter.Request{
+		Method: "upsert",
+		Data: adapter.UpsertRequest{
+			Select: map[string]interface{}{
+				"height": block.Height,
+			},
+			Collection: is.storageConfig.Collection + "_blocks",
+			Document:   block,
+		},
+	}
+
+	bodyBytes, err := jsoniter.Marshal(&storageRequest)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+
+	return err
+}
+
+func (is *InternalService) readBlock(height int64) (*model.Block, error) {
+	storageRequest := adapter.Request{
+		Method: "read",
+		Data: adapter.ReadRequest{
+			Select: map[string]interface{}{
+				"height": height,
+			},
+			Collection: is.storageConfig.Collection + "_blocks",
+		},
+	}
+
+	bodyBytes, err := jsoniter.Marshal(&storageRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	respBytes, err := utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Result []model.Block `json:"result"`
+	}
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Result) == 0 {
+		return nil, nil
+	}
+
+	return &resp.Result[0], nil
+}
+
+func (is *InternalService) getIndexes(collection string) (interface{}, error) {
+	storageRequest := adapter.Request{
+		Method: "get_indexes",
+		Data: adapter.GetIndexesRequest{
+			Collection: collection,
+		},
+	}
+
+	bodyBytes, err := jsoniter.Marshal(&storageRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	respBytes, err := utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp interface{}
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (is *InternalService) createIndexes(collection string, indexes []IndexData) (interface{}, error) {
+	indexesInterface := make([]interface{}, len(indexes))
+	for i, idx := range indexes {
+		indexesInterface[i] = idx
+	}
+
+	storageRequest := adapter.Request{
+		Method: "create_indexes",
+		Data: adapter.CreateIndexesRequest{
+			Collection: collection,
+			Data:       indexesInterface,
+		},
+	}
+
+	bodyBytes, err := jsoniter.Marshal(&storageRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	respBytes, err := utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp interface{}
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/saiBitcoinIndexer/internal/model"
+	"github.com/KiraCore/saiBitcoinIndexer/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeStorage keeps the stored blocks and records every request that changes
+// the index.
+type fakeStorage struct {
+	mu       sync.Mutex
+	blocks   map[int64]model.Block
+	requests []storageCall
+}
+
+type storageCall struct {
+	method     string
+	collection string
+	selector   map[string]interface{}
+	document   map[string]interface{}
+}
+
+func (s *fakeStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Method string `json:"method"`
+		Data   struct {
+			Collection string                 `json:"collection"`
+			Select     map[string]interface{} `json:"select"`
+			Document   map[string]interface{} `json:"document"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []model.Block{}
+	switch request.Method {
+	case "read":
+		if block, ok := s.blocks[int64(request.Data.Select["height"].(float64))]; ok {
+			result = append(result, block)
+		}
+	default:
+		s.requests = append(s.requests, storageCall{
+			method:     request.Method,
+			collection: request.Data.Collection,
+			selector:   request.Data.Select,
+			document:   request.Data.Document,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"Status": "OK", "result": result})
+}
+
+// fakeNode answers getblockhash and getblock for the chain in hashes, indexed
+// by height.
+func fakeNode(hashes []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request model.RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var result interface{}
+		switch request.Method {
+		case "getblockhash":
+			result = hashes[int(request.Params[0].(float64))]
+		case "getblock":
+			for height, hash := range hashes {
+				if hash == request.Params[0] {
+					result = model.BlockInfo{Hash: hash, Height: int64(height), PreviousBlockHash: hashes[height-1]}
+				}
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "id": request.ID})
+	}
+}
+
+// chain returns the hashes of blocks 0 to height, blocks from fork up are
+// named after branch.
+func chain(height, fork int64, branch string) []string {
+	hashes := make([]string, 0, height+1)
+	for i := int64(0); i <= height; i++ {
+		name := "a"
+		if i >= fork {
+			name = branch
+		}
+		hashes = append(hashes, fmt.Sprintf("%s%d", name, i))
+	}
+
+	return hashes
+}
+
+// newTestService indexes stored, from start, against a node following
+// node. The last handled block file is written to a temporary directory.
+func newTestService(t *testing.T, stored []string, start int64, node []string) (*InternalService, *fakeStorage) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	storage := &fakeStorage{blocks: map[int64]model.Block{}}
+	for height := start; height < int64(len(stored)); height++ {
+		storage.blocks[height] = model.Block{Hash: stored[height], Height: height}
+	}
+
+	storageServer := httptest.NewServer(storage)
+	t.Cleanup(storageServer.Close)
+
+	nodeServer := httptest.NewServer(fakeNode(node))
+	t.Cleanup(nodeServer.Close)
+
+	return &InternalService{
+		mu:            &sync.Mutex{},
+		config:        model.ServiceConfig{NodeAddress: nodeServer.URL, Confirmations: 6},
+		storageConfig: model.StorageConfig{Url: storageServer.URL, Collection: "bitcoin"},
+		addresses:     map[string]struct{}{},
+		indexed:       true,
+	}, storage
+}
+
+func TestForkHeight(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored []string
+		start  int64
+		node   []string
+		want   int64
+	}{
+		{name: "one block", stored: chain(9, 9, "x"), node: chain(10, 9, "b"), want: 9},
+		{name: "three blocks", stored: chain(9, 7, "x"), node: chain(10, 7, "b"), want: 7},
+		{name: "below the first stored block", stored: chain(9, 0, "x"), start: 5, node: chain(10, 0, "b"), want: 5},
+		{name: "from genesis", stored: chain(9, 0, "x"), node: chain(10, 0, "b"), want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			is, _ := newTestService(t, test.stored, test.start, test.node)
+
+			fork, err := is.forkHeight(9)
+			if err != nil {
+				t.Fatalf("forkHeight: %v", err)
+			}
+			if fork != test.want {
+				t.Fatalf("forkHeight = %d, want %d", fork, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleBlockReorg(t *testing.T) {
+	is, storage := newTestService(t, chain(9, 7, "x"), 0, chain(10, 7, "b"))
+	is.currentBlock = 10
+
+	if err := is.handleBlock(); !errors.Is(err, errReorg) {
+		t.Fatalf("handleBlock = %v, want %v", err, errReorg)
+	}
+
+	if is.currentBlock != 7 {
+		t.Fatalf("current block = %d, want the fork at 7", is.currentBlock)
+	}
+
+	latest, err := os.ReadFile(filePathLatestBlock)
+	if err != nil || strings.TrimSpace(string(latest)) != "6" {
+		t.Fatalf("last handled block = %q, %v, want 6", latest, err)
+	}
+
+	want := []struct {
+		method     string
+		collection string
+		field      string
+	}{
+		{"update", "bitcoin_utxos", "spent_height"},
+		{"delete", "bitcoin_utxos", "height"},
+		{"delete", "bitcoin_blocks", "height"},
+	}
+
+	if len(storage.requests) != len(want) {
+		t.Fatalf("storage requests = %+v, want %d", storage.requests, len(want))
+	}
+
+	for i, call := range storage.requests {
+		above, _ := call.selector[want[i].field].(map[string]interface{})
+		if call.method != want[i].method || call.collection != want[i].collection || above["$gte"] != float64(7) {
+			t.Fatalf("request %d = %+v, want %s of %s with %s from 7", i, call, want[i].method, want[i].collection, want[i].field)
+		}
+	}
+
+	// outputs spent by the orphaned blocks are unspent again
+	unset, _ := storage.requests[0].document["$unset"].(map[string]interface{})
+	set, _ := storage.requests[0].document["$set"].(map[string]interface{})
+	if set["spent"] != false || len(unset) != 2 {
+		t.Fatalf("unspend document = %v, want spent false and the spending unset", storage.requests[0].document)
+	}
+}
+
+func TestHandleBlockOnChain(t *testing.T) {
+	is, storage := newTestService(t, chain(9, 10, "x"), 0, chain(10, 10, "b"))
+	is.currentBlock = 10
+
+	if err := is.handleBlock(); err != nil {
+		t.Fatalf("handleBlock: %v", err)
+	}
+
+	if is.currentBlock != 10 {
+		t.Fatalf("current block = %d, want 10", is.currentBlock)
+	}
+
+	if len(storage.requests) != 1 || storage.requests[0].method != "upsert" || storage.requests[0].collection != "bitcoin_blocks" {
+		t.Fatalf("storage requests = %+v, want the upsert of block 10", storage.requests)
+	}
+}
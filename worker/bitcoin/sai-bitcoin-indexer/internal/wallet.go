@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"os"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func (is *InternalService) loadAddresses() error {
+	fileBytes, err := os.ReadFile(filePathAddresses)
+	if err != nil {
+		return err
+	}
+
+	var addressArray []string
+	err = jsoniter.Unmarshal(fileBytes, &addressArray)
+	if err != nil {
+		return err
+	}
+
+	is.mu.Lock()
+	for _, address := range addressArray {
+		is.addresses[address] = struct{}{}
+	}
+	is.mu.Unlock()
+
+	return nil
+}
+
+func (is *InternalService) rewriteAddressesFile() error {
+	is.mu.Lock()
+	addressArray := make([]string, 0, len(is.addresses))
+	for address := range is.addresses {
+		addressArray = append(addressArray, address)
+	}
+	is.mu.Unlock()
+
+	jsonBytes, err := jsoniter.Marshal(&addressArray)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePathAddresses, jsonBytes, os.ModePerm)
+}
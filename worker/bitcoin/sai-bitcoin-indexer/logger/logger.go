@@ -0,0 +1,5 @@
+package logger
+
+import "go.uber.org/zap"
+
+var Logger *zap.Logger
@@ -0,0 +1,28 @@
+package main
+
+import (
+	saiService "github.com/KiraCore/sai-service/service"
+	"github.com/KiraCore/saiBitcoinIndexer/internal"
+	"github.com/KiraCore/saiBitcoinIndexer/logger"
+)
+
+func main() {
+	svc := saiService.NewService("saiBitcoinIndexer")
+	is := internal.InternalService{Context: svc.Context}
+
+	svc.RegisterConfig("config.yml")
+
+	logger.Logger = svc.Logger
+
+	svc.RegisterInitTask(is.Init)
+
+	svc.RegisterTasks([]func(){
+		is.Process,
+	})
+
+	svc.RegisterHandlers(
+		is.NewHandler(),
+	)
+
+	svc.Start()
+}
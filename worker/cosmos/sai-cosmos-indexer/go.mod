@@ -3,12 +3,14 @@ module github.com/KiraCore/saiCosmosIndexer
 go 1.21
 
 require (
+	github.com/KiraCore/sai-service v1.0.5
+	github.com/KiraCore/sai-storage-mongo v1.1.5
 	github.com/KiraCore/sekai v0.4.13
+	github.com/cometbft/cometbft v0.37.2
 	github.com/cosmos/cosmos-sdk v0.47.6
 	github.com/json-iterator/go v1.1.12
-	github.com/KiraCore/sai-service v1.0.5
-	github.com/KiraCore/sai-storage-mongo v1.1.5
 	github.com/spf13/cast v1.7.1
+	go.mongodb.org/mongo-driver v1.13.1
 	go.uber.org/zap v1.27.0
 )
 
@@ -39,7 +41,6 @@ require (
 	github.com/cockroachdb/redact v1.1.5 // indirect
 	github.com/cockroachdb/tokenbucket v0.0.0-20230807174530-cc333fc44b06 // indirect
 	github.com/coinbase/rosetta-sdk-go/types v1.0.0 // indirect
-	github.com/cometbft/cometbft v0.37.2 // indirect
 	github.com/cometbft/cometbft-db v0.11.0 // indirect
 	github.com/confio/ics23/go v0.9.0 // indirect
 	github.com/cosmos/btcutil v1.0.5 // indirect
@@ -153,7 +154,6 @@ require (
 	github.com/zondax/hid v0.9.2 // indirect
 	github.com/zondax/ledger-go v0.14.3 // indirect
 	go.etcd.io/bbolt v1.3.10 // indirect
-	go.mongodb.org/mongo-driver v1.13.1 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/exp v0.0.0-20230711153332-06a737ee72cb // indirect
@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+	"github.com/KiraCore/saiCosmosIndexer/internal/model"
+	"github.com/KiraCore/saiCosmosIndexer/logger"
+	"github.com/KiraCore/saiCosmosIndexer/utils"
+)
+
+const filePathBackfillState = "./backfill_state"
+
+type backfillBatch struct {
+	from int64
+	to   int64
+}
+
+// startBackfill hands the heights between the resume point and the current tip
+// to the backfill workers and moves the live cursor to the tip. The range is
+// persisted before latest_handled_block, so a restart resumes both.
+func (is *InternalService) startBackfill() error {
+	state, err := is.loadBackfillState()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if state == nil {
+		latestBlock, err := is.getLatestBlock()
+		if err != nil {
+			return err
+		}
+
+		tip, err := strconv.ParseInt(latestBlock.LastHeight, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		state = &model.BackfillState{LowWaterMark: is.currentBlock, Target: tip}
+		if err := is.saveBackfillState(state); err != nil {
+			return err
+		}
+	}
+
+	if is.currentBlock < state.Target {
+		is.currentBlock = state.Target
+		if err := is.rewriteLastHandledBlock(is.currentBlock); err != nil {
+			return err
+		}
+	}
+
+	if state.LowWaterMark >= state.Target {
+		return nil
+	}
+
+	logger.Logger.Info("startBackfill",
+		zap.Int64("from", state.LowWaterMark),
+		zap.Int64("to", state.Target),
+		zap.Int("workers", is.config.BackfillWorkers))
+
+	go is.processBackfill(state)
+
+	return nil
+}
+
+// processBackfill indexes [LowWaterMark, Target) in batches on
+// is.config.BackfillWorkers workers. The low-water mark only moves past a batch
+// once every batch below it is stored, so no height is skipped on restart.
+func (is *InternalService) processBackfill(state *model.BackfillState) {
+	batches := make(chan backfillBatch)
+	done := make(chan backfillBatch)
+	rpcLimit := make(chan struct{}, is.config.BackfillRPCConcurrency)
+
+	for i := 0; i < is.config.BackfillWorkers; i++ {
+		go func() {
+			for batch := range batches {
+				for {
+					err := is.backfillBatch(batch, rpcLimit)
+					if err == nil {
+						break
+					}
+
+					logger.Logger.Error("backfillBatch", zap.Int64("from", batch.from), zap.Error(err))
+
+					select {
+					case <-is.Context.Context.Done():
+						return
+					case <-time.After(5 * time.Second):
+					}
+				}
+
+				select {
+				case done <- batch:
+				case <-is.Context.Context.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(batches)
+
+		for from := state.LowWaterMark; from < state.Target; from += is.config.BackfillBatchSize {
+			to := from + is.config.BackfillBatchSize
+			if to > state.Target {
+				to = state.Target
+			}
+
+			select {
+			case batches <- backfillBatch{from: from, to: to}:
+			case <-is.Context.Context.Done():
+				return
+			}
+		}
+	}()
+
+	completed := make(map[int64]int64)
+
+	for state.LowWaterMark < state.Target {
+		select {
+		case <-is.Context.Context.Done():
+			return
+		case batch := <-done:
+			completed[batch.from] = batch.to
+		}
+
+		advanced := false
+		for to, ok := completed[state.LowWaterMark]; ok; to, ok = completed[state.LowWaterMark] {
+			delete(completed, state.LowWaterMark)
+			state.LowWaterMark = to
+			advanced = true
+		}
+
+		if advanced {
+			if err := is.saveBackfillState(state); err != nil {
+				logger.Logger.Error("saveBackfillState", zap.Error(err))
+			}
+
+			logger.Logger.Debug("processBackfill", zap.Int64("low_water_mark", state.LowWaterMark))
+		}
+	}
+
+	logger.Logger.Info("processBackfill finished", zap.Int64("to", state.Target))
+}
+
+// backfillBatch fetches every height of the batch and replaces whatever a
+// previous, interrupted run stored for them, so retries never duplicate.
+func (is *InternalService) backfillBatch(batch backfillBatch, rpcLimit chan struct{}) error {
+	var (
+		blocks  []interface{}
+		txs     []interface{}
+		heights []string
+	)
+
+	for height := batch.from; height < batch.to; height++ {
+		rpcLimit <- struct{}{}
+		blockInfo, err := is.getBlockInfo(height)
+		<-rpcLimit
+		if err != nil {
+			return err
+		}
+
+		rpcLimit <- struct{}{}
+		blockTxs, err := is.getBlockTxs(height)
+		<-rpcLimit
+		if err != nil {
+			return err
+		}
+
+		txArray, err := is.prepareTxs(blockInfo, blockTxs)
+		if err != nil {
+			return err
+		}
+
+		heights = append(heights, strconv.FormatInt(height, 10))
+		if is.config.HandleBlocks {
+			blocks = append(blocks, blockInfo)
+		}
+		for _, tx := range txArray {
+			txs = append(txs, tx)
+		}
+	}
+
+	if is.config.HandleBlocks {
+		err := is.replaceDocuments(is.storageConfig.Collection+"_blocks", "block.header.height", heights, blocks)
+		if err != nil {
+			return err
+		}
+	}
+
+	return is.replaceDocuments(is.storageConfig.Collection+"_txs", "height", heights, txs)
+}
+
+func (is *InternalService) replaceDocuments(collection, heightField string, heights []string, documents []interface{}) error {
+	deleteRequest := adapter.Request{
+		Method: "delete",
+		Data: adapter.DeleteRequest{
+			Collection: collection,
+			Select: map[string]interface{}{
+				heightField: map[string]interface{}{"$in": heights},
+			},
+		},
+	}
+
+	bodyBytes, err := jsoniter.Marshal(&deleteRequest)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+	if err != nil {
+		return err
+	}
+
+	if len(documents) == 0 {
+		return nil
+	}
+
+	createRequest := adapter.Request{
+		Method: "create",
+		Data: adapter.CreateRequest{
+			Collection:    collection,
+			Documents:     documents,
+			IncludeFields: []string{"internal_id"},
+		},
+	}
+
+	bodyBytes, err = jsoniter.Marshal(&createRequest)
+	if err != nil {
+		return err
+	}
+
+	_, err = utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+
+	return err
+}
+
+func (is *InternalService) loadBackfillState() (*model.BackfillState, error) {
+	fileBytes, err := os.ReadFile(filePathBackfillState)
+	if err != nil {
+		return nil, err
+	}
+
+	state := new(model.BackfillState)
+	err = jsoniter.Unmarshal(fileBytes, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (is *InternalService) saveBackfillState(state *model.BackfillState) error {
+	fileBytes, err := jsoniter.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePathBackfillState, fileBytes, os.ModePerm)
+}
@@ -34,9 +34,9 @@ func (is *InternalService) getLatestBlock() (*model.LatestBlock, error) {
 	return lb, err
 }
 
-func (is *InternalService) getBlockInfo() (*model.BlockInfo, error) {
+func (is *InternalService) getBlockInfo(height int64) (*model.BlockInfo, error) {
 	var query = url.Values{}
-	query.Add("height", fmt.Sprintf("\"%d\"", is.currentBlock))
+	query.Add("height", fmt.Sprintf("\"%d\"", height))
 
 	res, err := is.makeTendermintRPCRequest("/block", query.Encode())
 	if err != nil {
@@ -54,28 +54,43 @@ func (is *InternalService) getBlockInfo() (*model.BlockInfo, error) {
 	return blockInfo, nil
 }
 
-func (is *InternalService) getBlockTxs() ([]model.Tx, error) {
-	var query = url.Values{}
-	if is.config.TxType != "" {
-		query.Add("query", fmt.Sprintf("\"tx.height=%d AND message.action='%s'\"", is.currentBlock, is.config.TxType))
-	} else {
-		query.Add("query", fmt.Sprintf("\"tx.height=%d\"", is.currentBlock))
-	}
-
-	res, err := is.makeTendermintRPCRequest("/tx_search", query.Encode())
-	if err != nil {
-		logger.Logger.Error("getBlockTxs", zap.Error(err))
-		return nil, err
+// getBlockTxs pages through /tx_search, it returns 30 txs per page by default
+// which silently truncated busy blocks.
+func (is *InternalService) getBlockTxs(height int64) ([]model.Tx, error) {
+	const perPage = 100
+
+	var txs []model.Tx
+
+	for page := 1; ; page++ {
+		var query = url.Values{}
+		if is.config.TxType != "" {
+			query.Add("query", fmt.Sprintf("\"tx.height=%d AND message.action='%s'\"", height, is.config.TxType))
+		} else {
+			query.Add("query", fmt.Sprintf("\"tx.height=%d\"", height))
+		}
+		query.Add("page", strconv.Itoa(page))
+		query.Add("per_page", strconv.Itoa(perPage))
+
+		res, err := is.makeTendermintRPCRequest("/tx_search", query.Encode())
+		if err != nil {
+			logger.Logger.Error("getBlockTxs", zap.Error(err))
+			return nil, err
+		}
+
+		blockInfo := model.BlockTransactions{}
+		err = jsoniter.Unmarshal(res, &blockInfo)
+		if err != nil {
+			logger.Logger.Error("getBlockTxs", zap.Error(err))
+			return nil, err
+		}
+
+		txs = append(txs, blockInfo.Txs...)
+
+		totalCount, _ := strconv.Atoi(blockInfo.TotalCount)
+		if len(blockInfo.Txs) < perPage || len(txs) >= totalCount {
+			return txs, nil
+		}
 	}
-
-	blockInfo := model.BlockTransactions{}
-	err = jsoniter.Unmarshal(res, &blockInfo)
-	if err != nil {
-		logger.Logger.Error("getBlockTxs", zap.Error(err))
-		return nil, err
-	}
-
-	return blockInfo.Txs, nil
 }
 
 func (is *InternalService) makeTendermintRPCRequest(url string, query string) ([]byte, error) {
@@ -22,6 +22,18 @@ func (is *InternalService) NewHandler() saiService.Handler {
 				return is.deleteAddress(data)
 			},
 		},
+		"indexer_health": saiService.HandlerElement{
+			Name:        "indexer_health",
+			Description: "Gaps, last verified height and repair backlog of the verifier",
+			Function: func(data, meta interface{}) (interface{}, int, error) {
+				report, err := is.health()
+				if err != nil {
+					return "indexerHealth", http.StatusInternalServerError, err
+				}
+
+				return report, http.StatusOK, nil
+			},
+		},
 	}
 }
 
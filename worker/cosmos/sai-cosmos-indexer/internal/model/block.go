@@ -1,5 +1,40 @@
 package model
 
+import "time"
+
 type LatestBlock struct {
 	LastHeight string `json:"last_height"`
 }
+
+// BackfillState is persisted by the backfill workers: every height below
+// LowWaterMark is stored, heights up to Target belong to the backfill and the
+// live loop starts at Target.
+type BackfillState struct {
+	LowWaterMark int64 `json:"low_water_mark"`
+	Target       int64 `json:"target"`
+}
+
+// VerifierState is persisted by the verifier: every height up to
+// LastVerifiedHeight was checked, Missing and Partial are the heights found
+// absent or with fewer stored txs than the block holds, waiting for repair.
+type VerifierState struct {
+	LastVerifiedHeight int64   `json:"last_verified_height"`
+	Missing            []int64 `json:"missing"`
+	Partial            []int64 `json:"partial"`
+}
+
+type HeightRange struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// IndexerHealth is served on /indexer/health.
+type IndexerHealth struct {
+	LatestHandledBlock int64         `json:"latest_handled_block"`
+	LastVerifiedHeight int64         `json:"last_verified_height"`
+	Gaps               []HeightRange `json:"gaps"`
+	PartialHeights     []int64       `json:"partial_heights"`
+	RepairBacklog      int           `json:"repair_backlog"`
+	LastRun            time.Time     `json:"last_run"`
+	LastError          string        `json:"last_error,omitempty"`
+}
@@ -65,12 +65,16 @@ type BlockInfo struct {
 			} `json:"signatures"`
 		} `json:"last_commit"`
 	} `json:"block"`
+	// SkippedTxs counts the txs of the block the indexer left out on purpose
+	// (skip_failed_tx, no events), the verifier expects the rest in storage.
+	SkippedTxs int `json:"skipped_txs"`
 }
 
 type BlockTransactions struct {
 	Txs         []Tx         `json:"txs"`
 	TxResponses []TxResponse `json:"tx_responses"`
 	Pagination  Pagination   `json:"pagination"`
+	TotalCount  string       `json:"total_count"`
 }
 
 type Pagination struct {
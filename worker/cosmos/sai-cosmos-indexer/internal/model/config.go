@@ -1,11 +1,24 @@
 package model
 
+import "time"
+
 type ServiceConfig struct {
-	NodeAddress    string
-	TxType         string
-	SkipFailedTxs  bool
-	HandleBlocks   bool
-	CollectionName string
+	NodeAddress      string
+	TxType           string
+	SkipFailedTxs    bool
+	HandleBlocks     bool
+	CollectionName   string
+	Websocket        bool
+	WebsocketTimeout time.Duration
+
+	Backfill               bool
+	BackfillWorkers        int
+	BackfillBatchSize      int64
+	BackfillRPCConcurrency int
+
+	Verifier         bool
+	VerifierInterval time.Duration
+	VerifierWindow   int64
 }
 
 type StorageConfig struct {
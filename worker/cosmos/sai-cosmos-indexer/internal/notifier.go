@@ -10,6 +10,7 @@ import (
 
 type Notifier interface {
 	SendTx(data interface{}) error
+	SendBlock(data interface{}) error
 }
 
 type notifier struct {
@@ -21,8 +22,9 @@ type notifier struct {
 }
 
 type notificationData struct {
-	From string      `json:"from"`
-	Tx   interface{} `json:"tx"`
+	From  string      `json:"from"`
+	Tx    interface{} `json:"tx,omitempty"`
+	Block interface{} `json:"block,omitempty"`
 }
 
 type Metadata struct {
@@ -46,12 +48,23 @@ func NewNotifier(senderID, email, password, token, address string) Notifier {
 }
 
 func (n *notifier) SendTx(tx interface{}) error {
+	return n.send(notificationData{
+		From: n.senderID,
+		Tx:   tx,
+	})
+}
+
+func (n *notifier) SendBlock(block interface{}) error {
+	return n.send(notificationData{
+		From:  n.senderID,
+		Block: block,
+	})
+}
+
+func (n *notifier) send(data notificationData) error {
 	req := notificationRequest{
 		Method: "notify",
-		Data: notificationData{
-			From: n.senderID,
-			Tx:   tx,
-		},
+		Data:   data,
 		Metadata: Metadata{
 			Token: n.token,
 		},
@@ -49,6 +49,7 @@ type InternalService struct {
 	notifier      Notifier
 	client        http.Client
 	indexed       bool
+	verifier      *verifier
 }
 
 func (is *InternalService) Init() {
@@ -70,6 +71,15 @@ func (is *InternalService) Init() {
 	is.config.CollectionName = cast.ToString(is.Context.GetConfig("storage.mongo_collection_name", ""))
 	is.config.SkipFailedTxs = cast.ToBool(is.Context.GetConfig("skip_failed_tx", false))
 	is.config.HandleBlocks = cast.ToBool(is.Context.GetConfig("handle_blocks", false))
+	is.config.Websocket = cast.ToBool(is.Context.GetConfig("websocket.enabled", false))
+	is.config.WebsocketTimeout = time.Duration(cast.ToInt(is.Context.GetConfig("websocket.timeout", 30))) * time.Second
+	is.config.Backfill = cast.ToBool(is.Context.GetConfig("backfill.enabled", false))
+	is.config.BackfillWorkers = cast.ToInt(is.Context.GetConfig("backfill.workers", 8))
+	is.config.BackfillBatchSize = cast.ToInt64(is.Context.GetConfig("backfill.batch_size", 100))
+	is.config.BackfillRPCConcurrency = cast.ToInt(is.Context.GetConfig("backfill.rpc_concurrency", 8))
+	is.config.Verifier = cast.ToBool(is.Context.GetConfig("verifier.enabled", false))
+	is.config.VerifierInterval = time.Duration(cast.ToInt(is.Context.GetConfig("verifier.interval", 60))) * time.Second
+	is.config.VerifierWindow = cast.ToInt64(is.Context.GetConfig("verifier.window", 500))
 	is.storageConfig = model.StorageConfig{
 		Token:      cast.ToString(is.Context.GetConfig("storage.token", "")),
 		Url:        cast.ToString(is.Context.GetConfig("storage.url", "")),
@@ -109,6 +119,8 @@ func (is *InternalService) Init() {
 	if is.currentBlock < startBlock {
 		is.currentBlock = startBlock
 	}
+
+	is.verifier = newVerifier(startBlock)
 }
 
 func (is *InternalService) ProcessIndexes() {
@@ -228,6 +240,21 @@ func hasRequiredIndexes(data interface{}, required []IndexData) bool {
 func (is *InternalService) Process() {
 	sleepDuration := cast.ToDuration(is.Context.GetConfig("sleep_duration", 2))
 
+	for is.config.Backfill {
+		err := is.startBackfill()
+		if err == nil {
+			break
+		}
+
+		logger.Logger.Error("startBackfill", zap.Error(err))
+
+		select {
+		case <-is.Context.Context.Done():
+			return
+		case <-time.After(time.Second * sleepDuration):
+		}
+	}
+
 	for {
 		select {
 		case <-is.Context.Context.Done():
@@ -249,6 +276,17 @@ func (is *InternalService) Process() {
 			}
 
 			if is.currentBlock >= int64(lb) {
+				// caught up, follow the tip over the websocket until it fails
+				// or stalls, then poll again to fill whatever was missed
+				if is.config.Websocket {
+					err = is.processWebsocket()
+					if err != nil {
+						logger.Logger.Error("processWebsocket", zap.Error(err))
+						time.Sleep(time.Second * sleepDuration)
+					}
+					continue
+				}
+
 				time.Sleep(time.Second * sleepDuration)
 				continue
 			}
@@ -268,35 +306,75 @@ func (is *InternalService) Process() {
 }
 
 func (is *InternalService) handleBlockTxs() error {
-	blockInfo, err := is.getBlockInfo()
+	blockInfo, err := is.getBlockInfo(is.currentBlock)
 	if err != nil {
 		logger.Logger.Error("handleBlockTxs", zap.Error(err))
 		return err
 	}
 
+	blockTxs, err := is.getBlockTxs(is.currentBlock)
+	if err != nil {
+		logger.Logger.Error("handleBlockTxs", zap.Error(err))
+		return err
+	}
+
+	return is.indexBlock(blockInfo, blockTxs)
+}
+
+// indexBlock stores the block at is.currentBlock with its txs, whether they
+// were fetched over RPC or collected from websocket events.
+func (is *InternalService) indexBlock(blockInfo *model.BlockInfo, blockTxs []model.Tx) error {
+	txArray, err := is.prepareTxs(blockInfo, blockTxs)
+	if err != nil {
+		logger.Logger.Error("indexBlock", zap.Error(err))
+		return err
+	}
+
 	if is.config.HandleBlocks {
-		err = is.sendBlockToStorage(blockInfo)
+		err := is.sendBlockToStorage(blockInfo)
 		if err != nil {
-			logger.Logger.Error("handleBlockTxs", zap.Error(err))
+			logger.Logger.Error("indexBlock", zap.Error(err))
 			return err
 		}
 	}
 
-	blockTxs, err := is.getBlockTxs()
-	if err != nil {
-		logger.Logger.Error("handleBlockTxs", zap.Error(err))
-		return err
+	for _, txRes := range txArray {
+		go is.sendTxNotification(txRes)
 	}
 
+	if len(txArray) > 0 {
+
+		err := is.sendTxsToStorage(txArray)
+		if err != nil {
+			logger.Logger.Error("indexBlock", zap.Error(err))
+			return err
+		}
+	}
+
+	go is.sendBlockNotification(blockInfo)
+
+	err = is.rewriteLastHandledBlock(is.currentBlock)
+
+	return err
+}
+
+// prepareTxs drops the txs the indexer is configured to skip, counting them in
+// blockInfo.SkippedTxs for the verifier, and decodes the messages of the others.
+func (is *InternalService) prepareTxs(blockInfo *model.BlockInfo, blockTxs []model.Tx) ([]model.Tx, error) {
 	var txArray []model.Tx
 	encode := sekaiapp.MakeEncodingConfig()
 
+	blockInfo.SkippedTxs = 0
+
 	for _, txRes := range blockTxs {
 		if is.config.SkipFailedTxs && txRes.TxResult.Code != 0 {
+			blockInfo.SkippedTxs++
 			continue
 		}
 
 		if len(txRes.TxResult.Events) < 1 {
+			logger.Logger.Debug("prepareTxs skipped tx without events", zap.String("hash", txRes.Hash))
+			blockInfo.SkippedTxs++
 			continue
 		}
 
@@ -304,12 +382,14 @@ func (is *InternalService) handleBlockTxs() error {
 
 		txBytes, err := base64.StdEncoding.DecodeString(txRes.Tx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		tx, err := encode.TxConfig.TxDecoder()(txBytes)
 		if err != nil {
-			logger.Logger.Error("handleBlockTxs", zap.Error(err))
+			// keep the tx without decoded messages rather than lose it
+			logger.Logger.Error("prepareTxs", zap.String("hash", txRes.Hash), zap.Error(err))
+			txArray = append(txArray, txRes)
 			continue
 		}
 
@@ -318,13 +398,13 @@ func (is *InternalService) handleBlockTxs() error {
 
 			msgBytes, err := json.Marshal(msg)
 			if err != nil {
-				logger.Logger.Error("handleBlockTxs", zap.Error(err))
+				logger.Logger.Error("prepareTxs", zap.Error(err))
 				continue
 			}
 
 			err = json.Unmarshal(msgBytes, &message)
 			if err != nil {
-				logger.Logger.Error("handleBlockTxs", zap.Error(err))
+				logger.Logger.Error("prepareTxs", zap.Error(err))
 				continue
 			}
 
@@ -334,21 +414,9 @@ func (is *InternalService) handleBlockTxs() error {
 		}
 
 		txArray = append(txArray, txRes)
-		go is.sendTxNotification(txRes)
-	}
-
-	if len(txArray) > 0 {
-
-		err = is.sendTxsToStorage(txArray)
-		if err != nil {
-			logger.Logger.Error("handleBlockTxs", zap.Error(err))
-			return err
-		}
 	}
 
-	err = is.rewriteLastHandledBlock(is.currentBlock)
-
-	return err
+	return txArray, nil
 }
 
 func (is *InternalService) getIndexes(collection string) (interface{}, error) {
@@ -453,7 +521,8 @@ func (is *InternalService) sendTxsToStorage(txs []model.Tx) error {
 
 		_, err = utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
 		if err != nil {
-			continue
+			// the block is retried, upserts make already stored txs harmless
+			return err
 		}
 	}
 
@@ -470,3 +539,10 @@ func (is *InternalService) sendTxNotification(tx interface{}) {
 		//logger.Logger.Error("is.notifier.SendTx", zap.Error(err))
 	}
 }
+
+func (is *InternalService) sendBlockNotification(block *model.BlockInfo) {
+	err := is.notifier.SendBlock(block)
+	if err != nil {
+		//logger.Logger.Error("is.notifier.SendBlock", zap.Error(err))
+	}
+}
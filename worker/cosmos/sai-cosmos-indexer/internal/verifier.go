@@ -0,0 +1,412 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/sai-storage-mongo/external/adapter"
+	"github.com/KiraCore/saiCosmosIndexer/internal/model"
+	"github.com/KiraCore/saiCosmosIndexer/logger"
+	"github.com/KiraCore/saiCosmosIndexer/utils"
+)
+
+const filePathVerifierState = "./verifier_state"
+
+// verifier keeps the progress of the gap check, shared between the
+// ProcessVerifier task and the health handlers.
+type verifier struct {
+	mu      sync.RWMutex
+	state   model.VerifierState
+	lastRun time.Time
+	lastErr error
+}
+
+func newVerifier(startBlock int64) *verifier {
+	if startBlock < 1 {
+		startBlock = 1
+	}
+
+	return &verifier{state: model.VerifierState{LastVerifiedHeight: startBlock - 1}}
+}
+
+// ProcessVerifier periodically checks the stored blocks for height continuity
+// and their stored tx counts against block.data.txs, then re-indexes the
+// missing and partial heights it found.
+func (is *InternalService) ProcessVerifier() {
+	if !is.config.Verifier {
+		return
+	}
+
+	if !is.config.HandleBlocks {
+		logger.Logger.Error("ProcessVerifier", zap.String("reason", "verifier needs handle_blocks to be enabled"))
+		return
+	}
+
+	state, err := is.loadVerifierState()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Logger.Error("loadVerifierState", zap.Error(err))
+	} else if state != nil {
+		is.verifier.mu.Lock()
+		is.verifier.state = *state
+		is.verifier.mu.Unlock()
+	}
+
+	for {
+		err := is.verify()
+		if err != nil {
+			logger.Logger.Error("verify", zap.Error(err))
+		}
+
+		repairErr := is.repair()
+		if repairErr != nil {
+			logger.Logger.Error("repair", zap.Error(repairErr))
+			err = repairErr
+		}
+
+		is.verifier.mu.Lock()
+		is.verifier.lastRun = time.Now()
+		is.verifier.lastErr = err
+		is.verifier.mu.Unlock()
+
+		select {
+		case <-is.Context.Context.Done():
+			return
+		case <-time.After(is.config.VerifierInterval):
+		}
+	}
+}
+
+// verify checks the heights between the last verified one and the last
+// handled block window by window. It never passes the backfill low-water mark,
+// the heights above it are still being written.
+func (is *InternalService) verify() error {
+	upper, err := readLatestHandledBlock()
+	if err != nil {
+		return err
+	}
+
+	backfill, err := is.loadBackfillState()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if backfill != nil && backfill.LowWaterMark < backfill.Target && backfill.LowWaterMark < upper {
+		upper = backfill.LowWaterMark
+	}
+
+	is.verifier.mu.RLock()
+	from := is.verifier.state.LastVerifiedHeight + 1
+	is.verifier.mu.RUnlock()
+
+	for ; from < upper; from += is.config.VerifierWindow {
+		to := from + is.config.VerifierWindow
+		if to > upper {
+			to = upper
+		}
+
+		missing, partial, err := is.verifyRange(from, to)
+		if err != nil {
+			return err
+		}
+
+		if len(missing) > 0 || len(partial) > 0 {
+			logger.Logger.Info("verify",
+				zap.Int64("from", from),
+				zap.Int64("to", to),
+				zap.Int64s("missing", missing),
+				zap.Int64s("partial", partial))
+		}
+
+		is.verifier.mu.Lock()
+		is.verifier.state.Missing = mergeHeights(is.verifier.state.Missing, missing)
+		is.verifier.state.Partial = mergeHeights(is.verifier.state.Partial, partial)
+		is.verifier.state.LastVerifiedHeight = to - 1
+		state := is.verifier.state
+		is.verifier.mu.Unlock()
+
+		err = is.saveVerifierState(&state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repair re-indexes every height of the backlog the same way the backfill
+// does and keeps those that still do not verify for the next run.
+func (is *InternalService) repair() error {
+	is.verifier.mu.RLock()
+	backlog := mergeHeights(is.verifier.state.Missing, is.verifier.state.Partial)
+	is.verifier.mu.RUnlock()
+
+	rpcLimit := make(chan struct{}, 1)
+
+	for _, height := range backlog {
+		select {
+		case <-is.Context.Context.Done():
+			return nil
+		default:
+		}
+
+		err := is.backfillBatch(backfillBatch{from: height, to: height + 1}, rpcLimit)
+		if err != nil {
+			return err
+		}
+
+		missing, partial, err := is.verifyRange(height, height+1)
+		if err != nil {
+			return err
+		}
+
+		is.verifier.mu.Lock()
+		is.verifier.state.Missing = removeHeight(is.verifier.state.Missing, height)
+		is.verifier.state.Partial = removeHeight(is.verifier.state.Partial, height)
+		is.verifier.state.Missing = mergeHeights(is.verifier.state.Missing, missing)
+		is.verifier.state.Partial = mergeHeights(is.verifier.state.Partial, partial)
+		state := is.verifier.state
+		is.verifier.mu.Unlock()
+
+		if len(missing) > 0 || len(partial) > 0 {
+			logger.Logger.Error("repair", zap.Int64("height", height), zap.String("reason", "height still incomplete after re-index"))
+		} else {
+			logger.Logger.Info("repair", zap.Int64("height", height))
+		}
+
+		err = is.saveVerifierState(&state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyRange returns the heights of [from, to) without a stored block and
+// those with fewer stored txs than the block holds minus the ones the indexer
+// skipped on purpose. Tx counts are not checked when tx_type filters the txs.
+func (is *InternalService) verifyRange(from, to int64) ([]int64, []int64, error) {
+	var heights []string
+	for height := from; height < to; height++ {
+		heights = append(heights, strconv.FormatInt(height, 10))
+	}
+
+	var blocks []model.BlockInfo
+	err := is.readDocuments(is.storageConfig.Collection+"_blocks", "block.header.height", heights,
+		[]string{"block.header.height", "block.data.txs", "skipped_txs"}, &blocks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expected := make(map[int64]int, len(blocks))
+	for _, block := range blocks {
+		height, err := strconv.ParseInt(block.Block.Header.Height, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		expected[height] = len(block.Block.Data.Txs) - block.SkippedTxs
+	}
+
+	stored := make(map[int64]int)
+	if is.config.TxType == "" {
+		var txs []model.Tx
+		err := is.readDocuments(is.storageConfig.Collection+"_txs", "height", heights, []string{"height", "hash"}, &txs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		hashes := make(map[string]struct{}, len(txs))
+		for _, tx := range txs {
+			if _, ok := hashes[tx.Hash]; ok {
+				continue
+			}
+			hashes[tx.Hash] = struct{}{}
+
+			height, err := strconv.ParseInt(tx.Height, 10, 64)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			stored[height]++
+		}
+	}
+
+	var missing, partial []int64
+	for height := from; height < to; height++ {
+		count, ok := expected[height]
+		if !ok {
+			missing = append(missing, height)
+			continue
+		}
+
+		if is.config.TxType == "" && stored[height] < count {
+			partial = append(partial, height)
+		}
+	}
+
+	return missing, partial, nil
+}
+
+func (is *InternalService) readDocuments(collection, heightField string, heights []string, fields []string, result interface{}) error {
+	storageRequest := adapter.Request{
+		Method: "read",
+		Data: adapter.ReadRequest{
+			Collection: collection,
+			Select: map[string]interface{}{
+				heightField: map[string]interface{}{"$in": heights},
+			},
+			IncludeFields: fields,
+		},
+	}
+
+	bodyBytes, err := jsoniter.Marshal(&storageRequest)
+	if err != nil {
+		return err
+	}
+
+	respBytes, err := utils.SaiQuerySender(bytes.NewBuffer(bodyBytes), is.storageConfig.Url, is.storageConfig.Token)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Result jsoniter.RawMessage `json:"result"`
+	}
+	err = jsoniter.Unmarshal(respBytes, &resp)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return nil
+	}
+
+	return jsoniter.Unmarshal(resp.Result, result)
+}
+
+// health builds the report served on /indexer/health.
+func (is *InternalService) health() (*model.IndexerHealth, error) {
+	latestHandledBlock, err := readLatestHandledBlock()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	is.verifier.mu.RLock()
+	defer is.verifier.mu.RUnlock()
+
+	report := &model.IndexerHealth{
+		LatestHandledBlock: latestHandledBlock,
+		LastVerifiedHeight: is.verifier.state.LastVerifiedHeight,
+		Gaps:               heightRanges(is.verifier.state.Missing),
+		PartialHeights:     append([]int64{}, is.verifier.state.Partial...),
+		RepairBacklog:      len(mergeHeights(is.verifier.state.Missing, is.verifier.state.Partial)),
+		LastRun:            is.verifier.lastRun,
+	}
+
+	if is.verifier.lastErr != nil {
+		report.LastError = is.verifier.lastErr.Error()
+	}
+
+	return report, nil
+}
+
+func (is *InternalService) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	report, err := is.health()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = jsoniter.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	_ = jsoniter.NewEncoder(w).Encode(report)
+}
+
+func (is *InternalService) loadVerifierState() (*model.VerifierState, error) {
+	fileBytes, err := os.ReadFile(filePathVerifierState)
+	if err != nil {
+		return nil, err
+	}
+
+	state := new(model.VerifierState)
+	err = jsoniter.Unmarshal(fileBytes, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (is *InternalService) saveVerifierState(state *model.VerifierState) error {
+	fileBytes, err := jsoniter.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePathVerifierState, fileBytes, os.ModePerm)
+}
+
+// readLatestHandledBlock reads the live cursor from its file rather than
+// is.currentBlock, which the indexing loop updates without locking.
+func readLatestHandledBlock() (int64, error) {
+	fileBytes, err := os.ReadFile(filePathLatestBlock)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(fileBytes)), 10, 64)
+}
+
+// mergeHeights returns the sorted union of a and b without duplicates.
+func mergeHeights(a, b []int64) []int64 {
+	seen := make(map[int64]struct{}, len(a)+len(b))
+	var merged []int64
+	for _, heights := range [][]int64{a, b} {
+		for _, height := range heights {
+			if _, ok := seen[height]; ok {
+				continue
+			}
+			seen[height] = struct{}{}
+			merged = append(merged, height)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+
+	return merged
+}
+
+func removeHeight(heights []int64, height int64) []int64 {
+	var result []int64
+	for _, h := range heights {
+		if h != height {
+			result = append(result, h)
+		}
+	}
+
+	return result
+}
+
+// heightRanges folds sorted heights into contiguous ranges.
+func heightRanges(heights []int64) []model.HeightRange {
+	var ranges []model.HeightRange
+	for _, height := range heights {
+		if n := len(ranges); n > 0 && ranges[n-1].To+1 == height {
+			ranges[n-1].To = height
+			continue
+		}
+		ranges = append(ranges, model.HeightRange{From: height, To: height})
+	}
+
+	return ranges
+}
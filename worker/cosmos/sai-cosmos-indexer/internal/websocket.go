@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	cmtjson "github.com/cometbft/cometbft/libs/json"
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+
+	"github.com/KiraCore/saiCosmosIndexer/internal/model"
+	"github.com/KiraCore/saiCosmosIndexer/logger"
+)
+
+const (
+	websocketSubscriber = "saiCosmosIndexer"
+	websocketCapacity   = 100
+)
+
+var errWebsocketStalled = errors.New("no new block received over websocket")
+
+// liveBlock collects the events of one height until the block is complete.
+type liveBlock struct {
+	block *model.BlockInfo
+	txs   []model.Tx
+	// expected is the number of tx events the block should produce,
+	// -1 when tx_type filters them and the count is unknown
+	expected int
+}
+
+func (b *liveBlock) complete() bool {
+	return b.block != nil && b.expected >= 0 && len(b.txs) == b.expected
+}
+
+// processWebsocket follows the chain tip through CometBFT NewBlock and Tx
+// events. A height is indexed as soon as all its tx events arrived, or when the
+// next block shows up. Heights skipped by the event stream are fetched over RPC
+// before it, so is.currentBlock still only moves one height at a time.
+func (is *InternalService) processWebsocket() error {
+	client, err := rpchttp.New(is.config.NodeAddress, "/websocket")
+	if err != nil {
+		return err
+	}
+
+	if err := client.Start(); err != nil {
+		return err
+	}
+	defer client.Stop()
+
+	ctx, cancel := context.WithCancel(is.Context.Context)
+	defer cancel()
+
+	blockEvents, err := client.Subscribe(ctx, websocketSubscriber, cmttypes.EventQueryNewBlock.String(), websocketCapacity)
+	if err != nil {
+		return err
+	}
+	defer client.UnsubscribeAll(context.Background(), websocketSubscriber)
+
+	txEvents, err := client.Subscribe(ctx, websocketSubscriber, is.txEventsQuery(), websocketCapacity)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Info("processWebsocket subscribed", zap.Int64("from", is.currentBlock))
+
+	pending := make(map[int64]*liveBlock)
+	stallTimer := time.NewTimer(is.config.WebsocketTimeout)
+	defer stallTimer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-stallTimer.C:
+			return errWebsocketStalled
+		case event := <-blockEvents:
+			data, ok := event.Data.(cmttypes.EventDataNewBlock)
+			if !ok || data.Block == nil {
+				continue
+			}
+
+			stallTimer.Reset(is.config.WebsocketTimeout)
+
+			blockInfo, err := blockInfoFromEvent(data.Block)
+			if err != nil {
+				return err
+			}
+
+			height := data.Block.Height
+			live := pendingBlock(pending, height)
+			live.block = blockInfo
+			if is.config.TxType == "" {
+				live.expected = len(data.Block.Data.Txs)
+			}
+
+			// a new block means no more tx events for the previous heights
+			if err := is.flushLiveBlocks(pending, height-1); err != nil {
+				return err
+			}
+
+			if live.complete() {
+				if err := is.flushLiveBlocks(pending, height); err != nil {
+					return err
+				}
+			}
+		case event := <-txEvents:
+			data, ok := event.Data.(cmttypes.EventDataTx)
+			if !ok || data.Height < is.currentBlock {
+				continue
+			}
+
+			tx, err := txFromEvent(data.TxResult)
+			if err != nil {
+				return err
+			}
+
+			live := pendingBlock(pending, data.Height)
+			live.txs = append(live.txs, tx)
+
+			if live.complete() {
+				if err := is.flushLiveBlocks(pending, data.Height); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// flushLiveBlocks indexes every pending height up to and including maxHeight in
+// order. Heights the events did not fully cover are re-fetched over RPC.
+func (is *InternalService) flushLiveBlocks(pending map[int64]*liveBlock, maxHeight int64) error {
+	heights := make([]int64, 0, len(pending))
+	for height := range pending {
+		if height <= maxHeight {
+			heights = append(heights, height)
+		}
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	for _, height := range heights {
+		live := pending[height]
+		delete(pending, height)
+
+		if height < is.currentBlock {
+			continue
+		}
+
+		// fill heights the event stream skipped, e.g. after a reconnect
+		for is.currentBlock < height {
+			if err := is.handleBlockTxs(); err != nil {
+				return err
+			}
+			is.currentBlock += 1
+		}
+
+		var err error
+		if live.block != nil && (live.expected < 0 || len(live.txs) == live.expected) {
+			sort.Slice(live.txs, func(i, j int) bool { return live.txs[i].Index < live.txs[j].Index })
+			err = is.indexBlock(live.block, live.txs)
+		} else {
+			err = is.handleBlockTxs()
+		}
+		if err != nil {
+			return err
+		}
+
+		logger.Logger.Debug("processWebsocket processed", zap.Any("block", is.currentBlock))
+
+		is.currentBlock += 1
+	}
+
+	return nil
+}
+
+func (is *InternalService) txEventsQuery() string {
+	if is.config.TxType != "" {
+		return fmt.Sprintf("%s AND message.action='%s'", cmttypes.EventQueryTx.String(), is.config.TxType)
+	}
+
+	return cmttypes.EventQueryTx.String()
+}
+
+func pendingBlock(pending map[int64]*liveBlock, height int64) *liveBlock {
+	live, ok := pending[height]
+	if !ok {
+		live = &liveBlock{expected: -1}
+		pending[height] = live
+	}
+
+	return live
+}
+
+// blockInfoFromEvent renders the block the same way the /block RPC does.
+func blockInfoFromEvent(block *cmttypes.Block) (*model.BlockInfo, error) {
+	partSet, err := block.MakePartSet(cmttypes.BlockPartSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := cmtjson.Marshal(&ctypes.ResultBlock{
+		BlockID: cmttypes.BlockID{Hash: block.Hash(), PartSetHeader: partSet.Header()},
+		Block:   block,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var blockInfo = new(model.BlockInfo)
+	err = jsoniter.Unmarshal(res, blockInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockInfo, nil
+}
+
+// txFromEvent renders the tx the same way the /tx_search RPC does.
+func txFromEvent(txResult abci.TxResult) (model.Tx, error) {
+	var tx model.Tx
+
+	res, err := cmtjson.Marshal(&ctypes.ResultTx{
+		Hash:     cmttypes.Tx(txResult.Tx).Hash(),
+		Height:   txResult.Height,
+		Index:    txResult.Index,
+		TxResult: txResult.Result,
+		Tx:       txResult.Tx,
+	})
+	if err != nil {
+		return tx, err
+	}
+
+	err = jsoniter.Unmarshal(res, &tx)
+
+	return tx, err
+}
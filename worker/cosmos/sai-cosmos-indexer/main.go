@@ -1,6 +1,8 @@
 package main
 
 import (
+	"net/http"
+
 	saiService "github.com/KiraCore/sai-service/service"
 	"github.com/KiraCore/saiCosmosIndexer/internal"
 	"github.com/KiraCore/saiCosmosIndexer/logger"
@@ -18,11 +20,15 @@ func main() {
 
 	svc.RegisterTasks([]func(){
 		is.Process,
+		is.ProcessVerifier,
 	})
 
 	svc.RegisterHandlers(
 		is.NewHandler(),
 	)
 
+	// served by the sai-service http server next to /check
+	http.HandleFunc("/indexer/health", is.HealthHandler)
+
 	svc.Start()
 }